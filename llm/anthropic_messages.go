@@ -0,0 +1,271 @@
+package llm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// UnmarshalAnthropicMessages builds a Conversation from a logged Anthropic
+// Messages API request body and, if resp is non-nil, its response body,
+// for replaying historic traffic through a Client.
+//
+// The response's content becomes a final assistant message and its usage
+// the conversation's Usage. User messages holding tool_result blocks become
+// RoleTool messages, which every provider sends back as a user turn.
+// An enabled thinking budget becomes the nearest ReasoningEffort.
+// Thinking blocks keep their signatures, and cache_control markers on
+// message blocks become ContentCachePoint parts after the block; redacted
+// thinking, documents, and markers on system blocks and tools are dropped.
+func UnmarshalAnthropicMessages(req, resp []byte) (Conversation, error) {
+	var r struct {
+		Model         string             `json:"model"`
+		System        json.RawMessage    `json:"system"`
+		Messages      []anthropicMessage `json:"messages"`
+		Tools         []anthropicTool    `json:"tools"`
+		ToolChoice    *anthropicChoice   `json:"tool_choice"`
+		MaxTokens     *int               `json:"max_tokens"`
+		Temperature   *float64           `json:"temperature"`
+		TopP          *float64           `json:"top_p"`
+		StopSequences []string           `json:"stop_sequences"`
+		Thinking      *struct {
+			Type         string `json:"type"`
+			BudgetTokens int    `json:"budget_tokens"`
+		} `json:"thinking"`
+	}
+	if err := json.Unmarshal(req, &r); err != nil {
+		return Conversation{}, fmt.Errorf("parsing Anthropic request: %w", err)
+	}
+
+	conv := Conversation{Model: r.Model}
+	conv.Config = Config{MaxTokens: r.MaxTokens, Temperature: r.Temperature, TopP: r.TopP, StopSequences: r.StopSequences}
+	if th := r.Thinking; th != nil && th.Type == "enabled" {
+		conv.Config.ReasoningEffort = anthropicEffort(th.BudgetTokens)
+	}
+	if len(r.System) > 0 && string(r.System) != "null" {
+		blocks, err := anthropicBlocks(r.System)
+		if err != nil {
+			return Conversation{}, fmt.Errorf("parsing Anthropic request: system: %w", err)
+		}
+		for _, b := range blocks {
+			if b.Type == "text" {
+				conv.System = append(conv.System, b.Text)
+			}
+		}
+	}
+	for _, t := range r.Tools {
+		conv.Tools = append(conv.Tools, ToolDefinition{Name: t.Name, Description: t.Description, Parameters: t.InputSchema})
+	}
+	if tc := r.ToolChoice; tc != nil {
+		switch tc.Type {
+		case "auto":
+			conv.Config.ToolChoice = &ToolChoice{Mode: ToolChoiceAuto}
+		case "any":
+			conv.Config.ToolChoice = &ToolChoice{Mode: ToolChoiceRequired}
+		case "none":
+			conv.Config.ToolChoice = &ToolChoice{Mode: ToolChoiceNone}
+		case "tool":
+			conv.Config.ToolChoice = &ToolChoice{Mode: ToolChoiceNamed, ToolName: tc.Name}
+		default:
+			return Conversation{}, fmt.Errorf("parsing Anthropic request: unknown tool_choice type %q", tc.Type)
+		}
+	}
+
+	for i, am := range r.Messages {
+		m, err := am.message()
+		if err != nil {
+			return Conversation{}, fmt.Errorf("parsing Anthropic request: message %d: %w", i, err)
+		}
+		conv.Messages = append(conv.Messages, m)
+	}
+
+	if resp != nil {
+		var out struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+			Usage   struct {
+				InputTokens              int `json:"input_tokens"`
+				OutputTokens             int `json:"output_tokens"`
+				CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+				CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+			} `json:"usage"`
+		}
+		if err := json.Unmarshal(resp, &out); err != nil {
+			return Conversation{}, fmt.Errorf("parsing Anthropic response: %w", err)
+		}
+		m, err := anthropicMessage{Role: "assistant", Content: out.Content}.message()
+		if err != nil {
+			return Conversation{}, fmt.Errorf("parsing Anthropic response: %w", err)
+		}
+		conv.Messages = append(conv.Messages, m)
+		conv.Usage = Usage{
+			InputTokens:      out.Usage.InputTokens,
+			OutputTokens:     out.Usage.OutputTokens,
+			CacheReadTokens:  out.Usage.CacheReadInputTokens,
+			CacheWriteTokens: out.Usage.CacheCreationInputTokens,
+		}
+	}
+	return conv, nil
+}
+
+type anthropicMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"` // a string or an array of blocks
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	Source    *anthropicImage `json:"source"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"` // tool_result: a string or an array of blocks
+	IsError   bool            `json:"is_error"`
+	Thinking  string          `json:"thinking"`
+	Signature string          `json:"signature"`
+
+	CacheControl json.RawMessage `json:"cache_control"`
+}
+
+type anthropicImage struct {
+	Type      string `json:"type"` // "base64" or "url"
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+	URL       string `json:"url"`
+}
+
+// anthropicBlocks decodes content that is either a string or an array of
+// content blocks.
+func anthropicBlocks(raw json.RawMessage) ([]anthropicBlock, error) {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return []anthropicBlock{{Type: "text", Text: text}}, nil
+	}
+	var blocks []anthropicBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+func (am anthropicMessage) message() (Message, error) {
+	var m Message
+	switch am.Role {
+	case "user":
+		m.Role = RoleUser
+	case "assistant":
+		m.Role = RoleAssistant
+	default:
+		return Message{}, fmt.Errorf("unknown role %q", am.Role)
+	}
+	blocks, err := anthropicBlocks(am.Content)
+	if err != nil {
+		return Message{}, err
+	}
+
+	var results []string
+	for i, b := range blocks {
+		switch b.Type {
+		case "text":
+			m.Content = append(m.Content, ContentPart{Kind: ContentText, Text: b.Text})
+		case "image":
+			img, err := b.Source.image()
+			if err != nil {
+				return Message{}, fmt.Errorf("block %d: %w", i, err)
+			}
+			m.Content = append(m.Content, ContentPart{Kind: ContentImage, Image: img})
+		case "tool_use":
+			args := b.Input
+			if len(args) == 0 {
+				args = json.RawMessage(`{}`)
+			}
+			m.Content = append(m.Content, ContentPart{Kind: ContentToolCall, ToolCall: &ToolCallData{ID: b.ID, Name: b.Name, Arguments: args}})
+		case "tool_result":
+			tr, err := b.toolResult()
+			if err != nil {
+				return Message{}, fmt.Errorf("block %d: %w", i, err)
+			}
+			m.Content = append(m.Content, ContentPart{Kind: ContentToolResult, ToolResult: tr})
+			results = append(results, tr.ToolCallID)
+		case "thinking":
+			m.Content = append(m.Content, ContentPart{Kind: ContentThinking, Thinking: &ThinkingData{Text: b.Thinking, Signature: b.Signature}})
+		default:
+			continue
+		}
+		if len(b.CacheControl) > 0 && string(b.CacheControl) != "null" {
+			m.Content = append(m.Content, CachePointPart())
+		}
+	}
+	if len(results) > 0 {
+		m.Role = RoleTool
+		if len(results) == 1 {
+			m.ToolCallID = results[0]
+		}
+	}
+	return m, nil
+}
+
+func (b anthropicBlock) toolResult() (*ToolResultData, error) {
+	tr := &ToolResultData{ToolCallID: b.ToolUseID, IsError: b.IsError}
+	if len(b.Content) == 0 || string(b.Content) == "null" {
+		return tr, nil
+	}
+	blocks, err := anthropicBlocks(b.Content)
+	if err != nil {
+		return nil, fmt.Errorf("tool_result content: %w", err)
+	}
+	for _, c := range blocks {
+		switch c.Type {
+		case "text":
+			tr.Content += c.Text
+		case "image":
+			img, err := c.Source.image()
+			if err != nil {
+				return nil, err
+			}
+			tr.Images = append(tr.Images, *img)
+		}
+	}
+	return tr, nil
+}
+
+func (s *anthropicImage) image() (*ImageData, error) {
+	switch {
+	case s == nil:
+		return nil, fmt.Errorf("image without source")
+	case s.Type == "url":
+		return &ImageData{URL: s.URL}, nil
+	case s.Type == "base64":
+		data, err := base64.StdEncoding.DecodeString(s.Data)
+		if err != nil {
+			return nil, fmt.Errorf("image data: %w", err)
+		}
+		return &ImageData{Data: data, MediaType: s.MediaType}, nil
+	}
+	return nil, fmt.Errorf("unsupported image source type %q", s.Type)
+}
+
+// anthropicEffort returns the ReasoningEffort whose thinking budget is
+// nearest to budget.
+func anthropicEffort(budget int) ReasoningEffort {
+	switch {
+	case budget <= 2048:
+		return ReasoningEffortLow
+	case budget <= 8192:
+		return ReasoningEffortMedium
+	}
+	return ReasoningEffortHigh
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalAnthropicMessages(t *testing.T) {
+	req := `{
+		"model": "claude-sonnet-4-5",
+		"max_tokens": 8192,
+		"thinking": {"type": "enabled", "budget_tokens": 4000},
+		"system": [{"type": "text", "text": "be brief", "cache_control": {"type": "ephemeral"}}],
+		"tools": [{"name": "get_weather", "description": "Weather", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "any"},
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "text", "text": "Weather in this city?"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw=="}, "cache_control": {"type": "ephemeral"}}
+			]},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "need weather", "signature": "sig"},
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"location": "Paris"}},
+				{"type": "tool_use", "id": "toolu_2", "name": "get_weather", "input": {"location": "Lyon"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": "sunny"},
+				{"type": "tool_result", "tool_use_id": "toolu_2", "is_error": true, "content": [{"type": "text", "text": "unavailable"}]}
+			]}
+		]
+	}`
+	resp := `{
+		"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet-4-5",
+		"content": [{"type": "text", "text": "Paris is sunny."}],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 100, "output_tokens": 10, "cache_read_input_tokens": 80}
+	}`
+	conv, err := UnmarshalAnthropicMessages([]byte(req), []byte(resp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.Model != "claude-sonnet-4-5" || *conv.Config.MaxTokens != 8192 || conv.Config.ToolChoice.Mode != ToolChoiceRequired ||
+		conv.Config.ReasoningEffort != ReasoningEffortMedium {
+		t.Errorf("config = %+v", conv.Config)
+	}
+	if strings.Join(conv.System, "|") != "be brief" || len(conv.Tools) != 1 || string(conv.Tools[0].Parameters) != `{"type": "object"}` {
+		t.Errorf("system = %q, tools = %+v", conv.System, conv.Tools)
+	}
+	if len(conv.Messages) != 4 {
+		t.Fatalf("messages = %+v", conv.Messages)
+	}
+	if img := conv.Messages[0].Content[1].Image; img.MediaType != "image/png" || len(img.Data) != 4 {
+		t.Errorf("image = %+v", img)
+	}
+	if parts := conv.Messages[0].Content; len(parts) != 3 || parts[2].Kind != ContentCachePoint {
+		t.Errorf("want a cache point after the image, got %+v", parts)
+	}
+	assistant := conv.Messages[1]
+	if th := assistant.Content[0].Thinking; th.Text != "need weather" || th.Signature != "sig" {
+		t.Errorf("thinking = %+v", th)
+	}
+	if calls := assistant.ToolCalls(); len(calls) != 2 || string(calls[0].Arguments) != `{"location": "Paris"}` {
+		t.Errorf("calls = %+v", calls)
+	}
+	results := conv.Messages[2]
+	if results.Role != RoleTool || len(results.Content) != 2 {
+		t.Fatalf("results = %+v", results)
+	}
+	if tr := results.Content[1].ToolResult; tr.ToolCallID != "toolu_2" || !tr.IsError || tr.Content != "unavailable" {
+		t.Errorf("tool result = %+v", tr)
+	}
+	if conv.Messages[3].Text() != "Paris is sunny." || conv.Usage.CacheReadTokens != 80 || conv.Usage.InputTokens != 100 {
+		t.Errorf("reply = %+v, usage = %+v", conv.Messages[3], conv.Usage)
+	}
+	if err := conv.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestUnmarshalAnthropicMessages_StringContent(t *testing.T) {
+	conv, err := UnmarshalAnthropicMessages([]byte(`{"model":"m","system":"be brief","messages":[{"role":"user","content":"hi"}]}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.System[0] != "be brief" || conv.Messages[0].Text() != "hi" || len(conv.Messages) != 1 {
+		t.Errorf("conv = %+v", conv)
+	}
+
+	for _, req := range []string{
+		`{"messages":[{"role":"system","content":"x"}]}`,
+		`{"messages":[{"role":"user","content":[{"type":"image","source":{"type":"file","file_id":"f"}}]}]}`,
+		`{"tool_choice":{"type":"maybe"},"messages":[]}`,
+		`{"messages":[{"role":"user","content":7}]}`,
+	} {
+		if _, err := UnmarshalAnthropicMessages([]byte(req), nil); err == nil {
+			t.Errorf("UnmarshalAnthropicMessages(%s) succeeded", req)
+		}
+	}
+}
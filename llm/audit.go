@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// ToolAudit records one tool invocation made by a Runner.
+type ToolAudit struct {
+	Time       time.Time       `json:"time"`
+	Turn       int             `json:"turn"` // model call that requested the tool, starting at 1
+	CallID     string          `json:"call_id"`
+	Name       string          `json:"name"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	Duration   time.Duration   `json:"duration"`
+	ResultSize int             `json:"result_size"` // bytes, before any ResultLimit
+	Error      string          `json:"error,omitempty"`
+}
+
+// AuditSink receives tool audit records. Implementations must be safe for
+// concurrent use.
+type AuditSink interface {
+	Audit(ToolAudit)
+}
+
+// AuditFunc adapts a function to AuditSink.
+type AuditFunc func(ToolAudit)
+
+func (f AuditFunc) Audit(a ToolAudit) { f(a) }
+
+// NewAuditWriter returns a sink that writes each record to w as a line of
+// JSON.
+func NewAuditWriter(w io.Writer) AuditSink {
+	return &auditWriter{w: w}
+}
+
+type auditWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (aw *auditWriter) Audit(a ToolAudit) {
+	line, err := json.Marshal(a)
+	if err != nil {
+		return
+	}
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	aw.w.Write(append(line, '\n'))
+}
+
+// WithAuditSink makes the Runner record every tool invocation to sink,
+// including rejected and failed ones, so an agent's actions can be
+// reconstructed afterwards.
+func WithAuditSink(sink AuditSink) RunnerOption {
+	return func(r *Runner) {
+		r.audit = sink
+	}
+}
+
+func newToolAudit(turn int, tc ToolCallData, res *ToolResultData, start time.Time) ToolAudit {
+	a := ToolAudit{
+		Time:       start,
+		Turn:       turn,
+		CallID:     tc.ID,
+		Name:       tc.Name,
+		Duration:   time.Since(start),
+		ResultSize: len(res.Text()),
+	}
+	if json.Valid(tc.Arguments) {
+		a.Arguments = tc.Arguments
+	} else if len(tc.Arguments) > 0 {
+		a.Arguments, _ = json.Marshal(string(tc.Arguments)) // keep malformed arguments as a string
+	}
+	if res.IsError {
+		a.Error = res.Content
+	}
+	return a
+}
@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunner_AuditSink(t *testing.T) {
+	provider := &scriptedProvider{responses: []*Response{
+		toolUseResponse(
+			ToolCallData{ID: "c1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)},
+			ToolCallData{ID: "c2", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Atlantis"}`)},
+		),
+		toolUseResponse(ToolCallData{ID: "c3", Name: "get_weather", Arguments: json.RawMessage(`{broken`)}),
+		simpleResponse("done"),
+	}}
+	var records []ToolAudit
+	runner := NewRunner(NewClientWithProvider(provider), weatherRegistry(),
+		WithAuditSink(AuditFunc(func(a ToolAudit) { records = append(records, a) })))
+
+	if _, _, err := runner.Run(context.Background(), NewConversation("model"), UserMessage("Weather?")); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("records = %d, want 3", len(records))
+	}
+	if r := records[0]; r.Turn != 1 || r.CallID != "c1" || r.Name != "get_weather" || r.Error != "" || r.ResultSize != len(`{"location":"Paris","temp":15}`) {
+		t.Errorf("first record = %+v", r)
+	}
+	if r := records[1]; r.Error != "unknown location" {
+		t.Errorf("second record = %+v", r)
+	}
+	if r := records[2]; r.Turn != 2 || string(r.Arguments) != `"{broken"` || !strings.HasPrefix(r.Error, "invalid arguments") {
+		t.Errorf("third record = %+v", r)
+	}
+}
+
+func TestNewAuditWriter(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewAuditWriter(&buf)
+	sink.Audit(ToolAudit{CallID: "c1", Name: "f", Arguments: json.RawMessage(`{"a":1}`)})
+	sink.Audit(ToolAudit{CallID: "c2", Name: "g", Error: "boom"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	var got ToolAudit
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.CallID != "c1" || string(got.Arguments) != `{"a":1}` {
+		t.Errorf("decoded = %+v", got)
+	}
+}
@@ -0,0 +1,193 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+// PoolStrategy selects which BedrockPool member serves a request.
+type PoolStrategy int
+
+const (
+	PoolRoundRobin   PoolStrategy = iota // rotate through healthy members
+	PoolLeastLatency                     // prefer the member with the lowest average latency
+)
+
+// BedrockPoolOption configures a BedrockPool.
+type BedrockPoolOption func(*BedrockPool)
+
+// WithPoolStrategy sets the member selection strategy. The default is
+// PoolRoundRobin.
+func WithPoolStrategy(s PoolStrategy) BedrockPoolOption {
+	return func(p *BedrockPool) {
+		p.strategy = s
+	}
+}
+
+// WithPoolHealth sets how many consecutive throttling or server errors take
+// a member out of rotation, and for how long. The defaults are 3 and 30s.
+func WithPoolHealth(threshold int, cooldown time.Duration) BedrockPoolOption {
+	return func(p *BedrockPool) {
+		p.threshold = threshold
+		p.cooldown = cooldown
+	}
+}
+
+// BedrockPool is a BedrockConverser that spreads requests across several
+// bedrockruntime clients, typically one per region. Members that keep
+// failing with throttling or server errors are skipped until their cooldown
+// expires. The pool does not retry a failed call on another member itself;
+// combine it with WithRetry so the next attempt lands elsewhere.
+type BedrockPool struct {
+	mu        sync.Mutex
+	members   []*poolMember
+	strategy  PoolStrategy
+	threshold int
+	cooldown  time.Duration
+	next      int
+}
+
+type poolMember struct {
+	client    BedrockConverser
+	latency   time.Duration // moving average of successful calls; zero until sampled
+	failures  int
+	downUntil time.Time
+}
+
+// latencyWeight is the weight of the newest sample in a member's moving
+// average latency.
+const latencyWeight = 0.2
+
+// NewBedrockPool creates a pool over clients. Pass it to NewClient or
+// NewBedrockProvider like a single bedrockruntime client.
+func NewBedrockPool(clients []BedrockConverser, opts ...BedrockPoolOption) *BedrockPool {
+	p := &BedrockPool{threshold: 3, cooldown: 30 * time.Second}
+	for _, c := range clients {
+		p.members = append(p.members, &poolMember{client: c})
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// Converse sends the request to the member chosen by the pool's strategy.
+func (p *BedrockPool) Converse(ctx context.Context, params *bedrockruntime.ConverseInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error) {
+	m := p.pick(time.Now())
+	if m == nil {
+		return nil, &Error{Kind: ErrConfig, Message: "bedrock pool has no clients"}
+	}
+
+	start := time.Now()
+	output, err := m.client.Converse(ctx, params, optFns...)
+	if err == nil || ctx.Err() == nil {
+		// A request the caller canceled or let expire says nothing about
+		// the member.
+		p.record(m, err, time.Since(start))
+	}
+	return output, err
+}
+
+// Healthy returns the number of members currently in rotation.
+func (p *BedrockPool) Healthy() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	n := 0
+	for _, m := range p.members {
+		if !now.Before(m.downUntil) {
+			n++
+		}
+	}
+	return n
+}
+
+func (p *BedrockPool) pick(now time.Time) *poolMember {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.members) == 0 {
+		return nil
+	}
+
+	var best *poolMember
+	for i := range p.members {
+		idx := (p.next + i) % len(p.members)
+		m := p.members[idx]
+		if now.Before(m.downUntil) {
+			continue
+		}
+		if p.strategy == PoolRoundRobin {
+			p.next = idx + 1
+			return m
+		}
+		if best == nil || m.latency < best.latency {
+			best = m
+		}
+	}
+	if best != nil {
+		return best
+	}
+
+	// Every member is down: try the one that recovers soonest rather than
+	// failing without a call.
+	best = p.members[0]
+	for _, m := range p.members[1:] {
+		if m.downUntil.Before(best.downUntil) {
+			best = m
+		}
+	}
+	return best
+}
+
+func (p *BedrockPool) record(m *poolMember, err error, elapsed time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err == nil {
+		m.failures = 0
+		m.downUntil = time.Time{}
+		if m.latency == 0 {
+			m.latency = elapsed
+		} else {
+			m.latency += time.Duration(latencyWeight * float64(elapsed-m.latency))
+		}
+		return
+	}
+	if !isUnhealthy(err) {
+		return
+	}
+	m.failures++
+	if m.failures >= p.threshold {
+		m.downUntil = time.Now().Add(p.cooldown)
+	}
+}
+
+// isUnhealthy reports whether a Converse error reflects on the member
+// rather than the request.
+func isUnhealthy(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	return isRetryable(classifyBedrockError(err))
+}
+
+// BedrockModelOverride wraps client so every request uses modelID, such as
+// a regional inference profile ARN, in place of the conversation's model.
+// It lets a BedrockPool mix regions that need different model identifiers.
+func BedrockModelOverride(client BedrockConverser, modelID string) BedrockConverser {
+	return modelOverride{client: client, modelID: modelID}
+}
+
+type modelOverride struct {
+	client  BedrockConverser
+	modelID string
+}
+
+func (o modelOverride) Converse(ctx context.Context, params *bedrockruntime.ConverseInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error) {
+	input := *params
+	input.ModelId = &o.modelID
+	return o.client.Converse(ctx, &input, optFns...)
+}
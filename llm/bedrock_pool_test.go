@@ -0,0 +1,161 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// regionConverser records calls and answers after delay, or fails with err.
+type regionConverser struct {
+	name   string
+	delay  time.Duration
+	err    error
+	calls  int
+	models []string
+}
+
+func (r *regionConverser) Converse(_ context.Context, params *bedrockruntime.ConverseInput, _ ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error) {
+	r.calls++
+	r.models = append(r.models, derefStr(params.ModelId))
+	time.Sleep(r.delay)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return simpleConverseOutput(r.name), nil
+}
+
+func sendVia(t *testing.T, pool *BedrockPool) (string, error) {
+	t.Helper()
+	_, resp, err := NewClient(pool).Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	if err != nil {
+		return "", err
+	}
+	return resp.Message.Text(), nil
+}
+
+func TestBedrockPool_RoundRobin(t *testing.T) {
+	east := &regionConverser{name: "east"}
+	west := &regionConverser{name: "west"}
+	pool := NewBedrockPool([]BedrockConverser{east, west})
+
+	var got []string
+	for range 4 {
+		text, err := sendVia(t, pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, text)
+	}
+	want := []string{"east", "west", "east", "west"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestBedrockPool_LeastLatency(t *testing.T) {
+	slow := &regionConverser{name: "slow", delay: 20 * time.Millisecond}
+	fast := &regionConverser{name: "fast"}
+	pool := NewBedrockPool([]BedrockConverser{slow, fast}, WithPoolStrategy(PoolLeastLatency))
+
+	for range 5 {
+		if _, err := sendVia(t, pool); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Each member is sampled once, then the fast one takes the rest.
+	if slow.calls != 1 || fast.calls != 4 {
+		t.Errorf("slow = %d, fast = %d calls", slow.calls, fast.calls)
+	}
+}
+
+func TestBedrockPool_RemovesUnhealthyMembers(t *testing.T) {
+	bad := &regionConverser{name: "bad", err: &types.ThrottlingException{Message: strPtr("slow down")}}
+	good := &regionConverser{name: "good"}
+	pool := NewBedrockPool([]BedrockConverser{bad, good}, WithPoolHealth(1, time.Minute))
+
+	_, err := sendVia(t, pool)
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrRateLimit {
+		t.Fatalf("err = %v, want ErrRateLimit", err)
+	}
+	if pool.Healthy() != 1 {
+		t.Errorf("Healthy() = %d, want 1", pool.Healthy())
+	}
+	for range 3 {
+		if text, err := sendVia(t, pool); err != nil || text != "good" {
+			t.Fatalf("text = %q, err = %v", text, err)
+		}
+	}
+	if bad.calls != 1 {
+		t.Errorf("unhealthy member called %d times", bad.calls)
+	}
+}
+
+func TestBedrockPool_RequestErrorsKeepMemberHealthy(t *testing.T) {
+	member := &regionConverser{err: &types.ValidationException{Message: strPtr("bad input")}}
+	pool := NewBedrockPool([]BedrockConverser{member}, WithPoolHealth(1, time.Minute))
+
+	sendVia(t, pool)
+	if pool.Healthy() != 1 {
+		t.Error("validation errors should not mark a member unhealthy")
+	}
+}
+
+func TestBedrockPool_CallerContextKeepsMemberHealthy(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	for _, ctx := range []context.Context{canceled, expired} {
+		member := &regionConverser{err: fmt.Errorf("operation error Bedrock Runtime: Converse: %w", ctx.Err())}
+		pool := NewBedrockPool([]BedrockConverser{member}, WithPoolHealth(1, time.Minute))
+
+		pool.Converse(ctx, &bedrockruntime.ConverseInput{ModelId: strPtr("model")})
+		if pool.Healthy() != 1 {
+			t.Errorf("%v: the caller's context should not mark a member unhealthy", ctx.Err())
+		}
+	}
+}
+
+func TestBedrockPool_AllDownStillTries(t *testing.T) {
+	member := &regionConverser{err: &types.InternalServerException{Message: strPtr("boom")}}
+	pool := NewBedrockPool([]BedrockConverser{member}, WithPoolHealth(1, time.Minute))
+
+	sendVia(t, pool)
+	sendVia(t, pool)
+	if member.calls != 2 {
+		t.Errorf("calls = %d, want 2", member.calls)
+	}
+}
+
+func TestBedrockPool_Empty(t *testing.T) {
+	_, err := sendVia(t, NewBedrockPool(nil))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrConfig {
+		t.Fatalf("err = %v, want ErrConfig", err)
+	}
+}
+
+func TestBedrockModelOverride(t *testing.T) {
+	west := &regionConverser{name: "west"}
+	client := BedrockModelOverride(west, "arn:aws:bedrock:us-west-2:123:inference-profile/x")
+
+	input := &bedrockruntime.ConverseInput{ModelId: strPtr("model")}
+	if _, err := client.Converse(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+	if west.models[0] != "arn:aws:bedrock:us-west-2:123:inference-profile/x" {
+		t.Errorf("ModelId = %q", west.models[0])
+	}
+	if *input.ModelId != "model" {
+		t.Error("override mutated the caller's input")
+	}
+}
@@ -0,0 +1,312 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// BlobStore holds large content payloads outside of conversations, such
+// as in S3, so that serialized conversations stay small enough for
+// workflow payloads. References are opaque to this package.
+type BlobStore interface {
+	Put(ctx context.Context, data []byte) (ref string, err error)
+	Get(ctx context.Context, ref string) ([]byte, error)
+}
+
+// OffloadBlobs moves image, audio, and document data and tool result
+// content and JSON of at least minSize bytes from conv into store,
+// replacing them with references: ImageData.Ref, AudioData.Ref,
+// DocumentData.Ref, ToolResultData.ContentRef, and ToolResultData.JSONRef.
+// Messages and parts are copied rather than modified, so conversations
+// sharing them with conv are unaffected. A store failure is returned as an
+// *Error of the store's own kind, or ErrServer.
+func OffloadBlobs(ctx context.Context, conv *Conversation, store BlobStore, minSize int) error {
+	// put returns the reference for data, or "" if data stays inline.
+	put := func(data []byte) (string, error) {
+		if len(data) == 0 || len(data) < minSize {
+			return "", nil
+		}
+		ref, err := store.Put(ctx, data)
+		if err != nil {
+			return "", blobError("offloading blob", err)
+		}
+		return ref, nil
+	}
+
+	cloned := false
+	for i, m := range conv.Messages {
+		var content []ContentPart
+		for j, p := range m.Content {
+			changed, err := offloadPart(&p, put)
+			if err != nil {
+				return err
+			}
+			if changed {
+				if content == nil {
+					content = slices.Clone(m.Content)
+				}
+				content[j] = p
+			}
+		}
+		if content != nil {
+			if !cloned {
+				conv.Messages, cloned = slices.Clone(conv.Messages), true
+			}
+			conv.Messages[i].Content = content
+		}
+	}
+	return nil
+}
+
+// offloadPart replaces the payloads of p that put moves to the store with
+// references, copying whatever it changes, and reports whether it changed
+// anything.
+func offloadPart(p *ContentPart, put func([]byte) (string, error)) (bool, error) {
+	switch {
+	case p.Kind == ContentImage && p.Image != nil && p.Image.Ref == "":
+		ref, err := put(p.Image.Data)
+		if ref == "" || err != nil {
+			return false, err
+		}
+		img := *p.Image
+		img.Ref, img.Data = ref, nil
+		p.Image = &img
+	case p.Kind == ContentAudio && p.Audio != nil && p.Audio.Ref == "":
+		ref, err := put(p.Audio.Data)
+		if ref == "" || err != nil {
+			return false, err
+		}
+		audio := *p.Audio
+		audio.Ref, audio.Data = ref, nil
+		p.Audio = &audio
+	case p.Kind == ContentDocument && p.Document != nil && p.Document.Ref == "":
+		ref, err := put(p.Document.Data)
+		if ref == "" || err != nil {
+			return false, err
+		}
+		doc := *p.Document
+		doc.Ref, doc.Data = ref, nil
+		p.Document = &doc
+	case p.Kind == ContentToolResult && p.ToolResult != nil:
+		tr := *p.ToolResult
+		tr.Images = slices.Clone(tr.Images)
+		changed := false
+		for k, img := range tr.Images {
+			if img.Ref != "" {
+				continue
+			}
+			ref, err := put(img.Data)
+			if err != nil {
+				return false, err
+			}
+			if ref != "" {
+				tr.Images[k].Ref, tr.Images[k].Data, changed = ref, nil, true
+			}
+		}
+		if tr.ContentRef == "" {
+			ref, err := put([]byte(tr.Content))
+			if err != nil {
+				return false, err
+			}
+			if ref != "" {
+				tr.ContentRef, tr.Content, changed = ref, "", true
+			}
+		}
+		if tr.JSONRef == "" {
+			ref, err := put(tr.JSON)
+			if err != nil {
+				return false, err
+			}
+			if ref != "" {
+				tr.JSONRef, tr.JSON, changed = ref, nil, true
+			}
+		}
+		if !changed {
+			return false, nil
+		}
+		p.ToolResult = &tr
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// ResolveBlobs returns a copy of conv with every blob reference replaced by
+// its data from store. conv is not modified. A store failure is returned as
+// an *Error of the store's own kind, or ErrServer.
+func ResolveBlobs(ctx context.Context, conv Conversation, store BlobStore) (Conversation, error) {
+	get := func(ref string) ([]byte, error) {
+		data, err := store.Get(ctx, ref)
+		if err != nil {
+			return nil, blobError("resolving blob "+ref, err)
+		}
+		return data, nil
+	}
+
+	conv.Messages = slices.Clone(conv.Messages)
+	for i, m := range conv.Messages {
+		if !m.hasBlobRefs() {
+			continue
+		}
+		content := slices.Clone(m.Content)
+		for j, p := range content {
+			if p.Image != nil && p.Image.Ref != "" {
+				img := *p.Image
+				data, err := get(img.Ref)
+				if err != nil {
+					return conv, err
+				}
+				img.Data, img.Ref = data, ""
+				content[j].Image = &img
+			}
+			if p.Audio != nil && p.Audio.Ref != "" {
+				audio := *p.Audio
+				data, err := get(audio.Ref)
+				if err != nil {
+					return conv, err
+				}
+				audio.Data, audio.Ref = data, ""
+				content[j].Audio = &audio
+			}
+			if p.Document != nil && p.Document.Ref != "" {
+				doc := *p.Document
+				data, err := get(doc.Ref)
+				if err != nil {
+					return conv, err
+				}
+				doc.Data, doc.Ref = data, ""
+				content[j].Document = &doc
+			}
+			if p.ToolResult != nil {
+				tr := *p.ToolResult
+				tr.Images = slices.Clone(tr.Images)
+				for k, img := range tr.Images {
+					if img.Ref == "" {
+						continue
+					}
+					data, err := get(img.Ref)
+					if err != nil {
+						return conv, err
+					}
+					tr.Images[k].Data, tr.Images[k].Ref = data, ""
+				}
+				if tr.ContentRef != "" {
+					data, err := get(tr.ContentRef)
+					if err != nil {
+						return conv, err
+					}
+					tr.Content, tr.ContentRef = string(data), ""
+				}
+				if tr.JSONRef != "" {
+					data, err := get(tr.JSONRef)
+					if err != nil {
+						return conv, err
+					}
+					tr.JSON, tr.JSONRef = data, ""
+				}
+				content[j].ToolResult = &tr
+			}
+		}
+		conv.Messages[i].Content = content
+	}
+	return conv, nil
+}
+
+func (m Message) hasBlobRefs() bool {
+	return slices.ContainsFunc(m.Content, ContentPart.hasBlobRef)
+}
+
+func (p ContentPart) hasBlobRef() bool {
+	switch {
+	case p.Image != nil && p.Image.Ref != "",
+		p.Audio != nil && p.Audio.Ref != "",
+		p.Document != nil && p.Document.Ref != "":
+		return true
+	case p.ToolResult != nil:
+		tr := p.ToolResult
+		return tr.ContentRef != "" || tr.JSONRef != "" ||
+			slices.ContainsFunc(tr.Images, func(img ImageData) bool { return img.Ref != "" })
+	}
+	return false
+}
+
+// blobError wraps a BlobStore failure, keeping the kind of an *Error the
+// store returned and reporting any other failure as ErrServer.
+func blobError(msg string, err error) *Error {
+	kind := ErrServer
+	var llmErr *Error
+	if errors.As(err, &llmErr) {
+		kind = llmErr.Kind
+	}
+	return &Error{Kind: kind, Message: msg + ": " + err.Error(), Cause: err}
+}
+
+// WithBlobOffload adds a BlobOffload middleware to the client.
+func WithBlobOffload(store BlobStore, minSize int) ClientOption {
+	return WithMiddleware(BlobOffload(store, minSize))
+}
+
+// BlobOffload returns middleware that offloads payloads of at least minSize
+// bytes to store, as OffloadBlobs does, in the conversation returned by
+// Send, and resolves every reference in the request sent to the provider.
+// Register it after middleware that changes the conversation, since those
+// changes to the resolved request are not kept.
+func BlobOffload(store BlobStore, minSize int) Middleware {
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		if err := OffloadBlobs(ctx, conv, store, minSize); err != nil {
+			return nil, err
+		}
+		resolved, err := ResolveBlobs(ctx, *conv, store)
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, &resolved)
+	}
+}
+
+// MemoryBlobStore is a BlobStore that keeps payloads in memory, for tests
+// and single-process use. References are content hashes, so identical
+// payloads are stored once. It is safe for concurrent use.
+type MemoryBlobStore struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+// NewMemoryBlobStore creates an empty MemoryBlobStore.
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{blobs: make(map[string][]byte)}
+}
+
+// Put implements BlobStore.
+func (s *MemoryBlobStore) Put(_ context.Context, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	ref := "sha256:" + hex.EncodeToString(sum[:])
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.blobs[ref]; !ok {
+		s.blobs[ref] = slices.Clone(data)
+	}
+	return ref, nil
+}
+
+// Get implements BlobStore. An unknown reference fails with ErrNotFound.
+func (s *MemoryBlobStore) Get(_ context.Context, ref string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.blobs[ref]
+	if !ok {
+		return nil, &Error{Kind: ErrNotFound, Message: "blob " + ref + " not found"}
+	}
+	return slices.Clone(data), nil
+}
+
+// Len returns the number of stored blobs.
+func (s *MemoryBlobStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.blobs)
+}
@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func blobConversation() Conversation {
+	big := bytes.Repeat([]byte{0x89}, 100)
+	return Conversation{Model: "m", Messages: []Message{
+		{Role: RoleUser, Content: []ContentPart{
+			{Kind: ContentText, Text: "look"},
+			{Kind: ContentImage, Image: &ImageData{Data: big, MediaType: "image/png"}},
+			{Kind: ContentImage, Image: &ImageData{Data: []byte{1}, MediaType: "image/png"}},
+		}},
+		toolUseResponse(ToolCallData{ID: "c1", Name: "fetch"}).Message,
+		ToolCallData{ID: "c1"}.ImageResult(strings.Repeat("x", 100), ImageData{Data: big}),
+	}}
+}
+
+func TestOffloadBlobs(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryBlobStore()
+	orig := blobConversation()
+	conv := orig
+	conv.Messages = append([]Message(nil), orig.Messages...)
+	if err := OffloadBlobs(ctx, &conv, store, 50); err != nil {
+		t.Fatal(err)
+	}
+
+	img := conv.Messages[0].Content[1].Image
+	if img.Ref == "" || img.Data != nil || img.MediaType != "image/png" {
+		t.Errorf("large image = %+v", img)
+	}
+	if small := conv.Messages[0].Content[2].Image; small.Ref != "" || len(small.Data) != 1 {
+		t.Errorf("small image = %+v", small)
+	}
+	tr := conv.Messages[2].Content[0].ToolResult
+	if tr.ContentRef == "" || tr.Content != "" || tr.Images[0].Ref != img.Ref {
+		t.Errorf("tool result = %+v", tr)
+	}
+	if store.Len() != 2 {
+		t.Errorf("store holds %d blobs, want 2 (identical images stored once)", store.Len())
+	}
+	if !reflect.DeepEqual(orig, blobConversation()) {
+		t.Error("OffloadBlobs modified parts shared with the original conversation")
+	}
+	if err := conv.Validate(); err == nil || !strings.Contains(err.Error(), "ResolveBlobs") {
+		t.Errorf("Validate of offloaded conversation = %v", err)
+	}
+
+	// Offloading again stores nothing new.
+	if err := OffloadBlobs(ctx, &conv, store, 50); err != nil || store.Len() != 2 {
+		t.Errorf("second offload: %d blobs, %v", store.Len(), err)
+	}
+
+	resolved, err := ResolveBlobs(ctx, conv, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resolved, orig) {
+		t.Errorf("resolved = %+v\nwant %+v", resolved, orig)
+	}
+	if conv.Messages[0].Content[1].Image.Ref == "" {
+		t.Error("ResolveBlobs modified its argument")
+	}
+}
+
+func TestOffloadBlobs_AudioDocumentJSON(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryBlobStore()
+	big := bytes.Repeat([]byte{1}, 100)
+	result := ToolCallData{ID: "c1"}.Result("ok")
+	result.Content[0].ToolResult.JSON = json.RawMessage(`{"rows":"` + strings.Repeat("r", 100) + `"}`)
+	orig := Conversation{Messages: []Message{
+		{Role: RoleUser, Content: []ContentPart{
+			{Kind: ContentAudio, Audio: &AudioData{Data: big, MediaType: "audio/wav"}},
+			{Kind: ContentDocument, Document: &DocumentData{Name: "report", Data: big, MediaType: "application/pdf"}},
+		}},
+		result,
+	}}
+	conv := orig
+	if err := OffloadBlobs(ctx, &conv, store, 50); err != nil {
+		t.Fatal(err)
+	}
+	if a := conv.Messages[0].Content[0].Audio; a.Ref == "" || a.Data != nil {
+		t.Errorf("audio = %+v", a)
+	}
+	if d := conv.Messages[0].Content[1].Document; d.Ref == "" || d.Data != nil || d.Name != "report" {
+		t.Errorf("document = %+v", d)
+	}
+	if tr := conv.Messages[1].Content[0].ToolResult; tr.JSONRef == "" || tr.JSON != nil || tr.Content != "ok" {
+		t.Errorf("tool result = %+v", tr)
+	}
+	if orig.Messages[0].Content[0].Audio.Ref != "" || orig.Messages[1].Content[0].ToolResult.JSONRef != "" {
+		t.Error("OffloadBlobs modified messages shared with the original conversation")
+	}
+	if err := conv.Validate(); err == nil || !strings.Contains(err.Error(), "ResolveBlobs") {
+		t.Errorf("Validate of offloaded conversation = %v", err)
+	}
+
+	resolved, err := ResolveBlobs(ctx, conv, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resolved, orig) {
+		t.Errorf("resolved = %+v\nwant %+v", resolved, orig)
+	}
+}
+
+type failingBlobStore struct{}
+
+func (failingBlobStore) Put(context.Context, []byte) (string, error) {
+	return "", errors.New("bucket unavailable")
+}
+
+func (failingBlobStore) Get(context.Context, string) ([]byte, error) {
+	return nil, errors.New("bucket unavailable")
+}
+
+func TestBlobStoreErrors(t *testing.T) {
+	conv := blobConversation()
+	err := OffloadBlobs(context.Background(), &conv, failingBlobStore{}, 50)
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrServer || llmErr.Cause == nil {
+		t.Errorf("offload err = %v, want ErrServer with a cause", err)
+	}
+
+	conv = Conversation{Messages: []Message{{Role: RoleUser, Content: []ContentPart{
+		{Kind: ContentImage, Image: &ImageData{Ref: "sha256:x"}},
+	}}}}
+	_, err = ResolveBlobs(context.Background(), conv, failingBlobStore{})
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrServer || llmErr.Cause == nil {
+		t.Errorf("resolve err = %v, want ErrServer with a cause", err)
+	}
+}
+
+func TestResolveBlobs_MissingBlob(t *testing.T) {
+	conv := Conversation{Messages: []Message{{Role: RoleUser, Content: []ContentPart{
+		{Kind: ContentImage, Image: &ImageData{Ref: "sha256:gone"}},
+	}}}}
+	_, err := ResolveBlobs(context.Background(), conv, NewMemoryBlobStore())
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrNotFound {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestBlobOffload(t *testing.T) {
+	store := NewMemoryBlobStore()
+	p := &scriptedProvider{responses: []*Response{simpleResponse("a picture")}}
+	client := NewClientWithProvider(p, WithBlobOffload(store, 50))
+
+	conv := blobConversation()
+	msgs := conv.Messages
+	conv.Messages = nil
+	got, _, err := client.Send(context.Background(), conv, msgs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := p.received[0].Messages[0].Content[1].Image; len(sent.Data) != 100 || sent.Ref != "" {
+		t.Errorf("provider received image %+v, want resolved data", sent)
+	}
+	if kept := got.Messages[0].Content[1].Image; kept.Ref == "" || kept.Data != nil {
+		t.Errorf("returned conversation holds image %+v, want a reference", kept)
+	}
+	if len(msgs[0].Content[1].Image.Data) != 100 {
+		t.Error("caller's message was modified")
+	}
+	if got.Messages[3].Text() != "a picture" {
+		t.Errorf("reply = %q", got.Messages[3].Text())
+	}
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitBreakerPolicy configures CircuitBreaker. Zero fields take the
+// documented defaults.
+type CircuitBreakerPolicy struct {
+	FailureThreshold int           // consecutive failures that open the circuit; default 5
+	Cooldown         time.Duration // time open before a probe is allowed; default 30s
+
+	// Trips reports whether an error counts as a failure. The default
+	// counts *Error values of kind ErrRateLimit, ErrServer, ErrTimeout, or
+	// ErrCapacity; other errors show the model is reachable and count as
+	// successes.
+	Trips func(error) bool
+}
+
+// WithCircuitBreaker adds a CircuitBreaker middleware to the client.
+func WithCircuitBreaker(policy CircuitBreakerPolicy) ClientOption {
+	return WithMiddleware(CircuitBreaker(policy))
+}
+
+// CircuitBreaker returns middleware that tracks failures per model ID.
+// After FailureThreshold consecutive failures the circuit opens and sends
+// to that model fail with ErrCircuitOpen without calling the provider.
+// Once Cooldown has passed, a single probe send is let through: success
+// closes the circuit, failure reopens it for another Cooldown. A send that
+// fails after its context ends is not counted either way.
+func CircuitBreaker(policy CircuitBreakerPolicy) Middleware {
+	if policy.FailureThreshold <= 0 {
+		policy.FailureThreshold = 5
+	}
+	if policy.Cooldown <= 0 {
+		policy.Cooldown = 30 * time.Second
+	}
+	if policy.Trips == nil {
+		policy.Trips = isRetryable
+	}
+
+	var mu sync.Mutex
+	circuits := make(map[string]*circuit)
+
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		model := conv.Model
+
+		mu.Lock()
+		c, ok := circuits[model]
+		if !ok {
+			c = &circuit{}
+			circuits[model] = c
+		}
+		probe, err := c.admit(time.Now(), policy.Cooldown)
+		mu.Unlock()
+		if err != nil {
+			return nil, &Error{Kind: ErrCircuitOpen, Message: fmt.Sprintf("circuit open for model %s", model), Cause: err}
+		}
+
+		resp, err := next(ctx, conv)
+
+		mu.Lock()
+		if err != nil && ctx.Err() != nil {
+			// The caller gave up, which says nothing about the model; just
+			// free the probe slot for another send.
+			c.release(probe)
+		} else {
+			c.record(err, err != nil && policy.Trips(err), probe, time.Now(), policy.FailureThreshold)
+		}
+		mu.Unlock()
+		return resp, err
+	}
+}
+
+// circuit is the breaker state for one model. Callers hold the
+// middleware's mutex.
+type circuit struct {
+	failures int
+	openedAt time.Time // zero while closed
+	lastErr  error     // failure that opened the circuit
+	probing  bool
+}
+
+// admit reports whether a send may proceed and whether it is the probe.
+// A refused send gets the error that last opened the circuit.
+func (c *circuit) admit(now time.Time, cooldown time.Duration) (probe bool, err error) {
+	if c.openedAt.IsZero() {
+		return false, nil
+	}
+	if c.probing || now.Sub(c.openedAt) < cooldown {
+		return false, c.lastErr
+	}
+	c.probing = true
+	return true, nil
+}
+
+// release ends a send without recording its outcome.
+func (c *circuit) release(probe bool) {
+	if probe {
+		c.probing = false
+	}
+}
+
+func (c *circuit) record(err error, failed, probe bool, now time.Time, threshold int) {
+	c.release(probe)
+	if !failed {
+		c.failures = 0
+		c.openedAt = time.Time{}
+		c.lastErr = nil
+		return
+	}
+	c.failures++
+	if probe || c.failures >= threshold {
+		c.openedAt = now
+		c.lastErr = err
+	}
+}
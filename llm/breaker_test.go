@@ -0,0 +1,125 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_OpensAfterThreshold(t *testing.T) {
+	provider := &mockProvider{err: &Error{Kind: ErrServer, Message: "down"}}
+	client := NewClientWithProvider(provider, WithCircuitBreaker(CircuitBreakerPolicy{FailureThreshold: 2}))
+	conv := NewConversation("model")
+
+	for range 2 {
+		_, _, err := client.Send(context.Background(), conv, UserMessage("hi"))
+		var llmErr *Error
+		if !errors.As(err, &llmErr) || llmErr.Kind != ErrServer {
+			t.Fatalf("err = %v, want ErrServer", err)
+		}
+	}
+
+	_, _, err := client.Send(context.Background(), conv, UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrCircuitOpen {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if !errors.Is(err, provider.err) {
+		t.Error("ErrCircuitOpen should wrap the failure that opened the circuit")
+	}
+
+	// Other models have their own circuit.
+	_, _, err = client.Send(context.Background(), NewConversation("other"), UserMessage("hi"))
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrServer {
+		t.Fatalf("other model err = %v, want ErrServer", err)
+	}
+}
+
+func TestCircuitBreaker_ProbeClosesCircuit(t *testing.T) {
+	provider := &flakyProvider{errs: []error{&Error{Kind: ErrRateLimit}}}
+	client := NewClientWithProvider(provider, WithCircuitBreaker(CircuitBreakerPolicy{
+		FailureThreshold: 1,
+		Cooldown:         20 * time.Millisecond,
+	}))
+	conv := NewConversation("model")
+
+	if _, _, err := client.Send(context.Background(), conv, UserMessage("hi")); err == nil {
+		t.Fatal("expected first send to fail")
+	}
+	if _, _, err := client.Send(context.Background(), conv, UserMessage("hi")); err == nil {
+		t.Fatal("expected open circuit")
+	}
+	if provider.calls != 1 {
+		t.Fatalf("calls = %d, open circuit should not call the provider", provider.calls)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, _, err := client.Send(context.Background(), conv, UserMessage("hi")); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if _, _, err := client.Send(context.Background(), conv, UserMessage("hi")); err != nil {
+		t.Fatalf("after probe: %v", err)
+	}
+}
+
+func TestCircuitBreaker_FailedProbeReopens(t *testing.T) {
+	provider := &mockProvider{err: &Error{Kind: ErrServer}}
+	client := NewClientWithProvider(provider, WithCircuitBreaker(CircuitBreakerPolicy{
+		FailureThreshold: 1,
+		Cooldown:         20 * time.Millisecond,
+	}))
+	conv := NewConversation("model")
+
+	client.Send(context.Background(), conv, UserMessage("hi"))
+	time.Sleep(30 * time.Millisecond)
+
+	_, _, err := client.Send(context.Background(), conv, UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrServer {
+		t.Fatalf("probe err = %v, want ErrServer", err)
+	}
+	_, _, err = client.Send(context.Background(), conv, UserMessage("hi"))
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrCircuitOpen {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestCircuitBreaker_CanceledProbeRecordsNothing(t *testing.T) {
+	provider := &flakyProvider{errs: []error{&Error{Kind: ErrServer}, &Error{Kind: ErrServer}}}
+	client := NewClientWithProvider(provider, WithCircuitBreaker(CircuitBreakerPolicy{
+		FailureThreshold: 1,
+		Cooldown:         20 * time.Millisecond,
+	}))
+	conv := NewConversation("model")
+
+	client.Send(context.Background(), conv, UserMessage("hi"))
+	time.Sleep(30 * time.Millisecond)
+
+	// The probe's caller gives up, so its failure does not reopen the
+	// circuit and the next send may probe at once.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := client.Send(ctx, conv, UserMessage("hi")); err == nil {
+		t.Fatal("expected canceled probe to fail")
+	}
+	if _, _, err := client.Send(context.Background(), conv, UserMessage("hi")); err != nil {
+		t.Fatalf("next probe: %v", err)
+	}
+	if provider.calls != 3 {
+		t.Errorf("calls = %d, want 3", provider.calls)
+	}
+}
+
+func TestCircuitBreaker_IgnoresNonTrippingErrors(t *testing.T) {
+	provider := &mockProvider{err: &Error{Kind: ErrInvalidRequest}}
+	client := NewClientWithProvider(provider, WithCircuitBreaker(CircuitBreakerPolicy{FailureThreshold: 1}))
+
+	for range 3 {
+		_, _, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+		var llmErr *Error
+		if !errors.As(err, &llmErr) || llmErr.Kind != ErrInvalidRequest {
+			t.Fatalf("err = %v, want ErrInvalidRequest", err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BudgetPolicy caps spend. A zero cap is unlimited.
+type BudgetPolicy struct {
+	MaxCost   float64 // USD, estimated from the model table
+	MaxTokens int     // input plus output tokens
+
+	// PerConversation checks the conversation's own accumulated Usage
+	// instead of the totals kept per budget key.
+	PerConversation bool
+}
+
+type budgetKey struct{}
+
+// WithBudgetKey returns a context whose requests are charged to key, such
+// as a tenant or user ID. Requests without a key share the "" key.
+func WithBudgetKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, budgetKey{}, key)
+}
+
+// Budget enforces a BudgetPolicy. Once spend reaches a cap, further
+// requests fail with ErrBudgetExceeded without calling the provider; the
+// request that crosses the cap is allowed to complete. It is safe for
+// concurrent use and can be shared by several clients.
+type Budget struct {
+	policy BudgetPolicy
+	mu     sync.Mutex
+	spent  map[string]Spend
+}
+
+// Spend is the cost and token usage charged to a budget key.
+type Spend struct {
+	Cost   float64 `json:"cost"`
+	Tokens int     `json:"tokens"`
+}
+
+// NewBudget creates a Budget enforcing policy.
+func NewBudget(policy BudgetPolicy) *Budget {
+	return &Budget{policy: policy, spent: make(map[string]Spend)}
+}
+
+// WithBudget adds b's middleware to the client.
+func WithBudget(b *Budget) ClientOption {
+	return WithMiddleware(b.Middleware())
+}
+
+// Middleware returns middleware that rejects requests over budget and
+// charges completed ones.
+func (b *Budget) Middleware() Middleware {
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		key, _ := ctx.Value(budgetKey{}).(string)
+
+		var current Spend
+		if b.policy.PerConversation {
+			current = Spend{
+				Cost:   conv.Usage.Cost(conv.Model),
+				Tokens: conv.Usage.InputTokens + conv.Usage.OutputTokens,
+			}
+		} else {
+			current = b.Spent(key)
+		}
+		if err := b.check(key, current); err != nil {
+			return nil, err
+		}
+
+		resp, err := next(ctx, conv)
+		if err != nil {
+			return resp, err
+		}
+		model := resp.Model
+		if model == "" {
+			model = conv.Model
+		}
+		b.charge(key, Spend{
+			Cost:   resp.Usage.Cost(model),
+			Tokens: resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		})
+		return resp, nil
+	}
+}
+
+func (b *Budget) check(key string, s Spend) error {
+	var exceeded string
+	switch {
+	case b.policy.MaxCost > 0 && s.Cost >= b.policy.MaxCost:
+		exceeded = fmt.Sprintf("cost $%.4f of $%.4f", s.Cost, b.policy.MaxCost)
+	case b.policy.MaxTokens > 0 && s.Tokens >= b.policy.MaxTokens:
+		exceeded = fmt.Sprintf("%d of %d tokens", s.Tokens, b.policy.MaxTokens)
+	default:
+		return nil
+	}
+	scope := "conversation"
+	if !b.policy.PerConversation {
+		scope = fmt.Sprintf("key %q", key)
+	}
+	return &Error{Kind: ErrBudgetExceeded, Message: fmt.Sprintf("budget exceeded for %s: spent %s", scope, exceeded)}
+}
+
+func (b *Budget) charge(key string, s Spend) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	total := b.spent[key]
+	total.Cost += s.Cost
+	total.Tokens += s.Tokens
+	b.spent[key] = total
+}
+
+// Spent returns the spend charged to key.
+func (b *Budget) Spent(key string) Spend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent[key]
+}
+
+// Reset clears the spend charged to key, for example at the start of a
+// billing period.
+func (b *Budget) Reset(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.spent, key)
+}
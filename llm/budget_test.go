@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func isBudgetExceeded(err error) bool {
+	var llmErr *Error
+	return errors.As(err, &llmErr) && llmErr.Kind == ErrBudgetExceeded
+}
+
+func TestBudget_TokensPerKey(t *testing.T) {
+	provider := &countingProvider{resp: simpleResponse("ok")} // 15 tokens per call
+	budget := NewBudget(BudgetPolicy{MaxTokens: 30})
+	client := NewClientWithProvider(provider, WithBudget(budget))
+	acme := WithBudgetKey(context.Background(), "acme")
+
+	for range 2 {
+		if _, _, err := client.Send(acme, NewConversation("m"), UserMessage("hi")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, _, err := client.Send(acme, NewConversation("m"), UserMessage("hi"))
+	if !isBudgetExceeded(err) {
+		t.Fatalf("err = %v, want ErrBudgetExceeded", err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("calls = %d, want 2", provider.calls)
+	}
+	if got := budget.Spent("acme").Tokens; got != 30 {
+		t.Errorf("Spent = %d, want 30", got)
+	}
+
+	// Other keys have their own budget.
+	other := WithBudgetKey(context.Background(), "globex")
+	if _, _, err := client.Send(other, NewConversation("m"), UserMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+
+	budget.Reset("acme")
+	if _, _, err := client.Send(acme, NewConversation("m"), UserMessage("hi")); err != nil {
+		t.Fatalf("after Reset: %v", err)
+	}
+}
+
+func TestBudget_Cost(t *testing.T) {
+	provider := &countingProvider{resp: &Response{
+		Message: AssistantMessage("ok"),
+		Usage:   Usage{InputTokens: 1_000_000},
+	}}
+	client := NewClientWithProvider(provider, WithBudget(NewBudget(BudgetPolicy{MaxCost: 5})))
+
+	// $3 per call: the second call crosses the cap, the third is rejected.
+	for range 2 {
+		if _, _, err := client.Send(context.Background(), NewConversation(sonnet), UserMessage("hi")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := client.Send(context.Background(), NewConversation(sonnet), UserMessage("hi")); !isBudgetExceeded(err) {
+		t.Fatalf("err = %v, want ErrBudgetExceeded", err)
+	}
+}
+
+func TestBudget_PerConversation(t *testing.T) {
+	provider := &countingProvider{resp: simpleResponse("ok")}
+	client := NewClientWithProvider(provider, WithBudget(NewBudget(BudgetPolicy{MaxTokens: 20, PerConversation: true})))
+
+	conv, _, err := client.Send(context.Background(), NewConversation("m"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv, _, err = client.Send(context.Background(), conv, UserMessage("again"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.Send(context.Background(), conv, UserMessage("more")); !isBudgetExceeded(err) {
+		t.Fatalf("err = %v, want ErrBudgetExceeded", err)
+	}
+	// A fresh conversation starts with an empty budget.
+	if _, _, err := client.Send(context.Background(), NewConversation("m"), UserMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,185 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+// Capture is one provider call as sent on the wire: the exact request body
+// the provider marshaled and the raw response bytes it received.
+type Capture struct {
+	Time     time.Time `json:"time"`
+	Provider string    `json:"provider"`
+	Model    string    `json:"model"`
+	URL      string    `json:"url,omitempty"`
+	Status   int       `json:"status,omitempty"`
+	Request  []byte    `json:"request"`
+	Response []byte    `json:"response,omitempty"`
+	Error    string    `json:"error,omitempty"` // transport error, if no response arrived
+}
+
+// CaptureSink receives captures. Implementations must be safe for
+// concurrent use.
+type CaptureSink interface {
+	Capture(Capture)
+}
+
+// CaptureFunc adapts a function to CaptureSink.
+type CaptureFunc func(Capture)
+
+func (f CaptureFunc) Capture(c Capture) { f(c) }
+
+// NewCaptureWriter returns a sink that writes each capture to w as a line
+// of JSON, such as an *os.File or *bytes.Buffer. JSON bodies are embedded
+// as JSON rather than base64 so the output stays readable.
+func NewCaptureWriter(w io.Writer) CaptureSink {
+	return &captureWriter{w: w}
+}
+
+type captureWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (cw *captureWriter) Capture(c Capture) {
+	line, err := json.Marshal(struct {
+		Capture
+		Request  any `json:"request"`
+		Response any `json:"response,omitempty"`
+	}{c, readableBody(c.Request), readableBody(c.Response)})
+	if err != nil {
+		return
+	}
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	cw.w.Write(append(line, '\n'))
+}
+
+func readableBody(b []byte) any {
+	switch {
+	case len(b) == 0:
+		return nil
+	case json.Valid(b):
+		return json.RawMessage(b)
+	default:
+		return string(b)
+	}
+}
+
+type rawRequestKey struct{}
+
+// WithRawRequest returns a context that makes the built-in providers keep
+// the request they build on Response.RawRequest.
+func WithRawRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawRequestKey{}, true)
+}
+
+// rawRequest returns req if ctx asks for raw requests to be kept.
+func rawRequest(ctx context.Context, req any) any {
+	if keep, _ := ctx.Value(rawRequestKey{}).(bool); keep {
+		return req
+	}
+	return nil
+}
+
+type captureKey struct{}
+
+// WithDebugCapture adds a DebugCapture middleware to the client.
+func WithDebugCapture(sink CaptureSink) ClientOption {
+	return WithMiddleware(DebugCapture(sink))
+}
+
+// DebugCapture returns middleware that makes the built-in providers record
+// every call they make to sink, for troubleshooting request and response
+// translation. Captures hold full message content; use them in
+// development, not in production logs.
+func DebugCapture(sink CaptureSink) Middleware {
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		return next(context.WithValue(ctx, captureKey{}, sink), conv)
+	}
+}
+
+func captureSink(ctx context.Context) CaptureSink {
+	sink, _ := ctx.Value(captureKey{}).(CaptureSink)
+	return sink
+}
+
+// captureHTTP records a completed HTTP exchange if ctx carries a sink.
+func captureHTTP(ctx context.Context, provider, model, url string, status int, request, response []byte, err error) {
+	sink := captureSink(ctx)
+	if sink == nil {
+		return
+	}
+	c := Capture{
+		Time:     time.Now(),
+		Provider: provider,
+		Model:    model,
+		URL:      url,
+		Status:   status,
+		Request:  request,
+		Response: response,
+	}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	sink.Capture(c)
+}
+
+// captureBedrockOptions returns a Converse option that records the signed
+// HTTP exchange if ctx carries a sink.
+func captureBedrockOptions(ctx context.Context, model string) []func(*bedrockruntime.Options) {
+	if captureSink(ctx) == nil {
+		return nil
+	}
+	return []func(*bedrockruntime.Options){func(o *bedrockruntime.Options) {
+		o.HTTPClient = &captureHTTPClient{ctx: ctx, inner: o.HTTPClient, model: model}
+	}}
+}
+
+// captureHTTPClient wraps the Bedrock SDK's HTTP client and records each
+// request and response body, restoring them for the SDK to read.
+type captureHTTPClient struct {
+	ctx   context.Context
+	inner bedrockruntime.HTTPClient
+	model string
+}
+
+func (c *captureHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(reqBody)), nil
+		}
+	}
+
+	inner := c.inner
+	if inner == nil {
+		inner = http.DefaultClient
+	}
+	resp, err := inner.Do(req)
+	if err != nil {
+		captureHTTP(c.ctx, "bedrock", c.model, req.URL.String(), 0, reqBody, nil, err)
+		return resp, err
+	}
+
+	respBody, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	captureHTTP(c.ctx, "bedrock", c.model, req.URL.String(), resp.StatusCode, reqBody, respBody, readErr)
+	if readErr != nil {
+		// A partial body would fail to decode with a misleading error;
+		// report the read failure as the transport error it is.
+		return nil, readErr
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
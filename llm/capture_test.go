@@ -0,0 +1,170 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+// captureList collects captures in memory.
+type captureList struct {
+	mu       sync.Mutex
+	captures []Capture
+}
+
+func (l *captureList) Capture(c Capture) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.captures = append(l.captures, c)
+}
+
+func TestDebugCapture_OpenAI(t *testing.T) {
+	srv, received := newTestOpenAIServer(t, http.StatusOK, chatCompletionResponse{
+		Choices: []chatChoice{{Message: chatMessage{Role: "assistant", Content: textContent("Hello!")}, FinishReason: "stop"}},
+	})
+	sink := &captureList{}
+	client := NewClientWithProvider(NewOpenAIProvider(srv.URL), WithDebugCapture(sink))
+
+	if _, _, err := client.Send(context.Background(), NewConversation("gpt"), UserMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.captures) != 1 {
+		t.Fatalf("captures = %d, want 1", len(sink.captures))
+	}
+	c := sink.captures[0]
+	if c.Provider != "openai" || c.Model != "gpt" || c.Status != http.StatusOK {
+		t.Errorf("capture = %+v", c)
+	}
+	if !bytes.Equal(c.Request, *received) {
+		t.Errorf("Request = %s, server received %s", c.Request, *received)
+	}
+	if !strings.Contains(string(c.Response), "Hello!") {
+		t.Errorf("Response = %s", c.Response)
+	}
+}
+
+func TestDebugCapture_Bedrock(t *testing.T) {
+	var received []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"output":{"message":{"role":"assistant","content":[{"text":"Hi!"}]}},"stopReason":"end_turn","usage":{"inputTokens":3,"outputTokens":2,"totalTokens":5},"metrics":{"latencyMs":1}}`)
+	}))
+	defer srv.Close()
+
+	bedrock := bedrockruntime.New(bedrockruntime.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		}),
+	})
+	sink := &captureList{}
+	client := NewClient(bedrock, WithDebugCapture(sink))
+
+	_, resp, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Text() != "Hi!" {
+		t.Errorf("Text = %q; the SDK should still read the restored body", resp.Message.Text())
+	}
+	if len(sink.captures) != 1 {
+		t.Fatalf("captures = %d, want 1", len(sink.captures))
+	}
+	c := sink.captures[0]
+	if c.Provider != "bedrock" || !bytes.Equal(c.Request, received) {
+		t.Errorf("capture = %+v, server received %s", c, received)
+	}
+	if !strings.Contains(string(c.Response), `"Hi!"`) {
+		t.Errorf("Response = %s", c.Response)
+	}
+}
+
+// brokenBodyClient returns responses whose body fails partway through.
+type brokenBodyClient struct{}
+
+func (brokenBodyClient) Do(*http.Request) (*http.Response, error) {
+	body := io.MultiReader(strings.NewReader(`{"output":`), iotest.ErrReader(io.ErrUnexpectedEOF))
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(body)}, nil
+}
+
+func TestDebugCapture_BedrockReadError(t *testing.T) {
+	sink := &captureList{}
+	ctx := context.WithValue(context.Background(), captureKey{}, CaptureSink(sink))
+	c := &captureHTTPClient{ctx: ctx, inner: brokenBodyClient{}, model: "model"}
+	req := httptest.NewRequest(http.MethodPost, "https://bedrock.test/model/converse", strings.NewReader("{}"))
+
+	resp, err := c.Do(req)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || resp != nil {
+		t.Fatalf("Do = %v, %v, want the read error", resp, err)
+	}
+	if len(sink.captures) != 1 || sink.captures[0].Error == "" || string(sink.captures[0].Response) != `{"output":` {
+		t.Errorf("captures = %+v", sink.captures)
+	}
+}
+
+func TestResponse_Raw(t *testing.T) {
+	srv, received := newTestOpenAIServer(t, http.StatusOK, chatCompletionResponse{
+		Choices: []chatChoice{{Message: chatMessage{Role: "assistant", Content: textContent("Hello!")}, FinishReason: "stop"}},
+	})
+	client := NewClientWithProvider(NewOpenAIProvider(srv.URL))
+
+	_, resp, err := client.Send(context.Background(), NewConversation("gpt"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw, ok := resp.Raw.(json.RawMessage); !ok || !strings.Contains(string(raw), `"finish_reason":"stop"`) {
+		t.Errorf("Raw = %v", resp.Raw)
+	}
+	if resp.RawRequest != nil {
+		t.Errorf("RawRequest = %s, want nil without WithRawRequest", resp.RawRequest)
+	}
+
+	_, resp, err = client.Send(WithRawRequest(context.Background()), NewConversation("gpt"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw, ok := resp.RawRequest.(json.RawMessage); !ok || !bytes.Equal(raw, *received) {
+		t.Errorf("RawRequest = %s, server received %s", resp.RawRequest, *received)
+	}
+
+	out := simpleConverseOutput("Hi!")
+	bedrock := NewClient(&mockConverser{output: out})
+	_, resp, err = bedrock.Send(WithRawRequest(context.Background()), NewConversation("model"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, ok := resp.RawRequest.(*bedrockruntime.ConverseInput)
+	if resp.Raw != out || !ok || derefStr(input.ModelId) != "model" {
+		t.Errorf("Raw = %v, RawRequest = %v", resp.Raw, resp.RawRequest)
+	}
+}
+
+func TestCaptureWriter(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewCaptureWriter(&buf)
+	sink.Capture(Capture{Provider: "openai", Request: []byte(`{"model":"gpt"}`), Response: []byte("not json")})
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if req, ok := line["request"].(map[string]any); !ok || req["model"] != "gpt" {
+		t.Errorf("request = %v, want embedded JSON", line["request"])
+	}
+	if line["response"] != "not json" {
+		t.Errorf("response = %v", line["response"])
+	}
+}
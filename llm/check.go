@@ -0,0 +1,209 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Validate checks the conversation for mistakes that providers reject,
+// so they can be caught and fixed before a round trip fails:
+//
+//   - system messages in Messages, where providers expect System instead
+//   - a first message that is not from the user
+//   - consecutive messages from the same side; tool results count as the
+//     user's turn, and consecutive tool results are one turn
+//   - tool results that answer no call in the preceding assistant message
+//   - tool calls that are not answered before the next assistant message
+//   - messages with no content and content parts with no payload
+//
+// It returns an ErrInvalidRequest error listing every problem found, or
+// nil. A conversation ending in unanswered tool calls is reported too,
+// since it cannot be sent until the calls are answered.
+func (c Conversation) Validate() error {
+	return c.ValidateFor("")
+}
+
+// ValidateFor is Validate plus a check that every content part can be
+// sent to the named provider, within its ImageLimits: "bedrock", or a name
+// from the provider registry such as "openai" or "gemini". Unknown names
+// get only the provider-independent checks.
+func (c Conversation) ValidateFor(provider string) error {
+	var problems []error
+	report := func(i int, format string, args ...any) {
+		problems = append(problems, fmt.Errorf("message %d: "+format, append([]any{i}, args...)...))
+	}
+
+	support, known := providerContent[provider]
+	var (
+		lastSide  Role
+		pending   map[string]bool // calls awaiting results
+		pendingAt int
+	)
+	for i, m := range c.Messages {
+		side := m.Role
+		if side == RoleTool {
+			side = RoleUser
+		}
+		switch {
+		case m.Role == RoleSystem:
+			report(i, "system message in history; use the conversation's System instead")
+		case i == 0 && side != RoleUser:
+			report(i, "conversation must start with a user message, not %s", m.Role)
+		case i > 0 && side == lastSide && !(m.Role == RoleTool && c.Messages[i-1].Role == RoleTool):
+			report(i, "consecutive %s messages; roles must alternate", side)
+		}
+		lastSide = side
+
+		if m.Role != RoleTool && len(pending) > 0 {
+			report(pendingAt, "tool calls %s were not answered", strings.Join(slices.Sorted(maps.Keys(pending)), ", "))
+			pending = nil
+		}
+		if len(m.Content) == 0 {
+			report(i, "no content")
+		}
+		for j, p := range m.Content {
+			if err := checkPart(p); err != nil {
+				report(i, "part %d: %v", j, err)
+				continue
+			}
+			if known {
+				if err := support.check(p); err != nil {
+					report(i, "part %d: %s %v", j, provider, err)
+				}
+			}
+			switch p.Kind {
+			case ContentToolCall:
+				if m.Role != RoleAssistant {
+					report(i, "part %d: tool call in a %s message", j, m.Role)
+				}
+				if pending == nil {
+					pending, pendingAt = make(map[string]bool), i
+				}
+				pending[p.ToolCall.ID] = true
+			case ContentToolResult:
+				id := p.ToolResult.ToolCallID
+				if !pending[id] {
+					report(i, "tool result for %q answers no call in the preceding assistant message", id)
+				}
+				delete(pending, id)
+			}
+		}
+	}
+	if len(pending) > 0 {
+		report(pendingAt, "tool calls %s were not answered", strings.Join(slices.Sorted(maps.Keys(pending)), ", "))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(problems))
+	for i, p := range problems {
+		msgs[i] = p.Error()
+	}
+	return &Error{Kind: ErrInvalidRequest, Message: "invalid conversation: " + strings.Join(msgs, "; "), Cause: errors.Join(problems...)}
+}
+
+// contentSupport describes which content a provider can send.
+type contentSupport struct {
+	images     bool // image parts
+	imageURLs  bool // images given by URL rather than data
+	s3URLs     bool // images given by s3:// URL
+	toolImages bool // images in tool results
+	audio      bool // audio parts
+	documents  bool // document parts
+	limits     ImageLimits
+}
+
+func (s contentSupport) check(p ContentPart) error {
+	switch {
+	case p.Kind == ContentImage && !s.images:
+		return errors.New("does not support images")
+	case p.Kind == ContentImage && len(p.Image.Data) == 0 && !s.imageURLs && !(s.s3URLs && strings.HasPrefix(p.Image.URL, "s3://")):
+		return errors.New("cannot send images by URL; include the data")
+	case p.Kind == ContentAudio && !s.audio:
+		return errors.New("does not support audio")
+	case p.Kind == ContentDocument && !s.documents:
+		return errors.New("does not support documents")
+	case p.Kind == ContentToolResult && len(p.ToolResult.Images) > 0 && !s.toolImages:
+		return errors.New("does not support images in tool results")
+	case p.Kind == ContentImage:
+		return s.limits.check(*p.Image)
+	case p.Kind == ContentToolResult:
+		for k, img := range p.ToolResult.Images {
+			if err := s.limits.check(img); err != nil {
+				return fmt.Errorf("tool result image %d: %w", k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// providerContent lists what each built-in provider's request translation
+// can carry; anything else is dropped by the provider.
+var providerContent = map[string]contentSupport{
+	"bedrock": {images: true, s3URLs: true, toolImages: true, audio: true, documents: true, limits: ImageLimits{
+		MaxBytes: 3_750_000, MaxSide: 8000, MediaTypes: []string{"image/png", "image/jpeg", "image/gif", "image/webp"},
+	}},
+	"gemini": {images: true, imageURLs: true, toolImages: true, limits: ImageLimits{
+		MaxBytes: 20 << 20, MediaTypes: []string{"image/png", "image/jpeg", "image/webp", "image/heic", "image/heif"},
+	}},
+	"openai": {images: true, imageURLs: true, limits: ImageLimits{
+		MaxBytes: 20 << 20, MediaTypes: []string{"image/png", "image/jpeg", "image/gif", "image/webp"},
+	}},
+	"ollama":   {images: true, limits: ImageLimits{MediaTypes: []string{"image/png", "image/jpeg"}}},
+	"llamacpp": {images: true, limits: ImageLimits{MediaTypes: []string{"image/png", "image/jpeg"}}},
+	"deepseek": {},
+}
+
+// checkPart reports a part whose payload is missing or empty.
+func checkPart(p ContentPart) error {
+	switch p.Kind {
+	case ContentText:
+		if p.Text == "" {
+			return errors.New("empty text")
+		}
+	case ContentImage:
+		if p.Image != nil && p.Image.Ref != "" {
+			return errors.New("image data is in a blob store; resolve it with ResolveBlobs")
+		}
+		if p.Image == nil || (len(p.Image.Data) == 0 && p.Image.URL == "") {
+			return errors.New("image part without data or URL")
+		}
+	case ContentToolCall:
+		if p.ToolCall == nil || p.ToolCall.ID == "" || p.ToolCall.Name == "" {
+			return errors.New("tool call without an ID and name")
+		}
+	case ContentToolResult:
+		if p.ToolResult == nil || p.ToolResult.ToolCallID == "" {
+			return errors.New("tool result without a call ID")
+		}
+		if p.hasBlobRef() {
+			return errors.New("tool result content is in a blob store; resolve it with ResolveBlobs")
+		}
+	case ContentAudio:
+		if p.Audio != nil && p.Audio.Ref != "" {
+			return errors.New("audio data is in a blob store; resolve it with ResolveBlobs")
+		}
+		if p.Audio == nil || (len(p.Audio.Data) == 0 && !strings.HasPrefix(p.Audio.URL, "s3://")) {
+			return errors.New("audio part without data or an s3:// URL")
+		}
+	case ContentDocument:
+		if p.Document != nil && p.Document.Ref != "" {
+			return errors.New("document data is in a blob store; resolve it with ResolveBlobs")
+		}
+		if p.Document == nil || (len(p.Document.Data) == 0 && !strings.HasPrefix(p.Document.URL, "s3://")) {
+			return errors.New("document part without data or an s3:// URL")
+		}
+	case ContentThinking:
+		if p.Thinking == nil {
+			return errors.New("thinking part without data")
+		}
+	case ContentCachePoint:
+	default:
+		return fmt.Errorf("unknown content kind %q", p.Kind)
+	}
+	return nil
+}
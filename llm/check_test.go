@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConversation_Validate(t *testing.T) {
+	call := ToolCallData{ID: "c1", Name: "get_weather", Arguments: json.RawMessage(`{}`)}
+	callMsg := Message{Role: RoleAssistant, Content: []ContentPart{{Kind: ContentToolCall, ToolCall: &call}}}
+
+	tests := []struct {
+		name     string
+		messages []Message
+		want     string // substring of the error; "" for valid
+	}{
+		{"valid", []Message{UserMessage("hi"), callMsg, call.Result("sunny"), AssistantMessage("it's sunny")}, ""},
+		{"system in history", []Message{SystemMessage("be brief"), UserMessage("hi")}, "message 0: system message in history"},
+		{"starts with assistant", []Message{AssistantMessage("hi")}, "must start with a user message"},
+		{"consecutive assistant", []Message{UserMessage("hi"), AssistantMessage("a"), AssistantMessage("b")}, "message 2: consecutive assistant messages"},
+		{"consecutive user", []Message{UserMessage("hi"), callMsg, call.Result("sunny"), UserMessage("and?")}, "message 3: consecutive user messages"},
+		{"orphan result", []Message{UserMessage("hi"), AssistantMessage("a"), ToolResultMessage("c9", "x", false)}, `tool result for "c9" answers no call`},
+		{"unanswered call", []Message{UserMessage("hi"), callMsg, UserMessage("never mind")}, "message 1: tool calls c1 were not answered"},
+		{"trailing call", []Message{UserMessage("hi"), callMsg}, "tool calls c1 were not answered"},
+		{"empty text", []Message{UserMessage("")}, "message 0: part 0: empty text"},
+		{"no content", []Message{{Role: RoleUser}}, "message 0: no content"},
+		{"unknown kind", []Message{{Role: RoleUser, Content: []ContentPart{{Kind: "video"}}}}, `unknown content kind "video"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Conversation{Messages: tt.messages}.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v", err)
+				}
+				return
+			}
+			var e *Error
+			if !errors.As(err, &e) || e.Kind != ErrInvalidRequest {
+				t.Fatalf("Validate() = %v, want ErrInvalidRequest", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConversation_ValidateFor(t *testing.T) {
+	byURL := Message{Role: RoleUser, Content: []ContentPart{
+		{Kind: ContentText, Text: "what is this?"},
+		{Kind: ContentImage, Image: &ImageData{URL: "https://example.com/cat.png"}},
+	}}
+	conv := Conversation{Messages: []Message{byURL}}
+
+	if err := conv.ValidateFor("gemini"); err != nil {
+		t.Errorf("gemini: %v", err)
+	}
+	if err := conv.ValidateFor("openai"); err != nil {
+		t.Errorf("openai: %v", err)
+	}
+	if err := conv.ValidateFor("ollama"); err == nil || !strings.Contains(err.Error(), "ollama cannot send images by URL") {
+		t.Errorf("ollama: %v", err)
+	}
+	if err := conv.ValidateFor("deepseek"); err == nil || !strings.Contains(err.Error(), "deepseek does not support images") {
+		t.Errorf("deepseek: %v", err)
+	}
+	if err := conv.ValidateFor("bedrock"); err == nil || !strings.Contains(err.Error(), "bedrock cannot send images by URL") {
+		t.Errorf("bedrock: %v", err)
+	}
+	if err := conv.ValidateFor("custom"); err != nil {
+		t.Errorf("unknown provider: %v", err)
+	}
+
+	byURL.Content[1].Image.URL = "s3://bucket/a.png"
+	if err := conv.ValidateFor("bedrock"); err != nil {
+		t.Errorf("bedrock with an S3 URL: %v", err)
+	}
+
+	speech := Conversation{Messages: []Message{{Role: RoleUser, Content: []ContentPart{
+		{Kind: ContentAudio, Audio: &AudioData{Data: []byte("ID3"), MediaType: "audio/mpeg"}},
+	}}}}
+	if err := speech.ValidateFor("bedrock"); err != nil {
+		t.Errorf("bedrock with audio: %v", err)
+	}
+	if err := speech.ValidateFor("openai"); err == nil || !strings.Contains(err.Error(), "openai does not support audio") {
+		t.Errorf("openai with audio: %v", err)
+	}
+	doc := Conversation{Messages: []Message{{Role: RoleUser, Content: []ContentPart{DocumentPart("a.pdf", []byte("%PDF"), "application/pdf")}}}}
+	if err := doc.ValidateFor("bedrock"); err != nil {
+		t.Errorf("bedrock with a document: %v", err)
+	}
+	if err := doc.ValidateFor("gemini"); err == nil || !strings.Contains(err.Error(), "gemini does not support documents") {
+		t.Errorf("gemini with a document: %v", err)
+	}
+
+	speech.Messages[0].Content[0].Audio = &AudioData{URL: "https://example.com/a.mp3"}
+	if err := speech.Validate(); err == nil || !strings.Contains(err.Error(), "audio part without data or an s3:// URL") {
+		t.Errorf("audio by https URL: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package llm
+
+import "slices"
+
+// Checkpoint marks a point in a conversation's history that Restore can
+// roll back to. It is small and serializable, so an agent loop can keep
+// one per turn, for example in workflow state.
+type Checkpoint struct {
+	Messages     int   `json:"messages"` // number of messages
+	Usage        Usage `json:"usage"`
+	UsageHistory int   `json:"usage_history,omitempty"` // number of UsageHistory entries
+}
+
+// Checkpoint records the conversation's current length and usage.
+func (c Conversation) Checkpoint() Checkpoint {
+	return Checkpoint{Messages: len(c.Messages), Usage: c.Usage, UsageHistory: len(c.UsageHistory)}
+}
+
+// Restore rolls c back to cp, a checkpoint taken from c or an earlier
+// state of it: messages added since are dropped and Usage and UsageHistory
+// return to their values at the checkpoint, so the spend of the discarded
+// turns is no longer counted in them. Everything else, such as tools and
+// config, is left as is.
+//
+// Restore panics if c has fewer messages than cp, like slicing.
+func (c *Conversation) Restore(cp Checkpoint) {
+	// Clip, so appending after a restore never overwrites messages a
+	// copy taken before it still holds.
+	c.Messages = slices.Clip(c.Messages[:cp.Messages])
+	c.Usage = cp.Usage
+	if cp.UsageHistory <= len(c.UsageHistory) {
+		c.UsageHistory = slices.Clip(c.UsageHistory[:cp.UsageHistory])
+	}
+}
@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestCheckpointRestore(t *testing.T) {
+	ctx := context.Background()
+	client := NewClientWithProvider(&mockProvider{resp: simpleResponse("ok")}, WithUsageHistory())
+	conv, _, err := client.Send(ctx, NewConversation("m"), UserMessage("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := conv.Checkpoint()
+	if cp.Messages != 2 || cp.Usage.InputTokens != 10 || cp.UsageHistory != 1 {
+		t.Fatalf("checkpoint = %+v", cp)
+	}
+
+	// The checkpoint survives a round trip through workflow state.
+	data, _ := json.Marshal(cp)
+	var decoded Checkpoint
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded != cp {
+		t.Fatalf("decoded checkpoint = %+v, %v", decoded, err)
+	}
+
+	failed, _, err := client.Send(ctx, conv, UserMessage("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := failed
+	restored.Restore(decoded)
+	if len(restored.Messages) != 2 || restored.Messages[1].Text() != "ok" || restored.Usage != conv.Usage || len(restored.UsageHistory) != 1 {
+		t.Errorf("restored = %+v", restored)
+	}
+
+	// Appending after a restore leaves copies taken before it intact.
+	restored.Messages = append(restored.Messages, UserMessage("retry"))
+	if failed.Messages[2].Text() != "second" {
+		t.Error("append after Restore overwrote a shared message")
+	}
+}
+
+func TestRestore_PanicsPastEnd(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Restore did not panic")
+		}
+	}()
+	conv := NewConversation("m")
+	conv.Restore(Checkpoint{Messages: 3})
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+// ChunkOptions configures ChunkText. Sizes are estimated tokens.
+type ChunkOptions struct {
+	MaxTokens     int    // upper bound per chunk; <= 0 disables chunking
+	OverlapTokens int    // trailing context repeated at the start of the next chunk
+	Model         string // model whose tokenizer estimates sizes; "" uses a generic estimate
+}
+
+// chunkSeparators are boundaries ChunkText prefers to split on, in order:
+// paragraphs, lines, sentences, words.
+var chunkSeparators = []string{"\n\n", "\n", ". ", " "}
+
+// ChunkText splits text into chunks of at most opts.MaxTokens tokens,
+// estimated as Conversation.EstimateTokens does for opts.Model. It splits on the coarsest structural boundary that makes each
+// piece fit, falling back to hard splits only for unbroken runs of text.
+func ChunkText(text string, opts ChunkOptions) []string {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	if opts.MaxTokens <= 0 {
+		return []string{text}
+	}
+
+	tok := tokenizerFor(opts.Model)
+	var chunks []string
+	var cur []string
+	curTokens := 0
+	flush := func() {
+		if c := strings.TrimSpace(strings.Join(cur, "")); c != "" {
+			chunks = append(chunks, c)
+		}
+	}
+	for _, piece := range tok.split(text, opts.MaxTokens, chunkSeparators) {
+		n := tok.text(piece)
+		if curTokens+n > opts.MaxTokens && len(cur) > 0 {
+			flush()
+			// Carry trailing pieces forward as overlap, as long as they
+			// leave room for the piece being added.
+			keep, keepTokens := 0, 0
+			for i := len(cur) - 1; i >= 0; i-- {
+				t := tok.text(cur[i])
+				if keepTokens+t > opts.OverlapTokens || keepTokens+t+n > opts.MaxTokens {
+					break
+				}
+				keep++
+				keepTokens += t
+			}
+			cur = append([]string(nil), cur[len(cur)-keep:]...)
+			curTokens = keepTokens
+		}
+		cur = append(cur, piece)
+		curTokens += n
+	}
+	flush()
+	return chunks
+}
+
+// split recursively splits text on seps until every piece fits in
+// maxTokens. Separators stay attached to the end of their piece so joining
+// the pieces reproduces the input.
+func (t tokenizer) split(text string, maxTokens int, seps []string) []string {
+	if t.text(text) <= maxTokens {
+		return []string{text}
+	}
+	if len(seps) == 0 {
+		var out []string
+		for t.text(text) > maxTokens {
+			n := t.fit(text, maxTokens)
+			out = append(out, text[:n])
+			text = text[n:]
+		}
+		return append(out, text)
+	}
+	var out []string
+	for _, part := range strings.SplitAfter(text, seps[0]) {
+		if part != "" {
+			out = append(out, t.split(part, maxTokens, seps[1:])...)
+		}
+	}
+	return out
+}
+
+// MapReduce sends each chunk on a copy of conv prefixed with mapPrompt, then
+// sends the joined partial results prefixed with reducePrompt and returns
+// the combined text. A single chunk skips the reduce step, and no chunks
+// return an empty result without any calls. Usage from every call is
+// summed, including calls made before a failure.
+//
+// The reduce step sends every partial result in one request, which must
+// fit in the model's context window. For many chunks, or map prompts that
+// produce long partials, chunk the partials with ChunkText and call
+// MapReduce on them again.
+func MapReduce(ctx context.Context, client *Client, conv Conversation, chunks []string, mapPrompt, reducePrompt string) (string, Usage, error) {
+	var usage Usage
+	if len(chunks) == 0 {
+		return "", usage, nil
+	}
+	partials := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		_, resp, err := client.Send(ctx, conv, UserMessage(mapPrompt+"\n\n"+chunk))
+		if err != nil {
+			return "", usage, err
+		}
+		usage = usage.Add(resp.Usage)
+		partials = append(partials, resp.Message.Text())
+	}
+	if len(partials) == 1 {
+		return partials[0], usage, nil
+	}
+
+	_, resp, err := client.Send(ctx, conv, UserMessage(reducePrompt+"\n\n"+strings.Join(partials, "\n\n---\n\n")))
+	if err != nil {
+		return "", usage, err
+	}
+	return resp.Message.Text(), usage.Add(resp.Usage), nil
+}
@@ -0,0 +1,147 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestChunkText_FitsInOneChunk(t *testing.T) {
+	chunks := ChunkText("short text", ChunkOptions{MaxTokens: 100})
+	if len(chunks) != 1 || chunks[0] != "short text" {
+		t.Errorf("chunks = %q", chunks)
+	}
+}
+
+func TestChunkText_Empty(t *testing.T) {
+	if chunks := ChunkText("  \n ", ChunkOptions{MaxTokens: 10}); chunks != nil {
+		t.Errorf("chunks = %q, want nil", chunks)
+	}
+}
+
+func TestChunkText_PrefersParagraphBoundaries(t *testing.T) {
+	para := strings.Repeat("word ", 15) // 75 chars, ~19 tokens
+	text := para + "\n\n" + para + "\n\n" + para
+	chunks := ChunkText(text, ChunkOptions{MaxTokens: 25})
+	if len(chunks) != 3 {
+		t.Fatalf("chunks len = %d, want 3: %q", len(chunks), chunks)
+	}
+	for i, c := range chunks {
+		if c != strings.TrimSpace(para) {
+			t.Errorf("chunk[%d] = %q", i, c)
+		}
+	}
+}
+
+func TestChunkText_RespectsMaxTokens(t *testing.T) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 200)
+	chunks := ChunkText(text, ChunkOptions{MaxTokens: 50, OverlapTokens: 10})
+	if len(chunks) < 2 {
+		t.Fatalf("chunks len = %d", len(chunks))
+	}
+	for i, c := range chunks {
+		// Allow for per-piece rounding in the estimate.
+		if n := estimateTextTokens(c); n > 55 {
+			t.Errorf("chunk[%d] has %d tokens", i, n)
+		}
+	}
+}
+
+func TestChunkText_Overlap(t *testing.T) {
+	text := "alpha beta gamma delta epsilon zeta eta theta"
+	chunks := ChunkText(text, ChunkOptions{MaxTokens: 4, OverlapTokens: 2})
+	if len(chunks) < 2 {
+		t.Fatalf("chunks = %q", chunks)
+	}
+	for i := 1; i < len(chunks); i++ {
+		prevWords := strings.Fields(chunks[i-1])
+		if !strings.HasPrefix(chunks[i], prevWords[len(prevWords)-1]) {
+			t.Errorf("chunk[%d] = %q does not overlap %q", i, chunks[i], chunks[i-1])
+		}
+	}
+}
+
+func TestChunkText_HardSplit(t *testing.T) {
+	chunks := ChunkText(strings.Repeat("x", 100), ChunkOptions{MaxTokens: 10})
+	if len(chunks) != 3 {
+		t.Fatalf("chunks len = %d, want 3", len(chunks))
+	}
+	if strings.Join(chunks, "") != strings.Repeat("x", 100) {
+		t.Error("hard split lost content")
+	}
+}
+
+func TestChunkText_HardSplitCJK(t *testing.T) {
+	text := strings.Repeat("漢字", 500)
+	chunks := ChunkText(text, ChunkOptions{MaxTokens: 100})
+	if len(chunks) != 10 {
+		t.Fatalf("chunks len = %d, want 10", len(chunks))
+	}
+	for i, c := range chunks {
+		if n := estimateTextTokens(c); n > 100 {
+			t.Errorf("chunk[%d] has %d tokens, want at most 100", i, n)
+		}
+	}
+	if strings.Join(chunks, "") != text {
+		t.Error("hard split lost content")
+	}
+}
+
+func TestChunkText_ModelTokenizer(t *testing.T) {
+	text := strings.Repeat("x", 800)
+	if chunks := ChunkText(text, ChunkOptions{MaxTokens: 100}); len(chunks) != 2 {
+		t.Errorf("generic chunks len = %d, want 2", len(chunks))
+	}
+	model := "us.anthropic.claude-sonnet-4-5-20250929-v1:0"
+	chunks := ChunkText(text, ChunkOptions{MaxTokens: 100, Model: model})
+	if len(chunks) != 3 {
+		t.Fatalf("Claude chunks len = %d, want 3", len(chunks))
+	}
+	for i, c := range chunks {
+		if n := tokenizerFor(model).text(c); n > 100 {
+			t.Errorf("chunk[%d] has %d tokens", i, n)
+		}
+	}
+}
+
+func TestMapReduce(t *testing.T) {
+	client := NewClientWithProvider(echoProvider{})
+	out, usage, err := MapReduce(context.Background(), client, NewConversation("model"),
+		[]string{"one", "two"}, "summarize:", "combine:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "COMBINE:") || !strings.Contains(out, "SUMMARIZE:\n\nONE") || !strings.Contains(out, "SUMMARIZE:\n\nTWO") {
+		t.Errorf("out = %q", out)
+	}
+	if usage.InputTokens != 30 {
+		t.Errorf("InputTokens = %d, want 30 (two maps + one reduce)", usage.InputTokens)
+	}
+}
+
+func TestMapReduce_SingleChunkSkipsReduce(t *testing.T) {
+	client := NewClientWithProvider(echoProvider{})
+	out, usage, err := MapReduce(context.Background(), client, NewConversation("model"),
+		[]string{"only"}, "summarize:", "combine:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "SUMMARIZE:\n\nONLY" {
+		t.Errorf("out = %q", out)
+	}
+	if usage.InputTokens != 10 {
+		t.Errorf("InputTokens = %d, want 10", usage.InputTokens)
+	}
+}
+
+func TestMapReduce_NoChunks(t *testing.T) {
+	provider := &countingProvider{resp: simpleResponse("summary")}
+	out, usage, err := MapReduce(context.Background(), NewClientWithProvider(provider), NewConversation("model"),
+		nil, "summarize:", "combine:")
+	if err != nil || out != "" || usage != (Usage{}) {
+		t.Errorf("MapReduce = %q, %+v, %v", out, usage, err)
+	}
+	if provider.calls != 0 {
+		t.Errorf("calls = %d, want 0", provider.calls)
+	}
+}
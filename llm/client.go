@@ -1,6 +1,12 @@
 package llm
 
-import "context"
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"time"
+)
 
 // Provider translates a Conversation into a provider-specific API call and
 // returns the result. Each implementation owns the full pipeline: type
@@ -17,8 +23,12 @@ type Middleware func(ctx context.Context, conv *Conversation, next SendFunc) (*R
 
 // Client calls an LLM provider via the Provider interface.
 type Client struct {
-	provider   Provider
-	middleware []Middleware
+	provider       Provider
+	middleware     []Middleware
+	validators     []Validator
+	repairAttempts int
+	timeout        time.Duration
+	usageHistory   bool
 }
 
 // ClientOption configures a Client.
@@ -31,6 +41,23 @@ func WithMiddleware(m ...Middleware) ClientOption {
 	}
 }
 
+// WithTimeout bounds each provider call to d. A call that runs out of time,
+// whether from this timeout or the caller's deadline, fails with ErrTimeout.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithUsageHistory makes Send append a TurnUsage to the returned
+// conversation's UsageHistory for every turn, alongside the running total
+// in Usage.
+func WithUsageHistory() ClientOption {
+	return func(c *Client) {
+		c.usageHistory = true
+	}
+}
+
 // NewClient creates a new Client backed by AWS Bedrock.
 // This is a convenience wrapper for backward compatibility; new code may
 // prefer NewClientWithProvider for other backends.
@@ -49,13 +76,16 @@ func NewClientWithProvider(provider Provider, opts ...ClientOption) *Client {
 
 // Send appends the provided messages to a copy of the conversation,
 // calls the provider, appends the assistant response, accumulates usage,
-// and returns the updated conversation and per-turn response.
+// updates the timestamps, and returns the updated conversation and per-turn
+// response. The response is stamped with the model and Fingerprint of the
+// conversation the provider received and its estimated cost.
 func (c *Client) Send(ctx context.Context, conv Conversation, messages ...Message) (Conversation, *Response, error) {
-	// Copy messages slice so caller's conversation is not mutated
+	// Copy messages slice and metadata so caller's conversation is not mutated
 	conv.Messages = append(append([]Message(nil), conv.Messages...), messages...)
+	conv.Metadata = maps.Clone(conv.Metadata)
 
 	core := func(ctx context.Context, conv *Conversation) (*Response, error) {
-		return c.provider.Send(ctx, conv)
+		return c.sendValidated(ctx, conv)
 	}
 
 	// Wrap with middleware (first registered = outermost)
@@ -68,14 +98,95 @@ func (c *Client) Send(ctx context.Context, conv Conversation, messages ...Messag
 		}
 	}
 
+	start := time.Now()
 	resp, err := fn(ctx, &conv)
+	latency := time.Since(start)
 	if err != nil {
 		return conv, nil, err
 	}
 
+	// Stamp the effective configuration (middleware may have altered it)
+	// unless middleware that swapped the model already did
+	if resp.Fingerprint == (Fingerprint{}) {
+		resp.Fingerprint = conv.Fingerprint()
+	}
+	if resp.Model == "" {
+		resp.Model = conv.Model
+	}
+	if resp.Cost == 0 {
+		resp.Cost = resp.Usage.Cost(resp.Model)
+	}
+	resp.Latency = latency
+
 	// Append assistant response and accumulate usage
 	conv.Messages = append(conv.Messages, resp.Message)
 	conv.Usage = conv.Usage.Add(resp.Usage)
+	now := time.Now().UTC()
+	if conv.CreatedAt.IsZero() {
+		conv.CreatedAt = now
+	}
+	conv.UpdatedAt = now
+	if c.usageHistory {
+		conv.UsageHistory = append(slices.Clip(conv.UsageHistory), TurnUsage{
+			Model:   resp.Model,
+			Usage:   resp.Usage,
+			Cost:    resp.Cost,
+			Latency: latency,
+			At:      now,
+		})
+	}
 
 	return conv, resp, nil
 }
+
+// Converse is Send for callers that keep one Conversation and update it in
+// place: it appends messages and the assistant reply to *conv and adds the
+// turn's usage to conv.Usage. The new assistant message is resp.Message.
+// On error *conv is left unchanged, so the turn can be retried as is.
+func (c *Client) Converse(ctx context.Context, conv *Conversation, messages ...Message) (*Response, error) {
+	next, resp, err := c.Send(ctx, *conv, messages...)
+	if err != nil {
+		return nil, err
+	}
+	*conv = next
+	return resp, nil
+}
+
+// invoke makes a single provider call bounded by the client timeout.
+func (c *Client) invoke(ctx context.Context, conv *Conversation) (*Response, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	resp, err := c.provider.Send(ctx, conv)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, timeoutError(conv.Model, err)
+	}
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up; whatever the provider made of it, the
+		// request must not be retried or counted against the model.
+		return nil, contextError("calling model "+conv.Model, ctx.Err())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponseFormat(conv, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func timeoutError(model string, cause error) *Error {
+	return &Error{Kind: ErrTimeout, Message: "deadline exceeded calling model " + model, Cause: cause}
+}
+
+// contextError wraps the error of a done context while waiting, as
+// ErrTimeout for an expired deadline and ErrCanceled otherwise. what
+// describes the wait.
+func contextError(what string, cause error) *Error {
+	if errors.Is(cause, context.DeadlineExceeded) {
+		return &Error{Kind: ErrTimeout, Message: "deadline exceeded " + what, Cause: cause}
+	}
+	return &Error{Kind: ErrCanceled, Message: "canceled " + what, Cause: cause}
+}
@@ -2,7 +2,10 @@ package llm
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 )
 
 // mockProvider is a test double for Provider.
@@ -82,6 +85,41 @@ func TestClientSend_AccumulatesUsage(t *testing.T) {
 	}
 }
 
+func TestClientSend_UsageHistory(t *testing.T) {
+	ctx := context.Background()
+	conv := NewConversation(sonnet)
+	conv, _, err := NewClientWithProvider(&mockProvider{resp: simpleResponse("reply")}).Send(ctx, conv, UserMessage("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.UsageHistory != nil {
+		t.Errorf("UsageHistory without WithUsageHistory = %+v", conv.UsageHistory)
+	}
+
+	client := NewClientWithProvider(&mockProvider{resp: simpleResponse("reply")}, WithUsageHistory())
+	first, resp, err := client.Send(ctx, conv, UserMessage("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := client.Send(ctx, first, UserMessage("third"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.UsageHistory) != 1 || len(second.UsageHistory) != 2 {
+		t.Fatalf("UsageHistory lengths = %d, %d", len(first.UsageHistory), len(second.UsageHistory))
+	}
+	turn := second.UsageHistory[1]
+	if turn.Model != sonnet || turn.Usage != resp.Usage || turn.Cost != resp.Cost || turn.Cost == 0 {
+		t.Errorf("turn = %+v", turn)
+	}
+	if turn.Latency <= 0 || !turn.At.Equal(second.UpdatedAt) {
+		t.Errorf("turn latency = %v, at = %v", turn.Latency, turn.At)
+	}
+	if second.Usage.InputTokens != 30 {
+		t.Errorf("Usage = %+v, want the running total", second.Usage)
+	}
+}
+
 func TestClientSend_ProviderError(t *testing.T) {
 	client := NewClientWithProvider(&mockProvider{
 		err: &Error{Kind: ErrRateLimit, Message: "slow down"},
@@ -118,6 +156,73 @@ func TestClientSend_ImmutableConversation(t *testing.T) {
 	}
 }
 
+func TestClientSend_TimestampsAndMetadata(t *testing.T) {
+	mw := func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		conv.Metadata["tagged"] = "yes"
+		return next(ctx, conv)
+	}
+	client := NewClientWithProvider(&mockProvider{resp: simpleResponse("reply")}, WithMiddleware(mw))
+
+	original := NewConversation("model", WithID("conv-1"), WithMetadata("tenant", "acme"))
+	if !original.CreatedAt.IsZero() {
+		t.Error("NewConversation set CreatedAt")
+	}
+	first, _, err := client.Send(context.Background(), original, UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.CreatedAt.IsZero() || !first.UpdatedAt.Equal(first.CreatedAt) {
+		t.Errorf("CreatedAt = %v, UpdatedAt = %v", first.CreatedAt, first.UpdatedAt)
+	}
+	if first.ID != "conv-1" || first.Metadata["tenant"] != "acme" || first.Metadata["tagged"] != "yes" {
+		t.Errorf("ID = %q, Metadata = %v", first.ID, first.Metadata)
+	}
+	if _, ok := original.Metadata["tagged"]; ok {
+		t.Error("middleware modified the caller's metadata")
+	}
+
+	second, _, err := client.Send(context.Background(), first, UserMessage("again"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.CreatedAt.Equal(first.CreatedAt) || second.UpdatedAt.Before(first.UpdatedAt) {
+		t.Errorf("CreatedAt = %v, UpdatedAt = %v", second.CreatedAt, second.UpdatedAt)
+	}
+}
+
+func TestClientConverse(t *testing.T) {
+	provider := &scriptedProvider{responses: []*Response{simpleResponse("Hi!"), simpleResponse("Bye!")}}
+	client := NewClientWithProvider(provider)
+	conv := NewConversation("test-model")
+
+	resp, err := client.Converse(context.Background(), &conv, UserMessage("Hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Text() != "Hi!" || len(conv.Messages) != 2 || conv.Messages[1].Text() != "Hi!" {
+		t.Errorf("conv = %+v", conv.Messages)
+	}
+	if _, err := client.Converse(context.Background(), &conv, UserMessage("Goodbye")); err != nil {
+		t.Fatal(err)
+	}
+	if len(conv.Messages) != 4 || conv.Usage.InputTokens != 20 {
+		t.Errorf("after two turns: %d messages, usage %+v", len(conv.Messages), conv.Usage)
+	}
+}
+
+func TestClientConverse_ErrorLeavesConversation(t *testing.T) {
+	client := NewClientWithProvider(&mockProvider{err: &Error{Kind: ErrServer, Message: "down"}})
+	conv := NewConversation("test-model")
+	conv.Messages = []Message{UserMessage("earlier")}
+
+	if _, err := client.Converse(context.Background(), &conv, UserMessage("Hello")); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(conv.Messages) != 1 {
+		t.Errorf("messages = %d, want the conversation unchanged", len(conv.Messages))
+	}
+}
+
 func TestClientSend_MiddlewareOrder(t *testing.T) {
 	var order []string
 	mw1 := func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
@@ -153,3 +258,63 @@ func TestClientSend_MiddlewareOrder(t *testing.T) {
 		}
 	}
 }
+
+func TestClientTimeout(t *testing.T) {
+	provider := &delayedProvider{delays: map[string]time.Duration{"slow": time.Second}}
+	client := NewClientWithProvider(provider, WithTimeout(10*time.Millisecond))
+
+	start := time.Now()
+	_, _, err := client.Send(context.Background(), NewConversation("slow"), UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrTimeout {
+		t.Fatalf("err = %v, want ErrTimeout", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("ErrTimeout should wrap context.DeadlineExceeded")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("timeout was not applied")
+	}
+}
+
+func TestClientTimeout_CallerDeadline(t *testing.T) {
+	provider := &delayedProvider{delays: map[string]time.Duration{"slow": time.Second}}
+	client := NewClientWithProvider(provider)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err := client.Send(ctx, NewConversation("slow"), UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrTimeout {
+		t.Fatalf("err = %v, want ErrTimeout", err)
+	}
+}
+
+func TestClientSend_CallerCancelNotRetried(t *testing.T) {
+	provider := &flakyProvider{errs: []error{fmt.Errorf("request failed: %w", context.Canceled)}}
+	client := NewClientWithProvider(provider, WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := client.Send(ctx, NewConversation("model"), UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrCanceled || !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want ErrCanceled", err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("calls = %d, want 1", provider.calls)
+	}
+}
+
+func TestClientTimeout_RetriedPerAttempt(t *testing.T) {
+	provider := &delayedProvider{delays: map[string]time.Duration{"slow": time.Second}}
+	client := NewClientWithProvider(provider,
+		WithRetry(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}),
+		WithTimeout(10*time.Millisecond),
+	)
+
+	client.Send(context.Background(), NewConversation("slow"), UserMessage("hi"))
+	if got := provider.canceled.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+}
@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultCompactionKeepTurns = 4
+	defaultCompactionPrompt    = `You summarize the earlier part of a conversation between a user and an AI assistant so the assistant can continue it without the full transcript. Record the user's goals and preferences, decisions made, facts learned, and every tool call with the essential parts of its result. Note anything still unresolved. Write plain prose in the third person, as concise as possible without losing information the assistant will need.`
+
+	// SummaryPrefix starts the text part that holds a compaction summary.
+	SummaryPrefix = "Summary of the earlier conversation:\n\n"
+)
+
+// CompactionPolicy configures a Compactor.
+type CompactionPolicy struct {
+	// Model writes the summaries, typically a cheaper model than the
+	// conversation's. The default is the conversation's model.
+	Model string
+
+	// MaxTokens is the input size, as estimated by
+	// Conversation.EstimateTokens, above which a conversation is compacted.
+	// Zero compacts on every call.
+	MaxTokens int
+
+	// KeepTurns is how many recent turns are kept verbatim. The default
+	// is 4.
+	KeepTurns int
+
+	// Prompt is the system prompt for the summarizing model.
+	Prompt string
+}
+
+// Compactor keeps long-running conversations under context limits by
+// replacing their oldest turns with a model-written summary. Unlike
+// TruncationPolicy, which only trims requests, compaction changes the
+// conversation itself, so the result should be stored in place of the
+// original.
+type Compactor struct {
+	client *Client
+	policy CompactionPolicy
+}
+
+// NewCompactor creates a Compactor that calls client to write summaries.
+func NewCompactor(client *Client, policy CompactionPolicy) *Compactor {
+	if policy.KeepTurns <= 0 {
+		policy.KeepTurns = defaultCompactionKeepTurns
+	}
+	if policy.Prompt == "" {
+		policy.Prompt = defaultCompactionPrompt
+	}
+	return &Compactor{client: client, policy: policy}
+}
+
+// Compact summarizes all but the most recent turns of conv if it exceeds
+// the policy's MaxTokens. A turn starts at a user message, so tool calls
+// are always summarized or kept together with their results. The summary
+// becomes a text part, starting with SummaryPrefix, at the front of the
+// first kept user message; an earlier summary is folded into the new one.
+//
+// It returns the compacted conversation and the summarizing model's
+// response, whose usage is not added to the conversation's since it is
+// usually priced as a different model. If nothing needs compacting, conv
+// is returned unchanged with a nil Response.
+func (c *Compactor) Compact(ctx context.Context, conv Conversation) (Conversation, *Response, error) {
+	if c.policy.MaxTokens > 0 && conv.EstimateTokens("") <= c.policy.MaxTokens {
+		return conv, nil, nil
+	}
+	turns := turnStarts(conv.Messages)
+	if len(turns) <= c.policy.KeepTurns {
+		return conv, nil, nil
+	}
+	cut := turns[len(turns)-c.policy.KeepTurns]
+
+	model := c.policy.Model
+	if model == "" {
+		model = conv.Model
+	}
+	req := NewConversation(model, WithSystem(c.policy.Prompt))
+	_, resp, err := c.client.Send(ctx, req, UserMessage(transcript(conv.Messages[:cut])))
+	if err != nil {
+		return conv, nil, err
+	}
+	summary := strings.TrimSpace(resp.Message.Text())
+	if summary == "" {
+		return conv, resp, &Error{Kind: ErrServer, Message: "compaction: model " + model + " returned an empty summary"}
+	}
+
+	first := conv.Messages[cut]
+	first.Content = append([]ContentPart{{Kind: ContentText, Text: SummaryPrefix + summary}}, first.Content...)
+	conv.Messages = append([]Message{first}, conv.Messages[cut+1:]...)
+	return conv, resp, nil
+}
+
+// transcript renders messages as plain text for a summarizing model.
+func transcript(msgs []Message) string {
+	var b strings.Builder
+	for _, m := range msgs {
+		for _, p := range m.Content {
+			switch p.Kind {
+			case ContentText:
+				fmt.Fprintf(&b, "%s: %s\n\n", m.Role, p.Text)
+			case ContentImage:
+				fmt.Fprintf(&b, "%s: [image]\n\n", m.Role)
+			case ContentAudio:
+				fmt.Fprintf(&b, "%s: [audio]\n\n", m.Role)
+			case ContentDocument:
+				fmt.Fprintf(&b, "%s: [document %s]\n\n", m.Role, p.Document.Name)
+			case ContentToolCall:
+				fmt.Fprintf(&b, "assistant called %s(%s) [%s]\n\n", p.ToolCall.Name, p.ToolCall.Arguments, p.ToolCall.ID)
+			case ContentToolResult:
+				label := "result"
+				if p.ToolResult.IsError {
+					label = "error"
+				}
+				fmt.Fprintf(&b, "tool %s [%s]: %s\n\n", label, p.ToolResult.ToolCallID, p.ToolResult.Text())
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCompactor_Compact(t *testing.T) {
+	provider := &scriptedProvider{responses: []*Response{simpleResponse("The user counted to two; Paris was sunny.")}}
+	compactor := NewCompactor(NewClientWithProvider(provider), CompactionPolicy{Model: "cheap", KeepTurns: 2})
+
+	conv := NewConversation("big", WithSystem("be brief"))
+	conv.Messages = toolTurnHistory()
+	conv.Usage = Usage{InputTokens: 100}
+	got, resp, err := compactor.Compact(context.Background(), conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || got.Usage != conv.Usage {
+		t.Errorf("resp = %v, usage = %+v", resp, got.Usage)
+	}
+
+	req := provider.received[0]
+	if req.Model != "cheap" || len(req.System) != 1 {
+		t.Errorf("summary request model = %s, system = %v", req.Model, req.System)
+	}
+	text := req.Messages[0].Text()
+	for _, want := range []string{"user: one", `assistant called get_weather({"location":"Paris"}) [c1]`, "tool result [c1]: sunny", "assistant: 2"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("transcript missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "three") {
+		t.Errorf("transcript includes kept turns:\n%s", text)
+	}
+
+	if texts := messageTexts(got.Messages); texts != SummaryPrefix+"The user counted to two; Paris was sunny.three,3,four" {
+		t.Errorf("messages = %q", texts)
+	}
+	if err := got.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if len(conv.Messages) != 9 || len(conv.Messages[6].Content) != 1 {
+		t.Error("Compact modified the input conversation")
+	}
+}
+
+func TestCompactor_UnderLimit(t *testing.T) {
+	provider := &scriptedProvider{}
+	conv := NewConversation("m")
+	conv.Messages = toolTurnHistory()
+
+	for _, policy := range []CompactionPolicy{{MaxTokens: 1000}, {KeepTurns: 4}} {
+		got, resp, err := NewCompactor(NewClientWithProvider(provider), policy).Compact(context.Background(), conv)
+		if err != nil || resp != nil || len(got.Messages) != len(conv.Messages) {
+			t.Errorf("%+v: Compact() = %d messages, %v, %v", policy, len(got.Messages), resp, err)
+		}
+	}
+	if len(provider.received) != 0 {
+		t.Errorf("summarized %d times", len(provider.received))
+	}
+}
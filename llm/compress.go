@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+)
+
+// MarshalCompressed returns the gzip-compressed JSON encoding of c. Message
+// history, and base64-encoded images in particular, compress well, which
+// helps keep conversations under payload limits such as Temporal's 2 MB.
+func (c Conversation) MarshalCompressed() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.writeCompressed(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c Conversation) writeCompressed(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(c); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// UnmarshalCompressed decodes data written by MarshalCompressed into c.
+// Uncompressed JSON is accepted too, so stored payloads can move to the
+// compressed encoding gradually.
+func (c *Conversation) UnmarshalCompressed(data []byte) error {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		if data, err = io.ReadAll(zr); err != nil {
+			return err
+		}
+	}
+	conv, err := DecodeConversation(data)
+	if err != nil {
+		return err
+	}
+	*c = conv
+	return nil
+}
+
+// EncodedSize returns the size in bytes of c's JSON encoding and of its
+// compressed encoding, for deciding whether a conversation still fits a
+// payload limit or should be compacted. Nothing is kept in memory beyond
+// the encoders' buffers.
+func (c Conversation) EncodedSize() (raw, compressed int, err error) {
+	var rawCount, compressedCount byteCounter
+	zw := gzip.NewWriter(&compressedCount)
+	if err := json.NewEncoder(io.MultiWriter(&rawCount, zw)).Encode(c); err != nil {
+		return 0, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, 0, err
+	}
+	return int(rawCount) - 1, int(compressedCount), nil // less the Encoder's newline
+}
+
+// byteCounter is an io.Writer that counts the bytes written to it.
+type byteCounter int64
+
+func (n *byteCounter) Write(p []byte) (int, error) {
+	*n += byteCounter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConversation_MarshalCompressed(t *testing.T) {
+	conv := NewConversation("model", WithSystem("be brief"))
+	conv.Messages = []Message{
+		UserMessage(strings.Repeat("tell me more. ", 500)),
+		{Role: RoleUser, Content: []ContentPart{{Kind: ContentImage, Image: &ImageData{Data: bytes.Repeat([]byte{0}, 10_000), MediaType: "image/png"}}}},
+	}
+
+	data, err := conv.MarshalCompressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := json.Marshal(conv)
+	if len(data) >= len(plain)/10 {
+		t.Errorf("compressed %d bytes, plain %d", len(data), len(plain))
+	}
+
+	raw, compressed, err := conv.EncodedSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw != len(plain) || compressed != len(data) {
+		t.Errorf("EncodedSize = %d, %d, want %d, %d", raw, compressed, len(plain), len(data))
+	}
+
+	for name, payload := range map[string][]byte{"compressed": data, "plain": plain} {
+		var restored Conversation
+		if err := restored.UnmarshalCompressed(payload); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if restored.Messages[0].Text() != conv.Messages[0].Text() || len(restored.Messages[1].Content[0].Image.Data) != 10_000 {
+			t.Errorf("%s: restored = %+v", name, restored.Model)
+		}
+	}
+
+	var restored Conversation
+	if err := restored.UnmarshalCompressed(data[:len(data)/2]); err == nil {
+		t.Error("truncated payload decoded")
+	}
+}
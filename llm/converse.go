@@ -3,6 +3,9 @@ package llm
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"path"
+	"slices"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
@@ -30,23 +33,29 @@ func toConverseInput(conv *Conversation) *bedrockruntime.ConverseInput {
 	// Messages — consecutive RoleTool messages must be merged into a single
 	// user message because Bedrock requires all tool results for an assistant
 	// turn to appear in one message.
-	isAnthropic := isAnthropicModel(conv.Model)
+	isAnthropic, cachePoints := isAnthropicModel(conv.Model), supportsCachePoints(conv.Model)
 	for i := 0; i < len(conv.Messages); {
 		m := conv.Messages[i]
 		if m.Role != RoleTool {
-			input.Messages = append(input.Messages, toConverseMessage(m, isAnthropic))
+			input.Messages = append(input.Messages, toConverseMessage(m, isAnthropic, cachePoints))
 			i++
 			continue
 		}
 		// Collect all consecutive tool-result messages.
 		merged := types.Message{Role: types.ConversationRoleUser}
 		for i < len(conv.Messages) && conv.Messages[i].Role == RoleTool {
-			cm := toConverseMessage(conv.Messages[i], isAnthropic)
+			cm := toConverseMessage(conv.Messages[i], isAnthropic, cachePoints)
 			merged.Content = append(merged.Content, cm.Content...)
 			i++
 		}
 		input.Messages = append(input.Messages, merged)
 	}
+	if jsonPrefill(conv) {
+		input.Messages = append(input.Messages, types.Message{
+			Role:    types.ConversationRoleAssistant,
+			Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: "{"}},
+		})
+	}
 
 	// Inference config
 	if conv.Config.MaxTokens != nil || conv.Config.Temperature != nil || conv.Config.TopP != nil || len(conv.Config.StopSequences) > 0 {
@@ -68,6 +77,24 @@ func toConverseInput(conv *Conversation) *bedrockruntime.ConverseInput {
 		}
 		input.InferenceConfig = ic
 	}
+	if len(conv.Config.StopSequences) > 1 && isAnthropicModel(conv.Model) {
+		// Claude reports which stop sequence it hit, but only on request.
+		input.AdditionalModelResponseFieldPaths = []string{"/stop_sequence"}
+	}
+	if g := conv.Config.Guardrail; g != nil {
+		input.GuardrailConfig = toConverseGuardrail(g)
+	}
+	fields := reasoningFields(conv, input)
+	if extra := additionalFields(conv); extra != nil {
+		if fields == nil {
+			fields = extra
+		} else {
+			maps.Copy(fields, extra)
+		}
+	}
+	if fields != nil {
+		input.AdditionalModelRequestFields = document.NewLazyDocument(fields)
+	}
 
 	// Tools
 	if len(conv.Tools) > 0 {
@@ -108,10 +135,156 @@ func toConverseInput(conv *Conversation) *bedrockruntime.ConverseInput {
 		input.ToolConfig = tc
 	}
 
+	// Response schema: a synthetic tool the model is made to call. With
+	// real tools available the model must call one of them or the schema
+	// tool, so tool loops keep working; a named tool choice is kept.
+	if rf := responseSchema(conv); rf != nil {
+		tc := input.ToolConfig
+		if tc == nil {
+			tc = &types.ToolConfiguration{}
+			input.ToolConfig = tc
+		}
+		var doc any
+		_ = json.Unmarshal(rf.Schema, &doc)
+		var schemaTool types.Tool = &types.ToolMemberToolSpec{Value: types.ToolSpecification{
+			Name:        strPtr(rf.schemaName()),
+			Description: strPtr("Respond with output matching this schema."),
+			InputSchema: &types.ToolInputSchemaMemberJson{Value: document.NewLazyDocument(doc)},
+		}}
+		// Keep the automatic cache point after the last tool.
+		n := len(tc.Tools)
+		if n > 0 {
+			if _, ok := tc.Tools[n-1].(*types.ToolMemberCachePoint); ok {
+				n--
+			}
+		}
+		tc.Tools = slices.Insert(tc.Tools, n, schemaTool)
+		switch tc.ToolChoice.(type) {
+		case *types.ToolChoiceMemberTool:
+		default:
+			if n == 0 {
+				tc.ToolChoice = &types.ToolChoiceMemberTool{Value: types.SpecificToolChoice{Name: strPtr(rf.schemaName())}}
+			} else {
+				tc.ToolChoice = &types.ToolChoiceMemberAny{Value: types.AnyToolChoice{}}
+			}
+		}
+	}
+	trimCachePoints(input)
+
 	return input
 }
 
-func toConverseMessage(m Message, isAnthropic bool) types.Message {
+// maxCachePoints is how many cache points Bedrock accepts in one request,
+// counting the ones after the system prompt and tools.
+const maxCachePoints = 4
+
+// trimCachePoints drops the earliest message cache points when input has
+// more than maxCachePoints in total, since Bedrock rejects the whole
+// request otherwise. The later points cover longer prefixes and are kept.
+func trimCachePoints(input *bedrockruntime.ConverseInput) {
+	used := 0
+	for _, b := range input.System {
+		if _, ok := b.(*types.SystemContentBlockMemberCachePoint); ok {
+			used++
+		}
+	}
+	if input.ToolConfig != nil {
+		for _, t := range input.ToolConfig.Tools {
+			if _, ok := t.(*types.ToolMemberCachePoint); ok {
+				used++
+			}
+		}
+	}
+	for _, m := range input.Messages {
+		for _, b := range m.Content {
+			if _, ok := b.(*types.ContentBlockMemberCachePoint); ok {
+				used++
+			}
+		}
+	}
+	excess := used - maxCachePoints
+	for i := 0; i < len(input.Messages) && excess > 0; i++ {
+		content := input.Messages[i].Content
+		kept := content[:0:0]
+		for _, b := range content {
+			if _, ok := b.(*types.ContentBlockMemberCachePoint); ok && excess > 0 {
+				excess--
+				continue
+			}
+			kept = append(kept, b)
+		}
+		input.Messages[i].Content = kept
+	}
+}
+
+// additionalFields returns conv's additional model request fields, or nil
+// if it has none or they are not a JSON object.
+func additionalFields(conv *Conversation) map[string]any {
+	var fields map[string]any
+	if extra := conv.Config.AdditionalModelRequestFields; len(extra) > 0 {
+		_ = json.Unmarshal(extra, &fields)
+	}
+	return fields
+}
+
+// minThinkingBudget is the smallest thinking budget Claude accepts.
+const minThinkingBudget = 1024
+
+// thinkingBudgetTooSmall reports whether conv asks Claude to think but caps
+// max tokens at or below the minimum thinking budget, leaving no valid
+// budget. Thinking set explicitly in additional fields is left to the model.
+func thinkingBudgetTooSmall(conv *Conversation) bool {
+	mt := conv.Config.MaxTokens
+	if mt == nil || *mt > minThinkingBudget || !isAnthropicModel(conv.Model) || conv.Config.ReasoningEffort.thinkingBudget() == 0 {
+		return false
+	}
+	_, explicit := additionalFields(conv)["thinking"]
+	return !explicit
+}
+
+// reasoningFields returns the additional model request fields that carry
+// conv's reasoning effort for its model, or nil. For Claude it sets a
+// thinking budget, which must be below the reply's max tokens: an unset
+// limit is raised to leave 4096 tokens for the reply, and a lower one caps
+// the budget, down to minThinkingBudget.
+func reasoningFields(conv *Conversation, input *bedrockruntime.ConverseInput) map[string]any {
+	effort := conv.Config.ReasoningEffort
+	switch {
+	case effort == "":
+		return nil
+	case isAnthropicModel(conv.Model):
+		budget := effort.thinkingBudget()
+		if budget == 0 {
+			return nil
+		}
+		if input.InferenceConfig == nil {
+			input.InferenceConfig = &types.InferenceConfiguration{}
+		}
+		if mt := input.InferenceConfig.MaxTokens; mt == nil {
+			v := int32(budget + 4096)
+			input.InferenceConfig.MaxTokens = &v
+		} else if int(*mt) <= budget {
+			budget = max(int(*mt)-1, minThinkingBudget)
+		}
+		return map[string]any{"thinking": map[string]any{"type": "enabled", "budget_tokens": budget}}
+	case strings.Contains(conv.Model, "amazon.nova-2"):
+		return map[string]any{"reasoningConfig": map[string]any{"type": "enabled", "maxReasoningEffort": string(effort)}}
+	case strings.Contains(conv.Model, "openai.gpt-oss"):
+		return map[string]any{"reasoning_effort": string(effort)}
+	}
+	return nil
+}
+
+// responseSchema returns conv's response format if it asks for JSON
+// matching a schema.
+func responseSchema(conv *Conversation) *ResponseFormat {
+	if rf := conv.Config.ResponseFormat; rf != nil && rf.Type == ResponseFormatJSONSchema && len(rf.Schema) > 0 {
+		return rf
+	}
+	return nil
+}
+
+func toConverseMessage(m Message, isAnthropic, cachePoints bool) types.Message {
 	msg := types.Message{}
 
 	switch m.Role {
@@ -142,22 +315,55 @@ func toConverseMessage(m Message, isAnthropic bool) types.Message {
 			if p.ToolResult.IsError {
 				status = types.ToolResultStatusError
 			}
+			var content []types.ToolResultContentBlock
+			if p.ToolResult.Content != "" || len(p.ToolResult.JSON) == 0 {
+				content = append(content, &types.ToolResultContentBlockMemberText{Value: p.ToolResult.Content})
+			}
+			if len(p.ToolResult.JSON) > 0 {
+				var doc any
+				if err := json.Unmarshal(p.ToolResult.JSON, &doc); err == nil {
+					content = append(content, &types.ToolResultContentBlockMemberJson{Value: document.NewLazyDocument(doc)})
+				} else {
+					content = append(content, &types.ToolResultContentBlockMemberText{Value: string(p.ToolResult.JSON)})
+				}
+			}
+			for _, img := range p.ToolResult.Images {
+				if block, ok := toConverseImage(img); ok {
+					content = append(content, &types.ToolResultContentBlockMemberImage{Value: block})
+				}
+			}
 			msg.Content = append(msg.Content, &types.ContentBlockMemberToolResult{
 				Value: types.ToolResultBlock{
 					ToolUseId: strPtr(p.ToolResult.ToolCallID),
-					Content: []types.ToolResultContentBlock{
-						&types.ToolResultContentBlockMemberText{Value: p.ToolResult.Content},
-					},
-					Status: status,
+					Content:   content,
+					Status:    status,
 				},
 			})
 		case ContentImage:
-			if p.Image != nil && len(p.Image.Data) > 0 {
-				msg.Content = append(msg.Content, &types.ContentBlockMemberImage{
-					Value: types.ImageBlock{
-						Format: types.ImageFormat(strings.TrimPrefix(p.Image.MediaType, "image/")),
-						Source: &types.ImageSourceMemberBytes{Value: p.Image.Data},
-					},
+			if p.Image == nil {
+				continue
+			}
+			if block, ok := toConverseImage(*p.Image); ok {
+				msg.Content = append(msg.Content, &types.ContentBlockMemberImage{Value: block})
+			}
+		case ContentAudio:
+			if p.Audio == nil {
+				continue
+			}
+			if block, ok := toConverseAudio(*p.Audio); ok {
+				msg.Content = append(msg.Content, &types.ContentBlockMemberAudio{Value: block})
+			}
+		case ContentDocument:
+			if p.Document == nil {
+				continue
+			}
+			if block, ok := toConverseDocument(*p.Document); ok {
+				msg.Content = append(msg.Content, &types.ContentBlockMemberDocument{Value: block})
+			}
+		case ContentCachePoint:
+			if cachePoints {
+				msg.Content = append(msg.Content, &types.ContentBlockMemberCachePoint{
+					Value: types.CachePointBlock{Type: types.CachePointTypeDefault},
 				})
 			}
 		case ContentThinking:
@@ -177,6 +383,98 @@ func toConverseMessage(m Message, isAnthropic bool) types.Message {
 	return msg
 }
 
+// toConverseImage translates image data, or an image in S3 given by an
+// s3:// URL, into an ImageBlock. Images at other URLs are not supported.
+// The format comes from the media type, or for S3 from the object key's
+// extension.
+func toConverseImage(img ImageData) (types.ImageBlock, bool) {
+	format := strings.TrimPrefix(img.MediaType, "image/")
+	switch {
+	case len(img.Data) > 0:
+		return types.ImageBlock{
+			Format: types.ImageFormat(format),
+			Source: &types.ImageSourceMemberBytes{Value: img.Data},
+		}, true
+	case strings.HasPrefix(img.URL, "s3://"):
+		if format == "" {
+			format = strings.ToLower(strings.TrimPrefix(path.Ext(img.URL), "."))
+			if format == "jpg" {
+				format = "jpeg"
+			}
+		}
+		return types.ImageBlock{
+			Format: types.ImageFormat(format),
+			Source: &types.ImageSourceMemberS3Location{Value: types.S3Location{Uri: strPtr(img.URL)}},
+		}, true
+	}
+	return types.ImageBlock{}, false
+}
+
+// toConverseAudio translates audio data, or audio in S3 given by an s3://
+// URL, into an AudioBlock, as toConverseImage does for images.
+func toConverseAudio(a AudioData) (types.AudioBlock, bool) {
+	format := strings.TrimPrefix(a.MediaType, "audio/")
+	if format == "" && strings.HasPrefix(a.URL, "s3://") {
+		format = strings.ToLower(strings.TrimPrefix(path.Ext(a.URL), "."))
+	}
+	switch format {
+	case "x-wav", "wave", "vnd.wave":
+		format = "wav"
+	case "x-flac":
+		format = "flac"
+	}
+	switch {
+	case len(a.Data) > 0:
+		return types.AudioBlock{
+			Format: types.AudioFormat(format),
+			Source: &types.AudioSourceMemberBytes{Value: a.Data},
+		}, true
+	case strings.HasPrefix(a.URL, "s3://"):
+		return types.AudioBlock{
+			Format: types.AudioFormat(format),
+			Source: &types.AudioSourceMemberS3Location{Value: types.S3Location{Uri: strPtr(a.URL)}},
+		}, true
+	}
+	return types.AudioBlock{}, false
+}
+
+// documentFormats maps media types to Converse document formats.
+var documentFormats = map[string]types.DocumentFormat{
+	"application/pdf":    types.DocumentFormatPdf,
+	"text/csv":           types.DocumentFormatCsv,
+	"application/msword": types.DocumentFormatDoc,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": types.DocumentFormatDocx,
+	"application/vnd.ms-excel": types.DocumentFormatXls,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": types.DocumentFormatXlsx,
+	"text/html":     types.DocumentFormatHtml,
+	"text/plain":    types.DocumentFormatTxt,
+	"text/markdown": types.DocumentFormatMd,
+}
+
+// toConverseDocument translates document data, or a document in S3 given
+// by an s3:// URL, into a DocumentBlock. The format comes from the media
+// type, or failing that from the name's or URL's extension.
+func toConverseDocument(d DocumentData) (types.DocumentBlock, bool) {
+	format, ok := documentFormats[d.MediaType]
+	if !ok {
+		ext := path.Ext(d.Name)
+		if ext == "" {
+			ext = path.Ext(d.URL)
+		}
+		format = types.DocumentFormat(strings.ToLower(strings.TrimPrefix(ext, ".")))
+	}
+	block := types.DocumentBlock{Name: strPtr(d.Name), Format: format}
+	switch {
+	case len(d.Data) > 0:
+		block.Source = &types.DocumentSourceMemberBytes{Value: d.Data}
+	case strings.HasPrefix(d.URL, "s3://"):
+		block.Source = &types.DocumentSourceMemberS3Location{Value: types.S3Location{Uri: strPtr(d.URL)}}
+	default:
+		return types.DocumentBlock{}, false
+	}
+	return block, true
+}
+
 // fromConverseOutput translates a Bedrock ConverseOutput into our types.
 func fromConverseOutput(out *bedrockruntime.ConverseOutput) (*Message, *Usage, FinishReason, error) {
 	msgOut, ok := out.Output.(*types.ConverseOutputMemberMessage)
@@ -239,6 +537,27 @@ func fromConverseOutput(out *bedrockruntime.ConverseOutput) (*Message, *Usage, F
 	return msg, usage, reason, nil
 }
 
+// converseStopSequence returns the stop sequence that ended out, or "" if
+// none did. Only Claude reports which of several sequences it hit; when
+// conv has just one, it is the one.
+func converseStopSequence(conv *Conversation, out *bedrockruntime.ConverseOutput) string {
+	if out.StopReason != types.StopReasonStopSequence {
+		return ""
+	}
+	if fields := out.AdditionalModelResponseFields; fields != nil {
+		var m map[string]any
+		if err := fields.UnmarshalSmithyDocument(&m); err == nil {
+			if s, ok := m["stop_sequence"].(string); ok {
+				return s
+			}
+		}
+	}
+	if len(conv.Config.StopSequences) == 1 {
+		return conv.Config.StopSequences[0]
+	}
+	return ""
+}
+
 func mapStopReason(sr types.StopReason) FinishReason {
 	switch sr {
 	case types.StopReasonEndTurn, types.StopReasonStopSequence:
@@ -258,6 +577,27 @@ func isAnthropicModel(model string) bool {
 	return strings.Contains(model, "anthropic.")
 }
 
+// jsonPrefill reports whether the reply to conv is prefilled with "{" to
+// emulate ResponseFormatJSON: for Claude and Nova models, when the model
+// is to answer a user turn. Claude does not accept a prefill while
+// thinking.
+func jsonPrefill(conv *Conversation) bool {
+	rf := conv.Config.ResponseFormat
+	if rf == nil || rf.Type != ResponseFormatJSON || len(conv.Messages) == 0 || conv.Messages[len(conv.Messages)-1].Role == RoleAssistant {
+		return false
+	}
+	if isAnthropicModel(conv.Model) && conv.Config.ReasoningEffort.thinkingBudget() > 0 {
+		return false
+	}
+	return isAnthropicModel(conv.Model) || strings.Contains(conv.Model, "amazon.nova")
+}
+
+// supportsCachePoints reports whether model accepts cache points in
+// messages.
+func supportsCachePoints(model string) bool {
+	return isAnthropicModel(model) || strings.Contains(model, "amazon.nova")
+}
+
 func derefStr(s *string) string {
 	if s == nil {
 		return ""
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"encoding/json"
+	"slices"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
@@ -189,6 +190,290 @@ func TestToConverseInput_ToolResultMessage(t *testing.T) {
 	}
 }
 
+func TestToConverseInput_ToolResultImages(t *testing.T) {
+	call := ToolCallData{ID: "call-1", Name: "screenshot", Arguments: []byte(`{}`)}
+	conv := Conversation{
+		Model: "us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+		Messages: []Message{
+			UserMessage("go"),
+			{Role: RoleAssistant, Content: []ContentPart{{Kind: ContentToolCall, ToolCall: &call}}},
+			call.ImageResult("captured", ImageData{Data: []byte("png"), MediaType: "image/png"}, ImageData{URL: "https://example.com/a.png"}),
+		},
+	}
+	input := toConverseInput(&conv)
+	trBlock := input.Messages[2].Content[0].(*types.ContentBlockMemberToolResult)
+	if len(trBlock.Value.Content) != 2 {
+		t.Fatalf("tool result blocks = %d, want text and one image", len(trBlock.Value.Content))
+	}
+	img, ok := trBlock.Value.Content[1].(*types.ToolResultContentBlockMemberImage)
+	if !ok {
+		t.Fatalf("block type = %T", trBlock.Value.Content[1])
+	}
+	if img.Value.Format != types.ImageFormatPng {
+		t.Errorf("Format = %q", img.Value.Format)
+	}
+	if src, ok := img.Value.Source.(*types.ImageSourceMemberBytes); !ok || string(src.Value) != "png" {
+		t.Errorf("Source = %#v", img.Value.Source)
+	}
+}
+
+func TestToConverseInput_Images(t *testing.T) {
+	conv := Conversation{
+		Model: "us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+		Messages: []Message{{Role: RoleUser, Content: []ContentPart{
+			{Kind: ContentText, Text: "compare"},
+			{Kind: ContentImage, Image: &ImageData{Data: []byte("jpg"), MediaType: "image/jpeg"}},
+			{Kind: ContentImage, Image: &ImageData{URL: "s3://bucket/photos/cat.JPG"}},
+			{Kind: ContentImage, Image: &ImageData{URL: "s3://bucket/chart", MediaType: "image/webp"}},
+			{Kind: ContentImage, Image: &ImageData{URL: "https://example.com/a.png"}},
+		}}},
+	}
+	blocks := toConverseInput(&conv).Messages[0].Content
+	if len(blocks) != 4 {
+		t.Fatalf("blocks = %d, want text and three images (https URL dropped)", len(blocks))
+	}
+
+	want := []struct {
+		format types.ImageFormat
+		s3     string
+	}{{types.ImageFormatJpeg, ""}, {types.ImageFormatJpeg, "s3://bucket/photos/cat.JPG"}, {types.ImageFormatWebp, "s3://bucket/chart"}}
+	for i, w := range want {
+		img := blocks[i+1].(*types.ContentBlockMemberImage).Value
+		if img.Format != w.format {
+			t.Errorf("image %d: Format = %q, want %q", i, img.Format, w.format)
+		}
+		switch src := img.Source.(type) {
+		case *types.ImageSourceMemberBytes:
+			if w.s3 != "" || string(src.Value) != "jpg" {
+				t.Errorf("image %d: bytes source %q", i, src.Value)
+			}
+		case *types.ImageSourceMemberS3Location:
+			if *src.Value.Uri != w.s3 {
+				t.Errorf("image %d: S3 URI = %q, want %q", i, *src.Value.Uri, w.s3)
+			}
+		default:
+			t.Errorf("image %d: Source = %T", i, src)
+		}
+	}
+}
+
+func TestToConverseInput_Audio(t *testing.T) {
+	conv := Conversation{
+		Model: "us.amazon.nova-lite-v1:0",
+		Messages: []Message{{Role: RoleUser, Content: []ContentPart{
+			{Kind: ContentAudio, Audio: &AudioData{Data: []byte("RIFF"), MediaType: "audio/x-wav"}},
+			{Kind: ContentAudio, Audio: &AudioData{URL: "s3://bucket/calls/42.MP3"}},
+		}}},
+	}
+	blocks := toConverseInput(&conv).Messages[0].Content
+	if len(blocks) != 2 {
+		t.Fatalf("blocks = %d, want 2", len(blocks))
+	}
+	wav := blocks[0].(*types.ContentBlockMemberAudio).Value
+	if src, ok := wav.Source.(*types.AudioSourceMemberBytes); wav.Format != types.AudioFormatWav || !ok || string(src.Value) != "RIFF" {
+		t.Errorf("wav block = %+v", wav)
+	}
+	mp3 := blocks[1].(*types.ContentBlockMemberAudio).Value
+	if src, ok := mp3.Source.(*types.AudioSourceMemberS3Location); mp3.Format != types.AudioFormatMp3 || !ok || *src.Value.Uri != "s3://bucket/calls/42.MP3" {
+		t.Errorf("mp3 block = %+v", mp3)
+	}
+}
+
+func TestToConverseInput_Documents(t *testing.T) {
+	conv := Conversation{
+		Model: "us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+		Messages: []Message{{Role: RoleUser, Content: []ContentPart{
+			{Kind: ContentText, Text: "summarize"},
+			DocumentPart("Q3 report", []byte("%PDF"), "application/pdf"),
+			{Kind: ContentDocument, Document: &DocumentData{Name: "sales.XLSX", URL: "s3://bucket/sales"}},
+		}}},
+	}
+	blocks := toConverseInput(&conv).Messages[0].Content
+	if len(blocks) != 3 {
+		t.Fatalf("blocks = %d, want 3", len(blocks))
+	}
+	pdf := blocks[1].(*types.ContentBlockMemberDocument).Value
+	if src, ok := pdf.Source.(*types.DocumentSourceMemberBytes); *pdf.Name != "Q3 report" || pdf.Format != types.DocumentFormatPdf || !ok || string(src.Value) != "%PDF" {
+		t.Errorf("pdf block = %+v", pdf)
+	}
+	xlsx := blocks[2].(*types.ContentBlockMemberDocument).Value
+	if src, ok := xlsx.Source.(*types.DocumentSourceMemberS3Location); xlsx.Format != types.DocumentFormatXlsx || !ok || *src.Value.Uri != "s3://bucket/sales" {
+		t.Errorf("xlsx block = %+v", xlsx)
+	}
+}
+
+func TestToConverseInput_CachePoints(t *testing.T) {
+	msgs := []Message{{Role: RoleUser, Content: []ContentPart{
+		{Kind: ContentText, Text: "long document"},
+		CachePointPart(),
+		{Kind: ContentText, Text: "question"},
+	}}}
+	for model, want := range map[string]bool{
+		"us.anthropic.claude-sonnet-4-5-20250929-v1:0": true,
+		"us.amazon.nova-pro-v1:0":                      true,
+		"meta.llama3-70b-instruct-v1:0":                false,
+	} {
+		blocks := toConverseInput(&Conversation{Model: model, Messages: msgs}).Messages[0].Content
+		_, isCachePoint := blocks[1].(*types.ContentBlockMemberCachePoint)
+		if isCachePoint != want || (want && len(blocks) != 3) || (!want && len(blocks) != 2) {
+			t.Errorf("%s: blocks = %#v", model, blocks)
+		}
+	}
+}
+
+func TestToConverseInput_CachePointLimit(t *testing.T) {
+	var msgs []Message
+	for _, text := range []string{"one", "two", "three"} {
+		msgs = append(msgs, Message{Role: RoleUser, Content: []ContentPart{{Kind: ContentText, Text: text}, CachePointPart()}},
+			Message{Role: RoleAssistant, Content: []ContentPart{{Kind: ContentText, Text: "ok"}}})
+	}
+	msgs = append(msgs, Message{Role: RoleUser, Content: []ContentPart{{Kind: ContentText, Text: "question"}}})
+	cachedAt := func(input *bedrockruntime.ConverseInput) []int {
+		var at []int
+		for i, m := range input.Messages {
+			for _, b := range m.Content {
+				if _, ok := b.(*types.ContentBlockMemberCachePoint); ok {
+					at = append(at, i)
+				}
+			}
+		}
+		return at
+	}
+
+	tests := []struct {
+		name string
+		conv Conversation
+		want []int
+	}{
+		{"messages only", Conversation{Messages: msgs}, []int{0, 2, 4}},
+		{"with system", Conversation{System: []string{"sys"}, Messages: msgs}, []int{0, 2, 4}},
+		{"with system and tools", Conversation{
+			System:   []string{"sys"},
+			Tools:    []ToolDefinition{{Name: "lookup", Parameters: json.RawMessage(`{"type":"object"}`)}},
+			Messages: msgs,
+		}, []int{2, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := tt.conv
+			conv.Model = "us.anthropic.claude-sonnet-4-5-20250929-v1:0"
+			if got := cachedAt(toConverseInput(&conv)); !slices.Equal(got, tt.want) {
+				t.Errorf("cache points in messages %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if msgs[0].Content[1].Kind != ContentCachePoint {
+		t.Error("trimming modified the caller's messages")
+	}
+}
+
+func TestToConverseInput_JSONPrefill(t *testing.T) {
+	jsonMode := WithResponseFormat(ResponseFormat{Type: ResponseFormatJSON})
+	tests := []struct {
+		conv Conversation
+		want bool
+	}{
+		{NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0", jsonMode), true},
+		{NewConversation("us.amazon.nova-pro-v1:0", jsonMode), true},
+		{NewConversation("meta.llama3-70b-instruct-v1:0", jsonMode), false},
+		{NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0", WithResponseFormat(ResponseFormat{Type: ResponseFormatText})), false},
+	}
+	for _, tt := range tests {
+		tt.conv.Messages = []Message{UserMessage("hi")}
+		msgs := toConverseInput(&tt.conv).Messages
+		prefilled := len(msgs) == 2 && msgs[1].Role == types.ConversationRoleAssistant &&
+			msgs[1].Content[0].(*types.ContentBlockMemberText).Value == "{"
+		if prefilled != tt.want || (!tt.want && len(msgs) != 1) {
+			t.Errorf("%s %+v: messages = %+v", tt.conv.Model, tt.conv.Config.ResponseFormat, msgs)
+		}
+	}
+
+	conv := NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0", jsonMode)
+	conv.Messages = []Message{UserMessage("hi"), AssistantMessage(`{"a":`)}
+	if msgs := toConverseInput(&conv).Messages; len(msgs) != 2 {
+		t.Errorf("caller's own prefill got another: %+v", msgs)
+	}
+}
+
+func TestToConverseInput_ResponseSchema(t *testing.T) {
+	conv := NewConversation("us.amazon.nova-pro-v1:0", WithResponseSchema("", citySchema))
+	conv.Messages = []Message{UserMessage("where?")}
+	tc := toConverseInput(&conv).ToolConfig
+	if tc == nil || len(tc.Tools) != 1 {
+		t.Fatalf("ToolConfig = %+v", tc)
+	}
+	if spec := tc.Tools[0].(*types.ToolMemberToolSpec).Value; *spec.Name != "respond" {
+		t.Errorf("tool = %q", *spec.Name)
+	}
+	if choice, ok := tc.ToolChoice.(*types.ToolChoiceMemberTool); !ok || *choice.Value.Name != "respond" {
+		t.Errorf("ToolChoice = %#v", tc.ToolChoice)
+	}
+}
+
+func TestToConverseInput_ResponseSchemaWithTools(t *testing.T) {
+	lookup := NewTool("lookup", "Look up a city", StringParam("query"))
+	conv := NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0", WithResponseSchema("", citySchema), WithTools(lookup))
+	conv.Messages = []Message{UserMessage("where?")}
+
+	tc := toConverseInput(&conv).ToolConfig
+	if tc == nil || len(tc.Tools) != 3 {
+		t.Fatalf("ToolConfig = %+v", tc)
+	}
+	if spec := tc.Tools[1].(*types.ToolMemberToolSpec).Value; *spec.Name != "respond" {
+		t.Errorf("tools[1] = %q, want the schema tool before the cache point", *spec.Name)
+	}
+	if _, ok := tc.Tools[2].(*types.ToolMemberCachePoint); !ok {
+		t.Errorf("last tool = %T, want cache point", tc.Tools[2])
+	}
+	if _, ok := tc.ToolChoice.(*types.ToolChoiceMemberAny); !ok {
+		t.Errorf("ToolChoice = %#v, want any", tc.ToolChoice)
+	}
+
+	// A tool the caller names is still forced.
+	conv.Config.ToolChoice = &ToolChoice{Mode: ToolChoiceNamed, ToolName: "lookup"}
+	if choice, ok := toConverseInput(&conv).ToolConfig.ToolChoice.(*types.ToolChoiceMemberTool); !ok || *choice.Value.Name != "lookup" {
+		t.Errorf("ToolChoice = %#v, want lookup", choice)
+	}
+}
+
+func TestToConverseInput_ToolResultJSON(t *testing.T) {
+	call := ToolCallData{ID: "call-1", Name: "lookup", Arguments: []byte(`{}`)}
+	structured := ToolResultJSON("call-1", map[string]int{"count": 2})
+	structured.Content[0].ToolResult.Content = "2 matches"
+	conv := Conversation{
+		Model: "us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+		Messages: []Message{
+			UserMessage("go"),
+			{Role: RoleAssistant, Content: []ContentPart{{Kind: ContentToolCall, ToolCall: &call}}},
+			structured,
+		},
+	}
+	input := toConverseInput(&conv)
+	blocks := input.Messages[2].Content[0].(*types.ContentBlockMemberToolResult).Value.Content
+	if len(blocks) != 2 {
+		t.Fatalf("tool result blocks = %d, want text and JSON", len(blocks))
+	}
+	if text, ok := blocks[0].(*types.ToolResultContentBlockMemberText); !ok || text.Value != "2 matches" {
+		t.Errorf("first block = %#v", blocks[0])
+	}
+	jsonBlock, ok := blocks[1].(*types.ToolResultContentBlockMemberJson)
+	if !ok {
+		t.Fatalf("second block = %T", blocks[1])
+	}
+	data, err := jsonBlock.Value.MarshalSmithyDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAssertJSONEqual(t, data, []byte(`{"count":2}`))
+
+	// JSON alone produces a single JSON block.
+	conv.Messages[2] = ToolResultJSON("call-1", []string{"a"})
+	blocks = toConverseInput(&conv).Messages[2].Content[0].(*types.ContentBlockMemberToolResult).Value.Content
+	if _, ok := blocks[0].(*types.ToolResultContentBlockMemberJson); len(blocks) != 1 || !ok {
+		t.Errorf("blocks = %#v", blocks)
+	}
+}
+
 func TestToConverseInput_ToolResultError(t *testing.T) {
 	conv := Conversation{
 		Model: "us.amazon.nova-pro-v1:0",
@@ -383,3 +668,63 @@ func TestFromConverseOutput_StopReasons(t *testing.T) {
 		})
 	}
 }
+
+func TestToConverseInput_ReasoningEffort(t *testing.T) {
+	claude := "us.anthropic.claude-sonnet-4-5-20250929-v1:0"
+	tests := []struct {
+		conv      Conversation
+		want      string
+		maxTokens int32
+	}{
+		{NewConversation(claude, WithReasoningEffort(ReasoningEffortMedium)), `{"thinking":{"type":"enabled","budget_tokens":4096}}`, 8192},
+		{NewConversation(claude, WithReasoningEffort(ReasoningEffortHigh), WithMaxTokens(8000)), `{"thinking":{"type":"enabled","budget_tokens":7999}}`, 8000},
+		{NewConversation(claude, WithReasoningEffort(ReasoningEffortLow), WithMaxTokens(20000)), `{"thinking":{"type":"enabled","budget_tokens":1024}}`, 20000},
+		{NewConversation("us.amazon.nova-2-lite-v1:0", WithReasoningEffort(ReasoningEffortHigh)), `{"reasoningConfig":{"type":"enabled","maxReasoningEffort":"high"}}`, 0},
+		{NewConversation("openai.gpt-oss-120b-1:0", WithReasoningEffort(ReasoningEffortLow)), `{"reasoning_effort":"low"}`, 0},
+		{NewConversation("meta.llama3-70b-instruct-v1:0", WithReasoningEffort(ReasoningEffortLow)), "", 0},
+		{NewConversation(claude), "", 0},
+	}
+	for _, tt := range tests {
+		input := toConverseInput(&tt.conv)
+		if tt.want == "" {
+			if input.AdditionalModelRequestFields != nil {
+				t.Errorf("%s: unexpected additional fields", tt.conv.Model)
+			}
+			continue
+		}
+		data, err := input.AdditionalModelRequestFields.MarshalSmithyDocument()
+		if err != nil {
+			t.Fatal(err)
+		}
+		testAssertJSONEqual(t, data, []byte(tt.want))
+		if tt.maxTokens != 0 && (input.InferenceConfig == nil || *input.InferenceConfig.MaxTokens != tt.maxTokens) {
+			t.Errorf("%s %s: inference config = %+v, want max tokens %d", tt.conv.Model, tt.conv.Config.ReasoningEffort, input.InferenceConfig, tt.maxTokens)
+		}
+	}
+
+	// Claude does not accept a prefill while thinking.
+	conv := NewConversation(claude, WithReasoningEffort(ReasoningEffortLow), WithResponseFormat(ResponseFormat{Type: ResponseFormatJSON}))
+	conv.Messages = []Message{UserMessage("hi")}
+	if msgs := toConverseInput(&conv).Messages; len(msgs) != 1 {
+		t.Errorf("messages = %+v", msgs)
+	}
+}
+
+func TestToConverseInput_AdditionalModelRequestFields(t *testing.T) {
+	conv := NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+		WithReasoningEffort(ReasoningEffortLow),
+		WithAdditionalModelRequestFields(json.RawMessage(`{"top_k": 40, "anthropic_beta": ["context-1m-2025-08-07"]}`)))
+	data, err := toConverseInput(&conv).AdditionalModelRequestFields.MarshalSmithyDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAssertJSONEqual(t, data, []byte(`{"thinking":{"type":"enabled","budget_tokens":1024},"top_k":40,"anthropic_beta":["context-1m-2025-08-07"]}`))
+
+	// Explicit fields replace those set for reasoning effort.
+	conv.Config.AdditionalModelRequestFields = json.RawMessage(`{"thinking": {"type": "disabled"}}`)
+	data, err = toConverseInput(&conv).AdditionalModelRequestFields.MarshalSmithyDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAssertJSONEqual(t, data, []byte(`{"thinking":{"type":"disabled"}}`))
+}
@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// Cost returns the USD cost of u at prices p.
+func (p Pricing) Cost(u Usage) float64 {
+	return (float64(u.InputTokens)*p.Input +
+		float64(u.OutputTokens)*p.Output +
+		float64(u.CacheReadTokens)*p.CacheRead +
+		float64(u.CacheWriteTokens)*p.CacheWrite) / 1e6
+}
+
+// Cost returns the estimated USD cost of u for model, using the model
+// table. It returns 0 for models without known pricing.
+func (u Usage) Cost(model string) float64 {
+	info, ok := LookupModel(model)
+	if !ok {
+		return 0
+	}
+	return info.Pricing.Cost(u)
+}
+
+// CostTracker aggregates the estimated cost of every response sent through
+// its middleware. It is safe for concurrent use and can be shared by
+// several clients.
+type CostTracker struct {
+	mu      sync.Mutex
+	total   float64
+	byModel map[string]float64
+}
+
+// NewCostTracker creates an empty CostTracker.
+func NewCostTracker() *CostTracker {
+	return &CostTracker{byModel: make(map[string]float64)}
+}
+
+// WithCostTracker adds t's middleware to the client.
+func WithCostTracker(t *CostTracker) ClientOption {
+	return WithMiddleware(t.Middleware())
+}
+
+// Middleware returns middleware that adds each response's cost to t.
+func (t *CostTracker) Middleware() Middleware {
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		resp, err := next(ctx, conv)
+		if err != nil {
+			return resp, err
+		}
+		model := resp.Model
+		if model == "" {
+			model = conv.Model
+		}
+		t.Add(model, resp.Usage.Cost(model))
+		return resp, nil
+	}
+}
+
+// Add records cost against model.
+func (t *CostTracker) Add(model string, cost float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total += cost
+	t.byModel[model] += cost
+}
+
+// Total returns the total cost recorded, in USD.
+func (t *CostTracker) Total() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// ByModel returns a copy of the recorded cost per model, in USD.
+func (t *CostTracker) ByModel() map[string]float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.byModel)
+}
@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+const sonnet = "us.anthropic.claude-sonnet-4-5-20250929-v1:0"
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestPricingCost(t *testing.T) {
+	p := Pricing{Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75}
+	u := Usage{InputTokens: 1_000_000, OutputTokens: 100_000, CacheReadTokens: 1_000_000, CacheWriteTokens: 1_000}
+	want := 3 + 1.5 + 0.3 + 0.00375
+	if got := p.Cost(u); !approxEqual(got, want) {
+		t.Errorf("Cost = %v, want %v", got, want)
+	}
+}
+
+func TestUsageCost(t *testing.T) {
+	u := Usage{InputTokens: 1000, OutputTokens: 1000}
+	if got := u.Cost(sonnet); !approxEqual(got, 0.018) {
+		t.Errorf("Cost = %v, want 0.018", got)
+	}
+	if got := u.Cost("unknown-model"); got != 0 {
+		t.Errorf("unknown model Cost = %v, want 0", got)
+	}
+}
+
+func TestClientSend_StampsCost(t *testing.T) {
+	client := NewClientWithProvider(&mockProvider{resp: simpleResponse("ok")})
+	_, resp, err := client.Send(context.Background(), NewConversation(sonnet), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10 input, 5 output tokens
+	if want := (10*3 + 5*15) / 1e6; !approxEqual(resp.Cost, want) {
+		t.Errorf("Cost = %v, want %v", resp.Cost, want)
+	}
+}
+
+func TestCostTracker(t *testing.T) {
+	tracker := NewCostTracker()
+	client := NewClientWithProvider(&countingProvider{resp: simpleResponse("ok")}, WithCostTracker(tracker))
+
+	for _, model := range []string{sonnet, sonnet, "unknown-model"} {
+		if _, _, err := client.Send(context.Background(), NewConversation(model), UserMessage("hi")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	perCall := (10*3 + 5*15) / 1e6
+	if got := tracker.Total(); !approxEqual(got, 2*perCall) {
+		t.Errorf("Total = %v, want %v", got, 2*perCall)
+	}
+	byModel := tracker.ByModel()
+	if !approxEqual(byModel[sonnet], 2*perCall) {
+		t.Errorf("ByModel[sonnet] = %v", byModel[sonnet])
+	}
+	if _, ok := byModel["unknown-model"]; !ok {
+		t.Error("unknown models should still be listed")
+	}
+}
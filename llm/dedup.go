@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+)
+
+// WithDedup adds a Dedup middleware to the client.
+func WithDedup() ClientOption {
+	return WithMiddleware(Dedup())
+}
+
+// Dedup returns middleware that coalesces identical concurrent requests:
+// while a request is in flight, another with the same model, system
+// prompts, messages, tools, and config waits for it and shares its result
+// instead of calling the provider again. Accumulated usage is ignored when
+// comparing conversations.
+//
+// The shared call runs with the first caller's context. Later callers stop
+// waiting when their own context ends. Each caller receives its own copy of
+// the Response, including the Usage of the single underlying call, so sum
+// usage across coalesced callers with care.
+func Dedup() Middleware {
+	var mu sync.Mutex
+	inflight := make(map[string]*dedupCall)
+
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		key := requestKey(conv)
+
+		mu.Lock()
+		if call, ok := inflight[key]; ok {
+			mu.Unlock()
+			select {
+			case <-call.done:
+				return call.result()
+			case <-ctx.Done():
+				return nil, contextError("waiting for a duplicate request to model "+conv.Model, ctx.Err())
+			}
+		}
+		call := &dedupCall{done: make(chan struct{})}
+		inflight[key] = call
+		mu.Unlock()
+
+		call.resp, call.err = next(ctx, conv)
+
+		mu.Lock()
+		delete(inflight, key)
+		mu.Unlock()
+		close(call.done)
+		return call.result()
+	}
+}
+
+type dedupCall struct {
+	done chan struct{}
+	resp *Response
+	err  error
+}
+
+// result returns a copy of the shared response, so callers may annotate
+// their Response independently.
+func (c *dedupCall) result() (*Response, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	resp := *c.resp
+	return &resp, nil
+}
+
+// requestKey returns a SHA-256 digest identifying everything the provider
+// sees in conv.
+func requestKey(conv *Conversation) string {
+	c := Conversation{
+		Model:    conv.Model,
+		System:   conv.System,
+		Messages: conv.Messages,
+		Tools:    conv.Tools,
+		Config:   conv.Config,
+	}
+	data, _ := json.Marshal(c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatedProvider blocks every call until release is closed.
+type gatedProvider struct {
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (p *gatedProvider) Send(_ context.Context, conv *Conversation) (*Response, error) {
+	p.calls.Add(1)
+	<-p.release
+	return simpleResponse("echo " + conv.Messages[len(conv.Messages)-1].Text()), nil
+}
+
+func TestDedup_CoalescesIdenticalRequests(t *testing.T) {
+	provider := &gatedProvider{release: make(chan struct{})}
+	client := NewClientWithProvider(provider, WithDedup())
+	conv := NewConversation("model", WithSystem("be brief"))
+
+	var wg sync.WaitGroup
+	texts := make([]string, 5)
+	for i := range texts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, resp, err := client.Send(context.Background(), conv, UserMessage("hi"))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			texts[i] = resp.Message.Text()
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	if got := provider.calls.Load(); got != 1 {
+		t.Errorf("provider calls = %d, want 1", got)
+	}
+	for i, text := range texts {
+		if text != "echo hi" {
+			t.Errorf("caller %d got %q", i, text)
+		}
+	}
+}
+
+func TestDedup_DistinctRequestsNotCoalesced(t *testing.T) {
+	provider := &gatedProvider{release: make(chan struct{})}
+	close(provider.release)
+	client := NewClientWithProvider(provider, WithDedup())
+
+	var wg sync.WaitGroup
+	for _, text := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Send(context.Background(), NewConversation("model"), UserMessage(text))
+		}()
+	}
+	wg.Wait()
+	if got := provider.calls.Load(); got != 3 {
+		t.Errorf("provider calls = %d, want 3", got)
+	}
+}
+
+func TestDedup_WaiterHonorsContext(t *testing.T) {
+	provider := &gatedProvider{release: make(chan struct{})}
+	defer close(provider.release)
+	client := NewClientWithProvider(provider, WithDedup())
+	conv := NewConversation("model")
+
+	go client.Send(context.Background(), conv, UserMessage("hi"))
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err := client.Send(ctx, conv, UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrTimeout {
+		t.Errorf("err = %v, want ErrTimeout when the waiter's deadline passes", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, _, err = client.Send(ctx, conv, UserMessage("hi"))
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrCanceled || !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want ErrCanceled when the waiter is canceled", err)
+	}
+}
+
+func TestRequestKey_IgnoresUsage(t *testing.T) {
+	a := NewConversation("model")
+	b := a
+	b.Usage = Usage{InputTokens: 100}
+	if requestKey(&a) != requestKey(&b) {
+		t.Error("usage should not affect the request key")
+	}
+	b.Messages = []Message{UserMessage("hi")}
+	if requestKey(&a) == requestKey(&b) {
+		t.Error("messages should affect the request key")
+	}
+}
@@ -1,5 +1,17 @@
-// Package llm provides a Conversation-centric wrapper around the AWS Bedrock Converse API.
+// Package llm provides a Conversation-centric client for LLM APIs.
 //
 // The Conversation type holds the entire conversation state as serializable data,
 // making it a natural fit for Temporal workflow payloads and other persistence mechanisms.
+// Client.Send treats it as a value and returns the next state; Client.Converse
+// advances a Conversation in place:
+//
+//	conv := llm.NewConversation(model, llm.WithSystem("Be concise."))
+//	resp, err := client.Converse(ctx, &conv, llm.UserMessage("Hello!"))
+//	// conv now holds both messages and the turn's usage.
+//
+// Client is transport-agnostic: it talks to a backend only through the Provider
+// interface, and middleware wraps that call without knowing which backend is in
+// use. Built-in providers cover AWS Bedrock Converse, OpenAI-compatible servers,
+// DeepSeek, and Gemini; any other backend can be plugged in with
+// NewClientWithProvider or registered by name with RegisterProvider.
 package llm
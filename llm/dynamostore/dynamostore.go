@@ -0,0 +1,294 @@
+// Package dynamostore implements llm.Store on an Amazon DynamoDB table.
+//
+// The table needs a string partition key named "id" and no sort key. Each
+// item holds one conversation: its summary fields as top-level attributes
+// and the full conversation, gzip-compressed JSON, in the "data" binary
+// attribute. Items are limited to 400 KB, so very long conversations or
+// ones with large images may not fit.
+package dynamostore
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+// Client abstracts the DynamoDB calls Store makes, for testing.
+// *dynamodb.Client implements it.
+type Client interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+// Item attribute names.
+const (
+	attrID        = "id"
+	attrRevision  = "revision"
+	attrModel     = "model"
+	attrCreatedAt = "created_at"
+	attrUpdatedAt = "updated_at"
+	attrMetadata  = "metadata"
+	attrMessages  = "message_count"
+	attrData      = "data"
+)
+
+// Expressions used by Save and List. Attribute names go through
+// placeholders because several of them are DynamoDB reserved words.
+const (
+	condNew      = "attribute_not_exists(#id)"
+	condRevision = "#rev = :rev"
+	projection   = "#id, #rev, #model, #created, #updated, #meta, #count"
+)
+
+// Store is an llm.Store backed by a DynamoDB table. It is safe for
+// concurrent use.
+type Store struct {
+	client Client
+	table  string
+}
+
+var _ llm.Store = (*Store)(nil)
+
+// New creates a Store that keeps conversations in table.
+func New(client Client, table string) *Store {
+	return &Store{client: client, table: table}
+}
+
+// Save implements llm.Store. The revision check is a PutItem condition,
+// so concurrent writers cannot overwrite each other.
+func (s *Store) Save(ctx context.Context, conv *llm.Conversation) error {
+	if conv.ID == "" {
+		return &llm.Error{Kind: llm.ErrInvalidRequest, Message: "conversation has no ID"}
+	}
+	saved := *conv
+	saved.Revision++
+	data, err := saved.MarshalCompressed()
+	if err != nil {
+		return &llm.Error{Kind: llm.ErrInvalidRequest, Message: "encoding conversation " + conv.ID, Cause: err}
+	}
+
+	item := map[string]types.AttributeValue{
+		attrID:        &types.AttributeValueMemberS{Value: saved.ID},
+		attrRevision:  number(saved.Revision),
+		attrModel:     &types.AttributeValueMemberS{Value: saved.Model},
+		attrCreatedAt: &types.AttributeValueMemberS{Value: formatTime(saved.CreatedAt)},
+		attrUpdatedAt: &types.AttributeValueMemberS{Value: formatTime(saved.UpdatedAt)},
+		attrMessages:  number(int64(len(saved.Messages))),
+		attrData:      &types.AttributeValueMemberB{Value: data},
+	}
+	if len(saved.Metadata) > 0 {
+		meta := make(map[string]types.AttributeValue, len(saved.Metadata))
+		for k, v := range saved.Metadata {
+			meta[k] = &types.AttributeValueMemberS{Value: v}
+		}
+		item[attrMetadata] = &types.AttributeValueMemberM{Value: meta}
+	}
+
+	input := &dynamodb.PutItemInput{TableName: aws.String(s.table), Item: item}
+	if conv.Revision == 0 {
+		input.ConditionExpression = aws.String(condNew)
+		input.ExpressionAttributeNames = map[string]string{"#id": attrID}
+	} else {
+		input.ConditionExpression = aws.String(condRevision)
+		input.ExpressionAttributeNames = map[string]string{"#rev": attrRevision}
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{":rev": number(conv.Revision)}
+	}
+	if _, err := s.client.PutItem(ctx, input); err != nil {
+		var failed *types.ConditionalCheckFailedException
+		if errors.As(err, &failed) {
+			return &llm.Error{
+				Kind:    llm.ErrConflict,
+				Message: "conversation " + conv.ID + " is not at revision " + strconv.FormatInt(conv.Revision, 10),
+				Cause:   err,
+			}
+		}
+		return classify("saving conversation "+conv.ID, err)
+	}
+	conv.Revision = saved.Revision
+	return nil
+}
+
+// Load implements llm.Store. Reads are strongly consistent.
+func (s *Store) Load(ctx context.Context, id string) (llm.Conversation, error) {
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            key(id),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return llm.Conversation{}, classify("loading conversation "+id, err)
+	}
+	if out.Item == nil {
+		return llm.Conversation{}, &llm.Error{Kind: llm.ErrNotFound, Message: "conversation " + id + " not found"}
+	}
+	data, ok := out.Item[attrData].(*types.AttributeValueMemberB)
+	if !ok {
+		return llm.Conversation{}, &llm.Error{Kind: llm.ErrServer, Message: "conversation " + id + " has no data attribute"}
+	}
+	var conv llm.Conversation
+	if err := conv.UnmarshalCompressed(data.Value); err != nil {
+		return llm.Conversation{}, &llm.Error{Kind: llm.ErrServer, Message: "decoding conversation " + id, Cause: err}
+	}
+	return conv, nil
+}
+
+// List implements llm.Store by scanning the table for summary attributes
+// and filtering the results, so its cost grows with the table size rather
+// than with the number of matches. Conversations are returned in scan
+// order; the cursor is the last ID of the previous page. The default limit
+// is 100.
+func (s *Store) List(ctx context.Context, opts llm.ListOptions) (llm.ListPage, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	input := &dynamodb.ScanInput{
+		TableName:            aws.String(s.table),
+		ProjectionExpression: aws.String(projection),
+		ExpressionAttributeNames: map[string]string{
+			"#id": attrID, "#rev": attrRevision, "#model": attrModel,
+			"#created": attrCreatedAt, "#updated": attrUpdatedAt,
+			"#meta": attrMetadata, "#count": attrMessages,
+		},
+	}
+	if opts.Cursor != "" {
+		input.ExclusiveStartKey = key(opts.Cursor)
+	}
+
+	var page llm.ListPage
+	for {
+		out, err := s.client.Scan(ctx, input)
+		if err != nil {
+			return llm.ListPage{}, classify("listing conversations", err)
+		}
+		for i, item := range out.Items {
+			info, err := decodeInfo(item)
+			if err != nil {
+				return llm.ListPage{}, err
+			}
+			if !opts.Matches(info) {
+				continue
+			}
+			page.Conversations = append(page.Conversations, info)
+			if len(page.Conversations) == limit {
+				if i < len(out.Items)-1 || out.LastEvaluatedKey != nil {
+					page.Next = info.ID
+				}
+				return page, nil
+			}
+		}
+		if out.LastEvaluatedKey == nil {
+			return page, nil
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// Delete implements llm.Store.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key:       key(id),
+	})
+	if err != nil {
+		return classify("deleting conversation "+id, err)
+	}
+	return nil
+}
+
+func decodeInfo(item map[string]types.AttributeValue) (llm.ConversationInfo, error) {
+	var info llm.ConversationInfo
+	var err error
+	info.ID = str(item[attrID])
+	info.Model = str(item[attrModel])
+	if info.Revision, err = num(item[attrRevision]); err != nil {
+		return info, badItem(info.ID, attrRevision, err)
+	}
+	n, err := num(item[attrMessages])
+	if err != nil {
+		return info, badItem(info.ID, attrMessages, err)
+	}
+	info.Messages = int(n)
+	if info.CreatedAt, err = parseTime(str(item[attrCreatedAt])); err != nil {
+		return info, badItem(info.ID, attrCreatedAt, err)
+	}
+	if info.UpdatedAt, err = parseTime(str(item[attrUpdatedAt])); err != nil {
+		return info, badItem(info.ID, attrUpdatedAt, err)
+	}
+	if m, ok := item[attrMetadata].(*types.AttributeValueMemberM); ok {
+		info.Metadata = make(map[string]string, len(m.Value))
+		for k, v := range m.Value {
+			info.Metadata[k] = str(v)
+		}
+	}
+	return info, nil
+}
+
+func badItem(id, attr string, err error) error {
+	return &llm.Error{Kind: llm.ErrServer, Message: "conversation " + id + ": invalid " + attr + " attribute", Cause: err}
+}
+
+// classify wraps an error from a DynamoDB call. Throttling maps to
+// ErrRateLimit, so that callers can back off as they do for providers.
+func classify(op string, err error) error {
+	kind := llm.ErrServer
+	var throughput *types.ProvisionedThroughputExceededException
+	var limit *types.RequestLimitExceeded
+	var notFound *types.ResourceNotFoundException
+	switch {
+	case errors.As(err, &throughput), errors.As(err, &limit):
+		kind = llm.ErrRateLimit
+	case errors.As(err, &notFound):
+		kind = llm.ErrConfig
+	}
+	return &llm.Error{Kind: kind, Message: op + ": " + err.Error(), Cause: err}
+}
+
+func key(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{attrID: &types.AttributeValueMemberS{Value: id}}
+}
+
+func number(n int64) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(n, 10)}
+}
+
+func str(v types.AttributeValue) string {
+	s, _ := v.(*types.AttributeValueMemberS)
+	if s == nil {
+		return ""
+	}
+	return s.Value
+}
+
+func num(v types.AttributeValue) (int64, error) {
+	n, _ := v.(*types.AttributeValueMemberN)
+	if n == nil {
+		return 0, nil
+	}
+	return strconv.ParseInt(n.Value, 10, 64)
+}
+
+// formatTime renders t in UTC so that stored timestamps sort as strings.
+// The zero time is stored as "".
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
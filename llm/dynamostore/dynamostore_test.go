@@ -0,0 +1,160 @@
+package dynamostore
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/quells-bot/unified-llm/llm"
+	"github.com/quells-bot/unified-llm/llm/llmtest"
+)
+
+// fakeDynamo is an in-memory table that understands the condition
+// expressions Store uses. Scans return items in ID order, pageSize at a
+// time.
+type fakeDynamo struct {
+	mu       sync.Mutex
+	items    map[string]map[string]types.AttributeValue
+	pageSize int
+	scans    int
+	err      error
+}
+
+func newFakeDynamo() *fakeDynamo {
+	return &fakeDynamo{items: make(map[string]map[string]types.AttributeValue), pageSize: 2}
+}
+
+func (f *fakeDynamo) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &dynamodb.GetItemOutput{Item: f.items[str(in.Key[attrID])]}, nil
+}
+
+func (f *fakeDynamo) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	id := str(in.Item[attrID])
+	existing, exists := f.items[id]
+	var ok bool
+	switch aws.ToString(in.ConditionExpression) {
+	case condNew:
+		ok = !exists && in.ExpressionAttributeNames["#id"] == attrID
+	case condRevision:
+		want := in.ExpressionAttributeValues[":rev"].(*types.AttributeValueMemberN).Value
+		ok = exists && in.ExpressionAttributeNames["#rev"] == attrRevision &&
+			existing[attrRevision].(*types.AttributeValueMemberN).Value == want
+	default:
+		return nil, errors.New("unexpected condition " + aws.ToString(in.ConditionExpression))
+	}
+	if !ok {
+		return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	}
+	f.items[id] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamo) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.items, str(in.Key[attrID]))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (f *fakeDynamo) Scan(_ context.Context, in *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scans++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if aws.ToString(in.ProjectionExpression) != projection {
+		return nil, errors.New("unexpected projection")
+	}
+	start := str(in.ExclusiveStartKey[attrID])
+	out := &dynamodb.ScanOutput{}
+	for _, id := range slices.Sorted(maps.Keys(f.items)) {
+		if id <= start {
+			continue
+		}
+		if len(out.Items) == f.pageSize {
+			out.LastEvaluatedKey = key(out.Items[f.pageSize-1][attrID].(*types.AttributeValueMemberS).Value)
+			break
+		}
+		item := maps.Clone(f.items[id])
+		delete(item, attrData)
+		out.Items = append(out.Items, item)
+	}
+	return out, nil
+}
+
+func TestStoreConformance(t *testing.T) {
+	llmtest.RunStoreConformance(t, func(t *testing.T) llm.Store {
+		return New(newFakeDynamo(), "conversations")
+	})
+}
+
+func TestList_FollowsScanPages(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeDynamo()
+	store := New(fake, "conversations")
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		conv := llm.NewConversation("m", llm.WithID(id))
+		if id == "e" {
+			conv.Model = "other"
+		}
+		if err := store.Save(ctx, &conv); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := store.List(ctx, llm.ListOptions{Model: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Conversations) != 1 || page.Conversations[0].ID != "e" || page.Next != "" {
+		t.Errorf("page = %+v", page)
+	}
+	if fake.scans != 3 {
+		t.Errorf("scans = %d, want 3", fake.scans)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeDynamo()
+	store := New(fake, "conversations")
+
+	tests := []struct {
+		err  error
+		want llm.ErrorKind
+	}{
+		{&types.ProvisionedThroughputExceededException{Message: aws.String("slow down")}, llm.ErrRateLimit},
+		{&types.ResourceNotFoundException{Message: aws.String("no table")}, llm.ErrConfig},
+		{errors.New("connection reset"), llm.ErrServer},
+	}
+	for _, tt := range tests {
+		fake.err = tt.err
+		conv := llm.NewConversation("m", llm.WithID("x"))
+		err := store.Save(ctx, &conv)
+		var llmErr *llm.Error
+		if !errors.As(err, &llmErr) || llmErr.Kind != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Save with %v = %v, want %s", tt.err, err, tt.want)
+		}
+		if conv.Revision != 0 {
+			t.Errorf("failed Save changed Revision to %d", conv.Revision)
+		}
+	}
+}
@@ -1,30 +1,51 @@
 package llm
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
-// ErrorKind classifies LLM errors.
+// ErrorKind classifies LLM errors. Each kind is also a sentinel error, so
+// errors.Is(err, ErrRateLimit) reports whether err is an *Error of that
+// kind.
 type ErrorKind int
 
 const (
-	ErrConfig         ErrorKind = iota // misconfiguration
-	ErrAuthentication                  // 401/403
-	ErrNotFound                        // 404
-	ErrInvalidRequest                  // 400
-	ErrRateLimit                       // 429
-	ErrServer                          // 500+
-	ErrContextLength                   // input too large
-	ErrContentFilter                   // blocked by safety guardrails
+	ErrConfig          ErrorKind = iota // misconfiguration
+	ErrAuthentication                   // 401/403
+	ErrNotFound                         // 404
+	ErrInvalidRequest                   // 400
+	ErrRateLimit                        // 429
+	ErrServer                           // 500+
+	ErrContextLength                    // input too large
+	ErrContentFilter                    // blocked by safety guardrails
+	ErrCircuitOpen                      // failed fast by an open circuit breaker
+	ErrTimeout                          // deadline exceeded before the provider answered
+	ErrBudgetExceeded                   // spend cap reached
+	ErrToolLoop                         // tool-use loop stopped by a guard
+	ErrConflict                         // stored conversation changed since it was loaded
+	ErrInvalidResponse                  // reply does not match the requested response format
+	ErrCapacity                         // model not ready or account quota exhausted
+	ErrCanceled                         // caller's context canceled
 )
 
 var errorKindNames = [...]string{
-	ErrConfig:         "config",
-	ErrAuthentication: "authentication",
-	ErrNotFound:       "not_found",
-	ErrInvalidRequest: "invalid_request",
-	ErrRateLimit:      "rate_limit",
-	ErrServer:         "server",
-	ErrContextLength:  "context_length",
-	ErrContentFilter:  "content_filter",
+	ErrConfig:          "config",
+	ErrAuthentication:  "authentication",
+	ErrNotFound:        "not_found",
+	ErrInvalidRequest:  "invalid_request",
+	ErrRateLimit:       "rate_limit",
+	ErrServer:          "server",
+	ErrContextLength:   "context_length",
+	ErrContentFilter:   "content_filter",
+	ErrCircuitOpen:     "circuit_open",
+	ErrTimeout:         "timeout",
+	ErrBudgetExceeded:  "budget_exceeded",
+	ErrToolLoop:        "tool_loop",
+	ErrConflict:        "conflict",
+	ErrInvalidResponse: "invalid_response",
+	ErrCapacity:        "capacity",
+	ErrCanceled:        "canceled",
 }
 
 func (k ErrorKind) String() string {
@@ -34,11 +55,17 @@ func (k ErrorKind) String() string {
 	return fmt.Sprintf("unknown(%d)", k)
 }
 
+// Error returns the kind's name, as String does.
+func (k ErrorKind) Error() string {
+	return k.String()
+}
+
 // Error is the library's error type.
 type Error struct {
-	Kind    ErrorKind
-	Message string
-	Cause   error // underlying error
+	Kind      ErrorKind
+	Message   string
+	Cause     error  // underlying error
+	RequestID string // provider's ID for the failed request, from Bedrock only
 }
 
 func (e *Error) Error() string {
@@ -48,3 +75,43 @@ func (e *Error) Error() string {
 func (e *Error) Unwrap() error {
 	return e.Cause
 }
+
+// Is reports whether target is e's Kind.
+func (e *Error) Is(target error) bool {
+	k, ok := target.(ErrorKind)
+	return ok && k == e.Kind
+}
+
+// ErrorKindForStatus classifies an HTTP error response by its status code
+// and message, as the HTTP providers do. A 400 whose message reports an
+// overlong input is ErrContextLength; gateway timeouts are ErrTimeout and
+// 503 is ErrCapacity, as Bedrock reports the same conditions.
+func ErrorKindForStatus(status int, message string) ErrorKind {
+	switch status {
+	case 400:
+		if isContextLengthMessage(message) {
+			return ErrContextLength
+		}
+		return ErrInvalidRequest
+	case 401, 403:
+		return ErrAuthentication
+	case 404:
+		return ErrNotFound
+	case 408, 504:
+		return ErrTimeout
+	case 429:
+		return ErrRateLimit
+	case 503:
+		return ErrCapacity
+	default:
+		return ErrServer
+	}
+}
+
+// isContextLengthMessage reports whether a provider's error message says
+// the input is too large for the model.
+func isContextLengthMessage(msg string) bool {
+	lower := strings.ToLower(msg)
+	return strings.Contains(lower, "context length") || strings.Contains(lower, "too many tokens") ||
+		strings.Contains(lower, "maximum number of tokens")
+}
@@ -37,6 +37,21 @@ func TestClassifyBedrockError(t *testing.T) {
 		{
 			name:     "ModelTimeoutException",
 			err:      &types.ModelTimeoutException{Message: strPtr("timeout")},
+			wantKind: ErrTimeout,
+		},
+		{
+			name:     "ModelNotReadyException",
+			err:      &types.ModelNotReadyException{Message: strPtr("model not ready")},
+			wantKind: ErrCapacity,
+		},
+		{
+			name:     "ServiceQuotaExceededException",
+			err:      &types.ServiceQuotaExceededException{Message: strPtr("quota exceeded")},
+			wantKind: ErrCapacity,
+		},
+		{
+			name:     "ServiceUnavailableException",
+			err:      &types.ServiceUnavailableException{Message: strPtr("unavailable")},
 			wantKind: ErrServer,
 		},
 		{
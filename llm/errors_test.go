@@ -29,6 +29,19 @@ func TestErrorUnwrap(t *testing.T) {
 	}
 }
 
+func TestErrorIsKind(t *testing.T) {
+	err := fmt.Errorf("sending: %w", &Error{Kind: ErrRateLimit, Message: "slow down"})
+	if !errors.Is(err, ErrRateLimit) {
+		t.Error("errors.Is(err, ErrRateLimit) = false")
+	}
+	if errors.Is(err, ErrServer) {
+		t.Error("errors.Is(err, ErrServer) = true")
+	}
+	if errors.Is(fmt.Errorf("plain"), ErrRateLimit) {
+		t.Error("a plain error matched a kind")
+	}
+}
+
 func TestErrorKindString(t *testing.T) {
 	tests := []struct {
 		kind ErrorKind
@@ -42,6 +55,14 @@ func TestErrorKindString(t *testing.T) {
 		{ErrServer, "server"},
 		{ErrContextLength, "context_length"},
 		{ErrContentFilter, "content_filter"},
+		{ErrCircuitOpen, "circuit_open"},
+		{ErrTimeout, "timeout"},
+		{ErrBudgetExceeded, "budget_exceeded"},
+		{ErrToolLoop, "tool_loop"},
+		{ErrConflict, "conflict"},
+		{ErrInvalidResponse, "invalid_response"},
+		{ErrCapacity, "capacity"},
+		{ErrCanceled, "canceled"},
 	}
 	for _, tt := range tests {
 		if got := tt.kind.String(); got != tt.want {
@@ -49,3 +70,27 @@ func TestErrorKindString(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorKindForStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		msg    string
+		want   ErrorKind
+	}{
+		{400, "bad field", ErrInvalidRequest},
+		{400, "Too many tokens in prompt", ErrContextLength},
+		{400, "exceeds the maximum number of tokens", ErrContextLength},
+		{403, "", ErrAuthentication},
+		{404, "", ErrNotFound},
+		{408, "", ErrTimeout},
+		{429, "", ErrRateLimit},
+		{500, "", ErrServer},
+		{503, "", ErrCapacity},
+		{504, "", ErrTimeout},
+	}
+	for _, tt := range tests {
+		if got := ErrorKindForStatus(tt.status, tt.msg); got != tt.want {
+			t.Errorf("ErrorKindForStatus(%d, %q) = %v, want %v", tt.status, tt.msg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand/v2"
+	"slices"
+)
+
+// Arm is one variant of an Experiment.
+type Arm struct {
+	Name   string
+	Weight float64 // relative share of traffic
+
+	// Model, if set, replaces the conversation's model.
+	Model string
+	// System, if non-nil, replaces the conversation's system prompts.
+	System []string
+	// Apply, if set, makes any other change to the request. It receives a
+	// copy whose System and Messages slices are safe to modify.
+	Apply func(*Conversation)
+}
+
+// Experiment splits traffic between arms. The control arm is usually one
+// with no changes.
+type Experiment struct {
+	Name string
+	Arms []Arm
+}
+
+type experimentKey struct{}
+
+// WithExperimentKey returns a context whose requests are assigned to arms
+// by key, such as a user or conversation ID, so the same key always lands
+// in the same arm. Requests without a key are assigned at random.
+func WithExperimentKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, experimentKey{}, key)
+}
+
+// WithExperiment adds an experiment middleware to the client.
+func WithExperiment(e Experiment) ClientOption {
+	return WithMiddleware(e.Middleware())
+}
+
+// Middleware returns middleware that assigns each request to an arm,
+// applies the arm's changes to a copy of the conversation, and records the
+// assignment in Response.Experiments. The conversation returned by
+// Client.Send is unchanged, so every turn is assigned afresh.
+func (e Experiment) Middleware() Middleware {
+	var total float64
+	for _, a := range e.Arms {
+		total += max(a.Weight, 0)
+	}
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		if total == 0 {
+			return next(ctx, conv)
+		}
+
+		var point float64
+		if key, ok := ctx.Value(experimentKey{}).(string); ok {
+			sum := sha256.Sum256([]byte(e.Name + "\x00" + key))
+			point = float64(binary.BigEndian.Uint64(sum[:8])) / (1 << 64) * total
+		} else {
+			point = rand.Float64() * total
+		}
+		arm := e.Arms[len(e.Arms)-1]
+		for _, a := range e.Arms {
+			if point < max(a.Weight, 0) {
+				arm = a
+				break
+			}
+			point -= max(a.Weight, 0)
+		}
+
+		variant := *conv
+		variant.System = slices.Clone(conv.System)
+		variant.Messages = slices.Clone(conv.Messages)
+		if arm.Model != "" {
+			variant.Model = arm.Model
+		}
+		if arm.System != nil {
+			variant.System = slices.Clone(arm.System)
+		}
+		if arm.Apply != nil {
+			arm.Apply(&variant)
+		}
+
+		resp, err := next(ctx, &variant)
+		if err != nil {
+			return resp, err
+		}
+		if resp.Experiments == nil {
+			resp.Experiments = make(map[string]string)
+		}
+		resp.Experiments[e.Name] = arm.Name
+		if resp.Model == "" {
+			resp.Model = variant.Model
+		}
+		if resp.Fingerprint == (Fingerprint{}) {
+			resp.Fingerprint = variant.Fingerprint()
+		}
+		return resp, nil
+	}
+}
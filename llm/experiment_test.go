@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestExperiment_SplitsTraffic(t *testing.T) {
+	provider := &modelProvider{}
+	client := NewClientWithProvider(provider, WithExperiment(Experiment{
+		Name: "cheaper-model",
+		Arms: []Arm{
+			{Name: "control", Weight: 80},
+			{Name: "haiku", Weight: 20, Model: "haiku"},
+		},
+	}))
+
+	counts := map[string]int{}
+	for i := range 1000 {
+		ctx := WithExperimentKey(context.Background(), fmt.Sprint("user-", i))
+		conv, resp, err := client.Send(ctx, NewConversation("sonnet"), UserMessage("hi"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		arm := resp.Experiments["cheaper-model"]
+		counts[arm]++
+		wantModel := map[string]string{"control": "sonnet", "haiku": "haiku"}[arm]
+		if resp.Model != wantModel || resp.Message.Text() != "from "+wantModel {
+			t.Fatalf("arm %q answered by %q", arm, resp.Model)
+		}
+		if conv.Model != "sonnet" {
+			t.Fatalf("conversation model = %q", conv.Model)
+		}
+	}
+	if counts["haiku"] < 120 || counts["haiku"] > 280 {
+		t.Errorf("counts = %v, want about 20%% haiku", counts)
+	}
+}
+
+func TestExperiment_StickyByKey(t *testing.T) {
+	client := NewClientWithProvider(&countingProvider{resp: simpleResponse("ok")}, WithExperiment(Experiment{
+		Name: "exp",
+		Arms: []Arm{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}},
+	}))
+	ctx := WithExperimentKey(context.Background(), "user-42")
+
+	_, first, _ := client.Send(ctx, NewConversation("m"), UserMessage("hi"))
+	for range 20 {
+		_, resp, _ := client.Send(ctx, NewConversation("m"), UserMessage("hi"))
+		if resp.Experiments["exp"] != first.Experiments["exp"] {
+			t.Fatal("same key assigned to different arms")
+		}
+	}
+}
+
+// systemProvider replies with the conversation's first system prompt.
+type systemProvider struct{}
+
+func (systemProvider) Send(_ context.Context, conv *Conversation) (*Response, error) {
+	return simpleResponse(conv.System[0]), nil
+}
+
+func TestExperiment_PromptVariant(t *testing.T) {
+	client := NewClientWithProvider(systemProvider{}, WithExperiment(Experiment{
+		Name: "prompt",
+		Arms: []Arm{{Name: "terse", Weight: 1, System: []string{"Be terse."}}},
+	}))
+	conv := NewConversation("m", WithSystem("Be helpful."))
+
+	conv, resp, err := client.Send(context.Background(), conv, UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Text() != "Be terse." {
+		t.Errorf("provider saw system %q", resp.Message.Text())
+	}
+	if conv.System[0] != "Be helpful." {
+		t.Errorf("conversation system = %q", conv.System[0])
+	}
+	if resp.Fingerprint.System == conv.Fingerprint().System {
+		t.Error("Fingerprint should describe the variant")
+	}
+}
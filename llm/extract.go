@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// JSON decodes the first JSON object or array in the response text into v.
+// It prefers the contents of a ```json code fence, otherwise skips prose
+// before the document, and ignores whatever follows, for models that
+// cannot be held to a response schema. A reply with no JSON document fails
+// with ErrInvalidResponse.
+func (r *Response) JSON(v any) error {
+	text := r.Message.Text()
+	raw, err := extractJSON(text)
+	if err == nil {
+		err = json.Unmarshal(raw, v)
+	}
+	if err != nil {
+		return &Error{Kind: ErrInvalidResponse, Message: "decoding JSON reply: " + err.Error(), Cause: err}
+	}
+	return nil
+}
+
+// extractJSON returns the JSON document in the first ```json fenced block
+// of text, or failing that the first complete JSON object or array, so
+// brackets in prose before a fenced block are not mistaken for the reply.
+func extractJSON(text string) (json.RawMessage, error) {
+	if _, rest, ok := strings.Cut(text, "```json"); ok {
+		if _, body, ok := strings.Cut(rest, "\n"); ok {
+			body, _, _ = strings.Cut(body, "```")
+			if raw, err := scanJSON(body); err == nil {
+				return raw, nil
+			}
+		}
+	}
+	return scanJSON(text)
+}
+
+// scanJSON returns the first complete JSON object or array in text.
+func scanJSON(text string) (json.RawMessage, error) {
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' && text[i] != '[' {
+			continue
+		}
+		var raw json.RawMessage
+		if json.NewDecoder(strings.NewReader(text[i:])).Decode(&raw) == nil {
+			return raw, nil
+		}
+	}
+	return nil, errors.New("no JSON object or array in reply")
+}
@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResponse_JSON(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"bare", `{"city": "Paris", "population": 2100000}`},
+		{"fenced", "```json\n{\"city\": \"Paris\", \"population\": 2100000}\n```"},
+		{"preamble", "Sure! Here is the data you asked for:\n\n{\"city\": \"Paris\", \"population\": 2100000}\n\nLet me know if you need more."},
+		{"brace in prose", `Fill in {city} below: {"city": "Paris", "population": 2100000}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got extractedCity
+			if err := simpleResponse(tt.text).JSON(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.City != "Paris" || got.Population != 2100000 {
+				t.Errorf("got %+v", got)
+			}
+		})
+	}
+
+	var named struct{ Name string }
+	if err := simpleResponse("Per the docs [1], here it is:\n```json\n{\"name\":\"Paris\"}\n```").JSON(&named); err != nil || named.Name != "Paris" {
+		t.Errorf("fenced after brackets = %+v, %v", named, err)
+	}
+
+	var list []int
+	if err := simpleResponse("The primes are [2, 3, 5].").JSON(&list); err != nil || len(list) != 3 {
+		t.Errorf("list = %v, %v", list, err)
+	}
+}
+
+func TestResponse_JSON_Errors(t *testing.T) {
+	var v map[string]any
+	for _, text := range []string{"No data today.", `{"city": "Par`} {
+		err := simpleResponse(text).JSON(&v)
+		var llmErr *Error
+		if !errors.As(err, &llmErr) || llmErr.Kind != ErrInvalidResponse {
+			t.Errorf("JSON(%q) = %v, want ErrInvalidResponse", text, err)
+		}
+	}
+	var n int
+	if err := simpleResponse(`{"a": 1}`).JSON(&n); err == nil {
+		t.Error("decoding an object into an int succeeded")
+	}
+}
@@ -0,0 +1,41 @@
+package llm
+
+import "context"
+
+// WithFallback adds a Fallback middleware to the client.
+func WithFallback(models ...string) ClientOption {
+	return WithMiddleware(Fallback(models...))
+}
+
+// Fallback returns middleware that resends a failed request to each of
+// models in order when the previous model fails with a rate-limit, server,
+// capacity, or timeout error. Other errors are returned immediately. The
+// conversation's own model is always tried first and is skipped if it
+// also appears in models.
+//
+// The returned Response's Model names the model that answered, and the
+// conversation returned by Client.Send keeps its original model, so the
+// next turn tries the primary again.
+func Fallback(models ...string) Middleware {
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		resp, err := next(ctx, conv)
+		for _, model := range models {
+			if err == nil || !isRetryable(err) || ctx.Err() != nil {
+				break
+			}
+			if model == conv.Model {
+				continue
+			}
+			attempt := *conv
+			attempt.Model = model
+			resp, err = next(ctx, &attempt)
+			if err == nil {
+				if resp.Model == "" {
+					resp.Model = model
+				}
+				resp.Fingerprint = attempt.Fingerprint()
+			}
+		}
+		return resp, err
+	}
+}
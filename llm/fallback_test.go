@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// modelProvider fails with the error mapped to the requested model and
+// records the models it was asked for.
+type modelProvider struct {
+	errs   map[string]error
+	models []string
+}
+
+func (p *modelProvider) Send(_ context.Context, conv *Conversation) (*Response, error) {
+	p.models = append(p.models, conv.Model)
+	if err := p.errs[conv.Model]; err != nil {
+		return nil, err
+	}
+	return simpleResponse("from " + conv.Model), nil
+}
+
+func TestFallback_UsesNextModel(t *testing.T) {
+	provider := &modelProvider{errs: map[string]error{
+		"sonnet": &Error{Kind: ErrRateLimit},
+		"haiku":  &Error{Kind: ErrServer},
+	}}
+	client := NewClientWithProvider(provider, WithFallback("haiku", "nova-pro"))
+
+	conv, resp, err := client.Send(context.Background(), NewConversation("sonnet"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "nova-pro" {
+		t.Errorf("Model = %q, want nova-pro", resp.Model)
+	}
+	if resp.Message.Text() != "from nova-pro" {
+		t.Errorf("Text = %q", resp.Message.Text())
+	}
+	if resp.Fingerprint.Model == NewConversation("sonnet").Fingerprint().Model {
+		t.Error("Fingerprint should describe the fallback model")
+	}
+	if conv.Model != "sonnet" {
+		t.Errorf("conversation model = %q, want sonnet", conv.Model)
+	}
+	if got := len(provider.models); got != 3 {
+		t.Errorf("provider called %d times, want 3", got)
+	}
+}
+
+func TestFallback_PrimarySucceeds(t *testing.T) {
+	provider := &modelProvider{}
+	client := NewClientWithProvider(provider, WithFallback("haiku"))
+
+	_, resp, err := client.Send(context.Background(), NewConversation("sonnet"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "sonnet" {
+		t.Errorf("Model = %q, want sonnet", resp.Model)
+	}
+	if len(provider.models) != 1 {
+		t.Errorf("models = %v", provider.models)
+	}
+}
+
+func TestFallback_PermanentErrorStops(t *testing.T) {
+	provider := &modelProvider{errs: map[string]error{
+		"sonnet": &Error{Kind: ErrInvalidRequest},
+	}}
+	client := NewClientWithProvider(provider, WithFallback("haiku"))
+
+	_, _, err := client.Send(context.Background(), NewConversation("sonnet"), UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrInvalidRequest {
+		t.Fatalf("err = %v, want ErrInvalidRequest", err)
+	}
+	if len(provider.models) != 1 {
+		t.Errorf("models = %v", provider.models)
+	}
+}
+
+func TestFallback_ChainExhausted(t *testing.T) {
+	provider := &modelProvider{errs: map[string]error{
+		"sonnet": &Error{Kind: ErrServer},
+		"haiku":  &Error{Kind: ErrRateLimit, Message: "last"},
+	}}
+	client := NewClientWithProvider(provider, WithFallback("sonnet", "haiku"))
+
+	_, _, err := client.Send(context.Background(), NewConversation("sonnet"), UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Message != "last" {
+		t.Fatalf("err = %v, want the last model's error", err)
+	}
+	if want := []string{"sonnet", "haiku"}; len(provider.models) != len(want) {
+		t.Errorf("models = %v, want %v", provider.models, want)
+	}
+}
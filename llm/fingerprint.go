@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Fingerprint identifies the effective prompt configuration that produced a
+// Response. Hashes are stable across processes, so fingerprints can be stored
+// alongside outputs and compared later for experiment tracking or incident
+// analysis. Message history is deliberately excluded.
+type Fingerprint struct {
+	Model  string `json:"model"`
+	System string `json:"system,omitempty"` // hash of the system prompts
+	Tools  string `json:"tools,omitempty"`  // hash of the tool definitions
+	Config string `json:"config"`           // hash of the inference config
+}
+
+// String returns a single hash combining all fingerprint fields.
+func (f Fingerprint) String() string {
+	return hashJSON(f)
+}
+
+// Fingerprint computes the prompt configuration fingerprint of the conversation.
+func (c Conversation) Fingerprint() Fingerprint {
+	f := Fingerprint{
+		Model:  c.Model,
+		Config: hashJSON(c.Config),
+	}
+	if len(c.System) > 0 {
+		f.System = hashJSON(c.System)
+	}
+	if len(c.Tools) > 0 {
+		f.Tools = hashJSON(c.Tools)
+	}
+	return f
+}
+
+// hashJSON returns a short hex SHA-256 digest of the JSON encoding of v.
+func hashJSON(v any) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
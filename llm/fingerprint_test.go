@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFingerprintStable(t *testing.T) {
+	tool := NewTool("get_weather", "Get weather", StringParam("location"))
+	a := NewConversation("model", WithSystem("Be helpful."), WithTools(tool), WithTemperature(0.5))
+	b := NewConversation("model", WithSystem("Be helpful."), WithTools(tool), WithTemperature(0.5))
+	b.Messages = []Message{UserMessage("history is not part of the fingerprint")}
+
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Errorf("fingerprints differ: %+v vs %+v", a.Fingerprint(), b.Fingerprint())
+	}
+	if a.Fingerprint().String() != b.Fingerprint().String() {
+		t.Error("combined hashes differ")
+	}
+}
+
+func TestFingerprintDetectsChanges(t *testing.T) {
+	base := NewConversation("model", WithSystem("Be helpful."), WithTemperature(0.5))
+	tests := []struct {
+		name  string
+		conv  Conversation
+		field func(Fingerprint) string
+	}{
+		{"model", NewConversation("other", WithSystem("Be helpful."), WithTemperature(0.5)), func(f Fingerprint) string { return f.Model }},
+		{"system", NewConversation("model", WithSystem("Be terse."), WithTemperature(0.5)), func(f Fingerprint) string { return f.System }},
+		{"tools", NewConversation("model", WithSystem("Be helpful."), WithTemperature(0.5), WithTools(NewTool("t", "d"))), func(f Fingerprint) string { return f.Tools }},
+		{"config", NewConversation("model", WithSystem("Be helpful."), WithTemperature(0.9)), func(f Fingerprint) string { return f.Config }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, want := tt.conv.Fingerprint(), base.Fingerprint()
+			if tt.field(got) == tt.field(want) {
+				t.Errorf("%s field unchanged: %q", tt.name, tt.field(got))
+			}
+			if got.String() == want.String() {
+				t.Error("combined hash unchanged")
+			}
+		})
+	}
+}
+
+func TestFingerprintOmitsEmptySections(t *testing.T) {
+	f := NewConversation("model").Fingerprint()
+	if f.System != "" || f.Tools != "" {
+		t.Errorf("expected empty system/tools hashes, got %+v", f)
+	}
+	if f.Config == "" {
+		t.Error("Config hash should always be set")
+	}
+}
+
+func TestClientSend_StampsFingerprint(t *testing.T) {
+	client := NewClientWithProvider(&mockProvider{resp: simpleResponse("ok")})
+	conv := NewConversation("model", WithSystem("Be helpful."))
+
+	_, resp, err := client.Send(context.Background(), conv, UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Fingerprint != conv.Fingerprint() {
+		t.Errorf("Fingerprint = %+v, want %+v", resp.Fingerprint, conv.Fingerprint())
+	}
+}
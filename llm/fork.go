@@ -0,0 +1,32 @@
+package llm
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// ForkPoint records where a forked conversation split from its parent.
+type ForkPoint struct {
+	ParentID string `json:"parent_id,omitempty"`
+	At       int    `json:"at"` // number of parent messages the fork shares
+}
+
+// Fork returns a new conversation sharing the first at messages of c and
+// its model, system prompts, tools, config, and metadata, for exploring
+// alternative turns without touching c. The fork has no ID, timestamps,
+// or Revision of its own, zero Usage and no UsageHistory so spend is not
+// counted twice, and Parent set to c's ID and at. Fork(len(c.Messages))
+// branches from the latest turn.
+//
+// Fork panics if at is out of range, like slicing.
+func (c Conversation) Fork(at int) Conversation {
+	fork := c
+	fork.Messages = slices.Clone(c.Messages[:at])
+	fork.Metadata = maps.Clone(c.Metadata)
+	fork.ID, fork.Revision = "", 0
+	fork.CreatedAt, fork.UpdatedAt = time.Time{}, time.Time{}
+	fork.Usage, fork.UsageHistory = Usage{}, nil
+	fork.Parent = &ForkPoint{ParentID: c.ID, At: at}
+	return fork
+}
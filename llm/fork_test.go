@@ -0,0 +1,37 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestConversation_Fork(t *testing.T) {
+	client := NewClientWithProvider(&mockProvider{resp: simpleResponse("reply")})
+	main := NewConversation("model", WithSystem("be brief"), WithID("main"), WithMetadata("tenant", "acme"))
+	main.Messages = toolTurnHistory()
+	main.Usage = Usage{InputTokens: 100}
+	main.UsageHistory = []TurnUsage{{Model: "m", Usage: main.Usage}}
+
+	fork := main.Fork(2)
+	if fork.Parent == nil || *fork.Parent != (ForkPoint{ParentID: "main", At: 2}) {
+		t.Errorf("Parent = %+v", fork.Parent)
+	}
+	if fork.ID != "" || fork.Usage != (Usage{}) || fork.UsageHistory != nil || len(fork.System) != 1 || fork.Metadata["tenant"] != "acme" {
+		t.Errorf("fork = %+v", fork)
+	}
+
+	fork, _, err := client.Send(context.Background(), fork, UserMessage("what if?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fork.Metadata["branch"] = "what-if"
+	if got := messageTexts(fork.Messages); got != "one,1,what if?,reply" {
+		t.Errorf("fork messages = %s", got)
+	}
+	if got := messageTexts(main.Messages[:3]); got != "one,1,two" || len(main.Messages) != 9 {
+		t.Errorf("main messages = %s", messageTexts(main.Messages))
+	}
+	if _, ok := main.Metadata["branch"]; ok {
+		t.Error("fork shares metadata with main")
+	}
+}
@@ -0,0 +1,151 @@
+package llm
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// Guardrail applies an Amazon Bedrock guardrail to a conversation's
+// requests. Other providers ignore it. When the guardrail intervenes the
+// reply is its blocked message and the finish reason is
+// FinishReasonContentFilter. With Trace enabled, Response.Guardrail
+// reports what the guardrail found.
+type Guardrail struct {
+	Identifier string         `json:"identifier"`      // guardrail ID or ARN
+	Version    string         `json:"version"`         // version number or "DRAFT"
+	Trace      GuardrailTrace `json:"trace,omitempty"` // disabled if empty
+
+	// StreamProcessingMode is "sync" or "async", for streaming requests.
+	// Converse does not use it.
+	StreamProcessingMode string `json:"stream_processing_mode,omitempty"`
+}
+
+// GuardrailTrace selects how much of a guardrail's assessment is returned.
+type GuardrailTrace string
+
+const (
+	GuardrailTraceDisabled GuardrailTrace = "disabled"
+	GuardrailTraceEnabled  GuardrailTrace = "enabled"
+	GuardrailTraceFull     GuardrailTrace = "enabled_full" // also reports policies that found nothing
+)
+
+// WithGuardrail sets the guardrail config.
+func WithGuardrail(g Guardrail) ConversationOption {
+	return func(c *Conversation) {
+		c.Config.Guardrail = &g
+	}
+}
+
+// GuardrailAssessment is what a guardrail found in a request and its reply.
+type GuardrailAssessment struct {
+	ActionReason string             `json:"action_reason,omitempty"`
+	Input        []GuardrailFinding `json:"input,omitempty"`
+	Output       []GuardrailFinding `json:"output,omitempty"`
+
+	// ModelOutput is the model's reply before the guardrail blocked or
+	// masked it. The response message holds the text after masking.
+	ModelOutput []string `json:"model_output,omitempty"`
+}
+
+// Intervened reports whether the guardrail blocked or masked anything.
+func (a *GuardrailAssessment) Intervened() bool {
+	intervened := func(f GuardrailFinding) bool { return f.Action != "" && f.Action != "NONE" }
+	return slices.ContainsFunc(a.Input, intervened) || slices.ContainsFunc(a.Output, intervened)
+}
+
+// Detected returns the input and output findings of policy, such as
+// "topic" or "content", that the guardrail detected.
+func (a *GuardrailAssessment) Detected(policy string) []GuardrailFinding {
+	var fs []GuardrailFinding
+	for _, f := range slices.Concat(a.Input, a.Output) {
+		if f.Policy == policy && f.Detected {
+			fs = append(fs, f)
+		}
+	}
+	return fs
+}
+
+// GuardrailFinding is one content filter, denied topic, word, sensitive
+// information, or contextual grounding check from a guardrail policy.
+type GuardrailFinding struct {
+	Guardrail string `json:"guardrail"`       // ID of the guardrail that assessed it
+	Policy    string `json:"policy"`          // "content", "topic", "word", "sensitive_information", or "contextual_grounding"
+	Type      string `json:"type"`            // filter type, topic name, PII entity type, regex name, or word list
+	Match     string `json:"match,omitempty"` // the matched text, for words and sensitive information
+	Action    string `json:"action"`          // such as "BLOCKED", "ANONYMIZED", or "NONE"
+	Detected  bool   `json:"detected"`
+}
+
+// toConverseGuardrail translates g into a Converse guardrail configuration.
+func toConverseGuardrail(g *Guardrail) *types.GuardrailConfiguration {
+	return &types.GuardrailConfiguration{
+		GuardrailIdentifier: strPtr(g.Identifier),
+		GuardrailVersion:    strPtr(g.Version),
+		Trace:               types.GuardrailTrace(g.Trace),
+	}
+}
+
+// fromConverseTrace returns the guardrail assessment in out's trace, or nil
+// if there is none.
+func fromConverseTrace(out *bedrockruntime.ConverseOutput) *GuardrailAssessment {
+	if out.Trace == nil || out.Trace.Guardrail == nil {
+		return nil
+	}
+	t := out.Trace.Guardrail
+	a := &GuardrailAssessment{ActionReason: derefStr(t.ActionReason), ModelOutput: t.ModelOutput}
+	for _, id := range slices.Sorted(maps.Keys(t.InputAssessment)) {
+		a.Input = appendFindings(a.Input, id, t.InputAssessment[id])
+	}
+	for _, id := range slices.Sorted(maps.Keys(t.OutputAssessments)) {
+		for _, as := range t.OutputAssessments[id] {
+			a.Output = appendFindings(a.Output, id, as)
+		}
+	}
+	return a
+}
+
+// appendFindings appends the findings of guardrail id's assessment to fs.
+func appendFindings(fs []GuardrailFinding, id string, a types.GuardrailAssessment) []GuardrailFinding {
+	add := func(policy, typ, match, action string, detected *bool) {
+		f := GuardrailFinding{Guardrail: id, Policy: policy, Type: typ, Match: match, Action: action, Detected: action != "NONE"}
+		if detected != nil {
+			f.Detected = *detected
+		}
+		fs = append(fs, f)
+	}
+	if p := a.ContentPolicy; p != nil {
+		for _, f := range p.Filters {
+			add("content", string(f.Type), "", string(f.Action), f.Detected)
+		}
+	}
+	if p := a.TopicPolicy; p != nil {
+		for _, t := range p.Topics {
+			add("topic", derefStr(t.Name), "", string(t.Action), t.Detected)
+		}
+	}
+	if p := a.WordPolicy; p != nil {
+		for _, w := range p.CustomWords {
+			add("word", "CUSTOM", derefStr(w.Match), string(w.Action), w.Detected)
+		}
+		for _, w := range p.ManagedWordLists {
+			add("word", string(w.Type), derefStr(w.Match), string(w.Action), w.Detected)
+		}
+	}
+	if p := a.SensitiveInformationPolicy; p != nil {
+		for _, e := range p.PiiEntities {
+			add("sensitive_information", string(e.Type), derefStr(e.Match), string(e.Action), e.Detected)
+		}
+		for _, r := range p.Regexes {
+			add("sensitive_information", derefStr(r.Name), derefStr(r.Match), string(r.Action), r.Detected)
+		}
+	}
+	if p := a.ContextualGroundingPolicy; p != nil {
+		for _, f := range p.Filters {
+			add("contextual_grounding", string(f.Type), "", string(f.Action), f.Detected)
+		}
+	}
+	return fs
+}
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+func TestToConverseInput_Guardrail(t *testing.T) {
+	conv := NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+		WithGuardrail(Guardrail{Identifier: "gr-1", Version: "DRAFT", Trace: GuardrailTraceEnabled}))
+	conv.Messages = []Message{UserMessage("hi")}
+
+	gc := toConverseInput(&conv).GuardrailConfig
+	if gc == nil || *gc.GuardrailIdentifier != "gr-1" || *gc.GuardrailVersion != "DRAFT" || gc.Trace != types.GuardrailTraceEnabled {
+		t.Errorf("guardrail config = %+v", gc)
+	}
+	conv.Config.Guardrail = nil
+	if gc := toConverseInput(&conv).GuardrailConfig; gc != nil {
+		t.Errorf("guardrail config = %+v, want nil", gc)
+	}
+}
+
+func TestBedrockProvider_GuardrailTrace(t *testing.T) {
+	out := simpleConverseOutput("Sorry, I can't help with that.")
+	out.StopReason = types.StopReasonGuardrailIntervened
+	out.Trace = &types.ConverseTrace{Guardrail: &types.GuardrailTraceAssessment{
+		ActionReason: aws.String("Guardrail blocked."),
+		ModelOutput:  []string{"Write to a@example.com."},
+		InputAssessment: map[string]types.GuardrailAssessment{"gr-1": {
+			ContentPolicy: &types.GuardrailContentPolicyAssessment{Filters: []types.GuardrailContentFilter{
+				{Type: types.GuardrailContentFilterTypeViolence, Action: types.GuardrailContentPolicyActionBlocked, Confidence: types.GuardrailContentFilterConfidenceHigh},
+			}},
+			TopicPolicy: &types.GuardrailTopicPolicyAssessment{Topics: []types.GuardrailTopic{
+				{Name: aws.String("investing"), Action: types.GuardrailTopicPolicyActionNone, Detected: aws.Bool(false)},
+			}},
+		}},
+		OutputAssessments: map[string][]types.GuardrailAssessment{"gr-1": {{
+			SensitiveInformationPolicy: &types.GuardrailSensitiveInformationPolicyAssessment{PiiEntities: []types.GuardrailPiiEntityFilter{
+				{Type: types.GuardrailPiiEntityTypeEmail, Match: aws.String("a@example.com"), Action: types.GuardrailSensitiveInformationPolicyActionAnonymized},
+			}},
+		}}},
+	}}
+	provider := NewBedrockProvider(&mockConverser{output: out})
+	conv := NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0", WithGuardrail(Guardrail{Identifier: "gr-1", Version: "1", Trace: GuardrailTraceEnabled}))
+	conv.Messages = []Message{UserMessage("hi")}
+
+	resp, err := provider.Send(context.Background(), &conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.FinishReason != FinishReasonContentFilter {
+		t.Errorf("FinishReason = %q", resp.FinishReason)
+	}
+	g := resp.Guardrail
+	if g == nil || g.ActionReason != "Guardrail blocked." || !g.Intervened() {
+		t.Fatalf("guardrail = %+v", g)
+	}
+	wantInput := []GuardrailFinding{
+		{Guardrail: "gr-1", Policy: "content", Type: "VIOLENCE", Action: "BLOCKED", Detected: true},
+		{Guardrail: "gr-1", Policy: "topic", Type: "investing", Action: "NONE"},
+	}
+	if len(g.Input) != len(wantInput) || g.Input[0] != wantInput[0] || g.Input[1] != wantInput[1] {
+		t.Errorf("input = %+v", g.Input)
+	}
+	want := GuardrailFinding{Guardrail: "gr-1", Policy: "sensitive_information", Type: "EMAIL", Match: "a@example.com", Action: "ANONYMIZED", Detected: true}
+	if len(g.Output) != 1 || g.Output[0] != want {
+		t.Errorf("output = %+v", g.Output)
+	}
+	if len(g.ModelOutput) != 1 || g.ModelOutput[0] != "Write to a@example.com." {
+		t.Errorf("model output = %q", g.ModelOutput)
+	}
+	if got := g.Detected("content"); len(got) != 1 || got[0] != wantInput[0] {
+		t.Errorf("detected content = %+v", got)
+	}
+	if got := g.Detected("topic"); len(got) != 0 {
+		t.Errorf("detected topics = %+v, want none", got)
+	}
+
+	// Without a trace there is no assessment.
+	out.Trace = nil
+	if resp, err := provider.Send(context.Background(), &conv); err != nil || resp.Guardrail != nil {
+		t.Errorf("guardrail = %+v, %v", resp.Guardrail, err)
+	}
+}
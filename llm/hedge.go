@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+)
+
+// HedgePolicy configures Hedge.
+type HedgePolicy struct {
+	// Delay is how long the primary request may run before the backup
+	// request is launched.
+	Delay time.Duration
+
+	// Model is the backup model. Empty means the conversation's model.
+	Model string
+
+	// Provider, if set, receives the backup request directly, for example a
+	// BedrockProvider for another region. Otherwise the backup goes through
+	// the rest of the middleware chain like the primary.
+	Provider Provider
+}
+
+// WithHedge adds a Hedge middleware to the client.
+func WithHedge(policy HedgePolicy) ClientOption {
+	return WithMiddleware(Hedge(policy))
+}
+
+// Hedge returns middleware that launches a backup request when the primary
+// has not completed within policy.Delay, returns whichever succeeds first,
+// and cancels the other. If the primary fails before the delay its error
+// is returned without hedging; once both are running, an error from one
+// waits for the other, and if both fail the primary's error is returned.
+//
+// A backup answer sets Response.Model and Response.Fingerprint to describe
+// the backup request. Both requests are billed by the provider.
+func Hedge(policy HedgePolicy) Middleware {
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type result struct {
+			resp   *Response
+			err    error
+			backup bool
+		}
+		results := make(chan result, 2)
+
+		// Each request gets its own copy, since the loser may still be
+		// reading it after Hedge returns and the caller updates conv.
+		primary := cloneForSend(conv)
+		backup := cloneForSend(conv)
+		if policy.Model != "" {
+			backup.Model = policy.Model
+		}
+
+		go func() {
+			resp, err := next(ctx, &primary)
+			results <- result{resp: resp, err: err}
+		}()
+
+		timer := time.NewTimer(policy.Delay)
+		defer timer.Stop()
+		select {
+		case r := <-results:
+			*conv = primary
+			return r.resp, r.err
+		case <-ctx.Done():
+			r := <-results
+			*conv = primary
+			return r.resp, r.err
+		case <-timer.C:
+		}
+
+		go func() {
+			send := next
+			if policy.Provider != nil {
+				send = policy.Provider.Send
+			}
+			resp, err := send(ctx, &backup)
+			results <- result{resp: resp, err: err, backup: true}
+		}()
+
+		var primaryErr error
+		for range 2 {
+			r := <-results
+			if r.err == nil {
+				if r.backup {
+					if r.resp.Model == "" {
+						r.resp.Model = backup.Model
+					}
+					r.resp.Fingerprint = backup.Fingerprint()
+				} else {
+					*conv = primary
+				}
+				return r.resp, nil
+			}
+			if !r.backup || primaryErr == nil {
+				primaryErr = r.err
+			}
+		}
+		return nil, primaryErr
+	}
+}
+
+// cloneForSend returns a copy of conv whose messages and metadata can be
+// changed, or read by another goroutine, independently of conv.
+func cloneForSend(conv *Conversation) Conversation {
+	c := *conv
+	c.Messages = slices.Clone(conv.Messages)
+	c.Metadata = maps.Clone(conv.Metadata)
+	return c
+}
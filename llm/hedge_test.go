@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// delayedProvider answers after the delay configured for the requested
+// model, or fails with ctx.Err() if canceled first.
+type delayedProvider struct {
+	delays   map[string]time.Duration
+	errs     map[string]error
+	canceled atomic.Int32
+}
+
+func (p *delayedProvider) Send(ctx context.Context, conv *Conversation) (*Response, error) {
+	select {
+	case <-time.After(p.delays[conv.Model]):
+	case <-ctx.Done():
+		p.canceled.Add(1)
+		return nil, ctx.Err()
+	}
+	if err := p.errs[conv.Model]; err != nil {
+		return nil, err
+	}
+	return simpleResponse("from " + conv.Model), nil
+}
+
+func TestHedge_BackupWins(t *testing.T) {
+	provider := &delayedProvider{delays: map[string]time.Duration{"slow": time.Second, "fast": 0}}
+	client := NewClientWithProvider(provider, WithHedge(HedgePolicy{Delay: 10 * time.Millisecond, Model: "fast"}))
+
+	start := time.Now()
+	conv, resp, err := client.Send(context.Background(), NewConversation("slow"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("hedged request waited for the slow primary")
+	}
+	if resp.Model != "fast" || resp.Message.Text() != "from fast" {
+		t.Errorf("Model = %q, Text = %q", resp.Model, resp.Message.Text())
+	}
+	if conv.Model != "slow" {
+		t.Errorf("conversation model = %q, want slow", conv.Model)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if provider.canceled.Load() != 1 {
+		t.Error("primary request was not canceled")
+	}
+}
+
+func TestHedge_PrimaryWithinDelay(t *testing.T) {
+	provider := &delayedProvider{delays: map[string]time.Duration{"primary": 0}}
+	client := NewClientWithProvider(provider, WithHedge(HedgePolicy{Delay: time.Second, Model: "backup"}))
+
+	_, resp, err := client.Send(context.Background(), NewConversation("primary"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "primary" {
+		t.Errorf("Model = %q, want primary", resp.Model)
+	}
+}
+
+func TestHedge_BackupProvider(t *testing.T) {
+	primary := &delayedProvider{delays: map[string]time.Duration{"m": time.Second}}
+	backup := &mockProvider{resp: simpleResponse("other region")}
+	client := NewClientWithProvider(primary, WithHedge(HedgePolicy{Delay: time.Millisecond, Provider: backup}))
+
+	_, resp, err := client.Send(context.Background(), NewConversation("m"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Text() != "other region" || resp.Model != "m" {
+		t.Errorf("Model = %q, Text = %q", resp.Model, resp.Message.Text())
+	}
+}
+
+func TestHedge_FailedBackupWaitsForPrimary(t *testing.T) {
+	provider := &delayedProvider{
+		delays: map[string]time.Duration{"primary": 50 * time.Millisecond},
+		errs:   map[string]error{"backup": &Error{Kind: ErrServer}},
+	}
+	client := NewClientWithProvider(provider, WithHedge(HedgePolicy{Delay: time.Millisecond, Model: "backup"}))
+
+	_, resp, err := client.Send(context.Background(), NewConversation("primary"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "primary" {
+		t.Errorf("Model = %q, want primary", resp.Model)
+	}
+}
+
+func TestHedge_BothFailReturnsPrimaryError(t *testing.T) {
+	primaryErr := &Error{Kind: ErrRateLimit, Message: "primary"}
+	provider := &delayedProvider{
+		delays: map[string]time.Duration{"primary": 20 * time.Millisecond},
+		errs:   map[string]error{"primary": primaryErr, "backup": &Error{Kind: ErrServer}},
+	}
+	client := NewClientWithProvider(provider, WithHedge(HedgePolicy{Delay: time.Millisecond, Model: "backup"}))
+
+	_, _, err := client.Send(context.Background(), NewConversation("primary"), UserMessage("hi"))
+	if !errors.Is(err, primaryErr) {
+		t.Errorf("err = %v, want primary error", err)
+	}
+}
+
+// lingeringProvider answers late and keeps reading the conversation after
+// it has been canceled, as a provider still encoding a request might.
+type lingeringProvider struct {
+	done chan struct{}
+}
+
+func (p *lingeringProvider) Send(ctx context.Context, conv *Conversation) (*Response, error) {
+	defer close(p.done)
+	<-ctx.Done()
+	time.Sleep(20 * time.Millisecond)
+	n := 0
+	for _, m := range conv.Messages {
+		n += len(m.Content)
+	}
+	_ = conv.Usage.InputTokens + n
+	return nil, ctx.Err()
+}
+
+func TestHedge_BackupWinsWhilePrimaryReads(t *testing.T) {
+	primary := &lingeringProvider{done: make(chan struct{})}
+	client := NewClientWithProvider(primary, WithHedge(HedgePolicy{
+		Delay:    time.Millisecond,
+		Provider: &mockProvider{resp: simpleResponse("backup")},
+	}))
+
+	conv, resp, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Text() != "backup" || len(conv.Messages) != 2 {
+		t.Errorf("Text = %q, messages = %d", resp.Message.Text(), len(conv.Messages))
+	}
+	<-primary.done
+}
@@ -0,0 +1,470 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ImageFetcher downloads images given by http(s) URL and inlines their
+// bytes and media type, for providers such as Bedrock that need image
+// data rather than a link. The zero value is ready to use; it must not be
+// copied after first use.
+type ImageFetcher struct {
+	// Client makes the requests. If nil, a client is used that refuses to
+	// connect to loopback, private, and link-local addresses, including
+	// through redirects, and ignores proxy settings.
+	Client *http.Client
+
+	MaxBytes   int64         // largest image accepted; 20 MiB if zero
+	Timeout    time.Duration // per image; 30s if zero
+	MediaTypes []string      // accepted media types; PNG, JPEG, GIF, and WebP if empty
+
+	// AllowHosts, if set, limits fetches, including redirects, to these
+	// hosts. An entry starting with "*." matches any subdomain of the rest.
+	AllowHosts []string
+
+	// CacheBytes bounds the total size of images Inline keeps for later
+	// calls, so a conversation's images are not downloaded again every
+	// turn; 64 MiB if zero. A negative value disables the cache. The
+	// oldest images are dropped first.
+	CacheBytes int64
+
+	mu        sync.Mutex
+	cache     map[string]ImageData
+	cached    []string // URLs in cache, oldest first
+	cacheSize int64
+}
+
+var defaultImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// Fetch downloads the image at url. It fails with ErrInvalidRequest if the
+// host is not allowed, the server does not answer 200 OK, the body is
+// larger than MaxBytes, or its media type is not accepted. A missing or
+// generic Content-Type is detected from the bytes.
+func (f *ImageFetcher) Fetch(ctx context.Context, url string) (ImageData, error) {
+	fail := func(msg string, cause error) (ImageData, error) {
+		return ImageData{}, &Error{Kind: ErrInvalidRequest, Message: "fetching image " + url + ": " + msg, Cause: cause}
+	}
+	timeout := f.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fail(err.Error(), err)
+	}
+	if err := f.checkHost(req.URL); err != nil {
+		return fail(err.Error(), err)
+	}
+	client := f.Client
+	if client == nil {
+		client = guardedClient
+	}
+	if len(f.AllowHosts) > 0 {
+		c := *client
+		c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if err := f.checkHost(req.URL); err != nil {
+				return err
+			}
+			if client.CheckRedirect != nil {
+				return client.CheckRedirect(req, via)
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		}
+		client = &c
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fail(err.Error(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fail("HTTP "+resp.Status, nil)
+	}
+
+	limit := f.MaxBytes
+	if limit <= 0 {
+		limit = 20 << 20
+	}
+	if resp.ContentLength > limit {
+		return fail(fmt.Sprintf("%d bytes exceeds the limit of %d", resp.ContentLength, limit), nil)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return fail(err.Error(), err)
+	}
+	if int64(len(data)) > limit {
+		return fail(fmt.Sprintf("more than the limit of %d bytes", limit), nil)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" || mediaType == "application/octet-stream" || mediaType == "binary/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	accepted := f.MediaTypes
+	if len(accepted) == 0 {
+		accepted = defaultImageTypes
+	}
+	if !slices.Contains(accepted, mediaType) {
+		return fail("unsupported media type "+mediaType, nil)
+	}
+	return ImageData{Data: data, MediaType: mediaType}, nil
+}
+
+// Inline returns a copy of conv with every image given by http(s) URL,
+// including images in tool results, replaced by its downloaded data. Each
+// URL is fetched once, and kept for later calls within CacheBytes. Other
+// images, such as s3:// URLs, are left as is; conv is not modified.
+func (f *ImageFetcher) Inline(ctx context.Context, conv Conversation) (Conversation, error) {
+	fetched := make(map[string]ImageData)
+	return mapImages(conv, func(img *ImageData) error {
+		if len(img.Data) > 0 || !(strings.HasPrefix(img.URL, "https://") || strings.HasPrefix(img.URL, "http://")) {
+			return nil
+		}
+		got, ok := fetched[img.URL]
+		if !ok {
+			if got, ok = f.lookup(img.URL); !ok {
+				var err error
+				if got, err = f.Fetch(ctx, img.URL); err != nil {
+					return err
+				}
+				f.remember(img.URL, got)
+			}
+			fetched[img.URL] = got
+		}
+		*img = got
+		return nil
+	})
+}
+
+func (f *ImageFetcher) lookup(url string) (ImageData, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	img, ok := f.cache[url]
+	return img, ok
+}
+
+// remember caches img, dropping the oldest images to stay within
+// CacheBytes. Images larger than the whole cache are not kept.
+func (f *ImageFetcher) remember(url string, img ImageData) {
+	limit := f.CacheBytes
+	if limit == 0 {
+		limit = 64 << 20
+	}
+	size := int64(len(img.Data))
+	if size > limit {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.cache[url]; ok {
+		return
+	}
+	for f.cacheSize+size > limit {
+		oldest := f.cached[0]
+		f.cached = f.cached[1:]
+		f.cacheSize -= int64(len(f.cache[oldest].Data))
+		delete(f.cache, oldest)
+	}
+	if f.cache == nil {
+		f.cache = make(map[string]ImageData)
+	}
+	f.cache[url] = img
+	f.cached = append(f.cached, url)
+	f.cacheSize += size
+}
+
+// checkHost reports an error if AllowHosts is set and does not match u.
+func (f *ImageFetcher) checkHost(u *url.URL) error {
+	if len(f.AllowHosts) == 0 {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range f.AllowHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:]) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %q is not in AllowHosts", host)
+}
+
+// guardedClient is ImageFetcher's default client. It checks the address
+// of every connection, so neither a URL nor a redirect can reach services
+// on the local network.
+var guardedClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 30 * time.Second, Control: refuseNonPublic}).DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+func refuseNonPublic(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("refusing to connect to non-public address %s", ip)
+	}
+	return nil
+}
+
+// WithImageInlining adds f's middleware to the client.
+func WithImageInlining(f *ImageFetcher) ClientOption {
+	return WithMiddleware(f.Middleware())
+}
+
+// Middleware returns middleware that inlines images given by URL in each
+// request, as Inline does. The conversation returned by Send keeps the
+// URLs, so it stays small.
+func (f *ImageFetcher) Middleware() Middleware {
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		inlined, err := f.Inline(ctx, *conv)
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, &inlined)
+	}
+}
+
+// ImageLimits describes the images a provider accepts. Zero fields are
+// unlimited.
+type ImageLimits struct {
+	MaxBytes   int      // encoded size
+	MaxSide    int      // longest side in pixels
+	MediaTypes []string // accepted media types
+}
+
+// ImageLimitsFor returns the image limits of a built-in provider, named as
+// for ValidateFor, which reports images that exceed them. Unknown
+// providers have no limits.
+func ImageLimitsFor(provider string) ImageLimits {
+	return providerContent[provider].limits
+}
+
+// Check reports, as ErrInvalidRequest, an image that exceeds the limits.
+// Images given by URL are not checked; their size is unknown until they
+// are fetched. Dimensions are checked for PNG, JPEG, and GIF images.
+func (l ImageLimits) Check(img ImageData) error {
+	if err := l.check(img); err != nil {
+		return &Error{Kind: ErrInvalidRequest, Message: err.Error()}
+	}
+	return nil
+}
+
+func (l ImageLimits) check(img ImageData) error {
+	if len(img.Data) == 0 {
+		return nil
+	}
+	if mediaType := imageMediaType(img); len(l.MediaTypes) > 0 && !slices.Contains(l.MediaTypes, mediaType) {
+		return fmt.Errorf("unsupported image type %s", mediaType)
+	}
+	if l.MaxBytes > 0 && len(img.Data) > l.MaxBytes {
+		return fmt.Errorf("image is %d bytes; the limit is %d", len(img.Data), l.MaxBytes)
+	}
+	if l.MaxSide > 0 {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data)); err == nil && max(cfg.Width, cfg.Height) > l.MaxSide {
+			return fmt.Errorf("image is %dx%d pixels; the limit is %d on the longest side", cfg.Width, cfg.Height, l.MaxSide)
+		}
+	}
+	return nil
+}
+
+// imageMediaType returns img's media type, detected from its data if
+// unset.
+func imageMediaType(img ImageData) string {
+	if img.MediaType != "" {
+		return img.MediaType
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(img.Data))
+	return mediaType
+}
+
+// Fit returns img unchanged if it is within the limits, or otherwise a
+// copy scaled down to fit and re-encoded: as JPEG if the image is opaque
+// and JPEG is accepted, else as PNG. It is scaled further until it is
+// small enough in bytes. Fit fails with ErrInvalidRequest if the image
+// cannot be decoded (only PNG, JPEG, and GIF can) or no accepted format
+// remains.
+func (l ImageLimits) Fit(img ImageData) (ImageData, error) {
+	if l.check(img) == nil {
+		return img, nil
+	}
+	fail := func(msg string) (ImageData, error) {
+		return ImageData{}, &Error{Kind: ErrInvalidRequest, Message: "image cannot be made to fit: " + msg}
+	}
+	src, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return fail(l.check(img).Error() + ", and " + imageMediaType(img) + " images cannot be re-encoded")
+	}
+
+	accepts := func(mediaType string) bool {
+		return len(l.MediaTypes) == 0 || slices.Contains(l.MediaTypes, mediaType)
+	}
+	opaque := true
+	if o, ok := src.(interface{ Opaque() bool }); ok {
+		opaque = o.Opaque()
+	}
+	var encode func(io.Writer, image.Image) error
+	var mediaType string
+	switch {
+	case opaque && accepts("image/jpeg"):
+		mediaType = "image/jpeg"
+		encode = func(w io.Writer, m image.Image) error { return jpeg.Encode(w, m, &jpeg.Options{Quality: 85}) }
+	case accepts("image/png"):
+		mediaType, encode = "image/png", png.Encode
+	default:
+		return fail("it cannot be re-encoded as any accepted type")
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if l.MaxSide > 0 {
+		w, h = fit(w, h, l.MaxSide)
+	}
+	for {
+		var buf bytes.Buffer
+		if err := encode(&buf, downscale(src, max(w, 1), max(h, 1))); err != nil {
+			return fail(err.Error())
+		}
+		if l.MaxBytes <= 0 || buf.Len() <= l.MaxBytes {
+			return ImageData{Data: buf.Bytes(), MediaType: mediaType}, nil
+		}
+		if max(w, h) <= 64 {
+			return fail(fmt.Sprintf("still %d bytes at %dx%d pixels; the limit is %d", buf.Len(), w, h, l.MaxBytes))
+		}
+		w, h = w*3/4, h*3/4
+	}
+}
+
+// downscale resizes src to w×h by averaging the source pixels that cover
+// each destination pixel. It returns src unchanged if the size matches.
+func downscale(src image.Image, w, h int) image.Image {
+	b := src.Bounds()
+	if b.Dx() == w && b.Dy() == h {
+		return src
+	}
+	in := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(in, in.Bounds(), src, b.Min, draw.Src)
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		y0, y1 := y*b.Dy()/h, max((y+1)*b.Dy()/h, y*b.Dy()/h+1)
+		for x := range w {
+			x0, x1 := x*b.Dx()/w, max((x+1)*b.Dx()/w, x*b.Dx()/w+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := in.Pix[sy*in.Stride+x0*4 : sy*in.Stride+x1*4]
+				for i, v := range row {
+					sum[i%4] += int(v)
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			o := out.PixOffset(x, y)
+			for i := range sum {
+				out.Pix[o+i] = uint8(sum[i] / n)
+			}
+		}
+	}
+	return out
+}
+
+// FitImages returns a copy of conv with every image that exceeds limits,
+// including images in tool results, replaced as Fit does. conv is not
+// modified.
+func FitImages(conv Conversation, limits ImageLimits) (Conversation, error) {
+	return mapImages(conv, func(img *ImageData) error {
+		fitted, err := limits.Fit(*img)
+		*img = fitted
+		return err
+	})
+}
+
+// WithImageLimits adds ImageFitting middleware to the client.
+func WithImageLimits(limits ImageLimits) ClientOption {
+	return WithMiddleware(ImageFitting(limits))
+}
+
+// ImageFitting returns middleware that fits the images in each request to
+// limits, as FitImages does, and fails before the provider is called if one
+// cannot be fitted. The conversation returned by Send keeps the original
+// images. Register it after WithImageInlining so fetched images are fitted
+// too.
+func ImageFitting(limits ImageLimits) Middleware {
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		fitted, err := FitImages(*conv, limits)
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, &fitted)
+	}
+}
+
+// mapImages returns a copy of conv in which fn has been applied to every
+// image, in message parts and tool results. Only messages holding images
+// are copied, so conv is not modified. fn receives a copy of the image it
+// may change in place.
+func mapImages(conv Conversation, fn func(img *ImageData) error) (Conversation, error) {
+	conv.Messages = slices.Clone(conv.Messages)
+	for i, m := range conv.Messages {
+		hasImages := slices.ContainsFunc(m.Content, func(p ContentPart) bool {
+			return p.Image != nil || (p.ToolResult != nil && len(p.ToolResult.Images) > 0)
+		})
+		if !hasImages {
+			continue
+		}
+		content := slices.Clone(m.Content)
+		for j, p := range content {
+			if p.Image != nil {
+				img := *p.Image
+				if err := fn(&img); err != nil {
+					return conv, fmt.Errorf("message %d: part %d: %w", i, j, err)
+				}
+				content[j].Image = &img
+			}
+			if p.ToolResult != nil && len(p.ToolResult.Images) > 0 {
+				tr := *p.ToolResult
+				tr.Images = slices.Clone(tr.Images)
+				for k := range tr.Images {
+					if err := fn(&tr.Images[k]); err != nil {
+						return conv, fmt.Errorf("message %d: part %d: tool result image %d: %w", i, j, k, err)
+					}
+				}
+				content[j].ToolResult = &tr
+			}
+		}
+		conv.Messages[i].Content = content
+	}
+	return conv, nil
+}
@@ -0,0 +1,343 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newImageServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/cat.jpg":
+			w.Header().Set("Content-Type", "image/jpeg; charset=binary")
+			w.Write([]byte("jpeg bytes"))
+		case "/sniffed":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(pngHeader)
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		case "/huge.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(bytes.Repeat([]byte{0}, 2048))
+		case "/slow.png":
+			time.Sleep(200 * time.Millisecond)
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngHeader)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestImageFetcher_Fetch(t *testing.T) {
+	srv, _ := newImageServer(t)
+	f := &ImageFetcher{Client: srv.Client(), MaxBytes: 1024, Timeout: 50 * time.Millisecond}
+	ctx := context.Background()
+
+	img, err := f.Fetch(ctx, srv.URL+"/cat.jpg")
+	if err != nil || string(img.Data) != "jpeg bytes" || img.MediaType != "image/jpeg" || img.URL != "" {
+		t.Errorf("cat.jpg = %+v, %v", img, err)
+	}
+	if img, err := f.Fetch(ctx, srv.URL+"/sniffed"); err != nil || img.MediaType != "image/png" {
+		t.Errorf("sniffed = %+v, %v", img, err)
+	}
+
+	for path, want := range map[string]string{
+		"/missing":   "404",
+		"/page.html": "unsupported media type text/html",
+		"/huge.png":  "exceeds the limit of 1024",
+		"/slow.png":  "deadline exceeded",
+	} {
+		_, err := f.Fetch(ctx, srv.URL+path)
+		var llmErr *Error
+		if !errors.As(err, &llmErr) || llmErr.Kind != ErrInvalidRequest || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want ErrInvalidRequest containing %q", path, err, want)
+		}
+	}
+}
+
+func TestImageFetcher_Inline(t *testing.T) {
+	srv, hits := newImageServer(t)
+	url := srv.URL + "/cat.jpg"
+	conv := Conversation{Messages: []Message{
+		{Role: RoleUser, Content: []ContentPart{
+			{Kind: ContentText, Text: "compare"},
+			{Kind: ContentImage, Image: &ImageData{URL: url}},
+			{Kind: ContentImage, Image: &ImageData{URL: "s3://bucket/a.png"}},
+		}},
+		toolUseResponse(ToolCallData{ID: "c1", Name: "find"}).Message,
+		ToolCallData{ID: "c1"}.ImageResult("found", ImageData{URL: url}),
+	}}
+
+	f := &ImageFetcher{Client: srv.Client()}
+	got, err := f.Inline(context.Background(), conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img := got.Messages[0].Content[1].Image; string(img.Data) != "jpeg bytes" || img.URL != "" {
+		t.Errorf("inlined image = %+v", img)
+	}
+	if img := got.Messages[0].Content[2].Image; img.URL != "s3://bucket/a.png" || img.Data != nil {
+		t.Errorf("s3 image = %+v", img)
+	}
+	if img := got.Messages[2].Content[0].ToolResult.Images[0]; string(img.Data) != "jpeg bytes" {
+		t.Errorf("tool result image = %+v", img)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("server hit %d times, want 1", hits.Load())
+	}
+	if conv.Messages[0].Content[1].Image.URL != url || conv.Messages[2].Content[0].ToolResult.Images[0].Data != nil {
+		t.Error("Inline modified its argument")
+	}
+
+	// Later calls reuse the fetched image unless the cache is disabled.
+	if _, err := f.Inline(context.Background(), conv); err != nil || hits.Load() != 1 {
+		t.Errorf("second Inline: server hit %d times, %v", hits.Load(), err)
+	}
+	uncached := &ImageFetcher{Client: srv.Client(), CacheBytes: -1}
+	for range 2 {
+		if _, err := uncached.Inline(context.Background(), conv); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hits.Load() != 3 {
+		t.Errorf("uncached: server hit %d times, want 3", hits.Load())
+	}
+}
+
+func TestImageFetcher_CacheEvictsOldest(t *testing.T) {
+	f := &ImageFetcher{CacheBytes: 10}
+	f.remember("a", ImageData{Data: make([]byte, 6)})
+	f.remember("b", ImageData{Data: make([]byte, 4)})
+	f.remember("c", ImageData{Data: make([]byte, 5)})
+	f.remember("huge", ImageData{Data: make([]byte, 11)})
+	for url, want := range map[string]bool{"a": false, "b": true, "c": true, "huge": false} {
+		if _, ok := f.lookup(url); ok != want {
+			t.Errorf("cached %s = %v, want %v", url, ok, want)
+		}
+	}
+	if f.cacheSize != 9 {
+		t.Errorf("cacheSize = %d, want 9", f.cacheSize)
+	}
+}
+
+func TestImageFetcher_Guards(t *testing.T) {
+	srv, hits := newImageServer(t)
+	ctx := context.Background()
+
+	// The default client refuses the loopback address of the test server.
+	_, err := (&ImageFetcher{}).Fetch(ctx, srv.URL+"/cat.jpg")
+	if err == nil || !strings.Contains(err.Error(), "non-public address") {
+		t.Errorf("default client: err = %v", err)
+	}
+
+	f := &ImageFetcher{Client: srv.Client(), AllowHosts: []string{"*.example.com"}}
+	if _, err := f.Fetch(ctx, srv.URL+"/cat.jpg"); err == nil || !strings.Contains(err.Error(), "not in AllowHosts") {
+		t.Errorf("disallowed host: err = %v", err)
+	}
+
+	// Redirects are held to the allowlist as well.
+	redirect := httptest.NewServer(http.RedirectHandler(srv.URL+"/cat.jpg", http.StatusFound))
+	t.Cleanup(redirect.Close)
+	f = &ImageFetcher{Client: srv.Client(), AllowHosts: []string{"localhost"}}
+	if _, err := f.Fetch(ctx, strings.Replace(redirect.URL, "127.0.0.1", "localhost", 1)); err == nil || !strings.Contains(err.Error(), "not in AllowHosts") {
+		t.Errorf("redirect to disallowed host: err = %v", err)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("server hit %d times, want 0", hits.Load())
+	}
+
+	f = &ImageFetcher{Client: srv.Client(), AllowHosts: []string{"127.0.0.1"}}
+	if _, err := f.Fetch(ctx, srv.URL+"/cat.jpg"); err != nil {
+		t.Errorf("allowed host: %v", err)
+	}
+}
+
+func TestImageFetcher_Middleware(t *testing.T) {
+	srv, _ := newImageServer(t)
+	p := &scriptedProvider{responses: []*Response{simpleResponse("a cat"), simpleResponse("unused")}}
+	client := NewClientWithProvider(p, WithImageInlining(&ImageFetcher{Client: srv.Client()}))
+
+	msg := Message{Role: RoleUser, Content: []ContentPart{{Kind: ContentImage, Image: &ImageData{URL: srv.URL + "/cat.jpg"}}}}
+	conv, _, err := client.Send(context.Background(), NewConversation("m"), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := p.received[0].Messages[0].Content[0].Image; string(sent.Data) != "jpeg bytes" {
+		t.Errorf("provider received %+v", sent)
+	}
+	if kept := conv.Messages[0].Content[0].Image; kept.Data != nil || kept.URL == "" {
+		t.Errorf("returned conversation holds %+v, want the URL", kept)
+	}
+
+	bad := Message{Role: RoleUser, Content: []ContentPart{{Kind: ContentImage, Image: &ImageData{URL: srv.URL + "/missing"}}}}
+	if _, _, err := client.Send(context.Background(), conv, bad); !strings.Contains(err.Error(), "message 2: part 0: ") || !strings.Contains(err.Error(), "fetching image") {
+		t.Errorf("err = %v", err)
+	}
+	if len(p.received) != 1 {
+		t.Error("request with an unfetchable image reached the provider")
+	}
+}
+
+func testImage(t *testing.T, w, h int, opaque bool, encode func(io.Writer, image.Image) error) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			a := uint8(255)
+			if !opaque && x == 0 {
+				a = 0
+			}
+			img.Set(x, y, color.NRGBA{R: uint8(x * 7), G: uint8(y * 13), B: uint8(x ^ y), A: a})
+		}
+	}
+	var buf bytes.Buffer
+	if err := encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func imageSize(t *testing.T, data []byte) (int, int) {
+	t.Helper()
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.Width, cfg.Height
+}
+
+func TestImageLimits_Check(t *testing.T) {
+	small := ImageData{Data: testImage(t, 10, 10, true, png.Encode), MediaType: "image/png"}
+	wide := ImageData{Data: testImage(t, 300, 100, true, png.Encode)}
+
+	tests := []struct {
+		limits ImageLimits
+		img    ImageData
+		want   string // substring of the error; "" for ok
+	}{
+		{ImageLimits{}, wide, ""},
+		{ImageLimitsFor("bedrock"), wide, ""},
+		{ImageLimits{MaxSide: 200}, wide, "300x100 pixels; the limit is 200"},
+		{ImageLimits{MaxBytes: 10}, small, "the limit is 10"},
+		{ImageLimits{MediaTypes: []string{"image/jpeg"}}, wide, "unsupported image type image/png"},
+		{ImageLimitsFor("gemini"), ImageData{Data: []byte("GIF89a..."), MediaType: "image/gif"}, "unsupported image type image/gif"},
+		{ImageLimits{MaxBytes: 1}, ImageData{URL: "https://example.com/a.png"}, ""},
+	}
+	for i, tt := range tests {
+		err := tt.limits.Check(tt.img)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%d: Check() = %v", i, err)
+			}
+			continue
+		}
+		var llmErr *Error
+		if !errors.As(err, &llmErr) || llmErr.Kind != ErrInvalidRequest || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%d: Check() = %v, want ErrInvalidRequest containing %q", i, err, tt.want)
+		}
+	}
+
+	conv := Conversation{Messages: []Message{{Role: RoleUser, Content: []ContentPart{
+		{Kind: ContentImage, Image: &ImageData{Data: testImage(t, 10, 10, true, jpegEncode), MediaType: "image/jpeg"}},
+		{Kind: ContentImage, Image: &ImageData{Data: []byte("RIFF....WEBP"), MediaType: "image/webp"}},
+	}}}}
+	if err := conv.ValidateFor("bedrock"); err != nil {
+		t.Errorf("ValidateFor(bedrock) = %v", err)
+	}
+	if err := conv.ValidateFor("ollama"); err == nil || !strings.Contains(err.Error(), "part 1: ollama unsupported image type image/webp") {
+		t.Errorf("ValidateFor(ollama) = %v", err)
+	}
+}
+
+func jpegEncode(w io.Writer, m image.Image) error { return jpeg.Encode(w, m, nil) }
+
+func TestImageLimits_Fit(t *testing.T) {
+	opaque := ImageData{Data: testImage(t, 400, 200, true, png.Encode), MediaType: "image/png"}
+
+	got, err := ImageLimits{MaxSide: 100}.Fit(opaque)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := imageSize(t, got.Data); w != 100 || h != 50 || got.MediaType != "image/jpeg" {
+		t.Errorf("fitted to %dx%d %s, want 100x50 image/jpeg", w, h, got.MediaType)
+	}
+
+	transparent := ImageData{Data: testImage(t, 400, 200, false, png.Encode), MediaType: "image/png"}
+	got, err = ImageLimits{MaxSide: 100}.Fit(transparent)
+	if err != nil || got.MediaType != "image/png" {
+		t.Errorf("transparent image fitted to %s, %v; want image/png", got.MediaType, err)
+	}
+
+	// Re-encoding as JPEG alone gives about 2 KB, so this must also scale.
+	got, err = ImageLimits{MaxBytes: 1500}.Fit(opaque)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := imageSize(t, got.Data); len(got.Data) > 1500 || w >= 400 {
+		t.Errorf("fitted to %d bytes at width %d", len(got.Data), w)
+	}
+
+	got, err = ImageLimits{MediaTypes: []string{"image/png"}}.Fit(ImageData{Data: testImage(t, 20, 20, true, jpegEncode), MediaType: "image/jpeg"})
+	if err != nil || got.MediaType != "image/png" {
+		t.Errorf("converted to %s, %v; want image/png", got.MediaType, err)
+	}
+
+	if got, err := (ImageLimits{MaxSide: 1000}).Fit(opaque); err != nil || !bytes.Equal(got.Data, opaque.Data) {
+		t.Errorf("image within limits changed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		limits ImageLimits
+		img    ImageData
+		want   string
+	}{
+		{ImageLimits{MaxBytes: 100}, opaque, "still"},
+		{ImageLimits{MediaTypes: []string{"image/webp"}}, opaque, "cannot be re-encoded as any accepted type"},
+		{ImageLimits{MaxBytes: 4}, ImageData{Data: []byte("RIFF....WEBP"), MediaType: "image/webp"}, "image/webp images cannot be re-encoded"},
+	} {
+		_, err := tt.limits.Fit(tt.img)
+		var llmErr *Error
+		if !errors.As(err, &llmErr) || llmErr.Kind != ErrInvalidRequest || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Fit() = %v, want ErrInvalidRequest containing %q", err, tt.want)
+		}
+	}
+}
+
+func TestImageFitting(t *testing.T) {
+	big := ImageData{Data: testImage(t, 400, 200, true, png.Encode), MediaType: "image/png"}
+	p := &scriptedProvider{responses: []*Response{simpleResponse("ok")}}
+	client := NewClientWithProvider(p, WithImageLimits(ImageLimits{MaxSide: 100}))
+
+	msg := Message{Role: RoleUser, Content: []ContentPart{{Kind: ContentImage, Image: &big}}}
+	conv, _, err := client.Send(context.Background(), NewConversation("m"), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := imageSize(t, p.received[0].Messages[0].Content[0].Image.Data); w != 100 || h != 50 {
+		t.Errorf("provider received %dx%d", w, h)
+	}
+	if !bytes.Equal(conv.Messages[0].Content[0].Image.Data, big.Data) {
+		t.Error("returned conversation does not keep the original image")
+	}
+}
@@ -0,0 +1,173 @@
+package llm
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// checkSchema reports the first way v, a value decoded from JSON, fails
+// schema, a JSON Schema decoded the same way. It checks the keywords that
+// structured output schemas use — type, enum, const, properties, required,
+// additionalProperties, items, anyOf, minimum, maximum, minLength,
+// maxLength, minItems, and maxItems — and ignores the rest. path names v
+// in errors, such as "items[2].sku"; it is empty at the top level.
+func checkSchema(schema map[string]any, v any, path string) error {
+	at := func(format string, args ...any) error {
+		msg := fmt.Sprintf(format, args...)
+		if path == "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return fmt.Errorf("%s: %s", path, msg)
+	}
+
+	if t, ok := schema["type"]; ok {
+		var types []string
+		switch t := t.(type) {
+		case string:
+			types = []string{t}
+		case []any:
+			for _, s := range t {
+				if s, ok := s.(string); ok {
+					types = append(types, s)
+				}
+			}
+		}
+		if !slices.ContainsFunc(types, func(t string) bool { return jsonTypeIs(v, t) }) {
+			return at("expected %s, got %s", strings.Join(types, " or "), jsonTypeOf(v))
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		return at("%s is not one of the allowed values", jsonString(v))
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, v) {
+		return at("must be %s", jsonString(c))
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		matched := slices.ContainsFunc(anyOf, func(s any) bool {
+			sub, ok := s.(map[string]any)
+			return ok && checkSchema(sub, v, path) == nil
+		})
+		if !matched {
+			return at("matches none of the allowed schemas")
+		}
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := float64(utf8.RuneCountInString(v))
+		if lo, ok := schema["minLength"].(float64); ok && n < lo {
+			return at("shorter than %v characters", lo)
+		}
+		if hi, ok := schema["maxLength"].(float64); ok && n > hi {
+			return at("longer than %v characters", hi)
+		}
+	case float64:
+		if lo, ok := schema["minimum"].(float64); ok && v < lo {
+			return at("%v is less than %v", v, lo)
+		}
+		if hi, ok := schema["maximum"].(float64); ok && v > hi {
+			return at("%v is greater than %v", v, hi)
+		}
+	case []any:
+		n := float64(len(v))
+		if lo, ok := schema["minItems"].(float64); ok && n < lo {
+			return at("fewer than %v items", lo)
+		}
+		if hi, ok := schema["maxItems"].(float64); ok && n > hi {
+			return at("more than %v items", hi)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := checkSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if name, ok := name.(string); ok {
+					if _, present := v[name]; !present {
+						return at("missing required property %q", name)
+					}
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		prefix := path
+		if prefix != "" {
+			prefix += "."
+		}
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			if sub, ok := props[name].(map[string]any); ok {
+				if err := checkSchema(sub, v[name], prefix+name); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, declared := props[name]; declared {
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					return at("unexpected property %q", name)
+				}
+			case map[string]any:
+				if err := checkSchema(extra, v[name], prefix+name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonTypeIs reports whether v, decoded from JSON, has JSON Schema type t.
+func jsonTypeIs(v any, t string) bool {
+	switch v := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || (t == "integer" && v == math.Trunc(v))
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+func jsonTypeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func jsonString(v any) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(v)
+}
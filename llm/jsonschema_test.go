@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCheckSchema(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0},
+			"role": {"enum": ["admin", "user"]},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+			"address": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]},
+			"nickname": {"type": ["string", "null"]},
+			"id": {"anyOf": [{"type": "string"}, {"type": "integer"}]}
+		},
+		"required": ["name"],
+		"additionalProperties": false
+	}`
+	tests := []struct {
+		value string
+		want  string // substring of the error; "" for valid
+	}{
+		{`{"name": "Ada", "age": 36, "role": "admin", "tags": ["x"], "address": {"city": "London"}, "nickname": null, "id": 7}`, ""},
+		{`{"name": "Ada", "id": "a7"}`, ""},
+		{`[]`, "expected object, got array"},
+		{`{}`, `missing required property "name"`},
+		{`{"name": ""}`, "name: shorter than 1 characters"},
+		{`{"name": "Ada", "age": 3.5}`, "age: expected integer, got number"},
+		{`{"name": "Ada", "age": -1}`, "age: -1 is less than 0"},
+		{`{"name": "Ada", "role": "root"}`, `role: "root" is not one of the allowed values`},
+		{`{"name": "Ada", "tags": ["a", 2]}`, "tags[1]: expected string, got number"},
+		{`{"name": "Ada", "tags": ["a", "b", "c"]}`, "tags: more than 2 items"},
+		{`{"name": "Ada", "address": {}}`, `address: missing required property "city"`},
+		{`{"name": "Ada", "nickname": 1}`, "nickname: expected string or null, got number"},
+		{`{"name": "Ada", "id": true}`, "id: matches none of the allowed schemas"},
+		{`{"name": "Ada", "email": "a@b"}`, `unexpected property "email"`},
+	}
+	var s map[string]any
+	if err := json.Unmarshal([]byte(schema), &s); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		var v any
+		if err := json.Unmarshal([]byte(tt.value), &v); err != nil {
+			t.Fatal(err)
+		}
+		err := checkSchema(s, v, "")
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.value, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.value, err, tt.want)
+		}
+	}
+}
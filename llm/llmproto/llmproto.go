@@ -0,0 +1,571 @@
+// Package llmproto encodes llm.Conversation in the Protocol Buffers format
+// described by conversation.proto, for payload systems and cross-language
+// services that use protobuf rather than JSON. It implements the wire format
+// directly and needs no generated code or protobuf runtime.
+//
+// Unknown fields are skipped when decoding, so payloads written by newer
+// versions of the schema can still be read.
+package llmproto
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+// Marshal returns the protobuf encoding of conv. The encoding is
+// deterministic: equal conversations encode to equal bytes.
+func Marshal(conv llm.Conversation) ([]byte, error) {
+	var e encoder
+	e.uint(1, llm.SchemaVersion)
+	e.string(2, conv.Model)
+	e.strings(3, conv.System)
+	for _, m := range conv.Messages {
+		e.message(4, func(e *encoder) { encodeMessage(e, m) })
+	}
+	for _, td := range conv.Tools {
+		e.message(5, func(e *encoder) {
+			e.string(1, td.Name)
+			e.string(2, td.Description)
+			e.bytes(3, td.Parameters)
+		})
+	}
+	e.message(6, func(e *encoder) { encodeConfig(e, conv.Config) })
+	e.message(7, func(e *encoder) { encodeUsage(e, conv.Usage) })
+	e.string(8, conv.ID)
+	encodeTime(&e, 9, conv.CreatedAt)
+	encodeTime(&e, 10, conv.UpdatedAt)
+	for _, k := range slices.Sorted(maps.Keys(conv.Metadata)) {
+		e.message(11, func(e *encoder) {
+			e.string(1, k)
+			e.string(2, conv.Metadata[k])
+		})
+	}
+	if p := conv.Parent; p != nil {
+		e.message(12, func(e *encoder) {
+			e.string(1, p.ParentID)
+			e.int(2, int64(p.At))
+		})
+	}
+	e.int(13, conv.Revision)
+	for _, t := range conv.UsageHistory {
+		e.message(14, func(e *encoder) {
+			e.string(1, t.Model)
+			e.message(2, func(e *encoder) { encodeUsage(e, t.Usage) })
+			e.double(3, t.Cost)
+			e.int(4, int64(t.Latency))
+			encodeTime(e, 5, t.At)
+		})
+	}
+	return e.buf, nil
+}
+
+func encodeUsage(e *encoder, u llm.Usage) {
+	e.int(1, int64(u.InputTokens))
+	e.int(2, int64(u.OutputTokens))
+	e.int(3, int64(u.CacheReadTokens))
+	e.int(4, int64(u.CacheWriteTokens))
+	e.int(5, int64(u.ReasoningTokens))
+}
+
+func encodeMessage(e *encoder, m llm.Message) {
+	e.string(1, string(m.Role))
+	for _, p := range m.Content {
+		e.message(2, func(e *encoder) { encodePart(e, p) })
+	}
+	e.string(3, m.ToolCallID)
+}
+
+func encodePart(e *encoder, p llm.ContentPart) {
+	e.string(1, string(p.Kind))
+	e.string(2, p.Text)
+	if p.Image != nil {
+		e.message(3, func(e *encoder) { encodeImage(e, *p.Image) })
+	}
+	if tc := p.ToolCall; tc != nil {
+		e.message(4, func(e *encoder) {
+			e.string(1, tc.ID)
+			e.string(2, tc.Name)
+			e.bytes(3, tc.Arguments)
+			e.string(4, tc.RawArguments)
+		})
+	}
+	if tr := p.ToolResult; tr != nil {
+		e.message(5, func(e *encoder) {
+			e.string(1, tr.ToolCallID)
+			e.string(2, tr.Content)
+			e.bytes(3, tr.JSON)
+			e.bool(4, tr.IsError)
+			for _, img := range tr.Images {
+				e.message(5, func(e *encoder) { encodeImage(e, img) })
+			}
+			e.string(6, tr.ContentRef)
+		})
+	}
+	if th := p.Thinking; th != nil {
+		e.message(6, func(e *encoder) {
+			e.string(1, th.Text)
+			e.string(2, th.Signature)
+		})
+	}
+	if a := p.Audio; a != nil {
+		e.message(7, func(e *encoder) {
+			e.string(1, a.URL)
+			e.bytes(2, a.Data)
+			e.string(3, a.MediaType)
+		})
+	}
+	if d := p.Document; d != nil {
+		e.message(8, func(e *encoder) {
+			e.string(1, d.Name)
+			e.string(2, d.URL)
+			e.bytes(3, d.Data)
+			e.string(4, d.MediaType)
+		})
+	}
+}
+
+func encodeImage(e *encoder, img llm.ImageData) {
+	e.string(1, img.URL)
+	e.bytes(2, img.Data)
+	e.string(3, img.MediaType)
+	e.string(4, img.Ref)
+}
+
+func encodeConfig(e *encoder, c llm.Config) {
+	if c.MaxTokens != nil {
+		e.forceUint(1, uint64(*c.MaxTokens))
+	}
+	if c.Temperature != nil {
+		e.forceDouble(2, *c.Temperature)
+	}
+	if c.TopP != nil {
+		e.forceDouble(3, *c.TopP)
+	}
+	e.strings(4, c.StopSequences)
+	if tc := c.ToolChoice; tc != nil {
+		e.message(5, func(e *encoder) {
+			e.string(1, string(tc.Mode))
+			e.string(2, tc.ToolName)
+		})
+	}
+	if c.FrequencyPenalty != nil {
+		e.forceDouble(6, *c.FrequencyPenalty)
+	}
+	if c.PresencePenalty != nil {
+		e.forceDouble(7, *c.PresencePenalty)
+	}
+	if rf := c.ResponseFormat; rf != nil {
+		e.message(8, func(e *encoder) {
+			e.string(1, string(rf.Type))
+			e.string(2, rf.Name)
+			e.bytes(3, rf.Schema)
+			e.bool(4, rf.Strict)
+		})
+	}
+	e.string(9, string(c.ReasoningEffort))
+	if g := c.Guardrail; g != nil {
+		e.message(10, func(e *encoder) {
+			e.string(1, g.Identifier)
+			e.string(2, g.Version)
+			e.string(3, string(g.Trace))
+			e.string(4, g.StreamProcessingMode)
+		})
+	}
+	e.bytes(11, c.AdditionalModelRequestFields)
+	for _, k := range slices.Sorted(maps.Keys(c.ProviderOptions)) {
+		e.message(12, func(e *encoder) {
+			e.string(1, k)
+			e.bytes(2, c.ProviderOptions[k])
+		})
+	}
+}
+
+// encodeTime writes t as a google.protobuf.Timestamp, omitting the zero
+// time.
+func encodeTime(e *encoder, field int, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	e.message(field, func(e *encoder) {
+		e.int(1, t.Unix())
+		e.int(2, int64(t.Nanosecond()))
+	})
+}
+
+// Unmarshal decodes a protobuf-encoded conversation into conv, replacing
+// its contents. Payloads from a newer SchemaVersion are rejected.
+func Unmarshal(data []byte, conv *llm.Conversation) error {
+	var c llm.Conversation
+	var version uint64
+	err := decode(data, func(f field) error {
+		switch f.num {
+		case 1:
+			version = f.n
+			return f.check(wireVarint)
+		case 2:
+			c.Model = f.string()
+		case 3:
+			c.System = append(c.System, f.string())
+		case 4:
+			m, err := decodeMessage(f.b)
+			if err != nil {
+				return fmt.Errorf("message %d: %w", len(c.Messages), err)
+			}
+			c.Messages = append(c.Messages, m)
+		case 5:
+			var td llm.ToolDefinition
+			err := decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					td.Name = f.string()
+				case 2:
+					td.Description = f.string()
+				case 3:
+					td.Parameters = json.RawMessage(f.string())
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("tool %d: %w", len(c.Tools), err)
+			}
+			c.Tools = append(c.Tools, td)
+		case 6:
+			return decodeConfig(f.b, &c.Config)
+		case 7:
+			return decodeUsage(f.b, &c.Usage)
+		case 8:
+			c.ID = f.string()
+		case 9:
+			return decodeTime(f.b, &c.CreatedAt)
+		case 10:
+			return decodeTime(f.b, &c.UpdatedAt)
+		case 11:
+			var k, v string
+			err := decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					k = f.string()
+				case 2:
+					v = f.string()
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("metadata: %w", err)
+			}
+			if c.Metadata == nil {
+				c.Metadata = make(map[string]string)
+			}
+			c.Metadata[k] = v
+		case 12:
+			c.Parent = &llm.ForkPoint{}
+			return decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					c.Parent.ParentID = f.string()
+				case 2:
+					c.Parent.At = int(f.int())
+				}
+				return nil
+			})
+		case 13:
+			c.Revision = f.int()
+		case 14:
+			var t llm.TurnUsage
+			err := decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					t.Model = f.string()
+				case 2:
+					return decodeUsage(f.b, &t.Usage)
+				case 3:
+					t.Cost = f.double()
+					return f.check(wireFixed64)
+				case 4:
+					t.Latency = time.Duration(f.int())
+				case 5:
+					return decodeTime(f.b, &t.At)
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("usage history %d: %w", len(c.UsageHistory), err)
+			}
+			c.UsageHistory = append(c.UsageHistory, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("llmproto: %w", err)
+	}
+	if version > llm.SchemaVersion {
+		return fmt.Errorf("llmproto: conversation schema version %d is newer than supported version %d", version, llm.SchemaVersion)
+	}
+	*conv = c
+	return nil
+}
+
+func decodeUsage(data []byte, u *llm.Usage) error {
+	return decode(data, func(f field) error {
+		n := int(f.int())
+		switch f.num {
+		case 1:
+			u.InputTokens = n
+		case 2:
+			u.OutputTokens = n
+		case 3:
+			u.CacheReadTokens = n
+		case 4:
+			u.CacheWriteTokens = n
+		case 5:
+			u.ReasoningTokens = n
+		}
+		return nil
+	})
+}
+
+func decodeMessage(data []byte) (llm.Message, error) {
+	var m llm.Message
+	err := decode(data, func(f field) error {
+		switch f.num {
+		case 1:
+			m.Role = llm.Role(f.string())
+		case 2:
+			p, err := decodePart(f.b)
+			if err != nil {
+				return fmt.Errorf("part %d: %w", len(m.Content), err)
+			}
+			m.Content = append(m.Content, p)
+		case 3:
+			m.ToolCallID = f.string()
+		}
+		return nil
+	})
+	return m, err
+}
+
+func decodePart(data []byte) (llm.ContentPart, error) {
+	var p llm.ContentPart
+	err := decode(data, func(f field) error {
+		switch f.num {
+		case 1:
+			p.Kind = llm.ContentKind(f.string())
+		case 2:
+			p.Text = f.string()
+		case 3:
+			img, err := decodeImage(f.b)
+			p.Image = &img
+			return err
+		case 4:
+			p.ToolCall = &llm.ToolCallData{}
+			return decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					p.ToolCall.ID = f.string()
+				case 2:
+					p.ToolCall.Name = f.string()
+				case 3:
+					p.ToolCall.Arguments = json.RawMessage(f.string())
+				case 4:
+					p.ToolCall.RawArguments = f.string()
+				}
+				return nil
+			})
+		case 5:
+			tr := &llm.ToolResultData{}
+			p.ToolResult = tr
+			return decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					tr.ToolCallID = f.string()
+				case 2:
+					tr.Content = f.string()
+				case 3:
+					tr.JSON = json.RawMessage(f.string())
+				case 4:
+					tr.IsError = f.bool()
+				case 5:
+					img, err := decodeImage(f.b)
+					tr.Images = append(tr.Images, img)
+					return err
+				case 6:
+					tr.ContentRef = f.string()
+				}
+				return nil
+			})
+		case 6:
+			p.Thinking = &llm.ThinkingData{}
+			return decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					p.Thinking.Text = f.string()
+				case 2:
+					p.Thinking.Signature = f.string()
+				}
+				return nil
+			})
+		case 7:
+			p.Audio = &llm.AudioData{}
+			return decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					p.Audio.URL = f.string()
+				case 2:
+					p.Audio.Data = append([]byte(nil), f.b...)
+				case 3:
+					p.Audio.MediaType = f.string()
+				}
+				return nil
+			})
+		case 8:
+			p.Document = &llm.DocumentData{}
+			return decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					p.Document.Name = f.string()
+				case 2:
+					p.Document.URL = f.string()
+				case 3:
+					p.Document.Data = append([]byte(nil), f.b...)
+				case 4:
+					p.Document.MediaType = f.string()
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return p, err
+}
+
+func decodeImage(data []byte) (llm.ImageData, error) {
+	var img llm.ImageData
+	err := decode(data, func(f field) error {
+		switch f.num {
+		case 1:
+			img.URL = f.string()
+		case 2:
+			img.Data = append([]byte(nil), f.b...)
+		case 3:
+			img.MediaType = f.string()
+		case 4:
+			img.Ref = f.string()
+		}
+		return nil
+	})
+	return img, err
+}
+
+func decodeConfig(data []byte, c *llm.Config) error {
+	return decode(data, func(f field) error {
+		switch f.num {
+		case 1:
+			n := int(f.int())
+			c.MaxTokens = &n
+			return f.check(wireVarint)
+		case 2:
+			v := f.double()
+			c.Temperature = &v
+			return f.check(wireFixed64)
+		case 3:
+			v := f.double()
+			c.TopP = &v
+			return f.check(wireFixed64)
+		case 4:
+			c.StopSequences = append(c.StopSequences, f.string())
+		case 5:
+			c.ToolChoice = &llm.ToolChoice{}
+			return decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					c.ToolChoice.Mode = llm.ToolChoiceMode(f.string())
+				case 2:
+					c.ToolChoice.ToolName = f.string()
+				}
+				return nil
+			})
+		case 6:
+			v := f.double()
+			c.FrequencyPenalty = &v
+			return f.check(wireFixed64)
+		case 7:
+			v := f.double()
+			c.PresencePenalty = &v
+			return f.check(wireFixed64)
+		case 8:
+			c.ResponseFormat = &llm.ResponseFormat{}
+			return decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					c.ResponseFormat.Type = llm.ResponseFormatType(f.string())
+				case 2:
+					c.ResponseFormat.Name = f.string()
+				case 3:
+					c.ResponseFormat.Schema = json.RawMessage(f.string())
+				case 4:
+					c.ResponseFormat.Strict = f.bool()
+				}
+				return nil
+			})
+		case 9:
+			c.ReasoningEffort = llm.ReasoningEffort(f.string())
+		case 10:
+			c.Guardrail = &llm.Guardrail{}
+			return decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					c.Guardrail.Identifier = f.string()
+				case 2:
+					c.Guardrail.Version = f.string()
+				case 3:
+					c.Guardrail.Trace = llm.GuardrailTrace(f.string())
+				case 4:
+					c.Guardrail.StreamProcessingMode = f.string()
+				}
+				return nil
+			})
+		case 11:
+			c.AdditionalModelRequestFields = json.RawMessage(f.string())
+		case 12:
+			var k string
+			var v json.RawMessage
+			err := decode(f.b, func(f field) error {
+				switch f.num {
+				case 1:
+					k = f.string()
+				case 2:
+					v = json.RawMessage(f.string())
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("provider options: %w", err)
+			}
+			if c.ProviderOptions == nil {
+				c.ProviderOptions = make(map[string]json.RawMessage)
+			}
+			c.ProviderOptions[k] = v
+		}
+		return nil
+	})
+}
+
+func decodeTime(data []byte, t *time.Time) error {
+	var sec, nsec int64
+	err := decode(data, func(f field) error {
+		switch f.num {
+		case 1:
+			sec = f.int()
+		case 2:
+			nsec = int64(int32(f.n))
+		}
+		return nil
+	})
+	*t = time.Unix(sec, nsec).UTC()
+	return err
+}
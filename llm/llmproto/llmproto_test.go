@@ -0,0 +1,130 @@
+package llmproto
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+func fullConversation() llm.Conversation {
+	maxTokens, temp, topP, penalty := 1024, 0.0, 0.9, 0.5
+	call := llm.ToolCallData{ID: "c1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`), RawArguments: `{location:"Paris"}`}
+	return llm.Conversation{
+		Model:  "us.anthropic.claude-haiku-4-5-20251001-v1:0",
+		System: []string{"be brief", ""},
+		Messages: []llm.Message{
+			{Role: llm.RoleUser, Content: []llm.ContentPart{
+				{Kind: llm.ContentText, Text: "What's this? 日本"},
+				{Kind: llm.ContentImage, Image: &llm.ImageData{Data: []byte{0x89, 'P', 'N', 'G'}, MediaType: "image/png"}},
+				{Kind: llm.ContentImage, Image: &llm.ImageData{URL: "https://example.com/a.jpg"}},
+				{Kind: llm.ContentImage, Image: &llm.ImageData{Ref: "sha256:ab", MediaType: "image/jpeg"}},
+				{Kind: llm.ContentAudio, Audio: &llm.AudioData{Data: []byte("RIFF"), MediaType: "audio/wav"}},
+				llm.DocumentPart("notes.md", []byte("# Notes"), "text/markdown"),
+				{Kind: llm.ContentDocument, Document: &llm.DocumentData{Name: "deck", URL: "s3://bucket/deck.pdf"}},
+				llm.CachePointPart(),
+			}},
+			{Role: llm.RoleAssistant, Content: []llm.ContentPart{
+				{Kind: llm.ContentThinking, Thinking: &llm.ThinkingData{Text: "hmm", Signature: "sig"}},
+				{Kind: llm.ContentToolCall, ToolCall: &call},
+			}},
+			{Role: llm.RoleTool, ToolCallID: "c1", Content: []llm.ContentPart{
+				{Kind: llm.ContentToolResult, ToolResult: &llm.ToolResultData{
+					ToolCallID: "c1", JSON: json.RawMessage(`{"temp":21}`), IsError: true,
+					Images:     []llm.ImageData{{Data: []byte{1, 2}, MediaType: "image/png"}},
+					ContentRef: "sha256:cd",
+				}},
+			}},
+		},
+		Tools: []llm.ToolDefinition{{Name: "get_weather", Description: "Weather", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		Config: llm.Config{
+			MaxTokens:     &maxTokens,
+			Temperature:   &temp,
+			TopP:          &topP,
+			StopSequences: []string{"END"},
+			ToolChoice:    &llm.ToolChoice{Mode: llm.ToolChoiceNamed, ToolName: "get_weather"},
+
+			FrequencyPenalty: &penalty,
+			PresencePenalty:  &temp,
+			ResponseFormat: &llm.ResponseFormat{
+				Type: llm.ResponseFormatJSONSchema, Name: "city", Schema: json.RawMessage(`{"type":"object"}`), Strict: true,
+			},
+			ReasoningEffort:              llm.ReasoningEffortHigh,
+			Guardrail:                    &llm.Guardrail{Identifier: "gr-1", Version: "2", Trace: llm.GuardrailTraceEnabled, StreamProcessingMode: "async"},
+			AdditionalModelRequestFields: json.RawMessage(`{"top_k":40}`),
+			ProviderOptions:              map[string]json.RawMessage{"openai": json.RawMessage(`{"seed":7}`), "gemini": json.RawMessage(`{}`)},
+		},
+		Usage:     llm.Usage{InputTokens: 10, OutputTokens: 5, CacheReadTokens: 3, CacheWriteTokens: 2, ReasoningTokens: 1},
+		ID:        "conv-1",
+		CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC),
+		UpdatedAt: time.Date(2025, 1, 2, 3, 5, 0, 0, time.UTC),
+		Metadata:  map[string]string{"tenant": "acme", "env": "prod"},
+		Parent:    &llm.ForkPoint{ParentID: "main", At: 0},
+		Revision:  7,
+		UsageHistory: []llm.TurnUsage{
+			{Model: "m1", Usage: llm.Usage{InputTokens: 6, OutputTokens: 2}, Cost: 0.0125, Latency: 1500 * time.Millisecond, At: time.Date(2025, 1, 2, 3, 4, 30, 0, time.UTC)},
+			{Model: "m2", Usage: llm.Usage{InputTokens: 4, OutputTokens: 3, ReasoningTokens: 1}},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for name, conv := range map[string]llm.Conversation{
+		"full":  fullConversation(),
+		"empty": llm.NewConversation("m"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := Marshal(conv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got llm.Conversation
+			if err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, conv) {
+				t.Errorf("round trip:\ngot  %+v\nwant %+v", got, conv)
+			}
+			again, _ := Marshal(got)
+			if !bytes.Equal(again, data) {
+				t.Error("encoding is not deterministic")
+			}
+		})
+	}
+}
+
+func TestMarshal_WireFormat(t *testing.T) {
+	data, err := Marshal(llm.Conversation{Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// schema_version=1, model="m", empty config and usage messages
+	want := []byte{0x08, 0x01, 0x12, 0x01, 'm', 0x32, 0x00, 0x3a, 0x00}
+	if !bytes.Equal(data, want) {
+		t.Errorf("Marshal = % x, want % x", data, want)
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	var conv llm.Conversation
+	// Unknown fields of every wire type are skipped.
+	unknown := []byte{0x12, 0x01, 'm', 0xf8, 0x06, 0x07, 0xf9, 0x06, 1, 2, 3, 4, 5, 6, 7, 8, 0xfa, 0x06, 0x01, 'x', 0xfd, 0x06, 1, 2, 3, 4}
+	if err := Unmarshal(unknown, &conv); err != nil || conv.Model != "m" {
+		t.Errorf("Unmarshal(unknown fields) = %+v, %v", conv, err)
+	}
+
+	for name, data := range map[string][]byte{
+		"truncated":     {0x12, 0x05, 'm'},
+		"newer version": {0x08, 0x63},
+		"group":         {0x0b},
+		"bad varint":    {0x08, 0xff},
+	} {
+		if err := Unmarshal(data, &conv); err == nil || !strings.HasPrefix(err.Error(), "llmproto: ") {
+			t.Errorf("%s: Unmarshal = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package llmproto
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Protocol Buffers wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// encoder appends fields in the protobuf wire format. Like proto3, it omits
+// fields holding their zero value unless the caller forces presence.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) tag(field, wire int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wire))
+}
+
+func (e *encoder) uint(field int, v uint64) {
+	if v != 0 {
+		e.forceUint(field, v)
+	}
+}
+
+func (e *encoder) forceUint(field int, v uint64) {
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *encoder) int(field int, v int64) {
+	e.uint(field, uint64(v))
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.uint(field, 1)
+	}
+}
+
+func (e *encoder) double(field int, v float64) {
+	if v != 0 {
+		e.forceDouble(field, v)
+	}
+}
+
+func (e *encoder) forceDouble(field int, v float64) {
+	e.tag(field, wireFixed64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+func (e *encoder) bytes(field int, b []byte) {
+	if len(b) > 0 {
+		e.tag(field, wireBytes)
+		e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+		e.buf = append(e.buf, b...)
+	}
+}
+
+func (e *encoder) string(field int, s string) {
+	e.bytes(field, []byte(s))
+}
+
+func (e *encoder) strings(field int, ss []string) {
+	for _, s := range ss {
+		// Repeated elements are always written, even when empty.
+		e.tag(field, wireBytes)
+		e.buf = binary.AppendUvarint(e.buf, uint64(len(s)))
+		e.buf = append(e.buf, s...)
+	}
+}
+
+// message writes a nested message built by fn. The message is written
+// even if empty, so it is present when decoded.
+func (e *encoder) message(field int, fn func(*encoder)) {
+	var sub encoder
+	fn(&sub)
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(sub.buf)))
+	e.buf = append(e.buf, sub.buf...)
+}
+
+// field is a single decoded field. For varint and fixed-width fields n holds
+// the value; for length-delimited fields b holds the payload.
+type field struct {
+	num  int
+	wire int
+	n    uint64
+	b    []byte
+}
+
+func (f field) int() int64 { return int64(f.n) }
+
+func (f field) bool() bool { return f.n != 0 }
+
+func (f field) double() float64 { return math.Float64frombits(f.n) }
+
+func (f field) string() string { return string(f.b) }
+
+// check reports a field whose wire type does not match its schema type.
+func (f field) check(wire int) error {
+	if f.wire != wire {
+		return fmt.Errorf("field %d: wire type %d, want %d", f.num, f.wire, wire)
+	}
+	return nil
+}
+
+var errTruncated = errors.New("truncated message")
+
+// decode calls fn for each field in data, in order. fn sees every field,
+// including unknown ones, which it should ignore for forward compatibility.
+func decode(data []byte, fn func(f field) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		f := field{num: int(key >> 3), wire: int(key & 7)}
+		if f.num <= 0 {
+			return fmt.Errorf("invalid field number %d", f.num)
+		}
+		switch f.wire {
+		case wireVarint:
+			f.n, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+			f.n, data = binary.LittleEndian.Uint64(data), data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+			f.n, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || size > uint64(len(data)-n) {
+				return errTruncated
+			}
+			f.b, data = data[n:n+int(size)], data[n+int(size):]
+		default:
+			return fmt.Errorf("field %d: unsupported wire type %d", f.num, f.wire)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package llmtest
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+// ArgsMatcher reports whether a tool call's arguments are the expected
+// ones. A nil ArgsMatcher matches any arguments.
+type ArgsMatcher func(args json.RawMessage) bool
+
+// ArgsEqual matches arguments equal to want once both are decoded from
+// JSON, so key order and spacing do not matter. want is encoded with
+// encoding/json, so it may be a map, a struct, or a json.RawMessage.
+func ArgsEqual(want any) ArgsMatcher {
+	data, err := json.Marshal(want)
+	if err != nil {
+		panic("llmtest: encoding expected arguments: " + err.Error())
+	}
+	var w any
+	_ = json.Unmarshal(data, &w)
+	return func(args json.RawMessage) bool {
+		var got any
+		return json.Unmarshal(args, &got) == nil && reflect.DeepEqual(got, w)
+	}
+}
+
+// AssertToolCalled fails t unless an assistant message in conv calls the
+// tool name with arguments that match. It returns the first matching call.
+func AssertToolCalled(t testing.TB, conv llm.Conversation, name string, match ArgsMatcher) llm.ToolCallData {
+	t.Helper()
+	var called []string
+	for _, m := range conv.Messages {
+		for _, tc := range m.ToolCalls() {
+			if tc.Name == name && (match == nil || match(tc.Arguments)) {
+				return tc
+			}
+			called = append(called, tc.Name+string(tc.Arguments))
+		}
+	}
+	t.Errorf("tool %s not called with matching arguments; calls: %v", name, called)
+	return llm.ToolCallData{}
+}
+
+// AssertNoToolErrors fails t for each tool result in conv that reports an
+// error.
+func AssertNoToolErrors(t testing.TB, conv llm.Conversation) {
+	t.Helper()
+	for _, m := range conv.Messages {
+		for _, p := range m.Content {
+			if tr := p.ToolResult; tr != nil && tr.IsError {
+				t.Errorf("tool call %s failed: %s", tr.ToolCallID, tr.Text())
+			}
+		}
+	}
+}
+
+// AssertFinish fails t unless resp finished for reason.
+func AssertFinish(t testing.TB, resp *llm.Response, reason llm.FinishReason) {
+	t.Helper()
+	switch {
+	case resp == nil:
+		t.Errorf("response is nil, want finish reason %q", reason)
+	case resp.FinishReason != reason:
+		t.Errorf("FinishReason = %q (%q), want %q", resp.FinishReason, resp.RawFinishReason, reason)
+	}
+}
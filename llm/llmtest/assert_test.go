@@ -0,0 +1,57 @@
+package llmtest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+// recorder is a testing.TB that records failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper()               {}
+func (r *recorder) Errorf(string, ...any) { r.failed = true }
+
+func TestAssertions(t *testing.T) {
+	conv := llm.NewConversation(Model)
+	conv.Messages = []llm.Message{
+		llm.UserMessage("Who is user 7?"),
+		ToolCallResponse(ToolCall("call_1", "get_user", map[string]any{"id": 7, "fields": []string{"name"}})).Message,
+		llm.ToolResultMessage("call_1", `{"name":"Ada"}`, false),
+	}
+
+	tests := []struct {
+		name     string
+		assert   func(t testing.TB)
+		wantFail bool
+	}{
+		{"called", func(t testing.TB) { AssertToolCalled(t, conv, "get_user", nil) }, false},
+		{"called with args", func(t testing.TB) {
+			AssertToolCalled(t, conv, "get_user", ArgsEqual(json.RawMessage(`{"fields": ["name"], "id": 7}`)))
+		}, false},
+		{"wrong args", func(t testing.TB) { AssertToolCalled(t, conv, "get_user", ArgsEqual(map[string]int{"id": 8})) }, true},
+		{"not called", func(t testing.TB) { AssertToolCalled(t, conv, "delete_user", nil) }, true},
+		{"no tool errors", func(t testing.TB) { AssertNoToolErrors(t, conv) }, false},
+		{"tool error", func(t testing.TB) {
+			failed := conv
+			failed.Messages = append(failed.Messages, llm.ToolResultMessage("call_2", "boom", true))
+			AssertNoToolErrors(t, failed)
+		}, true},
+		{"finish", func(t testing.TB) { AssertFinish(t, TextResponse("ok"), llm.FinishReasonStop) }, false},
+		{"wrong finish", func(t testing.TB) { AssertFinish(t, TextResponse("ok"), llm.FinishReasonToolUse) }, true},
+		{"nil response", func(t testing.TB) { AssertFinish(t, nil, llm.FinishReasonStop) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{TB: t}
+			tt.assert(r)
+			if r.failed != tt.wantFail {
+				t.Errorf("failed = %v, want %v", r.failed, tt.wantFail)
+			}
+		})
+	}
+}
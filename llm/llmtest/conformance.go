@@ -0,0 +1,217 @@
+// Package llmtest provides helpers for testing code built on package llm:
+// a scripted fake provider, a rule-based simulated model, assertions, and
+// conformance suites for custom Provider and Store implementations.
+package llmtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+// Case names a conformance scenario.
+type Case string
+
+// Conformance cases. Each case documents the reply its fixture backend must
+// produce and, where relevant, what it must find in the provider's request.
+// A fixture should answer with an error when a required value is missing
+// from the request, so the suite catches providers that drop content.
+const (
+	// CaseText: reply ReplyText with a stop finish reason and a usage of
+	// 10 input and 5 output tokens.
+	CaseText Case = "text"
+	// CaseSystem: require SystemPrompt in the request; reply ReplyText.
+	CaseSystem Case = "system"
+	// CaseToolCall: reply with a single call to ToolName with ID ToolCallID
+	// and arguments ToolArguments, and a tool-use finish reason.
+	CaseToolCall Case = "tool_call"
+	// CaseToolResult: require ToolCallID and ToolResultContent in the
+	// request; reply ReplyText.
+	CaseToolResult Case = "tool_result"
+	// CaseThinking: reply with reasoning ThinkingText and text ReplyText.
+	CaseThinking Case = "thinking"
+	// CaseImage: require the base64 encoding of ImageBytes in the request;
+	// reply ReplyText.
+	CaseImage Case = "image"
+	// CaseError: reply with a rate-limit error body or status.
+	CaseError Case = "error"
+)
+
+// Cases lists every conformance case in the order the suite runs them.
+var Cases = []Case{CaseText, CaseSystem, CaseToolCall, CaseToolResult, CaseThinking, CaseImage, CaseError}
+
+// Values exchanged by the conformance cases.
+const (
+	Model             = "conformance-model"
+	SystemPrompt      = "You are a conformance test."
+	UserText          = "Hello?"
+	ReplyText         = "Hello!"
+	ToolName          = "get_weather"
+	ToolCallID        = "call_1"
+	ToolArguments     = `{"location":"Paris"}`
+	ToolResultContent = "15C and cloudy"
+	ThinkingText      = "Let me think."
+)
+
+// ImageBytes is the image payload sent by CaseImage (a 1x1 PNG).
+var ImageBytes = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
+	0x49, 0x48, 0x44, 0x52, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01,
+	0x08, 0x02, 0x00, 0x00, 0x00, 0x90, 0x77, 0x53, 0xde, 0x00, 0x00, 0x00,
+	0x11, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0x00, 0x04, 0x00, 0xfb, 0xff,
+	0x02, 0xff, 0xff, 0xff, 0x03, 0x00, 0x06, 0x06, 0x03, 0x00, 0xad, 0xf8,
+	0x9b, 0x94, 0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e, 0x44, 0xae, 0x42,
+	0x60, 0x82,
+}
+
+// Fixtures maps each case to a Provider wired to a fake backend for that
+// case. Cases without a fixture are skipped.
+type Fixtures map[Case]llm.Provider
+
+// CaseConversation returns the conversation the suite sends for c.
+func CaseConversation(c Case) llm.Conversation {
+	tool := llm.NewTool(ToolName, "Get the weather", llm.StringParam("location"))
+	conv := llm.NewConversation(Model, llm.WithMaxTokens(256))
+	conv.Messages = []llm.Message{llm.UserMessage(UserText)}
+
+	switch c {
+	case CaseSystem:
+		conv.System = []string{SystemPrompt}
+	case CaseToolCall:
+		conv.Tools = []llm.ToolDefinition{tool}
+	case CaseToolResult:
+		conv.Tools = []llm.ToolDefinition{tool}
+		conv.Messages = append(conv.Messages,
+			llm.Message{Role: llm.RoleAssistant, Content: []llm.ContentPart{{
+				Kind:     llm.ContentToolCall,
+				ToolCall: &llm.ToolCallData{ID: ToolCallID, Name: ToolName, Arguments: json.RawMessage(ToolArguments)},
+			}}},
+			llm.ToolResultMessage(ToolCallID, ToolResultContent, false),
+		)
+	case CaseImage:
+		conv.Messages[0].Content = append(conv.Messages[0].Content, llm.ContentPart{
+			Kind:  llm.ContentImage,
+			Image: &llm.ImageData{Data: ImageBytes, MediaType: "image/png"},
+		})
+	}
+	return conv
+}
+
+// RunProviderConformance runs the conformance suite against the fixtures,
+// one subtest per case.
+func RunProviderConformance(t *testing.T, fixtures Fixtures) {
+	t.Helper()
+	for _, c := range Cases {
+		t.Run(string(c), func(t *testing.T) {
+			provider, ok := fixtures[c]
+			if !ok {
+				t.Skipf("no fixture for case %q", c)
+			}
+			runCase(t, c, provider)
+		})
+	}
+}
+
+func runCase(t *testing.T, c Case, provider llm.Provider) {
+	conv := CaseConversation(c)
+	before := CaseConversation(c)
+
+	resp, err := provider.Send(context.Background(), &conv)
+	if !reflect.DeepEqual(conv, before) {
+		t.Error("Send mutated the conversation")
+	}
+
+	if c == CaseError {
+		var llmErr *llm.Error
+		if !errors.As(err, &llmErr) {
+			t.Fatalf("error = %v (%T), want *llm.Error", err, err)
+		}
+		if llmErr.Kind != llm.ErrRateLimit {
+			t.Errorf("Kind = %v, want %v", llmErr.Kind, llm.ErrRateLimit)
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if resp.Message.Role != llm.RoleAssistant {
+		t.Errorf("Role = %q, want %q", resp.Message.Role, llm.RoleAssistant)
+	}
+
+	switch c {
+	case CaseToolCall:
+		if resp.FinishReason != llm.FinishReasonToolUse {
+			t.Errorf("FinishReason = %q, want %q", resp.FinishReason, llm.FinishReasonToolUse)
+		}
+		calls := resp.Message.ToolCalls()
+		if len(calls) != 1 {
+			t.Fatalf("ToolCalls len = %d, want 1", len(calls))
+		}
+		if calls[0].ID != ToolCallID || calls[0].Name != ToolName {
+			t.Errorf("ToolCall = %+v", calls[0])
+		}
+		var got, want any
+		if err := json.Unmarshal(calls[0].Arguments, &got); err != nil {
+			t.Fatalf("Arguments %q: %v", calls[0].Arguments, err)
+		}
+		_ = json.Unmarshal([]byte(ToolArguments), &want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Arguments = %s, want %s", calls[0].Arguments, ToolArguments)
+		}
+		return
+	case CaseThinking:
+		var thinking []string
+		for _, p := range resp.Message.Content {
+			if p.Kind == llm.ContentThinking && p.Thinking != nil {
+				thinking = append(thinking, p.Thinking.Text)
+			}
+		}
+		if strings.Join(thinking, "") != ThinkingText {
+			t.Errorf("thinking = %q, want %q", thinking, ThinkingText)
+		}
+	case CaseText:
+		if resp.Usage.InputTokens != 10 || resp.Usage.OutputTokens != 5 {
+			t.Errorf("Usage = %+v, want 10 input / 5 output", resp.Usage)
+		}
+	}
+
+	if resp.FinishReason != llm.FinishReasonStop {
+		t.Errorf("FinishReason = %q, want %q", resp.FinishReason, llm.FinishReasonStop)
+	}
+	if text := resp.Message.Text(); text != ReplyText {
+		t.Errorf("Text = %q, want %q", text, ReplyText)
+	}
+}
+
+// NewFixtureServer starts an HTTP server for fixtures of HTTP-based
+// providers. It answers every request with status and body, unless the
+// request body lacks one of required, in which case it answers 400 with a
+// message naming the missing value. The server is closed on test cleanup.
+func NewFixtureServer(t *testing.T, status int, body string, required ...string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		for _, s := range required {
+			if !strings.Contains(string(req), s) {
+				w.WriteHeader(http.StatusBadRequest)
+				msg, _ := json.Marshal("request is missing " + s)
+				_, _ = w.Write([]byte(`{"error":{"message":` + string(msg) + `}}`))
+				return
+			}
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
@@ -0,0 +1,162 @@
+package llmtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+func openAIFixtures(t *testing.T, newProvider func(baseURL string) llm.Provider) Fixtures {
+	reply := `{"choices":[{"message":{"role":"assistant","content":"Hello!"},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":5}}`
+	toolCall := `{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"location\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}`
+	thinking := `{"choices":[{"message":{"role":"assistant","content":"Hello!","reasoning_content":"Let me think."},"finish_reason":"stop"}]}`
+	rateLimit := `{"error":{"message":"slow down","type":"rate_limit"}}`
+
+	return Fixtures{
+		CaseText:       newProvider(NewFixtureServer(t, http.StatusOK, reply).URL),
+		CaseSystem:     newProvider(NewFixtureServer(t, http.StatusOK, reply, SystemPrompt).URL),
+		CaseToolCall:   newProvider(NewFixtureServer(t, http.StatusOK, toolCall).URL),
+		CaseToolResult: newProvider(NewFixtureServer(t, http.StatusOK, reply, ToolCallID, ToolResultContent).URL),
+		CaseThinking:   newProvider(NewFixtureServer(t, http.StatusOK, thinking).URL),
+		CaseError:      newProvider(NewFixtureServer(t, http.StatusTooManyRequests, rateLimit).URL),
+	}
+}
+
+func TestConformance_OpenAIProvider(t *testing.T) {
+	RunProviderConformance(t, openAIFixtures(t, func(baseURL string) llm.Provider {
+		return llm.NewOpenAIProvider(baseURL)
+	}))
+}
+
+func TestConformance_DeepSeekProvider(t *testing.T) {
+	RunProviderConformance(t, openAIFixtures(t, func(baseURL string) llm.Provider {
+		return llm.NewDeepSeekProvider(baseURL)
+	}))
+}
+
+func TestConformance_GeminiProvider(t *testing.T) {
+	reply := `{"candidates":[{"content":{"role":"model","parts":[{"text":"Hello!"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5}}`
+	toolCall := `{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"id":"call_1","name":"get_weather","args":{"location":"Paris"}},"thoughtSignature":"sig"}]},"finishReason":"STOP"}]}`
+	thinking := `{"candidates":[{"content":{"role":"model","parts":[{"text":"Let me think.","thought":true},{"text":"Hello!"}]},"finishReason":"STOP"}]}`
+	rateLimit := `{"error":{"code":429,"message":"slow down","status":"RESOURCE_EXHAUSTED"}}`
+	gemini := func(srv *httptest.Server) llm.Provider {
+		return llm.NewGeminiProvider("", llm.WithGeminiBaseURL(srv.URL))
+	}
+
+	RunProviderConformance(t, Fixtures{
+		CaseText:       gemini(NewFixtureServer(t, http.StatusOK, reply)),
+		CaseSystem:     gemini(NewFixtureServer(t, http.StatusOK, reply, SystemPrompt)),
+		CaseToolCall:   gemini(NewFixtureServer(t, http.StatusOK, toolCall)),
+		CaseToolResult: gemini(NewFixtureServer(t, http.StatusOK, reply, ToolCallID, ToolResultContent)),
+		CaseThinking:   gemini(NewFixtureServer(t, http.StatusOK, thinking)),
+		CaseImage:      gemini(NewFixtureServer(t, http.StatusOK, reply, base64.StdEncoding.EncodeToString(ImageBytes))),
+		CaseError:      gemini(NewFixtureServer(t, http.StatusTooManyRequests, rateLimit)),
+	})
+}
+
+func TestImageBytesIsValidPNG(t *testing.T) {
+	if _, err := png.Decode(bytes.NewReader(ImageBytes)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeConverser answers Converse with output, or with err when the input
+// fails check.
+type fakeConverser struct {
+	check  func(*bedrockruntime.ConverseInput) error
+	output *bedrockruntime.ConverseOutput
+	err    error
+}
+
+func (f *fakeConverser) Converse(_ context.Context, in *bedrockruntime.ConverseInput, _ ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error) {
+	if f.check != nil {
+		if err := f.check(in); err != nil {
+			return nil, &types.ValidationException{Message: aws.String(err.Error())}
+		}
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.output, nil
+}
+
+func converseOutput(stop types.StopReason, blocks ...types.ContentBlock) *bedrockruntime.ConverseOutput {
+	return &bedrockruntime.ConverseOutput{
+		Output: &types.ConverseOutputMemberMessage{
+			Value: types.Message{Role: types.ConversationRoleAssistant, Content: blocks},
+		},
+		StopReason: stop,
+		Usage:      &types.TokenUsage{InputTokens: aws.Int32(10), OutputTokens: aws.Int32(5)},
+	}
+}
+
+func TestConformance_BedrockProvider(t *testing.T) {
+	reply := converseOutput(types.StopReasonEndTurn, &types.ContentBlockMemberText{Value: ReplyText})
+	bedrock := func(check func(*bedrockruntime.ConverseInput) error, out *bedrockruntime.ConverseOutput) llm.Provider {
+		return llm.NewBedrockProvider(&fakeConverser{check: check, output: out})
+	}
+	hasSystem := func(in *bedrockruntime.ConverseInput) error {
+		for _, block := range in.System {
+			if text, ok := block.(*types.SystemContentBlockMemberText); ok && text.Value == SystemPrompt {
+				return nil
+			}
+		}
+		return fmt.Errorf("request is missing the system prompt")
+	}
+	hasToolResult := func(in *bedrockruntime.ConverseInput) error {
+		for _, m := range in.Messages {
+			for _, block := range m.Content {
+				tr, ok := block.(*types.ContentBlockMemberToolResult)
+				if !ok || aws.ToString(tr.Value.ToolUseId) != ToolCallID {
+					continue
+				}
+				for _, c := range tr.Value.Content {
+					if text, ok := c.(*types.ToolResultContentBlockMemberText); ok && text.Value == ToolResultContent {
+						return nil
+					}
+				}
+			}
+		}
+		return fmt.Errorf("request is missing the tool result")
+	}
+	hasImage := func(in *bedrockruntime.ConverseInput) error {
+		for _, block := range in.Messages[0].Content {
+			if img, ok := block.(*types.ContentBlockMemberImage); ok {
+				if src, ok := img.Value.Source.(*types.ImageSourceMemberBytes); ok && bytes.Equal(src.Value, ImageBytes) {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("request is missing the image")
+	}
+
+	RunProviderConformance(t, Fixtures{
+		CaseText:   bedrock(nil, reply),
+		CaseSystem: bedrock(hasSystem, reply),
+		CaseToolCall: bedrock(nil, converseOutput(types.StopReasonToolUse, &types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+			ToolUseId: aws.String(ToolCallID),
+			Name:      aws.String(ToolName),
+			Input:     document.NewLazyDocument(map[string]any{"location": "Paris"}),
+		}})),
+		CaseToolResult: bedrock(hasToolResult, reply),
+		CaseThinking: bedrock(nil, converseOutput(types.StopReasonEndTurn,
+			&types.ContentBlockMemberReasoningContent{Value: &types.ReasoningContentBlockMemberReasoningText{
+				Value: types.ReasoningTextBlock{Text: aws.String(ThinkingText)},
+			}},
+			&types.ContentBlockMemberText{Value: ReplyText},
+		)),
+		CaseImage: bedrock(hasImage, reply),
+		CaseError: llm.NewBedrockProvider(&fakeConverser{err: &types.ThrottlingException{Message: aws.String("slow down")}}),
+	})
+}
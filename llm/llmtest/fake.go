@@ -0,0 +1,101 @@
+package llmtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+// FakeProvider is an llm.Provider that answers each Send with the next of
+// its scripted responses, so that code built on llm.Client can be unit
+// tested without a backend. Pass it to llm.NewClientWithProvider. Once the
+// script runs out, Send fails with llm.ErrConfig. Every conversation it
+// receives is recorded, so multi-turn tests can check what each turn sent.
+// It is safe for concurrent use.
+type FakeProvider struct {
+	mu        sync.Mutex
+	responses []*llm.Response
+	requests  []llm.Conversation
+}
+
+// NewFakeProvider creates a FakeProvider that replies with responses in
+// order.
+func NewFakeProvider(responses ...*llm.Response) *FakeProvider {
+	return &FakeProvider{responses: responses}
+}
+
+// Send implements llm.Provider. It returns a copy of the next response, so
+// a response scripted more than once is not changed by the client.
+func (p *FakeProvider) Send(_ context.Context, conv *llm.Conversation) (*llm.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	req := *conv
+	req.Messages = slices.Clone(conv.Messages)
+	p.requests = append(p.requests, req)
+	if len(p.responses) == 0 {
+		return nil, &llm.Error{Kind: llm.ErrConfig, Message: "llmtest: no scripted response left for model " + conv.Model}
+	}
+	resp := *p.responses[0]
+	p.responses = p.responses[1:]
+	return &resp, nil
+}
+
+// Requests returns the conversations received by Send, in order, including
+// any that found the script empty.
+func (p *FakeProvider) Requests() []llm.Conversation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.requests)
+}
+
+// LastRequest returns the conversation most recently received by Send. It
+// fails t if there is none.
+func (p *FakeProvider) LastRequest(t testing.TB) llm.Conversation {
+	t.Helper()
+	reqs := p.Requests()
+	if len(reqs) == 0 {
+		t.Fatal("llmtest: no request received")
+	}
+	return reqs[len(reqs)-1]
+}
+
+// Remaining returns the number of scripted responses not yet sent.
+func (p *FakeProvider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.responses)
+}
+
+// TextResponse returns an assistant reply of text with a stop finish
+// reason.
+func TextResponse(text string) *llm.Response {
+	return &llm.Response{
+		Message:      llm.AssistantMessage(text),
+		FinishReason: llm.FinishReasonStop,
+	}
+}
+
+// ToolCallResponse returns an assistant reply that calls tools, with a
+// tool-use finish reason.
+func ToolCallResponse(calls ...llm.ToolCallData) *llm.Response {
+	msg := llm.Message{Role: llm.RoleAssistant}
+	for _, tc := range calls {
+		msg.Content = append(msg.Content, llm.ContentPart{Kind: llm.ContentToolCall, ToolCall: &tc})
+	}
+	return &llm.Response{Message: msg, FinishReason: llm.FinishReasonToolUse}
+}
+
+// ToolCall returns a call to the tool name with ID id and args encoded as
+// JSON. It panics if args cannot be encoded.
+func ToolCall(id, name string, args any) llm.ToolCallData {
+	data, err := json.Marshal(args)
+	if err != nil {
+		panic(fmt.Sprintf("llmtest: encoding arguments for tool %s: %v", name, err))
+	}
+	return llm.ToolCallData{ID: id, Name: name, Arguments: data}
+}
@@ -0,0 +1,60 @@
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+func TestFakeProvider_ToolLoop(t *testing.T) {
+	fake := NewFakeProvider(
+		ToolCallResponse(ToolCall("call_1", ToolName, map[string]string{"location": "Paris"})),
+		TextResponse("It's 15C and cloudy in Paris."),
+	)
+	var got string
+	tools := llm.NewToolRegistry().Register(
+		llm.NewTool(ToolName, "Get the weather", llm.StringParam("location")),
+		func(_ context.Context, args llm.ToolCallArgs) (string, error) {
+			got, _ = args.String("location")
+			return ToolResultContent, nil
+		},
+	)
+
+	client := llm.NewClientWithProvider(fake)
+	conv, resp, err := client.RunConversation(context.Background(), llm.NewConversation(Model), tools, llm.UserMessage("Weather in Paris?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Paris" {
+		t.Errorf("tool called with location %q", got)
+	}
+	if resp.Message.Text() != "It's 15C and cloudy in Paris." || len(conv.Messages) != 4 {
+		t.Errorf("reply = %q, messages = %d", resp.Message.Text(), len(conv.Messages))
+	}
+	if fake.Remaining() != 0 {
+		t.Errorf("Remaining = %d, want 0", fake.Remaining())
+	}
+
+	reqs := fake.Requests()
+	if len(reqs) != 2 || len(reqs[0].Messages) != 1 || len(reqs[0].Tools) != 1 {
+		t.Fatalf("requests = %+v", reqs)
+	}
+	result := fake.LastRequest(t).Messages[2]
+	if result.Role != llm.RoleTool || result.ToolCallID != "call_1" || result.Content[0].ToolResult.Content != ToolResultContent {
+		t.Errorf("second request ends with %+v", result)
+	}
+}
+
+func TestFakeProvider_Exhausted(t *testing.T) {
+	client := llm.NewClientWithProvider(NewFakeProvider(TextResponse(ReplyText)))
+	conv, _, err := client.Send(context.Background(), llm.NewConversation(Model), llm.UserMessage(UserText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = client.Send(context.Background(), conv, llm.UserMessage(UserText))
+	if !errors.Is(err, llm.ErrConfig) {
+		t.Errorf("err = %v, want ErrConfig", err)
+	}
+}
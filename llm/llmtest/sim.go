@@ -0,0 +1,123 @@
+package llmtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+// SimModel is an llm.Provider that simulates a tool-calling model with
+// rules, so agent loop logic can be tested without scripting every turn or
+// writing provider payloads. Each Send matches the rules in order against
+// the latest input: the text of the last user message, or the content of
+// the tool results that end the conversation, one per line. The first
+// matching rule replies; if none matches, Send fails with llm.ErrConfig.
+// It is safe for concurrent use.
+type SimModel struct {
+	rules []SimRule
+
+	mu    sync.Mutex
+	calls int
+}
+
+// NewSimModel creates a SimModel with rules.
+func NewSimModel(rules ...SimRule) *SimModel {
+	return &SimModel{rules: rules}
+}
+
+// SimRule is a SimModel rule: a pattern and the reply to give when the
+// latest input matches it. Build one with When.
+type SimRule struct {
+	pattern *regexp.Regexp
+	text    string
+	calls   []simCall
+}
+
+type simCall struct {
+	name, args string
+}
+
+// When starts a rule that applies when the latest input matches the
+// regular expression pattern. It panics if pattern does not compile.
+func When(pattern string) SimRule {
+	return SimRule{pattern: regexp.MustCompile(pattern)}
+}
+
+// Say makes the rule reply with text. Text and tool arguments are
+// templates for regexp.Expand, so $1 or ${name} insert a submatch of the
+// pattern.
+func (r SimRule) Say(text string) SimRule {
+	r.text = text
+	return r
+}
+
+// Call makes the rule call the tool name with the JSON arguments args.
+// Call it more than once for a reply with parallel tool calls.
+func (r SimRule) Call(name, args string) SimRule {
+	r.calls = append(r.calls[:len(r.calls):len(r.calls)], simCall{name, args})
+	return r
+}
+
+// Send implements llm.Provider.
+func (m *SimModel) Send(_ context.Context, conv *llm.Conversation) (*llm.Response, error) {
+	input := latestInput(conv.Messages)
+	for _, r := range m.rules {
+		match := r.pattern.FindStringSubmatchIndex(input)
+		if match == nil {
+			continue
+		}
+		expand := func(template string) string {
+			return string(r.pattern.ExpandString(nil, template, input, match))
+		}
+		msg := llm.Message{Role: llm.RoleAssistant}
+		if r.text != "" {
+			msg.Content = append(msg.Content, llm.ContentPart{Kind: llm.ContentText, Text: expand(r.text)})
+		}
+		for _, c := range r.calls {
+			msg.Content = append(msg.Content, llm.ContentPart{Kind: llm.ContentToolCall, ToolCall: &llm.ToolCallData{
+				ID:        m.nextCallID(),
+				Name:      c.name,
+				Arguments: json.RawMessage(expand(c.args)),
+			}})
+		}
+		reason := llm.FinishReasonStop
+		if len(r.calls) > 0 {
+			reason = llm.FinishReasonToolUse
+		}
+		return &llm.Response{Message: msg, FinishReason: reason}, nil
+	}
+	return nil, &llm.Error{Kind: llm.ErrConfig, Message: fmt.Sprintf("llmtest: no rule matches %q", input)}
+}
+
+func (m *SimModel) nextCallID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	return fmt.Sprintf("call_%d", m.calls)
+}
+
+// latestInput returns the text a SimModel matches its rules against.
+func latestInput(msgs []llm.Message) string {
+	var results []string
+	for i := len(msgs) - 1; i >= 0 && msgs[i].Role == llm.RoleTool; i-- {
+		for _, p := range msgs[i].Content {
+			if p.ToolResult != nil {
+				results = append([]string{p.ToolResult.Text()}, results...)
+			}
+		}
+	}
+	if results != nil {
+		return strings.Join(results, "\n")
+	}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == llm.RoleUser {
+			return msgs[i].Text()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+func weatherTools(calls *[]string) *llm.ToolRegistry {
+	return llm.NewToolRegistry().Register(
+		llm.NewTool(ToolName, "Get the weather", llm.StringParam("location")),
+		func(_ context.Context, args llm.ToolCallArgs) (string, error) {
+			location, _ := args.String("location")
+			*calls = append(*calls, location)
+			return "15C in " + location, nil
+		},
+	)
+}
+
+func TestSimModel_ToolLoop(t *testing.T) {
+	sim := NewSimModel(
+		When(`weather in (\w+) and (\w+)`).Call(ToolName, `{"location":"$1"}`).Call(ToolName, `{"location":"$2"}`),
+		When(`(?s)15C in (\w+)\n15C in (\w+)`).Say("Both $1 and $2 are 15C."),
+	)
+	var calls []string
+	client := llm.NewClientWithProvider(sim)
+
+	conv, resp, err := client.RunConversation(context.Background(), llm.NewConversation(Model), weatherTools(&calls), llm.UserMessage("What's the weather in Paris and Oslo?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[0] != "Paris" || calls[1] != "Oslo" {
+		t.Errorf("tool calls = %v", calls)
+	}
+	AssertToolCalled(t, conv, ToolName, ArgsEqual(map[string]string{"location": "Oslo"}))
+	AssertFinish(t, resp, llm.FinishReasonStop)
+	if got := resp.Message.Text(); got != "Both Paris and Oslo are 15C." {
+		t.Errorf("reply = %q", got)
+	}
+}
+
+func TestSimModel_MaxTurns(t *testing.T) {
+	sim := NewSimModel(When(`.`).Call(ToolName, `{"location":"Paris"}`))
+	var calls []string
+	runner := llm.NewRunner(llm.NewClientWithProvider(sim), weatherTools(&calls), llm.WithMaxTurns(3))
+
+	_, _, err := runner.Run(context.Background(), llm.NewConversation(Model), llm.UserMessage("Loop forever"))
+	if !errors.Is(err, llm.ErrToolLoop) {
+		t.Errorf("err = %v, want ErrToolLoop", err)
+	}
+}
+
+func TestSimModel_NoMatch(t *testing.T) {
+	client := llm.NewClientWithProvider(NewSimModel(When(`^hello$`).Say("hi")))
+	_, _, err := client.Send(context.Background(), llm.NewConversation(Model), llm.UserMessage("goodbye"))
+	if !errors.Is(err, llm.ErrConfig) {
+		t.Errorf("err = %v, want ErrConfig", err)
+	}
+}
@@ -0,0 +1,197 @@
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+// RunStoreConformance checks that a Store implementation follows the
+// llm.Store contract: round trips, optimistic locking, listing with filters
+// and pagination, and deletion. newStore must return an empty store; it is
+// called once per subtest.
+func RunStoreConformance(t *testing.T, newStore func(t *testing.T) llm.Store) {
+	ctx := context.Background()
+
+	t.Run("round trip", func(t *testing.T) {
+		store := newStore(t)
+		conv := storeConversation("c1", "model-a", "alice", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		if err := store.Save(ctx, &conv); err != nil {
+			t.Fatal(err)
+		}
+		if conv.Revision != 1 {
+			t.Errorf("Revision after first save = %d, want 1", conv.Revision)
+		}
+		got, err := store.Load(ctx, "c1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Revision != 1 || got.Model != "model-a" || got.Metadata["user"] != "alice" || !got.UpdatedAt.Equal(conv.UpdatedAt) {
+			t.Errorf("loaded = %+v", got)
+		}
+		if len(got.Messages) != 2 || got.Messages[1].Text() != ReplyText || !slices.Equal(got.Messages[0].Content[1].Image.Data, ImageBytes) {
+			t.Errorf("loaded messages = %+v", got.Messages)
+		}
+		if got.Usage != conv.Usage || len(got.System) != 1 {
+			t.Errorf("loaded usage = %+v, system = %v", got.Usage, got.System)
+		}
+	})
+
+	t.Run("optimistic locking", func(t *testing.T) {
+		store := newStore(t)
+		conv := storeConversation("c1", "model-a", "alice", time.Now())
+		if err := store.Save(ctx, &conv); err != nil {
+			t.Fatal(err)
+		}
+		stale := conv
+
+		conv.Messages = append(conv.Messages, llm.UserMessage("again"))
+		if err := store.Save(ctx, &conv); err != nil || conv.Revision != 2 {
+			t.Fatalf("second save: revision %d, %v", conv.Revision, err)
+		}
+		stale.Metadata = map[string]string{"user": "mallory"}
+		requireKind(t, store.Save(ctx, &stale), llm.ErrConflict)
+		if stale.Revision != 1 {
+			t.Errorf("failed save changed Revision to %d", stale.Revision)
+		}
+
+		again := storeConversation("c1", "model-a", "bob", time.Now())
+		requireKind(t, store.Save(ctx, &again), llm.ErrConflict)
+
+		got, err := store.Load(ctx, "c1")
+		if err != nil || got.Revision != 2 || len(got.Messages) != 3 || got.Metadata["user"] != "alice" {
+			t.Errorf("loaded = %+v, %v", got, err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		store := newStore(t)
+		_, err := store.Load(ctx, "missing")
+		requireKind(t, err, llm.ErrNotFound)
+		requireKind(t, store.Save(ctx, &llm.Conversation{Model: "m"}), llm.ErrInvalidRequest)
+		if err := store.Delete(ctx, "missing"); err != nil {
+			t.Errorf("Delete(missing) = %v", err)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		store := newStore(t)
+		day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+		for i, c := range []llm.Conversation{
+			storeConversation("c1", "model-a", "alice", day(1)),
+			storeConversation("c2", "model-b", "alice", day(2)),
+			storeConversation("c3", "model-a", "bob", day(3)),
+			storeConversation("c4", "model-a", "alice", day(4)),
+			storeConversation("c5", "model-a", "alice", day(5)),
+		} {
+			if err := store.Save(ctx, &c); err != nil {
+				t.Fatalf("save %d: %v", i, err)
+			}
+		}
+
+		tests := []struct {
+			name string
+			opts llm.ListOptions
+			want []string
+		}{
+			{"all", llm.ListOptions{}, []string{"c1", "c2", "c3", "c4", "c5"}},
+			{"model", llm.ListOptions{Model: "model-a"}, []string{"c1", "c3", "c4", "c5"}},
+			{"metadata", llm.ListOptions{Metadata: map[string]string{"user": "alice"}}, []string{"c1", "c2", "c4", "c5"}},
+			{"time range", llm.ListOptions{UpdatedAfter: day(2), UpdatedBefore: day(4)}, []string{"c2", "c3"}},
+			{"combined, paged", llm.ListOptions{Model: "model-a", Metadata: map[string]string{"user": "alice"}, Limit: 1}, []string{"c1", "c4", "c5"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got := listAll(t, store, tt.opts)
+				slices.Sort(got)
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("List = %v, want %v", got, tt.want)
+				}
+			})
+		}
+
+		page, err := store.List(ctx, llm.ListOptions{Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Conversations) > 2 || page.Next == "" {
+			t.Errorf("first page of 2 = %+v", page)
+		}
+		if info := page.Conversations[0]; info.Messages != 2 || info.Revision != 1 || info.CreatedAt.IsZero() {
+			t.Errorf("info = %+v", info)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		store := newStore(t)
+		conv := storeConversation("c1", "model-a", "alice", time.Now())
+		if err := store.Save(ctx, &conv); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Delete(ctx, "c1"); err != nil {
+			t.Fatal(err)
+		}
+		_, err := store.Load(ctx, "c1")
+		requireKind(t, err, llm.ErrNotFound)
+		if ids := listAll(t, store, llm.ListOptions{}); len(ids) != 0 {
+			t.Errorf("List after delete = %v", ids)
+		}
+
+		// A deleted conversation can be saved again from scratch.
+		conv.Revision = 0
+		if err := store.Save(ctx, &conv); err != nil || conv.Revision != 1 {
+			t.Errorf("save after delete: revision %d, %v", conv.Revision, err)
+		}
+	})
+}
+
+// storeConversation returns a two-message conversation with an image.
+func storeConversation(id, model, user string, updated time.Time) llm.Conversation {
+	conv := llm.NewConversation(model, llm.WithSystem(SystemPrompt), llm.WithID(id), llm.WithMetadata("user", user))
+	conv.Messages = []llm.Message{
+		{Role: llm.RoleUser, Content: []llm.ContentPart{
+			{Kind: llm.ContentText, Text: UserText},
+			{Kind: llm.ContentImage, Image: &llm.ImageData{Data: ImageBytes, MediaType: "image/png"}},
+		}},
+		llm.AssistantMessage(ReplyText),
+	}
+	conv.Usage = llm.Usage{InputTokens: 10, OutputTokens: 5}
+	conv.CreatedAt, conv.UpdatedAt = updated.Add(-time.Hour), updated
+	return conv
+}
+
+// listAll follows List's pagination and returns every matching ID.
+func listAll(t *testing.T, store llm.Store, opts llm.ListOptions) []string {
+	t.Helper()
+	var ids []string
+	for range 100 {
+		page, err := store.List(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opts.Limit > 0 && len(page.Conversations) > opts.Limit {
+			t.Errorf("page of %d exceeds limit %d", len(page.Conversations), opts.Limit)
+		}
+		for _, info := range page.Conversations {
+			ids = append(ids, info.ID)
+		}
+		if page.Next == "" {
+			return ids
+		}
+		opts.Cursor = page.Next
+	}
+	t.Fatal("List did not finish paginating")
+	return nil
+}
+
+func requireKind(t *testing.T, err error, kind llm.ErrorKind) {
+	t.Helper()
+	var llmErr *llm.Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != kind {
+		t.Errorf("err = %v, want %s", err, kind)
+	}
+}
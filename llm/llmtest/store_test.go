@@ -0,0 +1,11 @@
+package llmtest
+
+import (
+	"testing"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+func TestStoreConformance_MemoryStore(t *testing.T) {
+	RunStoreConformance(t, func(t *testing.T) llm.Store { return llm.NewMemoryStore() })
+}
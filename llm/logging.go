@@ -0,0 +1,151 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// LogContent selects how message content appears in logs.
+type LogContent int
+
+const (
+	LogContentNone     LogContent = iota // omit content; log metadata only
+	LogContentHash                       // short SHA-256 digest and length of each part
+	LogContentTruncate                   // first LogOptions.MaxLength characters of each part
+	LogContentFull                       // content verbatim
+)
+
+// LogOptions configures Logging.
+type LogOptions struct {
+	Level      slog.Level // level for successful sends; failures log at Error
+	Content    LogContent // default LogContentNone
+	MaxLength  int        // characters kept by LogContentTruncate; default 80
+	DropImages bool       // omit image parts instead of logging a placeholder
+}
+
+// WithLogging adds a Logging middleware to the client.
+func WithLogging(logger *slog.Logger, opts LogOptions) ClientOption {
+	return WithMiddleware(Logging(logger, opts))
+}
+
+// Logging returns middleware that logs one record per send with the model,
+// message and tool counts, duration, finish reason, token usage, and any
+// error. Message content of the newest request message and the reply is
+// included only as opts.Content allows; image bytes are never logged.
+func Logging(logger *slog.Logger, opts LogOptions) Middleware {
+	if opts.MaxLength <= 0 {
+		opts.MaxLength = 80
+	}
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		start := time.Now()
+		resp, err := next(ctx, conv)
+
+		attrs := []slog.Attr{
+			slog.String("model", conv.Model),
+			slog.Int("messages", len(conv.Messages)),
+			slog.Int("tools", len(conv.Tools)),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if opts.Content != LogContentNone && len(conv.Messages) > 0 {
+			attrs = append(attrs, slog.Any("request", opts.redactMessage(conv.Messages[len(conv.Messages)-1])))
+		}
+
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+			var llmErr *Error
+			if errors.As(err, &llmErr) {
+				attrs = append(attrs, slog.String("error_kind", llmErr.Kind.String()))
+				if llmErr.RequestID != "" {
+					attrs = append(attrs, slog.String("request_id", llmErr.RequestID))
+				}
+			}
+			logger.LogAttrs(ctx, slog.LevelError, "llm send failed", attrs...)
+			return resp, err
+		}
+
+		if resp.Model != "" && resp.Model != conv.Model {
+			attrs = append(attrs, slog.String("response_model", resp.Model))
+		}
+		attrs = append(attrs,
+			slog.String("finish_reason", string(resp.FinishReason)),
+			slog.Int("input_tokens", resp.Usage.InputTokens),
+			slog.Int("output_tokens", resp.Usage.OutputTokens),
+		)
+		if resp.RequestID != "" {
+			attrs = append(attrs, slog.String("request_id", resp.RequestID))
+		}
+		if opts.Content != LogContentNone {
+			attrs = append(attrs, slog.Any("response", opts.redactMessage(resp.Message)))
+		}
+		logger.LogAttrs(ctx, opts.Level, "llm send", attrs...)
+		return resp, nil
+	}
+}
+
+// redactMessage renders each content part of m as a string.
+func (o LogOptions) redactMessage(m Message) []string {
+	var parts []string
+	for _, p := range m.Content {
+		switch p.Kind {
+		case ContentText:
+			parts = append(parts, o.redact(p.Text))
+		case ContentThinking:
+			if p.Thinking != nil {
+				parts = append(parts, "thinking: "+o.redact(p.Thinking.Text))
+			}
+		case ContentImage:
+			if o.DropImages || p.Image == nil {
+				continue
+			}
+			if p.Image.URL != "" {
+				parts = append(parts, "image: "+o.redact(p.Image.URL))
+			} else {
+				parts = append(parts, fmt.Sprintf("image: %s, %d bytes", p.Image.MediaType, len(p.Image.Data)))
+			}
+		case ContentAudio:
+			if p.Audio == nil {
+				continue
+			}
+			if p.Audio.URL != "" {
+				parts = append(parts, "audio: "+o.redact(p.Audio.URL))
+			} else {
+				parts = append(parts, fmt.Sprintf("audio: %s, %d bytes", p.Audio.MediaType, len(p.Audio.Data)))
+			}
+		case ContentDocument:
+			if p.Document != nil {
+				parts = append(parts, fmt.Sprintf("document %s: %s, %d bytes", o.redact(p.Document.Name), p.Document.MediaType, len(p.Document.Data)))
+			}
+		case ContentToolCall:
+			if p.ToolCall != nil {
+				parts = append(parts, fmt.Sprintf("tool_call %s: %s", p.ToolCall.Name, o.redact(string(p.ToolCall.Arguments))))
+			}
+		case ContentToolResult:
+			if p.ToolResult != nil {
+				parts = append(parts, fmt.Sprintf("tool_result %s: %s", p.ToolResult.ToolCallID, o.redact(p.ToolResult.Text())))
+			}
+		}
+	}
+	return parts
+}
+
+func (o LogOptions) redact(s string) string {
+	switch o.Content {
+	case LogContentHash:
+		sum := sha256.Sum256([]byte(s))
+		return fmt.Sprintf("sha256:%s (%d chars)", hex.EncodeToString(sum[:8]), len([]rune(s)))
+	case LogContentTruncate:
+		r := []rune(s)
+		if len(r) <= o.MaxLength {
+			return s
+		}
+		return string(r[:o.MaxLength]) + "…"
+	case LogContentFull:
+		return s
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func logRecord(t *testing.T, opts LogOptions, provider Provider, msg Message) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	client := NewClientWithProvider(provider, WithLogging(logger, opts))
+	client.Send(context.Background(), NewConversation("model"), msg)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log output %q: %v", buf.String(), err)
+	}
+	return record
+}
+
+func TestLogging_MetadataOnlyByDefault(t *testing.T) {
+	record := logRecord(t, LogOptions{}, &mockProvider{resp: simpleResponse("secret reply")}, UserMessage("secret prompt"))
+
+	if record["model"] != "model" || record["input_tokens"] != float64(10) || record["output_tokens"] != float64(5) {
+		t.Errorf("record = %v", record)
+	}
+	if _, ok := record["request"]; ok {
+		t.Error("content logged without opting in")
+	}
+	if _, ok := record["response"]; ok {
+		t.Error("content logged without opting in")
+	}
+}
+
+func TestLogging_Redaction(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	tests := []struct {
+		name    string
+		opts    LogOptions
+		text    string
+		want    string
+		wantNot string
+	}{
+		{"hash", LogOptions{Content: LogContentHash}, "secret", "sha256:", "secret"},
+		{"truncate", LogOptions{Content: LogContentTruncate, MaxLength: 10}, long, strings.Repeat("a", 10) + "…", long},
+		{"full", LogOptions{Content: LogContentFull}, "hello", "hello", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := logRecord(t, tt.opts, &mockProvider{resp: simpleResponse("ok")}, UserMessage(tt.text))
+			request, _ := json.Marshal(record["request"])
+			if !strings.Contains(string(request), tt.want) {
+				t.Errorf("request = %s, want %q", request, tt.want)
+			}
+			if tt.wantNot != "" && strings.Contains(string(request), tt.wantNot) {
+				t.Errorf("request = %s leaks %q", request, tt.wantNot)
+			}
+		})
+	}
+}
+
+func TestLogging_Images(t *testing.T) {
+	msg := Message{Role: RoleUser, Content: []ContentPart{
+		{Kind: ContentImage, Image: &ImageData{Data: []byte("pngbytes"), MediaType: "image/png"}},
+	}}
+
+	record := logRecord(t, LogOptions{Content: LogContentFull}, &mockProvider{resp: simpleResponse("ok")}, msg)
+	request, _ := json.Marshal(record["request"])
+	if !strings.Contains(string(request), "image/png, 8 bytes") || strings.Contains(string(request), "pngbytes") {
+		t.Errorf("request = %s", request)
+	}
+
+	record = logRecord(t, LogOptions{Content: LogContentFull, DropImages: true}, &mockProvider{resp: simpleResponse("ok")}, msg)
+	if record["request"] != nil {
+		t.Errorf("request = %v, want images dropped", record["request"])
+	}
+}
+
+func TestLogging_Error(t *testing.T) {
+	record := logRecord(t, LogOptions{}, &mockProvider{err: &Error{Kind: ErrRateLimit, Message: "slow", RequestID: "req-1"}}, UserMessage("hi"))
+	if record["level"] != "ERROR" || record["error_kind"] != "rate_limit" || record["request_id"] != "req-1" {
+		t.Errorf("record = %v", record)
+	}
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"strings"
+	"sync"
+)
+
+// ModelInfo describes the limits and list pricing of a model.
+type ModelInfo struct {
+	ContextWindow   int     `json:"context_window"`    // max input + output tokens
+	MaxOutputTokens int     `json:"max_output_tokens"` // max tokens per response
+	Pricing         Pricing `json:"pricing"`
+}
+
+// Pricing is the price of a model in USD per million tokens.
+type Pricing struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheRead  float64 `json:"cache_read,omitempty"`
+	CacheWrite float64 `json:"cache_write,omitempty"`
+}
+
+// Built-in model table, keyed by a substring of the model ID so that region
+// prefixes ("us.") and version suffixes ("-v1:0") match. Prices are
+// approximate on-demand list prices; use RegisterModel to override them.
+var (
+	modelsMu sync.RWMutex
+	models   = map[string]ModelInfo{
+		"anthropic.claude-opus-4-1":   {200_000, 32_000, Pricing{15, 75, 1.5, 18.75}},
+		"anthropic.claude-sonnet-4-5": {200_000, 64_000, Pricing{3, 15, 0.3, 3.75}},
+		"anthropic.claude-sonnet-4":   {200_000, 64_000, Pricing{3, 15, 0.3, 3.75}},
+		"anthropic.claude-haiku-4-5":  {200_000, 64_000, Pricing{1, 5, 0.1, 1.25}},
+		"anthropic.claude-3-5-haiku":  {200_000, 8_192, Pricing{0.8, 4, 0.08, 1}},
+		"amazon.nova-pro-v1":          {300_000, 10_000, Pricing{0.8, 3.2, 0.2, 0}},
+		"amazon.nova-lite-v1":         {300_000, 10_000, Pricing{0.06, 0.24, 0.015, 0}},
+		"amazon.nova-micro-v1":        {128_000, 10_000, Pricing{0.035, 0.14, 0.00875, 0}},
+		"openai.gpt-oss-20b":          {128_000, 32_768, Pricing{0.07, 0.3, 0, 0}},
+		"openai.gpt-oss-120b":         {128_000, 32_768, Pricing{0.15, 0.6, 0, 0}},
+		"deepseek.r1":                 {128_000, 32_768, Pricing{1.35, 5.4, 0, 0}},
+	}
+)
+
+// RegisterModel adds or replaces the ModelInfo for model IDs containing key.
+func RegisterModel(key string, info ModelInfo) {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	models[key] = info
+}
+
+// LookupModel returns the ModelInfo whose key is the longest substring of
+// the given model ID.
+func LookupModel(model string) (ModelInfo, bool) {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+	var best string
+	for key := range models {
+		if len(key) > len(best) && strings.Contains(model, key) {
+			best = key
+		}
+	}
+	if best == "" {
+		return ModelInfo{}, false
+	}
+	return models[best], true
+}
@@ -0,0 +1,50 @@
+package llm
+
+import "testing"
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		model  string
+		want   float64 // input price
+		wantOK bool
+	}{
+		{"us.anthropic.claude-sonnet-4-5-20250929-v1:0", 3, true},
+		{"us.anthropic.claude-haiku-4-5-20251001-v1:0", 1, true},
+		{"openai.gpt-oss-120b-1:0", 0.15, true},
+		{"llama3", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			info, ok := LookupModel(tt.model)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if info.Pricing.Input != tt.want {
+				t.Errorf("Input price = %v, want %v", info.Pricing.Input, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupModelLongestMatch(t *testing.T) {
+	// "anthropic.claude-sonnet-4" is a prefix of "anthropic.claude-sonnet-4-5";
+	// the longer key must win.
+	info, ok := LookupModel("us.anthropic.claude-sonnet-4-5-20250929-v1:0")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	if info.MaxOutputTokens != 64_000 {
+		t.Errorf("MaxOutputTokens = %d", info.MaxOutputTokens)
+	}
+}
+
+func TestRegisterModel(t *testing.T) {
+	RegisterModel("test-register-model", ModelInfo{ContextWindow: 8192, MaxOutputTokens: 1024, Pricing: Pricing{Input: 1, Output: 2}})
+	info, ok := LookupModel("my-test-register-model-v2")
+	if !ok {
+		t.Fatal("expected registered model to match")
+	}
+	if info.ContextWindow != 8192 || info.Pricing.Output != 2 {
+		t.Errorf("info = %+v", info)
+	}
+}
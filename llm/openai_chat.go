@@ -0,0 +1,229 @@
+package llm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MarshalOpenAIChat encodes conv as an OpenAI chat completions request body:
+// model, messages (system prompts first), tools, and inference parameters.
+// Content the OpenAI provider cannot send is dropped, as it would be by
+// OpenAIProvider.Send.
+func MarshalOpenAIChat(conv Conversation) ([]byte, error) {
+	return json.Marshal(toOpenAIRequest(&conv))
+}
+
+// WriteOpenAIJSONL writes each conversation as one line of OpenAI
+// fine-tuning JSONL, holding only its messages and tools.
+func WriteOpenAIJSONL(w io.Writer, convs ...Conversation) error {
+	enc := json.NewEncoder(w)
+	for i, conv := range convs {
+		req := toOpenAIRequest(&conv)
+		line := struct {
+			Messages []chatMessage `json:"messages"`
+			Tools    []chatTool    `json:"tools,omitempty"`
+		}{req.Messages, req.Tools}
+		if err := enc.Encode(line); err != nil {
+			return fmt.Errorf("conversation %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// UnmarshalOpenAIChat builds a Conversation from OpenAI chat messages JSON:
+// a chat completions request body or a fine-tuning JSONL line. System and
+// developer messages become System prompts, assistant tool_calls become
+// tool call parts, and each tool message becomes a tool result message.
+// Message content may be a string or an array of text and image_url parts;
+// images given as data URLs are decoded.
+func UnmarshalOpenAIChat(data []byte) (Conversation, error) {
+	var req struct {
+		Model       string              `json:"model"`
+		Messages    []openAIChatMessage `json:"messages"`
+		Tools       []chatTool          `json:"tools"`
+		ToolChoice  json.RawMessage     `json:"tool_choice"`
+		MaxTokens   *int                `json:"max_tokens"`
+		MaxComplete *int                `json:"max_completion_tokens"`
+		Temperature *float64            `json:"temperature"`
+		TopP        *float64            `json:"top_p"`
+		Stop        json.RawMessage     `json:"stop"`
+
+		FrequencyPenalty *float64 `json:"frequency_penalty"`
+		PresencePenalty  *float64 `json:"presence_penalty"`
+
+		ResponseFormat *chatResponseFormat `json:"response_format"`
+
+		ReasoningEffort string `json:"reasoning_effort"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return Conversation{}, fmt.Errorf("parsing OpenAI chat: %w", err)
+	}
+
+	conv := Conversation{Model: req.Model}
+	conv.Config.MaxTokens = req.MaxTokens
+	if req.MaxComplete != nil {
+		conv.Config.MaxTokens = req.MaxComplete
+	}
+	conv.Config.Temperature = req.Temperature
+	conv.Config.TopP = req.TopP
+	conv.Config.FrequencyPenalty = req.FrequencyPenalty
+	conv.Config.PresencePenalty = req.PresencePenalty
+	conv.Config.ReasoningEffort = ReasoningEffort(req.ReasoningEffort)
+	if rf := req.ResponseFormat; rf != nil {
+		conv.Config.ResponseFormat = &ResponseFormat{Type: ResponseFormatType(rf.Type)}
+		if s := rf.JSONSchema; s != nil {
+			conv.Config.ResponseFormat.Name, conv.Config.ResponseFormat.Schema, conv.Config.ResponseFormat.Strict = s.Name, s.Schema, s.Strict
+		}
+	}
+	if len(req.Stop) > 0 && string(req.Stop) != "null" {
+		var one string
+		if json.Unmarshal(req.Stop, &one) == nil {
+			conv.Config.StopSequences = []string{one}
+		} else if err := json.Unmarshal(req.Stop, &conv.Config.StopSequences); err != nil {
+			return Conversation{}, fmt.Errorf("parsing OpenAI chat: stop: %w", err)
+		}
+	}
+	if len(req.ToolChoice) > 0 && string(req.ToolChoice) != "null" {
+		tc, err := parseOpenAIToolChoice(req.ToolChoice)
+		if err != nil {
+			return Conversation{}, fmt.Errorf("parsing OpenAI chat: tool_choice: %w", err)
+		}
+		conv.Config.ToolChoice = tc
+	}
+	for _, t := range req.Tools {
+		conv.Tools = append(conv.Tools, ToolDefinition{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			Parameters:  t.Function.Parameters,
+		})
+	}
+
+	for i, cm := range req.Messages {
+		parts, err := cm.parts()
+		if err != nil {
+			return Conversation{}, fmt.Errorf("parsing OpenAI chat: message %d: %w", i, err)
+		}
+		switch cm.Role {
+		case "system", "developer":
+			conv.System = append(conv.System, textOf(parts))
+		case "user":
+			conv.Messages = append(conv.Messages, Message{Role: RoleUser, Content: parts})
+		case "assistant":
+			m := Message{Role: RoleAssistant}
+			if cm.ReasoningContent != "" {
+				m.Content = append(m.Content, ContentPart{Kind: ContentThinking, Thinking: &ThinkingData{Text: cm.ReasoningContent}})
+			}
+			m.Content = append(m.Content, parts...)
+			for _, tc := range cm.ToolCalls {
+				m.Content = append(m.Content, ContentPart{Kind: ContentToolCall, ToolCall: &ToolCallData{
+					ID:        tc.ID,
+					Name:      tc.Function.Name,
+					Arguments: json.RawMessage(tc.Function.Arguments),
+				}})
+			}
+			conv.Messages = append(conv.Messages, m)
+		case "tool":
+			conv.Messages = append(conv.Messages, ToolResultMessage(cm.ToolCallID, textOf(parts), false))
+		default:
+			return Conversation{}, fmt.Errorf("parsing OpenAI chat: message %d: unknown role %q", i, cm.Role)
+		}
+	}
+	return conv, nil
+}
+
+// openAIChatMessage is a chat message whose content may be a string or an
+// array of content parts.
+type openAIChatMessage struct {
+	Role             string          `json:"role"`
+	Content          json.RawMessage `json:"content"`
+	ReasoningContent string          `json:"reasoning_content"`
+	ToolCalls        []chatToolCall  `json:"tool_calls"`
+	ToolCallID       string          `json:"tool_call_id"`
+}
+
+func (cm openAIChatMessage) parts() ([]ContentPart, error) {
+	if len(cm.Content) == 0 || string(cm.Content) == "null" {
+		return nil, nil
+	}
+	var text string
+	if json.Unmarshal(cm.Content, &text) == nil {
+		if text == "" {
+			return nil, nil
+		}
+		return []ContentPart{{Kind: ContentText, Text: text}}, nil
+	}
+
+	var raw []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		ImageURL struct {
+			URL string `json:"url"`
+		} `json:"image_url"`
+	}
+	if err := json.Unmarshal(cm.Content, &raw); err != nil {
+		return nil, fmt.Errorf("content: %w", err)
+	}
+	var parts []ContentPart
+	for _, r := range raw {
+		switch r.Type {
+		case "text":
+			parts = append(parts, ContentPart{Kind: ContentText, Text: r.Text})
+		case "image_url":
+			img, err := parseImageURL(r.ImageURL.URL)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, ContentPart{Kind: ContentImage, Image: img})
+		default:
+			return nil, fmt.Errorf("unsupported content part type %q", r.Type)
+		}
+	}
+	return parts, nil
+}
+
+// parseImageURL decodes a data URL into image bytes, or keeps any other
+// URL as is.
+func parseImageURL(u string) (*ImageData, error) {
+	rest, ok := strings.CutPrefix(u, "data:")
+	if !ok {
+		return &ImageData{URL: u}, nil
+	}
+	meta, payload, ok := strings.Cut(rest, ",")
+	mediaType, isBase64 := strings.CutSuffix(meta, ";base64")
+	if !ok || !isBase64 {
+		return nil, fmt.Errorf("image data URL is not base64")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("image data URL: %w", err)
+	}
+	return &ImageData{Data: data, MediaType: mediaType}, nil
+}
+
+func parseOpenAIToolChoice(raw json.RawMessage) (*ToolChoice, error) {
+	var mode string
+	if json.Unmarshal(raw, &mode) == nil {
+		switch ToolChoiceMode(mode) {
+		case ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+			return &ToolChoice{Mode: ToolChoiceMode(mode)}, nil
+		}
+		return nil, fmt.Errorf("unknown mode %q", mode)
+	}
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &named); err != nil {
+		return nil, err
+	}
+	return &ToolChoice{Mode: ToolChoiceNamed, ToolName: named.Function.Name}, nil
+}
+
+// textOf concatenates the text parts of parts.
+func textOf(parts []ContentPart) string {
+	return Message{Content: parts}.Text()
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalOpenAIChat(t *testing.T) {
+	data := `{
+		"model": "gpt-4o",
+		"max_completion_tokens": 256,
+		"temperature": 0.2,
+		"presence_penalty": 0.6,
+		"reasoning_effort": "high",
+		"response_format": {"type": "json_schema", "json_schema": {"name": "weather", "schema": {"type": "object"}, "strict": true}},
+		"stop": "END",
+		"tool_choice": {"type": "function", "function": {"name": "get_weather"}},
+		"tools": [{"type": "function", "function": {"name": "get_weather", "description": "Weather", "parameters": {"type": "object"}}}],
+		"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "developer", "content": [{"type": "text", "text": "no emoji"}]},
+			{"role": "user", "content": [
+				{"type": "text", "text": "Weather here?"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw=="}},
+				{"type": "image_url", "image_url": {"url": "https://example.com/a.jpg"}}
+			]},
+			{"role": "assistant", "content": null, "reasoning_content": "need weather", "tool_calls": [
+				{"id": "c1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"location\":\"Paris\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "c1", "content": "sunny"},
+			{"role": "assistant", "content": "It's sunny."}
+		]
+	}`
+	conv, err := UnmarshalOpenAIChat([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.Model != "gpt-4o" || *conv.Config.MaxTokens != 256 || *conv.Config.Temperature != 0.2 ||
+		*conv.Config.PresencePenalty != 0.6 || conv.Config.FrequencyPenalty != nil || conv.Config.ResponseFormat.Type != ResponseFormatJSONSchema ||
+		conv.Config.ResponseFormat.Name != "weather" || string(conv.Config.ResponseFormat.Schema) != `{"type": "object"}` || !conv.Config.ResponseFormat.Strict ||
+		conv.Config.ReasoningEffort != ReasoningEffortHigh {
+		t.Errorf("config = %+v", conv.Config)
+	}
+	if len(conv.Config.StopSequences) != 1 || *conv.Config.ToolChoice != (ToolChoice{Mode: ToolChoiceNamed, ToolName: "get_weather"}) {
+		t.Errorf("stop = %v, tool choice = %+v", conv.Config.StopSequences, conv.Config.ToolChoice)
+	}
+	if len(conv.Tools) != 1 || string(conv.Tools[0].Parameters) != `{"type": "object"}` {
+		t.Errorf("tools = %+v", conv.Tools)
+	}
+	if strings.Join(conv.System, "|") != "be brief|no emoji" {
+		t.Errorf("system = %q", conv.System)
+	}
+	if len(conv.Messages) != 4 {
+		t.Fatalf("messages = %+v", conv.Messages)
+	}
+	user := conv.Messages[0].Content
+	if user[0].Text != "Weather here?" || user[1].Image.MediaType != "image/png" || len(user[1].Image.Data) != 4 || user[2].Image.URL != "https://example.com/a.jpg" {
+		t.Errorf("user content = %+v", user)
+	}
+	call := conv.Messages[1]
+	if call.Content[0].Thinking.Text != "need weather" || call.ToolCalls()[0].Name != "get_weather" {
+		t.Errorf("assistant = %+v", call)
+	}
+	if tr := conv.Messages[2].Content[0].ToolResult; tr.ToolCallID != "c1" || tr.Content != "sunny" {
+		t.Errorf("tool result = %+v", tr)
+	}
+	if err := conv.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestUnmarshalOpenAIChat_Errors(t *testing.T) {
+	for _, data := range []string{
+		`not json`,
+		`{"messages":[{"role":"narrator","content":"x"}]}`,
+		`{"messages":[{"role":"user","content":[{"type":"input_audio"}]}]}`,
+		`{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png,abc"}}]}]}`,
+		`{"tool_choice":"sometimes","messages":[]}`,
+	} {
+		if _, err := UnmarshalOpenAIChat([]byte(data)); err == nil {
+			t.Errorf("UnmarshalOpenAIChat(%s) succeeded", data)
+		}
+	}
+}
+
+func TestOpenAIChat_RoundTrip(t *testing.T) {
+	call := ToolCallData{ID: "c1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}
+	conv := NewConversation("gpt-4o", WithSystem("be brief"), WithMaxTokens(100), WithToolChoice(ToolChoice{Mode: ToolChoiceAuto}),
+		WithTools(NewTool("get_weather", "Weather", StringParam("location"))))
+	conv.Messages = []Message{UserMessage("Weather?"), toolUseResponse(call).Message, call.Result("sunny"), AssistantMessage("Sunny.")}
+
+	data, err := MarshalOpenAIChat(conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalOpenAIChat(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(conv)
+	gotJSON, _ := json.Marshal(got)
+	testAssertJSONEqual(t, gotJSON, want)
+}
+
+func TestWriteOpenAIJSONL(t *testing.T) {
+	a := NewConversation("m", WithSystem("be brief"))
+	a.Messages = []Message{UserMessage("hi"), AssistantMessage("hello")}
+	b := NewConversation("m")
+	b.Messages = []Message{UserMessage("bye"), AssistantMessage("bye")}
+
+	var buf bytes.Buffer
+	if err := WriteOpenAIJSONL(&buf, a, b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	testAssertJSONEqual(t, []byte(lines[0]), []byte(`{"messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]}`))
+}
@@ -0,0 +1,393 @@
+// Package openapi turns the operations of an OpenAPI 3 document into
+// llm.Tools whose handlers call the API over HTTP, so an existing REST API
+// can be offered to a model without writing a tool per endpoint.
+//
+// Each operation becomes one tool named after its operationId. Path, query,
+// and header parameters become tool parameters of the same name, and a JSON
+// request body becomes a "body" parameter. A query or header parameter
+// whose name is already taken is prefixed with its location, as in
+// "query_id". The document must be JSON; convert YAML specs before loading
+// them.
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+// Option configures Tools.
+type Option func(*config)
+
+type config struct {
+	baseURL    string
+	client     *http.Client
+	headers    http.Header
+	authorize  func(ctx context.Context, req *http.Request) error
+	operations []string
+	maxBody    int64
+}
+
+// WithBaseURL sets the URL operation paths are relative to. The default is
+// the first entry in the document's servers list.
+func WithBaseURL(u string) Option {
+	return func(c *config) {
+		c.baseURL = u
+	}
+}
+
+// WithHTTPClient sets the client used to call the API. The default is
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithHeader adds a header to every request, such as a static API key.
+// Headers set here are never exposed to the model.
+func WithHeader(key, value string) Option {
+	return func(c *config) {
+		c.headers.Add(key, value)
+	}
+}
+
+// WithAuthorizer sets a function that decorates every request before it is
+// sent, for credentials that vary by caller or expire, such as a bearer
+// token read from ctx. An error fails the tool call.
+func WithAuthorizer(fn func(ctx context.Context, req *http.Request) error) Option {
+	return func(c *config) {
+		c.authorize = fn
+	}
+}
+
+// WithOperations limits the generated tools to the operations with the
+// given operationIds.
+func WithOperations(ids ...string) Option {
+	return func(c *config) {
+		c.operations = ids
+	}
+}
+
+// WithMaxResponseBytes caps how much of a response body is returned to the
+// model. The default is 1 MiB.
+func WithMaxResponseBytes(n int64) Option {
+	return func(c *config) {
+		c.maxBody = n
+	}
+}
+
+// Tools returns a tool for each operation in the OpenAPI 3 document spec,
+// in path order.
+func Tools(spec []byte, opts ...Option) ([]llm.Tool, error) {
+	cfg := config{client: http.DefaultClient, headers: make(http.Header), maxBody: 1 << 20}
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: parsing spec: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("openapi: unsupported version %q, want 3.x", doc.OpenAPI)
+	}
+	if cfg.baseURL == "" {
+		if len(doc.Servers) == 0 {
+			return nil, fmt.Errorf("openapi: spec has no servers; use WithBaseURL")
+		}
+		cfg.baseURL = doc.Servers[0].URL
+	}
+	cfg.baseURL = strings.TrimSuffix(cfg.baseURL, "/")
+
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+
+	var tools []llm.Tool
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		item := doc.Paths[path]
+		for _, m := range item.operations() {
+			if len(cfg.operations) > 0 && !slices.Contains(cfg.operations, m.op.OperationID) {
+				continue
+			}
+			tool, err := doc.tool(&cfg, m.method, path, item.Parameters, m.op)
+			if err != nil {
+				return nil, fmt.Errorf("openapi: %s %s: %w", m.method, path, err)
+			}
+			if seen[tool.Definition.Name] {
+				return nil, fmt.Errorf("openapi: %s %s: duplicate tool name %q", m.method, path, tool.Definition.Name)
+			}
+			seen[tool.Definition.Name] = true
+			tools = append(tools, tool)
+		}
+	}
+	return tools, nil
+}
+
+// argument maps a tool parameter to where it goes in the HTTP request.
+type argument struct {
+	param string // tool parameter name
+	name  string // name in the request
+	in    string // "path", "query", "header", or "body"
+}
+
+func (d *document) tool(cfg *config, method, path string, shared []*parameter, op *operation) (llm.Tool, error) {
+	name := toolName(op.OperationID, method, path)
+	description := op.Summary
+	if description == "" {
+		description = op.Description
+	} else if op.Description != "" {
+		description += "\n\n" + op.Description
+	}
+
+	// Operation parameters override path-level ones with the same name
+	// and location.
+	var params []*parameter
+	for _, p := range append(slices.Clone(shared), op.Parameters...) {
+		p, err := d.resolveParameter(p)
+		if err != nil {
+			return llm.Tool{}, err
+		}
+		i := slices.IndexFunc(params, func(q *parameter) bool { return q.Name == p.Name && q.In == p.In })
+		if i >= 0 {
+			params[i] = p
+		} else {
+			params = append(params, p)
+		}
+	}
+
+	// Path parameters and the body keep their names; a query or header
+	// parameter whose name is taken is prefixed with its location.
+	taken := make(map[string]bool)
+	for _, p := range params {
+		if p.In == "path" {
+			taken[p.Name] = true
+		}
+	}
+	if op.RequestBody != nil {
+		if taken["body"] {
+			return llm.Tool{}, errors.New(`path parameter "body" collides with the request body`)
+		}
+		taken["body"] = true
+	}
+	var (
+		toolParams []llm.Param
+		args       []argument
+	)
+	for _, p := range params {
+		if p.In == "cookie" {
+			continue
+		}
+		name := p.Name
+		if p.In != "path" {
+			if taken[name] {
+				name = p.In + "_" + name
+			}
+			if taken[name] {
+				return llm.Tool{}, fmt.Errorf("%s parameter %q collides with another parameter", p.In, p.Name)
+			}
+			taken[name] = true
+		}
+		tp := d.param(name, p.Schema, p.Required || p.In == "path", map[string]bool{})
+		if p.Description != "" {
+			tp.Description = p.Description
+		}
+		if name != p.Name && tp.Description == "" {
+			tp.Description = fmt.Sprintf("The %q %s parameter.", p.Name, p.In)
+		}
+		toolParams = append(toolParams, tp)
+		args = append(args, argument{param: name, name: p.Name, in: p.In})
+	}
+
+	if op.RequestBody != nil {
+		body, err := d.resolveRequestBody(op.RequestBody)
+		if err != nil {
+			return llm.Tool{}, err
+		}
+		if media, ok := body.Content["application/json"]; ok {
+			tp := d.param("body", media.Schema, body.Required, map[string]bool{})
+			if body.Description != "" {
+				tp.Description = body.Description
+			}
+			toolParams = append(toolParams, tp)
+			args = append(args, argument{param: "body", name: "body", in: "body"})
+		}
+	}
+
+	def := llm.NewTool(name, description, toolParams...)
+	return llm.Tool{Definition: def, Handler: cfg.handler(method, path, args)}, nil
+}
+
+// placeholder matches a path template variable.
+var placeholder = regexp.MustCompile(`\{[^{}]+\}`)
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// toolName returns a name that satisfies every provider's tool naming
+// rules: letters, digits, underscores, and hyphens, at most 64 long.
+func toolName(operationID, method, path string) string {
+	sanitize := func(s string) string {
+		return strings.Trim(invalidNameChars.ReplaceAllString(s, "_"), "_")
+	}
+	name := sanitize(operationID)
+	if name == "" {
+		name = strings.ToLower(method) + "_" + sanitize(path)
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// param converts a JSON Schema into an llm.Param. seen holds the component
+// schemas being expanded; a recursive reference becomes an unchecked
+// object.
+func (d *document) param(name string, s *schema, required bool, seen map[string]bool) llm.Param {
+	p := llm.Param{Name: name, Required: required}
+	if s == nil {
+		return p
+	}
+	if s.Ref != "" {
+		if seen[s.Ref] {
+			p.Type = "object"
+			return p
+		}
+		resolved := d.schemaRef(s.Ref)
+		if resolved == nil {
+			return p
+		}
+		seen[s.Ref] = true
+		defer delete(seen, s.Ref)
+		s = resolved
+	}
+
+	p.Type = s.typeName()
+	p.Description = s.Description
+	for _, v := range s.Enum {
+		if str, ok := v.(string); ok {
+			p.Enum = append(p.Enum, str)
+		}
+	}
+	switch p.Type {
+	case "array":
+		items := d.param("", s.Items, false, seen)
+		p.Items = &items
+	case "object":
+		names := make([]string, 0, len(s.Properties))
+		for n := range s.Properties {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		for _, n := range names {
+			p.Properties = append(p.Properties, d.param(n, s.Properties[n], slices.Contains(s.Required, n), seen))
+		}
+	}
+	return p
+}
+
+func (cfg *config) handler(method, path string, args []argument) llm.ToolHandler {
+	return func(ctx context.Context, toolArgs llm.ToolCallArgs) (string, error) {
+		target := path
+		query := url.Values{}
+		header := make(http.Header)
+		var body io.Reader
+		for _, a := range args {
+			v, ok := toolArgs[a.param]
+			if !ok {
+				continue
+			}
+			switch a.in {
+			case "path":
+				value := formatValue(v)
+				if value == "" {
+					return "", fmt.Errorf("path parameter %q is empty", a.param)
+				}
+				target = strings.ReplaceAll(target, "{"+a.name+"}", url.PathEscape(value))
+			case "query":
+				if list, ok := v.([]any); ok {
+					for _, item := range list {
+						query.Add(a.name, formatValue(item))
+					}
+				} else {
+					query.Set(a.name, formatValue(v))
+				}
+			case "header":
+				header.Set(a.name, formatValue(v))
+			case "body":
+				data, err := json.Marshal(v)
+				if err != nil {
+					return "", fmt.Errorf("encoding body: %w", err)
+				}
+				body = bytes.NewReader(data)
+				header.Set("Content-Type", "application/json")
+			}
+		}
+
+		if m := placeholder.FindString(target); m != "" {
+			return "", fmt.Errorf("missing path parameter %s", m)
+		}
+		u := cfg.baseURL + target
+		if len(query) > 0 {
+			u += "?" + query.Encode()
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u, body)
+		if err != nil {
+			return "", err
+		}
+		for k, vs := range header {
+			req.Header[k] = vs
+		}
+		for k, vs := range cfg.headers {
+			req.Header[k] = slices.Clone(vs)
+		}
+		req.Header.Set("Accept", "application/json")
+		if cfg.authorize != nil {
+			if err := cfg.authorize(ctx, req); err != nil {
+				return "", fmt.Errorf("authorizing request: %w", err)
+			}
+		}
+
+		resp, err := cfg.client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(io.LimitReader(resp.Body, cfg.maxBody))
+		if err != nil {
+			return "", fmt.Errorf("reading response: %w", err)
+		}
+		if resp.StatusCode >= 400 {
+			return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+		return string(data), nil
+	}
+}
+
+// formatValue renders a scalar argument for a path, query, or header.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,276 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+const petstore = `{
+	"openapi": "3.0.3",
+	"servers": [{"url": "https://pets.example.com/v1"}],
+	"paths": {
+		"/pets": {
+			"get": {
+				"operationId": "listPets",
+				"summary": "List pets",
+				"parameters": [
+					{"name": "limit", "in": "query", "schema": {"type": "integer"}},
+					{"name": "tags", "in": "query", "schema": {"type": "array", "items": {"type": "string"}}}
+				]
+			},
+			"post": {
+				"operationId": "createPet",
+				"summary": "Create a pet",
+				"requestBody": {
+					"required": true,
+					"content": {"application/json": {"schema": {"$ref": "#/components/schemas/NewPet"}}}
+				}
+			}
+		},
+		"/pets/{petId}": {
+			"parameters": [{"$ref": "#/components/parameters/PetId"}],
+			"get": {"operationId": "getPet", "description": "Get a pet by ID"},
+			"delete": {"summary": "Delete a pet"}
+		}
+	},
+	"components": {
+		"parameters": {
+			"PetId": {"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}
+		},
+		"schemas": {
+			"NewPet": {
+				"type": "object",
+				"required": ["name"],
+				"properties": {
+					"name": {"type": "string"},
+					"kind": {"type": "string", "enum": ["cat", "dog"]},
+					"owner": {"$ref": "#/components/schemas/Owner"}
+				}
+			},
+			"Owner": {
+				"type": "object",
+				"properties": {"name": {"type": "string"}, "pets": {"type": "array", "items": {"$ref": "#/components/schemas/NewPet"}}}
+			}
+		}
+	}
+}`
+
+func toolsByName(t *testing.T, tools []llm.Tool) map[string]llm.Tool {
+	t.Helper()
+	byName := make(map[string]llm.Tool)
+	for _, tool := range tools {
+		byName[tool.Definition.Name] = tool
+	}
+	return byName
+}
+
+func TestTools_Definitions(t *testing.T) {
+	tools, err := Tools([]byte(petstore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Definition.Name)
+	}
+	if got := strings.Join(names, ","); got != "listPets,createPet,getPet,delete_pets_petId" {
+		t.Errorf("names = %s", got)
+	}
+
+	byName := toolsByName(t, tools)
+	if d := byName["getPet"].Definition; d.Description != "Get a pet by ID" {
+		t.Errorf("description = %q", d.Description)
+	}
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Required   []string                   `json:"required"`
+	}
+	if err := json.Unmarshal(byName["createPet"].Definition.Parameters, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.Required) != 1 || schema.Required[0] != "body" {
+		t.Errorf("required = %v", schema.Required)
+	}
+	body := string(schema.Properties["body"])
+	if !strings.Contains(body, `"enum":["cat","dog"]`) || !strings.Contains(body, `"owner"`) {
+		t.Errorf("body schema = %s", body)
+	}
+}
+
+func TestTools_Handlers(t *testing.T) {
+	var got *http.Request
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		data, _ := io.ReadAll(r.Body)
+		gotBody = string(data)
+		if r.URL.Path == "/v1/pets/missing" {
+			http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	tools, err := Tools([]byte(petstore),
+		WithBaseURL(srv.URL+"/v1/"),
+		WithHeader("X-Api-Key", "secret"),
+		WithAuthorizer(func(ctx context.Context, req *http.Request) error {
+			req.Header.Set("Authorization", "Bearer token")
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := llm.NewToolRegistry().Add(tools...)
+	execute := func(name, args string) llm.ToolResultData {
+		msg := reg.Execute(context.Background(), llm.ToolCallData{ID: "1", Name: name, Arguments: json.RawMessage(args)})
+		return *msg.Content[0].ToolResult
+	}
+
+	res := execute("listPets", `{"limit":10,"tags":["a","b"]}`)
+	if res.IsError || res.Content != `{"ok":true}` {
+		t.Errorf("listPets = %+v", res)
+	}
+	if got.Method != "GET" || got.URL.Path != "/v1/pets" || got.URL.RawQuery != "limit=10&tags=a&tags=b" {
+		t.Errorf("request = %s %s", got.Method, got.URL)
+	}
+	if got.Header.Get("X-Api-Key") != "secret" || got.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("headers = %v", got.Header)
+	}
+
+	execute("createPet", `{"body":{"name":"Rex","kind":"dog"}}`)
+	if got.Method != "POST" || got.Header.Get("Content-Type") != "application/json" || gotBody != `{"kind":"dog","name":"Rex"}` {
+		t.Errorf("request = %s %s %s", got.Method, got.Header.Get("Content-Type"), gotBody)
+	}
+
+	execute("getPet", `{"petId":"a b"}`)
+	if got.URL.EscapedPath() != "/v1/pets/a%20b" {
+		t.Errorf("path = %s", got.URL.EscapedPath())
+	}
+
+	res = execute("getPet", `{"petId":"missing"}`)
+	if !res.IsError || res.Content != `HTTP 404: {"error":"not found"}` {
+		t.Errorf("error result = %+v", res)
+	}
+
+	res = execute("createPet", `{"body":{"kind":"dog"}}`)
+	if !res.IsError || !strings.Contains(res.Content, `"body.name"`) {
+		t.Errorf("validation result = %+v", res)
+	}
+}
+
+func TestTools_Options(t *testing.T) {
+	tools, err := Tools([]byte(petstore), WithOperations("getPet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 1 || tools[0].Definition.Name != "getPet" {
+		t.Errorf("tools = %+v", tools)
+	}
+
+	for _, spec := range []string{
+		`not json`,
+		`{"swagger":"2.0"}`,
+		`{"openapi":"3.0.0","paths":{}}`,
+		`{"openapi":"3.0.0","servers":[{"url":"x"}],"paths":{"/a":{"get":{"parameters":[{"$ref":"#/components/parameters/Nope"}]}}}}`,
+	} {
+		if _, err := Tools([]byte(spec)); err == nil {
+			t.Errorf("Tools(%s) succeeded", spec)
+		}
+	}
+}
+
+func TestTools_UntypedSchema(t *testing.T) {
+	spec := `{"openapi":"3.1.0","servers":[{"url":"x"}],"paths":{"/a":{"post":{"operationId":"a",
+		"parameters":[{"name":"q","in":"query","schema":{"type":["string","null"]}}],
+		"requestBody":{"content":{"application/json":{"schema":{}}}}}}}}`
+	tools, err := Tools([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want any
+	json.Unmarshal(tools[0].Definition.Parameters, &got)
+	json.Unmarshal([]byte(`{"type":"object","properties":{"q":{"type":"string"},"body":{}},"required":[]}`), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("schema = %s", tools[0].Definition.Parameters)
+	}
+}
+
+func TestTools_ParameterNameCollisions(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	spec := `{"openapi":"3.0.0","paths":{"/items/{id}":{"post":{"operationId":"update",
+		"parameters":[
+			{"name":"id","in":"path","required":true,"schema":{"type":"string"}},
+			{"name":"id","in":"query","schema":{"type":"string"}},
+			{"name":"body","in":"header","schema":{"type":"string"}}
+		],
+		"requestBody":{"content":{"application/json":{"schema":{"type":"object"}}}}}}}}`
+	tools, err := Tools([]byte(spec), WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+	}
+	json.Unmarshal(tools[0].Definition.Parameters, &schema)
+	for _, name := range []string{"id", "query_id", "header_body", "body"} {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("no %q parameter in %s", name, tools[0].Definition.Parameters)
+		}
+	}
+
+	reg := llm.NewToolRegistry().Add(tools...)
+	args := `{"id":"7","query_id":"q","header_body":"h","body":{"a":1}}`
+	res := reg.Execute(context.Background(), llm.ToolCallData{ID: "1", Name: "update", Arguments: json.RawMessage(args)})
+	if r := res.Content[0].ToolResult; r.IsError {
+		t.Fatalf("result = %+v", r)
+	}
+	if got.URL.Path != "/items/7" || got.URL.RawQuery != "id=q" || got.Header.Get("body") != "h" {
+		t.Errorf("request = %s, headers %v", got.URL, got.Header)
+	}
+
+	for _, spec := range []string{
+		// A path parameter can't be renamed away from the body.
+		`{"openapi":"3.0.0","servers":[{"url":"x"}],"paths":{"/a/{body}":{"post":{
+			"parameters":[{"name":"body","in":"path","required":true,"schema":{"type":"string"}}],
+			"requestBody":{"content":{"application/json":{"schema":{}}}}}}}}`,
+		// The prefixed name is taken too.
+		`{"openapi":"3.0.0","servers":[{"url":"x"}],"paths":{"/a/{id}":{"get":{
+			"parameters":[{"name":"id","in":"path","required":true,"schema":{"type":"string"}},
+				{"name":"query_id","in":"query","schema":{"type":"string"}},
+				{"name":"id","in":"query","schema":{"type":"string"}}]}}}}`,
+	} {
+		if _, err := Tools([]byte(spec)); err == nil {
+			t.Errorf("Tools(%s) accepted colliding parameters", spec)
+		}
+	}
+}
+
+func TestTools_UnresolvedPathParameter(t *testing.T) {
+	tools, err := Tools([]byte(petstore), WithOperations("getPet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := tools[0].Handler
+	for _, args := range []llm.ToolCallArgs{{}, {"petId": ""}} {
+		if _, err := handler(context.Background(), args); err == nil {
+			t.Errorf("handler(%v) succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// document is the subset of an OpenAPI 3 document needed to build tools.
+type document struct {
+	OpenAPI string `json:"openapi"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths      map[string]*pathItem `json:"paths"`
+	Components struct {
+		Schemas       map[string]*schema      `json:"schemas"`
+		Parameters    map[string]*parameter   `json:"parameters"`
+		RequestBodies map[string]*requestBody `json:"requestBodies"`
+	} `json:"components"`
+}
+
+type pathItem struct {
+	Parameters []*parameter `json:"parameters"`
+	Get        *operation   `json:"get"`
+	Put        *operation   `json:"put"`
+	Post       *operation   `json:"post"`
+	Delete     *operation   `json:"delete"`
+	Patch      *operation   `json:"patch"`
+}
+
+type methodOperation struct {
+	method string
+	op     *operation
+}
+
+// operations returns the item's operations in a fixed method order.
+func (p *pathItem) operations() []methodOperation {
+	var ops []methodOperation
+	for _, m := range []methodOperation{
+		{"GET", p.Get}, {"POST", p.Post}, {"PUT", p.Put}, {"PATCH", p.Patch}, {"DELETE", p.Delete},
+	} {
+		if m.op != nil {
+			ops = append(ops, m)
+		}
+	}
+	return ops
+}
+
+type operation struct {
+	OperationID string       `json:"operationId"`
+	Summary     string       `json:"summary"`
+	Description string       `json:"description"`
+	Parameters  []*parameter `json:"parameters"`
+	RequestBody *requestBody `json:"requestBody"`
+}
+
+type parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Ref         string `json:"$ref"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+	Content     map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"content"`
+}
+
+type schema struct {
+	Ref         string             `json:"$ref"`
+	Type        json.RawMessage    `json:"type"` // a string, or a list of strings in OpenAPI 3.1
+	Description string             `json:"description"`
+	Enum        []any              `json:"enum"`
+	Items       *schema            `json:"items"`
+	Properties  map[string]*schema `json:"properties"`
+	Required    []string           `json:"required"`
+}
+
+// typeName returns the schema's JSON type, ignoring "null" in 3.1 type
+// lists and inferring object and array from their keywords.
+func (s *schema) typeName() string {
+	var name string
+	if json.Unmarshal(s.Type, &name) != nil {
+		var names []string
+		_ = json.Unmarshal(s.Type, &names)
+		for _, n := range names {
+			if n != "null" {
+				name = n
+				break
+			}
+		}
+	}
+	switch {
+	case name != "":
+		return name
+	case s.Properties != nil:
+		return "object"
+	case s.Items != nil:
+		return "array"
+	}
+	return ""
+}
+
+// schemaRef returns the component schema ref points to, or nil.
+func (d *document) schemaRef(ref string) *schema {
+	name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+	if !ok {
+		return nil
+	}
+	return d.Components.Schemas[name]
+}
+
+func (d *document) resolveParameter(p *parameter) (*parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+	if resolved := d.Components.Parameters[name]; ok && resolved != nil {
+		return resolved, nil
+	}
+	return nil, fmt.Errorf("unresolved reference %q", p.Ref)
+}
+
+func (d *document) resolveRequestBody(b *requestBody) (*requestBody, error) {
+	if b.Ref == "" {
+		return b, nil
+	}
+	name, ok := strings.CutPrefix(b.Ref, "#/components/requestBodies/")
+	if resolved := d.Components.RequestBodies[name]; ok && resolved != nil {
+		return resolved, nil
+	}
+	return nil, fmt.Errorf("unresolved reference %q", b.Ref)
+}
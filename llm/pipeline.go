@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"context"
+	"errors"
+)
+
+// Step is one typed stage of a pipeline. It returns the tokens it consumed
+// alongside its output; usage is reported even when the step fails part way.
+type Step[In, Out any] func(ctx context.Context, in In) (Out, Usage, error)
+
+// PromptStep creates a Step that renders its input into a message, sends it
+// on a copy of conv, and parses the model's reply into the output type.
+// Every invocation starts from conv, so steps do not share history.
+func PromptStep[In, Out any](client *Client, conv Conversation, render func(In) Message, parse func(*Response) (Out, error)) Step[In, Out] {
+	return func(ctx context.Context, in In) (Out, Usage, error) {
+		var zero Out
+		_, resp, err := client.Send(ctx, conv, render(in))
+		if err != nil {
+			// A reply rejected by the response schema was still paid for.
+			var formatErr *ResponseFormatError
+			if errors.As(err, &formatErr) {
+				return zero, formatErr.Usage, err
+			}
+			return zero, Usage{}, err
+		}
+		out, err := parse(resp)
+		if err != nil {
+			return zero, resp.Usage, err
+		}
+		return out, resp.Usage, nil
+	}
+}
+
+// TypedStep creates a Step that renders its input into a message and sends
+// it on a copy of conv with CompleteAs, so the reply is held to a schema
+// derived from Out instead of parsed by hand. Every invocation starts from
+// conv, so steps do not share history.
+func TypedStep[In, Out any](client *Client, conv Conversation, render func(In) Message, opts ...CompleteOption) Step[In, Out] {
+	conv.Usage = Usage{} // so the returned conversation's usage is the step's own
+	return func(ctx context.Context, in In) (Out, Usage, error) {
+		out, next, err := CompleteAs[Out](ctx, client, conv, render(in), opts...)
+		return out, next.Usage, err
+	}
+}
+
+// TextOutput is a PromptStep parser that returns the reply text.
+func TextOutput(resp *Response) (string, error) {
+	return resp.Message.Text(), nil
+}
+
+// FuncStep lifts a plain function into a Step that consumes no tokens.
+func FuncStep[In, Out any](fn func(ctx context.Context, in In) (Out, error)) Step[In, Out] {
+	return func(ctx context.Context, in In) (Out, Usage, error) {
+		out, err := fn(ctx, in)
+		return out, Usage{}, err
+	}
+}
+
+// Then composes two steps sequentially, feeding the output of first into
+// second and summing their usage.
+func Then[A, B, C any](first Step[A, B], second Step[B, C]) Step[A, C] {
+	return func(ctx context.Context, in A) (C, Usage, error) {
+		var zero C
+		mid, usage, err := first(ctx, in)
+		if err != nil {
+			return zero, usage, err
+		}
+		out, u, err := second(ctx, mid)
+		return out, usage.Add(u), err
+	}
+}
+
+// Branch routes each input to ifTrue or ifFalse depending on cond.
+func Branch[In, Out any](cond func(In) bool, ifTrue, ifFalse Step[In, Out]) Step[In, Out] {
+	return func(ctx context.Context, in In) (Out, Usage, error) {
+		if cond(in) {
+			return ifTrue(ctx, in)
+		}
+		return ifFalse(ctx, in)
+	}
+}
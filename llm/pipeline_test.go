@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// echoProvider replies with the text of the last message, upper-cased.
+type echoProvider struct{}
+
+func (echoProvider) Send(_ context.Context, conv *Conversation) (*Response, error) {
+	last := conv.Messages[len(conv.Messages)-1]
+	return simpleResponse(strings.ToUpper(last.Text())), nil
+}
+
+func TestPipeline_Then(t *testing.T) {
+	client := NewClientWithProvider(echoProvider{})
+	conv := NewConversation("model")
+
+	extract := PromptStep(client, conv, func(in string) Message { return UserMessage("extract " + in) }, TextOutput)
+	count := FuncStep(func(_ context.Context, s string) (int, error) { return len(s), nil })
+	draft := PromptStep(client, conv, func(n int) Message { return UserMessage(strings.Repeat("x", n)) }, TextOutput)
+
+	pipeline := Then(Then(extract, count), draft)
+	out, usage, err := pipeline(context.Background(), "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != strings.Repeat("X", len("EXTRACT ABC")) {
+		t.Errorf("out = %q", out)
+	}
+	// Two model calls at 10 input / 5 output tokens each.
+	if usage.InputTokens != 20 || usage.OutputTokens != 10 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestPipeline_Branch(t *testing.T) {
+	short := FuncStep(func(_ context.Context, s string) (string, error) { return "short", nil })
+	long := FuncStep(func(_ context.Context, s string) (string, error) { return "long", nil })
+	step := Branch(func(s string) bool { return len(s) < 5 }, short, long)
+
+	for in, want := range map[string]string{"hi": "short", "hello world": "long"} {
+		got, _, err := step(context.Background(), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("step(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPipeline_ErrorStopsAndKeepsUsage(t *testing.T) {
+	client := NewClientWithProvider(echoProvider{})
+	conv := NewConversation("model")
+	parseErr := errors.New("unparseable")
+
+	first := PromptStep(client, conv, UserMessage, TextOutput)
+	second := PromptStep(client, conv, UserMessage, func(*Response) (string, error) { return "", parseErr })
+	called := false
+	third := FuncStep(func(_ context.Context, s string) (string, error) { called = true; return s, nil })
+
+	_, usage, err := Then(Then(first, second), third)(context.Background(), "go")
+	if !errors.Is(err, parseErr) {
+		t.Fatalf("err = %v, want %v", err, parseErr)
+	}
+	if called {
+		t.Error("third step should not run after a failure")
+	}
+	if usage.InputTokens != 20 {
+		t.Errorf("InputTokens = %d, want 20 (both model calls)", usage.InputTokens)
+	}
+}
+
+func TestPipeline_TypedStep(t *testing.T) {
+	provider := &scriptedProvider{responses: []*Response{simpleResponse(`{"city":"Paris","population":2100000}`)}}
+	client := NewClientWithProvider(provider)
+	conv := NewConversation("model")
+	conv.Usage = Usage{InputTokens: 1000}
+
+	extract := TypedStep[string, extractedCity](client, conv, UserMessage)
+	describe := FuncStep(func(_ context.Context, c extractedCity) (string, error) { return c.City, nil })
+	out, usage, err := Then(extract, describe)(context.Background(), "Paris has 2.1M people")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "Paris" {
+		t.Errorf("out = %q", out)
+	}
+	if usage.InputTokens != 10 || usage.OutputTokens != 5 {
+		t.Errorf("usage = %+v, want only the step's own", usage)
+	}
+	if rf := provider.received[0].Config.ResponseFormat; rf == nil || rf.Type != ResponseFormatJSONSchema {
+		t.Errorf("ResponseFormat = %+v, want a JSON schema", rf)
+	}
+}
+
+func TestPipeline_FailedStepsKeepUsage(t *testing.T) {
+	ctx := context.Background()
+	provider := &scriptedProvider{responses: []*Response{
+		simpleResponse(`{"town":"Paris"}`), simpleResponse(`{"town":"Paris"}`),
+	}}
+	typed := TypedStep[string, extractedCity](NewClientWithProvider(provider), NewConversation("model"), UserMessage, WithSchemaRetries(1))
+	if _, usage, err := typed(ctx, "Paris"); err == nil || usage.InputTokens != 20 {
+		t.Errorf("TypedStep = %+v, %v, want both rejected attempts' usage", usage, err)
+	}
+
+	provider = &scriptedProvider{responses: []*Response{simpleResponse(`{"town":"Paris"}`)}}
+	conv := NewConversation("model", WithResponseSchema("city", citySchema))
+	prompt := PromptStep(NewClientWithProvider(provider), conv, UserMessage, TextOutput)
+	if _, usage, err := prompt(ctx, "Paris"); err == nil || usage.InputTokens != 10 {
+		t.Errorf("PromptStep = %+v, %v, want the rejected reply's usage", usage, err)
+	}
+}
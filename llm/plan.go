@@ -0,0 +1,47 @@
+package llm
+
+// Plan is a pre-flight estimate for sending a conversation, so orchestration
+// layers can choose between models before committing to a call.
+type Plan struct {
+	Model           string  `json:"model"`
+	InputTokens     int     `json:"input_tokens"`      // estimated
+	MaxOutputTokens int     `json:"max_output_tokens"` // worst case
+	ContextWindow   int     `json:"context_window,omitempty"`
+	MinCost         float64 `json:"min_cost"` // USD, input only
+	MaxCost         float64 `json:"max_cost"` // USD, input plus worst-case output
+	NeedsTruncation bool    `json:"needs_truncation"`
+
+	// KnownModel reports whether the model was found via LookupModel. When
+	// false, costs are zero and NeedsTruncation is always false.
+	KnownModel bool `json:"known_model"`
+}
+
+// Plan estimates the cost and size of sending the conversation with the
+// given messages appended. Token counts are local approximations; no
+// network call is made. The worst-case output is Config.MaxTokens, or the
+// model's maximum output when unset.
+func (c Conversation) Plan(messages ...Message) Plan {
+	c.Messages = append(append([]Message(nil), c.Messages...), messages...)
+
+	p := Plan{
+		Model:       c.Model,
+		InputTokens: estimateInputTokens(&c),
+	}
+	if c.Config.MaxTokens != nil {
+		p.MaxOutputTokens = *c.Config.MaxTokens
+	}
+
+	info, ok := LookupModel(c.Model)
+	if !ok {
+		return p
+	}
+	p.KnownModel = true
+	p.ContextWindow = info.ContextWindow
+	if p.MaxOutputTokens == 0 || p.MaxOutputTokens > info.MaxOutputTokens {
+		p.MaxOutputTokens = info.MaxOutputTokens
+	}
+	p.MinCost = float64(p.InputTokens) * info.Pricing.Input / 1e6
+	p.MaxCost = p.MinCost + float64(p.MaxOutputTokens)*info.Pricing.Output/1e6
+	p.NeedsTruncation = p.InputTokens+p.MaxOutputTokens > info.ContextWindow
+	return p
+}
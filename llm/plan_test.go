@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestConversationPlan(t *testing.T) {
+	conv := NewConversation("us.anthropic.claude-haiku-4-5-20251001-v1:0", WithMaxTokens(1000))
+	// Claude's estimate: 3482 chars at 3.5 per token, plus 5 for the message.
+	msg := UserMessage(strings.Repeat("x", 3482))
+	p := conv.Plan(msg)
+
+	if !p.KnownModel {
+		t.Fatal("expected KnownModel")
+	}
+	if p.InputTokens != 1000 {
+		t.Errorf("InputTokens = %d, want 1000", p.InputTokens)
+	}
+	conv.Messages = []Message{msg}
+	if n := conv.EstimateTokens(""); p.InputTokens != n {
+		t.Errorf("InputTokens = %d, EstimateTokens = %d; they should agree", p.InputTokens, n)
+	}
+	conv.Messages = nil
+	if p.MaxOutputTokens != 1000 {
+		t.Errorf("MaxOutputTokens = %d, want 1000", p.MaxOutputTokens)
+	}
+	// $1/M input, $5/M output
+	if math.Abs(p.MinCost-0.001) > 1e-9 {
+		t.Errorf("MinCost = %v", p.MinCost)
+	}
+	if math.Abs(p.MaxCost-0.006) > 1e-9 {
+		t.Errorf("MaxCost = %v", p.MaxCost)
+	}
+	if p.NeedsTruncation {
+		t.Error("NeedsTruncation should be false")
+	}
+	if len(conv.Messages) != 0 {
+		t.Error("Plan must not mutate the conversation")
+	}
+}
+
+func TestConversationPlan_DefaultsToModelMaxOutput(t *testing.T) {
+	p := NewConversation("us.anthropic.claude-haiku-4-5-20251001-v1:0").Plan(UserMessage("hi"))
+	if p.MaxOutputTokens != 64_000 {
+		t.Errorf("MaxOutputTokens = %d, want 64000", p.MaxOutputTokens)
+	}
+}
+
+func TestConversationPlan_NeedsTruncation(t *testing.T) {
+	conv := NewConversation("us.amazon.nova-micro-v1:0", WithMaxTokens(4096))
+	p := conv.Plan(UserMessage(strings.Repeat("x", 4*126_000)))
+	if !p.NeedsTruncation {
+		t.Errorf("expected NeedsTruncation, plan = %+v", p)
+	}
+}
+
+func TestConversationPlan_UnknownModel(t *testing.T) {
+	p := NewConversation("llama3", WithMaxTokens(512)).Plan(UserMessage("hello"))
+	if p.KnownModel {
+		t.Error("expected unknown model")
+	}
+	if p.InputTokens == 0 {
+		t.Error("InputTokens should still be estimated")
+	}
+	if p.MaxOutputTokens != 512 || p.MinCost != 0 || p.MaxCost != 0 || p.NeedsTruncation {
+		t.Errorf("plan = %+v", p)
+	}
+}
@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"path"
+	"slices"
+)
+
+// ToolPolicy decides which tools a request may use. Allow and Deny hold
+// tool names or path.Match patterns such as "github_*". A tool is allowed
+// if it matches no Deny pattern and, when Allow is non-empty, at least one
+// Allow pattern.
+type ToolPolicy struct {
+	Allow []string
+	Deny  []string
+}
+
+// Allows reports whether p permits the named tool.
+func (p ToolPolicy) Allows(name string) bool {
+	if slices.ContainsFunc(p.Deny, matchesTool(name)) {
+		return false
+	}
+	return len(p.Allow) == 0 || slices.ContainsFunc(p.Allow, matchesTool(name))
+}
+
+func matchesTool(name string) func(pattern string) bool {
+	return func(pattern string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}
+}
+
+type toolPolicyKey struct{}
+
+// WithToolPolicy returns a context whose requests are restricted by p, for
+// example according to the caller's role or tenant. Policies accumulate:
+// a tool must be allowed by every policy on the context.
+//
+// ToolRegistry.Execute, and so Runner, answer calls to tools the context's
+// policies forbid with an error result. WithToolPolicyFilter also removes
+// forbidden tools from requests so the model never sees them.
+func WithToolPolicy(ctx context.Context, p ToolPolicy) context.Context {
+	return context.WithValue(ctx, toolPolicyKey{}, append(toolPolicies(ctx), p))
+}
+
+func toolPolicies(ctx context.Context) []ToolPolicy {
+	policies, _ := ctx.Value(toolPolicyKey{}).([]ToolPolicy)
+	return slices.Clip(policies)
+}
+
+// toolAllowed reports whether every policy on ctx permits the named tool.
+func toolAllowed(ctx context.Context, name string) bool {
+	for _, p := range toolPolicies(ctx) {
+		if !p.Allows(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// WithToolPolicyFilter adds a ToolPolicyFilter middleware to the client.
+func WithToolPolicyFilter() ClientOption {
+	return WithMiddleware(ToolPolicyFilter())
+}
+
+// ToolPolicyFilter returns middleware that removes the tools forbidden by
+// the context's policies from each request. A request whose tool choice
+// names a forbidden tool fails with ErrInvalidRequest.
+func ToolPolicyFilter() Middleware {
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		if len(toolPolicies(ctx)) == 0 {
+			return next(ctx, conv)
+		}
+		if tc := conv.Config.ToolChoice; tc != nil && tc.Mode == ToolChoiceNamed && !toolAllowed(ctx, tc.ToolName) {
+			return nil, &Error{Kind: ErrInvalidRequest, Message: "tool choice " + tc.ToolName + " is not allowed by policy"}
+		}
+		filtered := *conv
+		filtered.Tools = slices.DeleteFunc(slices.Clone(conv.Tools), func(td ToolDefinition) bool {
+			return !toolAllowed(ctx, td.Name)
+		})
+		return next(ctx, &filtered)
+	}
+}
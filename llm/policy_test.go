@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestToolPolicy_Allows(t *testing.T) {
+	p := ToolPolicy{Allow: []string{"github_*", "search"}, Deny: []string{"github_delete_*"}}
+	tests := map[string]bool{
+		"github_list_issues":  true,
+		"search":              true,
+		"github_delete_repo":  false,
+		"send_email":          false,
+		"github_delete_issue": false,
+	}
+	for name, want := range tests {
+		if got := p.Allows(name); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if !(ToolPolicy{}).Allows("anything") {
+		t.Error("zero policy should allow everything")
+	}
+}
+
+func TestToolPolicyFilter(t *testing.T) {
+	provider := &scriptedProvider{responses: []*Response{simpleResponse("ok"), simpleResponse("ok")}}
+	client := NewClientWithProvider(provider, WithToolPolicyFilter())
+	conv := NewConversation("model", WithTools(
+		NewTool("search", "Search"),
+		NewTool("send_email", "Send email"),
+		NewTool("delete_account", "Delete account"),
+	))
+
+	ctx := WithToolPolicy(context.Background(), ToolPolicy{Deny: []string{"delete_*"}})
+	ctx = WithToolPolicy(ctx, ToolPolicy{Allow: []string{"search", "delete_account"}})
+	out, _, err := client.Send(ctx, conv, UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := toolNames(provider.received[0].Tools); len(got) != 1 || got[0] != "search" {
+		t.Errorf("tools sent = %v, want [search]", got)
+	}
+	if len(out.Tools) != 3 {
+		t.Error("filter changed the returned conversation's tools")
+	}
+
+	// Without a policy, every tool is sent.
+	if _, _, err := client.Send(context.Background(), conv, UserMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.received[1].Tools) != 3 {
+		t.Errorf("tools sent without policy = %v", toolNames(provider.received[1].Tools))
+	}
+
+	conv.Config.ToolChoice = &ToolChoice{Mode: ToolChoiceNamed, ToolName: "send_email"}
+	_, _, err = client.Send(ctx, conv, UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrInvalidRequest {
+		t.Errorf("err = %v, want ErrInvalidRequest", err)
+	}
+}
+
+func TestToolRegistry_ExecuteRejectsForbiddenTool(t *testing.T) {
+	ctx := WithToolPolicy(context.Background(), ToolPolicy{Deny: []string{"get_weather"}})
+	res := toolResult(t, weatherRegistry().Execute(ctx, ToolCallData{ID: "1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}))
+	if !res.IsError || res.Content != `tool "get_weather" is not allowed` {
+		t.Errorf("result = %+v", res)
+	}
+}
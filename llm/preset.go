@@ -0,0 +1,32 @@
+package llm
+
+// Presets are ConversationOptions that set Temperature and MaxTokens
+// together. They leave TopP unset, since Claude Sonnet 4.5 and later accept
+// only one of the two. Options after a preset override single fields:
+//
+//	conv := llm.NewConversation(model, llm.Creative(), llm.WithMaxTokens(8192))
+
+// Deterministic suits extraction, classification, and other tasks with
+// one right answer: temperature 0 and 1024 max tokens.
+func Deterministic() ConversationOption {
+	return preset(0, 1024)
+}
+
+// Balanced suits general chat and question answering: temperature 0.7 and
+// 2048 max tokens.
+func Balanced() ConversationOption {
+	return preset(0.7, 2048)
+}
+
+// Creative suits brainstorming and fiction: temperature 1 and 4096 max
+// tokens.
+func Creative() ConversationOption {
+	return preset(1, 4096)
+}
+
+func preset(temperature float64, maxTokens int) ConversationOption {
+	return func(c *Conversation) {
+		WithTemperature(temperature)(c)
+		WithMaxTokens(maxTokens)(c)
+	}
+}
@@ -0,0 +1,29 @@
+package llm
+
+import "testing"
+
+func TestPresets(t *testing.T) {
+	tests := []struct {
+		name      string
+		opt       ConversationOption
+		temp      float64
+		maxTokens int
+	}{
+		{"deterministic", Deterministic(), 0, 1024},
+		{"balanced", Balanced(), 0.7, 2048},
+		{"creative", Creative(), 1, 4096},
+	}
+	for _, tt := range tests {
+		c := NewConversation("m", tt.opt).Config
+		if *c.Temperature != tt.temp || *c.MaxTokens != tt.maxTokens || c.TopP != nil {
+			t.Errorf("%s: config = %v, %v, top-p %v", tt.name, *c.Temperature, *c.MaxTokens, c.TopP)
+		}
+	}
+
+	// Later options override single fields; presets do not share state.
+	a := NewConversation("m", Balanced(), WithTemperature(0.3), WithTopP(0.9))
+	b := NewConversation("m", Balanced())
+	if *a.Config.Temperature != 0.3 || *a.Config.TopP != 0.9 || *b.Config.Temperature != 0.7 || b.Config.TopP != nil {
+		t.Errorf("a = %v, b = %v", *a.Config.Temperature, *b.Config.Temperature)
+	}
+}
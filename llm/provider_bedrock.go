@@ -3,10 +3,18 @@ package llm
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/aws/smithy-go/middleware"
 )
 
 // BedrockConverser abstracts the Bedrock Converse call for testing.
@@ -24,11 +32,59 @@ func NewBedrockProvider(client BedrockConverser) *BedrockProvider {
 	return &BedrockProvider{client: client}
 }
 
+type bedrockOptionsKey struct{}
+
+// WithBedrockOptions returns a context that makes BedrockProvider pass fns
+// to Converse, overriding client options such as the region, endpoint, or
+// retryer for requests sent with that context. Calls accumulate: options
+// from an outer context are applied first.
+func WithBedrockOptions(ctx context.Context, fns ...func(*bedrockruntime.Options)) context.Context {
+	prev := bedrockOptions(ctx)
+	all := append(prev[:len(prev):len(prev)], fns...)
+	return context.WithValue(ctx, bedrockOptionsKey{}, all)
+}
+
+func bedrockOptions(ctx context.Context) []func(*bedrockruntime.Options) {
+	fns, _ := ctx.Value(bedrockOptionsKey{}).([]func(*bedrockruntime.Options))
+	return fns
+}
+
+type requestMetadataKey struct{}
+
+// WithRequestMetadata returns a context that makes BedrockProvider send md
+// as Converse request metadata, which Bedrock records in its model
+// invocation logs, so that log entries can be matched to application
+// request IDs. Calls accumulate, with later values replacing earlier ones
+// for the same key. Bedrock accepts at most 16 pairs.
+func WithRequestMetadata(ctx context.Context, md map[string]string) context.Context {
+	all := maps.Clone(requestMetadata(ctx))
+	if all == nil {
+		all = make(map[string]string, len(md))
+	}
+	maps.Copy(all, md)
+	return context.WithValue(ctx, requestMetadataKey{}, all)
+}
+
+func requestMetadata(ctx context.Context) map[string]string {
+	md, _ := ctx.Value(requestMetadataKey{}).(map[string]string)
+	return md
+}
+
 // Send translates the conversation to Bedrock format, calls Converse, and
-// translates the response back.
+// translates the response back. Options attached with WithBedrockOptions
+// are passed through to Converse, as is metadata attached with
+// WithRequestMetadata.
 func (p *BedrockProvider) Send(ctx context.Context, conv *Conversation) (*Response, error) {
+	if extra := conv.Config.AdditionalModelRequestFields; len(extra) > 0 && additionalFields(conv) == nil {
+		return nil, &Error{Kind: ErrConfig, Message: "additional model request fields are not a JSON object"}
+	}
+	if thinkingBudgetTooSmall(conv) {
+		return nil, &Error{Kind: ErrConfig, Message: fmt.Sprintf("max tokens %d leaves no room for a thinking budget of at least %d", *conv.Config.MaxTokens, minThinkingBudget)}
+	}
 	input := toConverseInput(conv)
-	output, err := p.client.Converse(ctx, input)
+	input.RequestMetadata = requestMetadata(ctx)
+	optFns := slices.Concat(bedrockOptions(ctx), captureBedrockOptions(ctx, conv.Model))
+	output, err := p.client.Converse(ctx, input, optFns...)
 	if err != nil {
 		return nil, classifyBedrockError(err)
 	}
@@ -36,14 +92,82 @@ func (p *BedrockProvider) Send(ctx context.Context, conv *Conversation) (*Respon
 	if err != nil {
 		return nil, err
 	}
+	if jsonPrefill(conv) {
+		restorePrefill(msg, "{")
+	}
+	if rf := responseSchema(conv); rf != nil && unwrapSchemaCall(msg, rf.schemaName()) && reason == FinishReasonToolUse && len(msg.ToolCalls()) == 0 {
+		reason = FinishReasonStop
+	}
 	return &Response{
-		Message:      *msg,
-		FinishReason: reason,
-		Usage:        *usage,
+		Message:         *msg,
+		FinishReason:    reason,
+		RawFinishReason: string(output.StopReason),
+		StopSequence:    converseStopSequence(conv, output),
+		Usage:           *usage,
+		Guardrail:       fromConverseTrace(output),
+		Raw:             output,
+		RawRequest:      rawRequest(ctx, input),
+		ProviderLatency: converseLatency(output),
+		Retries:         sdkRetries(output),
+		RequestID:       requestID(output.ResultMetadata),
 	}, nil
 }
 
+// requestID returns the AWS request ID recorded in md.
+func requestID(md middleware.Metadata) string {
+	id, _ := awsmiddleware.GetRequestIDMetadata(md)
+	return id
+}
+
+// converseLatency returns the latency Bedrock reported for out.
+func converseLatency(out *bedrockruntime.ConverseOutput) time.Duration {
+	if out.Metrics == nil || out.Metrics.LatencyMs == nil {
+		return 0
+	}
+	return time.Duration(*out.Metrics.LatencyMs) * time.Millisecond
+}
+
+// sdkRetries returns the number of attempts the AWS SDK's retryer made
+// before the one that produced out.
+func sdkRetries(out *bedrockruntime.ConverseOutput) int {
+	if results, ok := retry.GetAttemptResults(out.ResultMetadata); ok && len(results.Results) > 1 {
+		return len(results.Results) - 1
+	}
+	return 0
+}
+
+// restorePrefill prepends prefill, which the model continued from, to the
+// reply's text.
+func restorePrefill(msg *Message, prefill string) {
+	for i, p := range msg.Content {
+		if p.Kind == ContentText {
+			msg.Content[i].Text = prefill + p.Text
+			return
+		}
+	}
+	msg.Content = append([]ContentPart{{Kind: ContentText, Text: prefill}}, msg.Content...)
+}
+
+// unwrapSchemaCall replaces a call to the synthetic response schema tool
+// with a text part holding its arguments, and reports whether there was
+// one.
+func unwrapSchemaCall(msg *Message, name string) bool {
+	for i, p := range msg.Content {
+		if p.Kind == ContentToolCall && p.ToolCall != nil && p.ToolCall.Name == name {
+			msg.Content[i] = ContentPart{Kind: ContentText, Text: string(p.ToolCall.Arguments)}
+			return true
+		}
+	}
+	return false
+}
+
 func classifyBedrockError(err error) error {
+	// Errors already classified, e.g. by a BedrockPool, pass through
+	var llmErr *Error
+	if errors.As(err, &llmErr) {
+		return err
+	}
+
 	var kind ErrorKind
 	msg := err.Error()
 
@@ -52,6 +176,8 @@ func classifyBedrockError(err error) error {
 	var notFound *types.ResourceNotFoundException
 	var throttling *types.ThrottlingException
 	var timeout *types.ModelTimeoutException
+	var notReady *types.ModelNotReadyException
+	var quota *types.ServiceQuotaExceededException
 	var internal *types.InternalServerException
 	var modelErr *types.ModelErrorException
 
@@ -65,7 +191,9 @@ func classifyBedrockError(err error) error {
 	case errors.As(err, &throttling):
 		kind = ErrRateLimit
 	case errors.As(err, &timeout):
-		kind = ErrServer
+		kind = ErrTimeout
+	case errors.As(err, &notReady), errors.As(err, &quota):
+		kind = ErrCapacity
 	case errors.As(err, &internal):
 		kind = ErrServer
 	case errors.As(err, &modelErr):
@@ -73,7 +201,7 @@ func classifyBedrockError(err error) error {
 	default:
 		lower := strings.ToLower(msg)
 		switch {
-		case strings.Contains(lower, "context length") || strings.Contains(lower, "too many tokens"):
+		case isContextLengthMessage(msg):
 			kind = ErrContextLength
 		case strings.Contains(lower, "content filter") || strings.Contains(lower, "guardrail"):
 			kind = ErrContentFilter
@@ -82,9 +210,14 @@ func classifyBedrockError(err error) error {
 		}
 	}
 
-	return &Error{
+	e := &Error{
 		Kind:    kind,
 		Message: msg,
 		Cause:   err,
 	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		e.RequestID = respErr.ServiceRequestID()
+	}
+	return e
 }
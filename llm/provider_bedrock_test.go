@@ -2,10 +2,18 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 )
 
@@ -67,6 +75,91 @@ func TestBedrockProvider_Send(t *testing.T) {
 	}
 }
 
+func TestBedrockProvider_JSONPrefill(t *testing.T) {
+	provider := NewBedrockProvider(&mockConverser{output: simpleConverseOutput(`"city": "Paris"}`)})
+	conv := NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0", WithResponseFormat(ResponseFormat{Type: ResponseFormatJSON}))
+	conv.Messages = []Message{UserMessage("Which city? Answer in JSON.")}
+
+	resp, err := provider.Send(context.Background(), &conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Message.Text(); got != `{"city": "Paris"}` {
+		t.Errorf("Text = %q", got)
+	}
+}
+
+func TestBedrockProvider_ResponseSchema(t *testing.T) {
+	out := simpleConverseOutput("")
+	out.Output.(*types.ConverseOutputMemberMessage).Value.Content = []types.ContentBlock{
+		&types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+			ToolUseId: strPtr("t1"), Name: strPtr("city"), Input: document.NewLazyDocument(map[string]any{"city": "Paris"}),
+		}},
+	}
+	out.StopReason = types.StopReasonToolUse
+	client := NewClient(&mockConverser{output: out})
+
+	conv := NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0", WithResponseSchema("city", citySchema))
+	_, resp, err := client.Send(context.Background(), conv, UserMessage("where?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Text() != `{"city":"Paris"}` || resp.FinishReason != FinishReasonStop || resp.RawFinishReason != "tool_use" || len(resp.Message.ToolCalls()) != 0 {
+		t.Errorf("resp = %+v", resp)
+	}
+}
+
+func TestBedrockProvider_ResponseSchemaWithToolCall(t *testing.T) {
+	out := simpleConverseOutput("")
+	out.Output.(*types.ConverseOutputMemberMessage).Value.Content = []types.ContentBlock{
+		&types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+			ToolUseId: strPtr("t1"), Name: strPtr("lookup"), Input: document.NewLazyDocument(map[string]any{"query": "Paris"}),
+		}},
+	}
+	out.StopReason = types.StopReasonToolUse
+	client := NewClient(&mockConverser{output: out})
+
+	conv := NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0", WithResponseSchema("city", citySchema),
+		WithTools(NewTool("lookup", "Look up a city", StringParam("query"))))
+	_, resp, err := client.Send(context.Background(), conv, UserMessage("where?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.FinishReason != FinishReasonToolUse || !resp.Message.HasToolCall("lookup") {
+		t.Errorf("resp = %+v", resp)
+	}
+}
+
+func TestBedrockProvider_AdditionalFieldsNotObject(t *testing.T) {
+	mock := &mockConverser{output: simpleConverseOutput("ok")}
+	conv := NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0", WithAdditionalModelRequestFields(json.RawMessage(`[1]`)))
+	conv.Messages = []Message{UserMessage("hi")}
+
+	_, err := NewBedrockProvider(mock).Send(context.Background(), &conv)
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrConfig {
+		t.Errorf("err = %v, want ErrConfig", err)
+	}
+}
+
+func TestBedrockProvider_ThinkingMaxTokensTooSmall(t *testing.T) {
+	mock := &mockConverser{output: simpleConverseOutput("ok")}
+	conv := NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0", WithReasoningEffort(ReasoningEffortLow), WithMaxTokens(1024))
+	conv.Messages = []Message{UserMessage("hi")}
+
+	_, err := NewBedrockProvider(mock).Send(context.Background(), &conv)
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrConfig {
+		t.Errorf("err = %v, want ErrConfig", err)
+	}
+
+	// Thinking configured explicitly is passed through as given.
+	conv.Config.AdditionalModelRequestFields = json.RawMessage(`{"thinking": {"type": "disabled"}}`)
+	if _, err := NewBedrockProvider(mock).Send(context.Background(), &conv); err != nil {
+		t.Errorf("err = %v", err)
+	}
+}
+
 func TestBedrockProvider_Error(t *testing.T) {
 	provider := NewBedrockProvider(&mockConverser{
 		err: &types.ThrottlingException{Message: strPtr("slow down")},
@@ -104,3 +197,167 @@ func TestBedrockProvider_BackwardCompat(t *testing.T) {
 		t.Errorf("Messages len = %d, want 2", len(conv.Messages))
 	}
 }
+
+// optionsConverser applies the per-call option functions and records the
+// resulting options.
+type optionsConverser struct {
+	got   bedrockruntime.Options
+	input *bedrockruntime.ConverseInput
+}
+
+func (o *optionsConverser) Converse(_ context.Context, input *bedrockruntime.ConverseInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ConverseOutput, error) {
+	o.input = input
+	for _, fn := range optFns {
+		fn(&o.got)
+	}
+	return simpleConverseOutput("ok"), nil
+}
+
+func TestBedrockProvider_ContextOptions(t *testing.T) {
+	converser := &optionsConverser{}
+	client := NewClient(converser)
+
+	ctx := WithBedrockOptions(context.Background(), func(o *bedrockruntime.Options) {
+		o.Region = "us-west-2"
+	})
+	ctx = WithBedrockOptions(ctx, func(o *bedrockruntime.Options) {
+		o.BaseEndpoint = strPtr("https://bedrock.example.com")
+	})
+
+	if _, _, err := client.Send(ctx, NewConversation("model"), UserMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if converser.got.Region != "us-west-2" {
+		t.Errorf("Region = %q", converser.got.Region)
+	}
+	if derefStr(converser.got.BaseEndpoint) != "https://bedrock.example.com" {
+		t.Errorf("BaseEndpoint = %v", converser.got.BaseEndpoint)
+	}
+}
+
+func TestBedrockProvider_RequestMetadata(t *testing.T) {
+	converser := &optionsConverser{}
+	client := NewClient(converser)
+
+	ctx := WithRequestMetadata(context.Background(), map[string]string{"request_id": "r-1", "tenant": "acme"})
+	inner := WithRequestMetadata(ctx, map[string]string{"request_id": "r-2"})
+
+	if _, _, err := client.Send(inner, NewConversation("model"), UserMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if md := converser.input.RequestMetadata; len(md) != 2 || md["request_id"] != "r-2" || md["tenant"] != "acme" {
+		t.Errorf("RequestMetadata = %v", md)
+	}
+	// The outer context is unchanged.
+	if md := requestMetadata(ctx); md["request_id"] != "r-1" {
+		t.Errorf("outer metadata = %v", md)
+	}
+
+	if _, _, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if converser.input.RequestMetadata != nil {
+		t.Errorf("RequestMetadata = %v, want nil", converser.input.RequestMetadata)
+	}
+}
+
+// newTestBedrockClient returns a Bedrock runtime client that sends
+// requests to handler, retrying without delay.
+func newTestBedrockClient(t *testing.T, handler http.HandlerFunc) *bedrockruntime.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return bedrockruntime.New(bedrockruntime.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		}),
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+		}),
+	})
+}
+
+func TestBedrockProvider_Timing(t *testing.T) {
+	calls := 0
+	bedrock := newTestBedrockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls == 1 {
+			http.Error(w, `{"message":"try again"}`, http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"output":{"message":{"role":"assistant","content":[{"text":"Hi!"}]}},"stopReason":"end_turn","usage":{"inputTokens":3,"outputTokens":2,"totalTokens":5},"metrics":{"latencyMs":420}}`)
+	})
+
+	_, resp, err := NewClient(bedrock).Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ProviderLatency != 420*time.Millisecond || resp.Retries != 1 || resp.Latency <= 0 {
+		t.Errorf("ProviderLatency = %v, Retries = %d, Latency = %v", resp.ProviderLatency, resp.Retries, resp.Latency)
+	}
+}
+
+func TestBedrockProvider_RequestID(t *testing.T) {
+	fail := false
+	bedrock := newTestBedrockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if fail {
+			w.Header().Set("x-amzn-RequestId", "req-bad")
+			w.Header().Set("x-amzn-ErrorType", "ValidationException")
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"message":"bad input"}`)
+			return
+		}
+		w.Header().Set("x-amzn-RequestId", "req-ok")
+		io.WriteString(w, `{"output":{"message":{"role":"assistant","content":[{"text":"Hi!"}]}},"stopReason":"end_turn","usage":{"inputTokens":3,"outputTokens":2,"totalTokens":5},"metrics":{"latencyMs":1}}`)
+	})
+	client := NewClient(bedrock)
+
+	_, resp, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.RequestID != "req-ok" {
+		t.Errorf("RequestID = %q", resp.RequestID)
+	}
+
+	fail = true
+	_, _, err = client.Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrInvalidRequest || llmErr.RequestID != "req-bad" {
+		t.Errorf("err = %#v", err)
+	}
+}
+
+func TestBedrockProvider_StopSequence(t *testing.T) {
+	var body map[string]any
+	bedrock := newTestBedrockClient(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"output":{"message":{"role":"assistant","content":[{"text":"Step 1"}]}},"stopReason":"stop_sequence","additionalModelResponseFields":{"stop_sequence":"STEP 2"},"usage":{"inputTokens":3,"outputTokens":2,"totalTokens":5},"metrics":{"latencyMs":1}}`)
+	})
+	conv := NewConversation("us.anthropic.claude-sonnet-4-5-20250929-v1:0", WithStopSequences("END", "STEP 2"))
+
+	_, resp, err := NewClient(bedrock).Send(context.Background(), conv, UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StopSequence != "STEP 2" || resp.FinishReason != FinishReasonStop {
+		t.Errorf("StopSequence = %q, FinishReason = %q", resp.StopSequence, resp.FinishReason)
+	}
+	if paths, _ := body["additionalModelResponseFieldPaths"].([]any); len(paths) != 1 || paths[0] != "/stop_sequence" {
+		t.Errorf("additionalModelResponseFieldPaths = %v", body["additionalModelResponseFieldPaths"])
+	}
+
+	// With one stop sequence, any model's stop_sequence reason names it.
+	out := simpleConverseOutput("Step 1")
+	out.StopReason = types.StopReasonStopSequence
+	provider := NewBedrockProvider(&mockConverser{output: out})
+	conv = NewConversation("amazon.nova-pro-v1:0", WithStopSequences("END"))
+	conv.Messages = []Message{UserMessage("hi")}
+	if resp, err := provider.Send(context.Background(), &conv); err != nil || resp.StopSequence != "END" {
+		t.Errorf("StopSequence = %q, %v", resp.StopSequence, err)
+	}
+}
@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+// DeepSeekBaseURL is the base URL of the hosted DeepSeek API.
+const DeepSeekBaseURL = "https://api.deepseek.com"
+
+// DeepSeekProvider implements Provider for DeepSeek models served over the
+// OpenAI-compatible chat completions API (the hosted API, or R1 distills on
+// llama.cpp, vLLM, or Ollama). Reasoning that the model inlines as
+// <think>…</think> is split out of the completion into ContentThinking parts.
+type DeepSeekProvider struct {
+	openai *OpenAIProvider
+}
+
+// NewDeepSeekProvider creates a Provider that calls POST {baseURL}/v1/chat/completions.
+func NewDeepSeekProvider(baseURL string, opts ...OpenAIOption) *DeepSeekProvider {
+	return &DeepSeekProvider{openai: NewOpenAIProvider(baseURL, opts...)}
+}
+
+// Send calls the chat completions API and separates inline reasoning from
+// the answer text.
+func (p *DeepSeekProvider) Send(ctx context.Context, conv *Conversation) (*Response, error) {
+	resp, err := p.openai.Send(ctx, conv)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []ContentPart
+	for _, part := range resp.Message.Content {
+		if part.Kind != ContentText {
+			parts = append(parts, part)
+			continue
+		}
+		thinking, text := splitThinkTags(part.Text)
+		if thinking != "" {
+			parts = append(parts, ContentPart{Kind: ContentThinking, Thinking: &ThinkingData{Text: thinking}})
+		}
+		if text != "" {
+			parts = append(parts, ContentPart{Kind: ContentText, Text: text})
+		}
+	}
+	resp.Message.Content = parts
+	return resp, nil
+}
+
+// splitThinkTags separates <think>…</think> segments from the rest of the
+// text. Chat templates that open the think block in the prompt leave only
+// the closing tag in the completion, so a leading unmatched </think> ends a
+// reasoning segment too. An unterminated <think> (e.g. the generation hit
+// the token limit) treats everything after it as reasoning.
+func splitThinkTags(s string) (thinking, text string) {
+	const openTag, closeTag = "<think>", "</think>"
+
+	var think, out strings.Builder
+	if i, j := strings.Index(s, openTag), strings.Index(s, closeTag); j >= 0 && (i < 0 || j < i) {
+		think.WriteString(s[:j])
+		s = s[j+len(closeTag):]
+	}
+	for {
+		i := strings.Index(s, openTag)
+		if i < 0 {
+			out.WriteString(s)
+			break
+		}
+		out.WriteString(s[:i])
+		s = s[i+len(openTag):]
+		if think.Len() > 0 {
+			think.WriteString("\n")
+		}
+		j := strings.Index(s, closeTag)
+		if j < 0 {
+			think.WriteString(s)
+			break
+		}
+		think.WriteString(s[:j])
+		s = s[j+len(closeTag):]
+	}
+	return strings.TrimSpace(think.String()), strings.TrimSpace(out.String())
+}
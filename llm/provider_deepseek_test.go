@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSplitThinkTags(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           string
+		wantThinking string
+		wantText     string
+	}{
+		{"no tags", "The answer is 42.", "", "The answer is 42."},
+		{"leading block", "<think>\n6*7 = 42\n</think>\n\nThe answer is 42.", "6*7 = 42", "The answer is 42."},
+		{"missing open tag", "6*7 = 42\n</think>\n\nThe answer is 42.", "6*7 = 42", "The answer is 42."},
+		{"multiple blocks", "<think>a</think>one <think>b</think>two", "a\nb", "one two"},
+		{"unterminated", "<think>still going", "still going", ""},
+		{"empty block", "<think></think>Hi", "", "Hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thinking, text := splitThinkTags(tt.in)
+			if thinking != tt.wantThinking {
+				t.Errorf("thinking = %q, want %q", thinking, tt.wantThinking)
+			}
+			if text != tt.wantText {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestDeepSeekProvider_ThinkTags(t *testing.T) {
+	resp := chatCompletionResponse{
+		Choices: []chatChoice{{
+			Message:      chatMessage{Role: "assistant", Content: textContent("<think>Multiply.</think>\n\n42")},
+			FinishReason: "stop",
+		}},
+		Usage: &chatUsage{
+			PromptTokens:            12,
+			CompletionTokens:        30,
+			PromptCacheHitTokens:    8,
+			CompletionTokensDetails: &chatCompletionDetails{ReasoningTokens: 25},
+		},
+	}
+	srv, _ := newTestOpenAIServer(t, 200, resp)
+
+	provider := NewDeepSeekProvider(srv.URL)
+	conv := NewConversation("deepseek-reasoner")
+	conv.Messages = []Message{UserMessage("What is 6*7?")}
+
+	result, err := provider.Send(context.Background(), &conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Message.Content) != 2 {
+		t.Fatalf("Content len = %d, want 2", len(result.Message.Content))
+	}
+	if p := result.Message.Content[0]; p.Kind != ContentThinking || p.Thinking.Text != "Multiply." {
+		t.Errorf("Content[0] = %+v", p)
+	}
+	if result.Message.Text() != "42" {
+		t.Errorf("Text = %q", result.Message.Text())
+	}
+	if result.Usage.CacheReadTokens != 8 {
+		t.Errorf("CacheReadTokens = %d", result.Usage.CacheReadTokens)
+	}
+	if result.Usage.ReasoningTokens != 25 {
+		t.Errorf("ReasoningTokens = %d", result.Usage.ReasoningTokens)
+	}
+}
+
+func TestDeepSeekProvider_ReasoningContentPassthrough(t *testing.T) {
+	resp := chatCompletionResponse{
+		Choices: []chatChoice{{
+			Message: chatMessage{
+				Role:             "assistant",
+				Content:          textContent("42"),
+				ReasoningContent: "Multiply.",
+			},
+			FinishReason: "stop",
+		}},
+	}
+	srv, _ := newTestOpenAIServer(t, 200, resp)
+
+	provider := NewDeepSeekProvider(srv.URL)
+	conv := NewConversation("deepseek-reasoner")
+	conv.Messages = []Message{UserMessage("What is 6*7?")}
+
+	result, err := provider.Send(context.Background(), &conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Message.Content) != 2 {
+		t.Fatalf("Content len = %d, want 2", len(result.Message.Content))
+	}
+	if result.Message.Content[0].Kind != ContentThinking {
+		t.Errorf("Content[0].Kind = %q", result.Message.Content[0].Kind)
+	}
+	if result.Message.Text() != "42" {
+		t.Errorf("Text = %q", result.Message.Text())
+	}
+}
@@ -0,0 +1,480 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GeminiBaseURL is the base URL of the Google AI Studio Gemini API.
+const GeminiBaseURL = "https://generativelanguage.googleapis.com"
+
+// GeminiProvider implements Provider using the Gemini generateContent API.
+type GeminiProvider struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// GeminiOption configures a GeminiProvider.
+type GeminiOption func(*GeminiProvider)
+
+// WithGeminiBaseURL overrides GeminiBaseURL, e.g. for a proxy or tests.
+func WithGeminiBaseURL(baseURL string) GeminiOption {
+	return func(p *GeminiProvider) { p.baseURL = strings.TrimRight(baseURL, "/") }
+}
+
+// WithGeminiHTTPClient overrides the default HTTP client.
+func WithGeminiHTTPClient(c *http.Client) GeminiOption {
+	return func(p *GeminiProvider) { p.httpClient = c }
+}
+
+// NewGeminiProvider creates a Provider that calls
+// POST {baseURL}/v1beta/models/{model}:generateContent with the given API key.
+func NewGeminiProvider(apiKey string, opts ...GeminiOption) *GeminiProvider {
+	p := &GeminiProvider{
+		baseURL:    GeminiBaseURL,
+		apiKey:     apiKey,
+		httpClient: http.DefaultClient,
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+// Send translates the conversation to the Gemini format, makes the HTTP
+// request, and translates the response back.
+func (p *GeminiProvider) Send(ctx context.Context, conv *Conversation) (*Response, error) {
+	if err := checkGeminiContent(conv); err != nil {
+		return nil, err
+	}
+	jsonData, err := json.Marshal(toGeminiRequest(conv))
+	if err != nil {
+		return nil, &Error{Kind: ErrConfig, Message: "failed to marshal request", Cause: err}
+	}
+	if jsonData, err = applyProviderOptions(conv, "gemini", jsonData); err != nil {
+		return nil, err
+	}
+
+	u := p.baseURL + "/v1beta/models/" + url.PathEscape(conv.Model) + ":generateContent"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, &Error{Kind: ErrConfig, Message: "failed to create request", Cause: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("x-goog-api-key", p.apiKey)
+	}
+
+	httpResp, err := p.httpClient.Do(req)
+	if err != nil {
+		captureHTTP(ctx, "gemini", conv.Model, u, 0, jsonData, nil, err)
+		return nil, &Error{Kind: ErrServer, Message: err.Error(), Cause: err}
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(httpResp.Body)
+	captureHTTP(ctx, "gemini", conv.Model, u, httpResp.StatusCode, jsonData, body, err)
+	if err != nil {
+		return nil, &Error{Kind: ErrServer, Message: "failed to read response", Cause: err}
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, classifyHTTPError(httpResp.StatusCode, body)
+	}
+
+	var genResp geminiResponse
+	if err := json.Unmarshal(body, &genResp); err != nil {
+		return nil, &Error{Kind: ErrServer, Message: "failed to decode response", Cause: err}
+	}
+
+	resp, err := fromGeminiResponse(genResp)
+	if err != nil {
+		return nil, err
+	}
+	resp.Raw, resp.RawRequest = json.RawMessage(body), rawRequest(ctx, json.RawMessage(jsonData))
+	return resp, nil
+}
+
+// --- request/response wire types (unexported) ---
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent         `json:"contents"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	ThoughtSignature string                  `json:"thoughtSignature,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MIMEType string `json:"mimeType"`
+	Data     []byte `json:"data"` // base64 on the wire
+}
+
+type geminiFileData struct {
+	MIMEType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig geminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type geminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+
+	ResponseMIMEType   string          `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
+
+	ThinkingConfig *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget  int  `json:"thinkingBudget"`
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates     []geminiCandidate    `json:"candidates"`
+	UsageMetadata  *geminiUsageMetadata `json:"usageMetadata,omitempty"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
+	Error *chatError `json:"error,omitempty"` // sent with a 200 status by some proxies
+}
+
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+}
+
+// --- translation ---
+
+func toGeminiRequest(conv *Conversation) geminiRequest {
+	var req geminiRequest
+
+	if len(conv.System) > 0 {
+		sys := &geminiContent{}
+		for _, s := range conv.System {
+			sys.Parts = append(sys.Parts, geminiPart{Text: s})
+		}
+		req.SystemInstruction = sys
+	}
+
+	// Function responses must name the function, but tool results only
+	// carry the call ID, so resolve names from the calls in the history.
+	callNames := make(map[string]string)
+	for _, m := range conv.Messages {
+		for _, tc := range m.ToolCalls() {
+			callNames[tc.ID] = tc.Name
+		}
+	}
+
+	for _, m := range conv.Messages {
+		role := "user"
+		if m.Role == RoleAssistant {
+			role = "model"
+		}
+		content := geminiContent{Role: role}
+		for _, p := range m.Content {
+			switch p.Kind {
+			case ContentText:
+				content.Parts = append(content.Parts, geminiPart{Text: p.Text})
+			case ContentImage:
+				if p.Image == nil {
+					continue
+				}
+				if len(p.Image.Data) > 0 {
+					content.Parts = append(content.Parts, geminiPart{InlineData: &geminiBlob{MIMEType: p.Image.MediaType, Data: p.Image.Data}})
+				} else if p.Image.URL != "" {
+					content.Parts = append(content.Parts, geminiPart{FileData: &geminiFileData{MIMEType: p.Image.MediaType, FileURI: p.Image.URL}})
+				}
+			case ContentToolCall:
+				if p.ToolCall == nil {
+					continue
+				}
+				content.Parts = append(content.Parts, geminiPart{
+					ThoughtSignature: p.ToolCall.Signature,
+					FunctionCall: &geminiFunctionCall{
+						ID:   p.ToolCall.ID,
+						Name: p.ToolCall.Name,
+						Args: p.ToolCall.Arguments,
+					},
+				})
+			case ContentThinking:
+				// Gemini validates the signatures of its own thoughts, so
+				// only signed thoughts from the model are sent back.
+				if p.Thinking == nil || p.Thinking.Signature == "" || m.Role != RoleAssistant {
+					continue
+				}
+				content.Parts = append(content.Parts, geminiPart{Text: p.Thinking.Text, Thought: true, ThoughtSignature: p.Thinking.Signature})
+			case ContentToolResult:
+				if p.ToolResult == nil {
+					continue
+				}
+				content.Parts = append(content.Parts, geminiPart{FunctionResponse: &geminiFunctionResponse{
+					ID:       p.ToolResult.ToolCallID,
+					Name:     callNames[p.ToolResult.ToolCallID],
+					Response: geminiFunctionResult(p.ToolResult),
+				}})
+				// Images ride along as inline parts in the same turn.
+				for _, img := range p.ToolResult.Images {
+					if len(img.Data) > 0 {
+						content.Parts = append(content.Parts, geminiPart{InlineData: &geminiBlob{MIMEType: img.MediaType, Data: img.Data}})
+					}
+				}
+			}
+		}
+		if len(content.Parts) == 0 {
+			continue
+		}
+		// Consecutive tool results belong in a single user turn.
+		if n := len(req.Contents); n > 0 && m.Role == RoleTool && req.Contents[n-1].Role == role {
+			req.Contents[n-1].Parts = append(req.Contents[n-1].Parts, content.Parts...)
+			continue
+		}
+		req.Contents = append(req.Contents, content)
+	}
+
+	if len(conv.Tools) > 0 {
+		tool := geminiTool{}
+		for _, td := range conv.Tools {
+			decl := geminiFunctionDeclaration{Name: td.Name, Description: td.Description}
+			if hasSchemaProperties(td.Parameters) {
+				decl.Parameters = td.Parameters
+			}
+			tool.FunctionDeclarations = append(tool.FunctionDeclarations, decl)
+		}
+		req.Tools = []geminiTool{tool}
+	}
+
+	if tc := conv.Config.ToolChoice; tc != nil {
+		cfg := geminiFunctionCallingConfig{}
+		switch tc.Mode {
+		case ToolChoiceAuto:
+			cfg.Mode = "AUTO"
+		case ToolChoiceNone:
+			cfg.Mode = "NONE"
+		case ToolChoiceRequired:
+			cfg.Mode = "ANY"
+		case ToolChoiceNamed:
+			cfg.Mode = "ANY"
+			cfg.AllowedFunctionNames = []string{tc.ToolName}
+		}
+		req.ToolConfig = &geminiToolConfig{FunctionCallingConfig: cfg}
+	}
+
+	if c := conv.Config; c.MaxTokens != nil || c.Temperature != nil || c.TopP != nil || len(c.StopSequences) > 0 ||
+		c.FrequencyPenalty != nil || c.PresencePenalty != nil || c.ResponseFormat != nil || c.ReasoningEffort != "" {
+		req.GenerationConfig = &geminiGenerationConfig{
+			MaxOutputTokens:  c.MaxTokens,
+			Temperature:      c.Temperature,
+			TopP:             c.TopP,
+			StopSequences:    c.StopSequences,
+			FrequencyPenalty: c.FrequencyPenalty,
+			PresencePenalty:  c.PresencePenalty,
+		}
+		if rf := c.ResponseFormat; rf != nil {
+			switch rf.Type {
+			case ResponseFormatJSON:
+				req.GenerationConfig.ResponseMIMEType = "application/json"
+			case ResponseFormatJSONSchema:
+				req.GenerationConfig.ResponseMIMEType = "application/json"
+				req.GenerationConfig.ResponseJSONSchema = rf.Schema
+			case ResponseFormatText:
+				req.GenerationConfig.ResponseMIMEType = "text/plain"
+			}
+		}
+		if budget := c.ReasoningEffort.thinkingBudget(); budget > 0 {
+			// Without includeThoughts Gemini thinks but returns no thought
+			// parts, so there would be nothing to show or send back.
+			req.GenerationConfig.ThinkingConfig = &geminiThinkingConfig{ThinkingBudget: budget, IncludeThoughts: true}
+		}
+	}
+
+	return req
+}
+
+// checkGeminiContent rejects audio and document parts, which the Gemini
+// request cannot carry, rather than dropping them from the prompt.
+func checkGeminiContent(conv *Conversation) error {
+	for i, m := range conv.Messages {
+		for j, p := range m.Content {
+			if p.Kind != ContentAudio && p.Kind != ContentDocument {
+				continue
+			}
+			if err := providerContent["gemini"].check(p); err != nil {
+				return &Error{Kind: ErrConfig, Message: fmt.Sprintf("gemini: message %d: part %d: %v", i, j, err), Cause: err}
+			}
+		}
+	}
+	return nil
+}
+
+// geminiFunctionResult wraps a tool result as the JSON object Gemini
+// requires. Results that are already JSON objects are passed through.
+func geminiFunctionResult(tr *ToolResultData) json.RawMessage {
+	key := "content"
+	if tr.IsError {
+		key = "error"
+	}
+	text := tr.Text()
+	var obj map[string]json.RawMessage
+	if !tr.IsError && json.Unmarshal([]byte(text), &obj) == nil {
+		return json.RawMessage(text)
+	}
+	data, _ := json.Marshal(map[string]string{key: text})
+	return data
+}
+
+// hasSchemaProperties reports whether a JSON Schema declares any
+// properties; Gemini rejects object schemas with an empty property set.
+func hasSchemaProperties(schema json.RawMessage) bool {
+	var s struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	_ = json.Unmarshal(schema, &s)
+	return len(s.Properties) > 0
+}
+
+func fromGeminiResponse(resp geminiResponse) (*Response, error) {
+	usage := Usage{}
+	if u := resp.UsageMetadata; u != nil {
+		usage.InputTokens = u.PromptTokenCount - u.CachedContentTokenCount
+		usage.OutputTokens = u.CandidatesTokenCount + u.ThoughtsTokenCount
+		usage.CacheReadTokens = u.CachedContentTokenCount
+		usage.ReasoningTokens = u.ThoughtsTokenCount
+	}
+
+	if resp.Error != nil {
+		return nil, resp.Error.classify()
+	}
+	if len(resp.Candidates) == 0 {
+		if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+			return &Response{
+				Message:         Message{Role: RoleAssistant},
+				FinishReason:    FinishReasonContentFilter,
+				RawFinishReason: resp.PromptFeedback.BlockReason,
+				Usage:           usage,
+			}, nil
+		}
+		return nil, &Error{Kind: ErrServer, Message: "no candidates in response"}
+	}
+
+	cand := resp.Candidates[0]
+	msg := Message{Role: RoleAssistant}
+	for i, part := range cand.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("%s-%d", part.FunctionCall.Name, i)
+			}
+			msg.Content = append(msg.Content, ContentPart{
+				Kind: ContentToolCall,
+				ToolCall: &ToolCallData{
+					ID:        id,
+					Name:      part.FunctionCall.Name,
+					Arguments: part.FunctionCall.Args,
+					Signature: part.ThoughtSignature,
+				},
+			})
+		case part.Thought, part.Text == "" && part.ThoughtSignature != "":
+			// A signature may also arrive on a part of its own.
+			msg.Content = append(msg.Content, ContentPart{
+				Kind:     ContentThinking,
+				Thinking: &ThinkingData{Text: part.Text, Signature: part.ThoughtSignature},
+			})
+		case part.Text != "":
+			msg.Content = append(msg.Content, ContentPart{Kind: ContentText, Text: part.Text})
+		}
+	}
+
+	reason := mapGeminiFinishReason(cand.FinishReason)
+	if reason == FinishReasonStop && len(msg.ToolCalls()) > 0 {
+		reason = FinishReasonToolUse
+	}
+
+	return &Response{
+		Message:         msg,
+		FinishReason:    reason,
+		RawFinishReason: cand.FinishReason,
+		Usage:           usage,
+	}, nil
+}
+
+func mapGeminiFinishReason(reason string) FinishReason {
+	switch reason {
+	case "STOP":
+		return FinishReasonStop
+	case "MAX_TOKENS":
+		return FinishReasonLength
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return FinishReasonContentFilter
+	case "MALFORMED_FUNCTION_CALL":
+		return FinishReasonError
+	default:
+		return FinishReason(reason)
+	}
+}
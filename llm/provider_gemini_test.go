@@ -0,0 +1,284 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestGeminiServer creates an httptest server that captures the request
+// path, API key, and body, and returns respBody with the given status code.
+func newTestGeminiServer(t *testing.T, statusCode int, respBody string) (*httptest.Server, *http.Request, *[]byte) {
+	t.Helper()
+	var captured []byte
+	var capturedReq http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured, _ = io.ReadAll(r.Body)
+		capturedReq = *r
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(respBody))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &capturedReq, &captured
+}
+
+func TestGeminiProvider_SimpleText(t *testing.T) {
+	srv, req, _ := newTestGeminiServer(t, 200, `{
+		"candidates":[{"content":{"role":"model","parts":[{"text":"Hello!"}]},"finishReason":"STOP"}],
+		"usageMetadata":{"promptTokenCount":12,"candidatesTokenCount":3,"cachedContentTokenCount":4}
+	}`)
+
+	provider := NewGeminiProvider("secret", WithGeminiBaseURL(srv.URL))
+	conv := NewConversation("gemini-2.5-flash", WithSystem("Be helpful."))
+	conv.Messages = []Message{UserMessage("hi")}
+
+	result, err := provider.Send(context.Background(), &conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.Path != "/v1beta/models/gemini-2.5-flash:generateContent" {
+		t.Errorf("path = %q", req.URL.Path)
+	}
+	if req.Header.Get("x-goog-api-key") != "secret" {
+		t.Errorf("api key header = %q", req.Header.Get("x-goog-api-key"))
+	}
+	if result.Message.Text() != "Hello!" {
+		t.Errorf("Text = %q", result.Message.Text())
+	}
+	if result.FinishReason != FinishReasonStop {
+		t.Errorf("FinishReason = %q", result.FinishReason)
+	}
+	if result.Usage.InputTokens != 8 || result.Usage.CacheReadTokens != 4 || result.Usage.OutputTokens != 3 {
+		t.Errorf("Usage = %+v", result.Usage)
+	}
+}
+
+func TestGeminiProvider_RequestFormat(t *testing.T) {
+	srv, _, captured := newTestGeminiServer(t, 200, `{"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`)
+
+	tool := NewTool("get_weather", "Get weather", StringParam("location"))
+	conv := NewConversation("gemini-2.5-flash",
+		WithSystem("Be helpful."),
+		WithTools(tool, NewTool("noop", "No parameters")),
+		WithToolChoice(ToolChoice{Mode: ToolChoiceNamed, ToolName: "get_weather"}),
+		WithMaxTokens(100),
+		WithTemperature(0.2),
+		WithFrequencyPenalty(0.5),
+		WithPresencePenalty(0),
+		WithResponseFormat(ResponseFormat{Type: ResponseFormatJSON}),
+		WithReasoningEffort(ReasoningEffortMedium),
+	)
+	conv.Messages = []Message{
+		{Role: RoleUser, Content: []ContentPart{
+			{Kind: ContentText, Text: "weather?"},
+			{Kind: ContentImage, Image: &ImageData{Data: []byte("png"), MediaType: "image/png"}},
+		}},
+		{Role: RoleAssistant, Content: []ContentPart{
+			{Kind: ContentToolCall, ToolCall: &ToolCallData{ID: "c1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}},
+			{Kind: ContentToolCall, ToolCall: &ToolCallData{ID: "c2", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Rome"}`)}},
+		}},
+		ToolResultMessage("c1", `{"temp":15}`, false),
+		ToolResultMessage("c2", "unavailable", true),
+	}
+
+	if _, err := NewGeminiProvider("", WithGeminiBaseURL(srv.URL)).Send(context.Background(), &conv); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+		"systemInstruction":{"parts":[{"text":"Be helpful."}]},
+		"contents":[
+			{"role":"user","parts":[{"text":"weather?"},{"inlineData":{"mimeType":"image/png","data":"cG5n"}}]},
+			{"role":"model","parts":[
+				{"functionCall":{"id":"c1","name":"get_weather","args":{"location":"Paris"}}},
+				{"functionCall":{"id":"c2","name":"get_weather","args":{"location":"Rome"}}}
+			]},
+			{"role":"user","parts":[
+				{"functionResponse":{"id":"c1","name":"get_weather","response":{"temp":15}}},
+				{"functionResponse":{"id":"c2","name":"get_weather","response":{"error":"unavailable"}}}
+			]}
+		],
+		"tools":[{"functionDeclarations":[
+			{"name":"get_weather","description":"Get weather","parameters":{"type":"object","properties":{"location":{"type":"string"}},"required":["location"]}},
+			{"name":"noop","description":"No parameters"}
+		]}],
+		"toolConfig":{"functionCallingConfig":{"mode":"ANY","allowedFunctionNames":["get_weather"]}},
+		"generationConfig":{"maxOutputTokens":100,"temperature":0.2,"frequencyPenalty":0.5,"presencePenalty":0,"responseMimeType":"application/json","thinkingConfig":{"thinkingBudget":4096,"includeThoughts":true}}
+	}`
+	testAssertJSONEqual(t, *captured, []byte(want))
+}
+
+func TestGeminiProvider_RejectsAudioAndDocuments(t *testing.T) {
+	srv, _, captured := newTestGeminiServer(t, 200, `{"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`)
+	provider := NewGeminiProvider("", WithGeminiBaseURL(srv.URL))
+	for _, part := range []ContentPart{
+		AudioPart([]byte("wav"), "audio/wav"),
+		DocumentPart("report", []byte("%PDF"), "application/pdf"),
+	} {
+		conv := NewConversation("gemini-2.5-flash")
+		conv.Messages = []Message{{Role: RoleUser, Content: []ContentPart{{Kind: ContentText, Text: "summarize"}, part}}}
+		_, err := provider.Send(context.Background(), &conv)
+		if !errors.Is(err, ErrConfig) {
+			t.Errorf("%s: err = %v, want ErrConfig", part.Kind, err)
+		}
+	}
+	if *captured != nil {
+		t.Error("request was sent")
+	}
+}
+
+func TestToGeminiRequest_ToolResultImages(t *testing.T) {
+	call := ToolCallData{ID: "c1", Name: "chart", Arguments: json.RawMessage(`{}`)}
+	conv := NewConversation("gemini-2.5-flash")
+	conv.Messages = []Message{
+		UserMessage("plot it"),
+		{Role: RoleAssistant, Content: []ContentPart{{Kind: ContentToolCall, ToolCall: &call}}},
+		call.ImageResult("rendered", ImageData{Data: []byte("png"), MediaType: "image/png"}),
+	}
+	data, err := json.Marshal(toGeminiRequest(&conv).Contents[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"role":"user","parts":[
+		{"functionResponse":{"id":"c1","name":"chart","response":{"content":"rendered"}}},
+		{"inlineData":{"mimeType":"image/png","data":"cG5n"}}
+	]}`
+	testAssertJSONEqual(t, data, []byte(want))
+}
+
+func TestToGeminiRequest_ResponseSchema(t *testing.T) {
+	conv := NewConversation("gemini-2.5-flash", WithResponseSchema("city", citySchema))
+	data, err := json.Marshal(toGeminiRequest(&conv).GenerationConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAssertJSONEqual(t, data, []byte(`{"responseMimeType":"application/json","responseJsonSchema":`+string(citySchema)+`}`))
+}
+
+func TestGeminiProvider_FunctionCallAndThoughts(t *testing.T) {
+	srv, _, _ := newTestGeminiServer(t, 200, `{
+		"candidates":[{"content":{"role":"model","parts":[
+			{"text":"Need the weather.","thought":true},
+			{"functionCall":{"name":"get_weather","args":{"location":"Paris"}},"thoughtSignature":"sig"}
+		]},"finishReason":"STOP"}],
+		"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"thoughtsTokenCount":7}
+	}`)
+
+	conv := NewConversation("gemini-2.5-pro")
+	conv.Messages = []Message{UserMessage("weather in Paris?")}
+	result, err := NewGeminiProvider("", WithGeminiBaseURL(srv.URL)).Send(context.Background(), &conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FinishReason != FinishReasonToolUse || result.RawFinishReason != "STOP" {
+		t.Errorf("FinishReason = %q (%q)", result.FinishReason, result.RawFinishReason)
+	}
+	if p := result.Message.Content[0]; p.Kind != ContentThinking || p.Thinking.Text != "Need the weather." {
+		t.Errorf("Content[0] = %+v", p)
+	}
+	calls := result.Message.ToolCalls()
+	if len(calls) != 1 || calls[0].Name != "get_weather" || calls[0].ID == "" {
+		t.Fatalf("ToolCalls = %+v", calls)
+	}
+	testAssertJSONEqual(t, calls[0].Arguments, []byte(`{"location":"Paris"}`))
+	if calls[0].Signature != "sig" {
+		t.Errorf("Signature = %q", calls[0].Signature)
+	}
+	if result.Usage.OutputTokens != 12 || result.Usage.ReasoningTokens != 7 {
+		t.Errorf("Usage = %+v", result.Usage)
+	}
+}
+
+func TestGeminiProvider_ThoughtSignatureRoundTrip(t *testing.T) {
+	srv, _, _ := newTestGeminiServer(t, 200, `{
+		"candidates":[{"content":{"role":"model","parts":[
+			{"text":"Need the weather.","thought":true,"thoughtSignature":"sig1"},
+			{"functionCall":{"name":"get_weather","args":{"location":"Paris"}},"thoughtSignature":"sig2"},
+			{"thoughtSignature":"sig3"}
+		]},"finishReason":"STOP"}]
+	}`)
+	conv := NewConversation("gemini-2.5-pro")
+	conv.Messages = []Message{UserMessage("weather in Paris?")}
+	result, err := NewGeminiProvider("", WithGeminiBaseURL(srv.URL)).Send(context.Background(), &conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := result.Message.ToolCalls()[0]
+	conv.Messages = append(conv.Messages, result.Message, call.Result("15C"))
+
+	data, err := json.Marshal(toGeminiRequest(&conv).Contents[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"role":"model","parts":[
+		{"text":"Need the weather.","thought":true,"thoughtSignature":"sig1"},
+		{"functionCall":{"id":"` + call.ID + `","name":"get_weather","args":{"location":"Paris"}},"thoughtSignature":"sig2"},
+		{"thought":true,"thoughtSignature":"sig3"}
+	]}`
+	testAssertJSONEqual(t, data, []byte(want))
+}
+
+func TestGeminiProvider_PromptBlocked(t *testing.T) {
+	srv, _, _ := newTestGeminiServer(t, 200, `{"promptFeedback":{"blockReason":"SAFETY"}}`)
+	conv := NewConversation("gemini-2.5-flash")
+	conv.Messages = []Message{UserMessage("something bad")}
+
+	result, err := NewGeminiProvider("", WithGeminiBaseURL(srv.URL)).Send(context.Background(), &conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FinishReason != FinishReasonContentFilter {
+		t.Errorf("FinishReason = %q", result.FinishReason)
+	}
+}
+
+func TestGeminiProvider_ErrorClassification(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   ErrorKind
+	}{
+		{429, `{"error":{"code":429,"message":"Resource exhausted","status":"RESOURCE_EXHAUSTED"}}`, ErrRateLimit},
+		{400, `{"error":{"code":400,"message":"The input token count (2000000) exceeds the maximum number of tokens allowed (1048576).","status":"INVALID_ARGUMENT"}}`, ErrContextLength},
+		{403, `{"error":{"code":403,"message":"API key not valid","status":"PERMISSION_DENIED"}}`, ErrAuthentication},
+		{500, `{"error":{"code":500,"message":"Internal error","status":"INTERNAL"}}`, ErrServer},
+		{200, `{"error":{"code":403,"message":"API key not valid","status":"PERMISSION_DENIED"}}`, ErrAuthentication},
+	}
+	for _, tt := range tests {
+		srv, _, _ := newTestGeminiServer(t, tt.status, tt.body)
+		conv := NewConversation("gemini-2.5-flash")
+		conv.Messages = []Message{UserMessage("hi")}
+		_, err := NewGeminiProvider("", WithGeminiBaseURL(srv.URL)).Send(context.Background(), &conv)
+		var llmErr *Error
+		if !errors.As(err, &llmErr) {
+			t.Fatalf("status %d: expected *Error, got %v", tt.status, err)
+		}
+		if llmErr.Kind != tt.want {
+			t.Errorf("status %d: Kind = %v, want %v", tt.status, llmErr.Kind, tt.want)
+		}
+	}
+}
+
+func TestMapGeminiFinishReason(t *testing.T) {
+	tests := []struct {
+		in   string
+		want FinishReason
+	}{
+		{"STOP", FinishReasonStop},
+		{"MAX_TOKENS", FinishReasonLength},
+		{"SAFETY", FinishReasonContentFilter},
+		{"RECITATION", FinishReasonContentFilter},
+		{"MALFORMED_FUNCTION_CALL", FinishReasonError},
+		{"OTHER", FinishReason("OTHER")},
+	}
+	for _, tt := range tests {
+		if got := mapGeminiFinishReason(tt.in); got != tt.want {
+			t.Errorf("mapGeminiFinishReason(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -3,13 +3,21 @@ package llm
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 )
 
+// Default base URLs of local OpenAI-compatible servers.
+const (
+	OllamaBaseURL   = "http://localhost:11434"
+	LlamaCppBaseURL = "http://localhost:8080"
+)
+
 // OpenAIProvider implements Provider using the OpenAI-compatible chat
 // completions API (e.g. llama.cpp, vLLM, Ollama, or OpenAI itself).
 type OpenAIProvider struct {
@@ -51,6 +59,9 @@ func (p *OpenAIProvider) Send(ctx context.Context, conv *Conversation) (*Respons
 	if err != nil {
 		return nil, &Error{Kind: ErrConfig, Message: "failed to marshal request", Cause: err}
 	}
+	if jsonData, err = applyProviderOptions(conv, "openai", jsonData); err != nil {
+		return nil, err
+	}
 
 	url := p.baseURL + "/v1/chat/completions"
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
@@ -64,17 +75,19 @@ func (p *OpenAIProvider) Send(ctx context.Context, conv *Conversation) (*Respons
 
 	httpResp, err := p.httpClient.Do(req)
 	if err != nil {
+		captureHTTP(ctx, "openai", conv.Model, url, 0, jsonData, nil, err)
 		return nil, &Error{Kind: ErrServer, Message: err.Error(), Cause: err}
 	}
 	defer httpResp.Body.Close()
 
 	body, err := io.ReadAll(httpResp.Body)
+	captureHTTP(ctx, "openai", conv.Model, url, httpResp.StatusCode, jsonData, body, err)
 	if err != nil {
 		return nil, &Error{Kind: ErrServer, Message: "failed to read response", Cause: err}
 	}
 
 	if httpResp.StatusCode != http.StatusOK {
-		return nil, classifyOpenAIError(httpResp.StatusCode, body)
+		return nil, classifyHTTPError(httpResp.StatusCode, body)
 	}
 
 	var chatResp chatCompletionResponse
@@ -82,7 +95,12 @@ func (p *OpenAIProvider) Send(ctx context.Context, conv *Conversation) (*Respons
 		return nil, &Error{Kind: ErrServer, Message: "failed to decode response", Cause: err}
 	}
 
-	return fromOpenAIResponse(chatResp)
+	resp, err := fromOpenAIResponse(chatResp)
+	if err != nil {
+		return nil, err
+	}
+	resp.Raw, resp.RawRequest = json.RawMessage(body), rawRequest(ctx, json.RawMessage(jsonData))
+	return resp, nil
 }
 
 // --- request/response wire types (unexported) ---
@@ -96,16 +114,112 @@ type chatCompletionRequest struct {
 	Temperature *float64      `json:"temperature,omitempty"`
 	TopP        *float64      `json:"top_p,omitempty"`
 	Stop        []string      `json:"stop,omitempty"`
+
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+
+	ResponseFormat *chatResponseFormat `json:"response_format,omitempty"`
+
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+}
+
+type chatResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema *chatJSONSchema `json:"json_schema,omitempty"`
+}
+
+type chatJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema,omitempty"`
+	Strict bool            `json:"strict,omitempty"`
 }
 
 type chatMessage struct {
 	Role             string         `json:"role"`
-	Content          *string        `json:"content"`                     // pointer so we can send null
+	Content          chatContent    `json:"content"`
 	ReasoningContent string         `json:"reasoning_content,omitempty"` // llama.cpp extended field
 	ToolCalls        []chatToolCall `json:"tool_calls,omitempty"`
 	ToolCallID       string         `json:"tool_call_id,omitempty"`
 }
 
+// chatContent is message content: a string, null, or, for messages with
+// images, an array of content parts. Plain text is sent as a string, which
+// every OpenAI-compatible server accepts.
+type chatContent struct {
+	Text  *string
+	Parts []chatContentPart
+}
+
+type chatContentPart struct {
+	Type     string        `json:"type"` // "text" or "image_url"
+	Text     string        `json:"text,omitempty"`
+	ImageURL *chatImageURL `json:"image_url,omitempty"`
+}
+
+type chatImageURL struct {
+	URL string `json:"url"` // an http(s) or base64 data URL
+}
+
+func textContent(s string) chatContent {
+	return chatContent{Text: &s}
+}
+
+func (c chatContent) MarshalJSON() ([]byte, error) {
+	if c.Parts != nil {
+		return json.Marshal(c.Parts)
+	}
+	return json.Marshal(c.Text)
+}
+
+func (c *chatContent) UnmarshalJSON(data []byte) error {
+	*c = chatContent{}
+	if len(data) > 0 && data[0] == '[' {
+		return json.Unmarshal(data, &c.Parts)
+	}
+	return json.Unmarshal(data, &c.Text)
+}
+
+// String returns the text of the content, joining text parts.
+func (c chatContent) String() string {
+	if c.Text != nil {
+		return *c.Text
+	}
+	var b strings.Builder
+	for _, p := range c.Parts {
+		if p.Type == "text" {
+			b.WriteString(p.Text)
+		}
+	}
+	return b.String()
+}
+
+// toChatContent translates user message content, using content parts only
+// if it holds images. Images are sent by URL, or as data URLs.
+func toChatContent(m Message) chatContent {
+	hasImages := slices.ContainsFunc(m.Content, func(p ContentPart) bool {
+		return p.Kind == ContentImage && p.Image != nil
+	})
+	if !hasImages {
+		return textContent(m.Text())
+	}
+	parts := []chatContentPart{}
+	for _, p := range m.Content {
+		switch {
+		case p.Kind == ContentText:
+			parts = append(parts, chatContentPart{Type: "text", Text: p.Text})
+		case p.Kind == ContentImage && p.Image != nil:
+			url := p.Image.URL
+			if len(p.Image.Data) > 0 {
+				url = "data:" + p.Image.MediaType + ";base64," + base64.StdEncoding.EncodeToString(p.Image.Data)
+			}
+			if url != "" {
+				parts = append(parts, chatContentPart{Type: "image_url", ImageURL: &chatImageURL{URL: url}})
+			}
+		}
+	}
+	return chatContent{Parts: parts}
+}
+
 type chatToolCall struct {
 	ID       string           `json:"id"`
 	Type     string           `json:"type"`
@@ -131,23 +245,42 @@ type chatFunction struct {
 type chatCompletionResponse struct {
 	Choices []chatChoice `json:"choices"`
 	Usage   *chatUsage   `json:"usage,omitempty"`
+	Error   *chatError   `json:"error,omitempty"` // sent with a 200 status by some gateways
 }
 
 type chatChoice struct {
 	Message      chatMessage `json:"message"`
 	FinishReason string      `json:"finish_reason"`
+
+	// StopReason is vLLM's extended field: the stop string or token ID
+	// that ended the completion, or null.
+	StopReason json.RawMessage `json:"stop_reason,omitempty"`
 }
 
 type chatUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
+	PromptTokens            int                    `json:"prompt_tokens"`
+	CompletionTokens        int                    `json:"completion_tokens"`
+	PromptCacheHitTokens    int                    `json:"prompt_cache_hit_tokens,omitempty"` // DeepSeek extended field
+	PromptTokensDetails     *chatPromptDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *chatCompletionDetails `json:"completion_tokens_details,omitempty"`
+}
+
+type chatPromptDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+type chatCompletionDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
 }
 
 type chatErrorResponse struct {
-	Error struct {
-		Message string `json:"message"`
-		Type    string `json:"type"`
-	} `json:"error"`
+	Error chatError `json:"error"`
+}
+
+type chatError struct {
+	Message string          `json:"message"`
+	Type    string          `json:"type"`
+	Code    json.RawMessage `json:"code,omitempty"` // an HTTP status from Gemini and most gateways, a string from OpenAI
 }
 
 // --- translation ---
@@ -159,14 +292,24 @@ func toOpenAIRequest(conv *Conversation) chatCompletionRequest {
 		Temperature: conv.Config.Temperature,
 		TopP:        conv.Config.TopP,
 		Stop:        conv.Config.StopSequences,
+
+		FrequencyPenalty: conv.Config.FrequencyPenalty,
+		PresencePenalty:  conv.Config.PresencePenalty,
+
+		ReasoningEffort: string(conv.Config.ReasoningEffort),
+	}
+	if rf := conv.Config.ResponseFormat; rf != nil {
+		req.ResponseFormat = &chatResponseFormat{Type: string(rf.Type)}
+		if rf.Type == ResponseFormatJSONSchema {
+			req.ResponseFormat.JSONSchema = &chatJSONSchema{Name: rf.schemaName(), Schema: rf.Schema, Strict: rf.Strict}
+		}
 	}
 
 	// System prompt as a single system message.
 	if len(conv.System) > 0 {
-		text := strings.Join(conv.System, "\n\n")
 		req.Messages = append(req.Messages, chatMessage{
 			Role:    "system",
-			Content: &text,
+			Content: textContent(strings.Join(conv.System, "\n\n")),
 		})
 	}
 
@@ -174,18 +317,16 @@ func toOpenAIRequest(conv *Conversation) chatCompletionRequest {
 	for _, m := range conv.Messages {
 		switch m.Role {
 		case RoleUser:
-			text := m.Text()
 			req.Messages = append(req.Messages, chatMessage{
 				Role:    "user",
-				Content: &text,
+				Content: toChatContent(m),
 			})
 
 		case RoleAssistant:
 			cm := chatMessage{Role: "assistant"}
 			// Collect text content.
-			text := m.Text()
-			if text != "" {
-				cm.Content = &text
+			if text := m.Text(); text != "" {
+				cm.Content = textContent(text)
 			}
 			// Collect tool calls.
 			for _, tc := range m.ToolCalls() {
@@ -203,10 +344,9 @@ func toOpenAIRequest(conv *Conversation) chatCompletionRequest {
 		case RoleTool:
 			for _, p := range m.Content {
 				if p.Kind == ContentToolResult && p.ToolResult != nil {
-					content := p.ToolResult.Content
 					req.Messages = append(req.Messages, chatMessage{
 						Role:       "tool",
-						Content:    &content,
+						Content:    textContent(p.ToolResult.Text()),
 						ToolCallID: p.ToolResult.ToolCallID,
 					})
 				}
@@ -247,6 +387,9 @@ func toOpenAIRequest(conv *Conversation) chatCompletionRequest {
 }
 
 func fromOpenAIResponse(resp chatCompletionResponse) (*Response, error) {
+	if resp.Error != nil {
+		return nil, resp.Error.classify()
+	}
 	if len(resp.Choices) == 0 {
 		return nil, &Error{Kind: ErrServer, Message: "no choices in response"}
 	}
@@ -263,10 +406,10 @@ func fromOpenAIResponse(resp chatCompletionResponse) (*Response, error) {
 	}
 
 	// Text content.
-	if choice.Message.Content != nil && *choice.Message.Content != "" {
+	if text := choice.Message.Content.String(); text != "" {
 		msg.Content = append(msg.Content, ContentPart{
 			Kind: ContentText,
-			Text: *choice.Message.Content,
+			Text: text,
 		})
 	}
 
@@ -288,17 +431,38 @@ func fromOpenAIResponse(resp chatCompletionResponse) (*Response, error) {
 	// Usage.
 	usage := Usage{}
 	if resp.Usage != nil {
-		usage.InputTokens = resp.Usage.PromptTokens
 		usage.OutputTokens = resp.Usage.CompletionTokens
+		usage.CacheReadTokens = resp.Usage.PromptCacheHitTokens
+		if d := resp.Usage.PromptTokensDetails; d != nil && d.CachedTokens > 0 {
+			usage.CacheReadTokens = d.CachedTokens
+		}
+		// prompt_tokens includes cached tokens; report them only as cache
+		// reads, as Bedrock does, so they are not billed twice.
+		usage.InputTokens = resp.Usage.PromptTokens - usage.CacheReadTokens
+		if d := resp.Usage.CompletionTokensDetails; d != nil {
+			usage.ReasoningTokens = d.ReasoningTokens
+		}
 	}
 
 	return &Response{
-		Message:      msg,
-		FinishReason: reason,
-		Usage:        usage,
+		Message:         msg,
+		FinishReason:    reason,
+		RawFinishReason: choice.FinishReason,
+		StopSequence:    choice.stopSequence(),
+		Usage:           usage,
 	}, nil
 }
 
+// stopSequence returns the stop string that ended the choice. Only vLLM
+// reports it; a stop token ID is not a sequence and is ignored.
+func (c chatChoice) stopSequence() string {
+	var s string
+	if json.Unmarshal(c.StopReason, &s) != nil {
+		return ""
+	}
+	return s
+}
+
 func mapOpenAIFinishReason(reason string) FinishReason {
 	switch reason {
 	case "stop":
@@ -314,37 +478,72 @@ func mapOpenAIFinishReason(reason string) FinishReason {
 	}
 }
 
-func classifyOpenAIError(statusCode int, body []byte) error {
+// classifyHTTPError classifies a non-200 response from an HTTP provider whose
+// error body has the common {"error":{"message":...}} shape.
+func classifyHTTPError(statusCode int, body []byte) error {
 	var errResp chatErrorResponse
 	_ = json.Unmarshal(body, &errResp) // best-effort parse
 	msg := errResp.Error.Message
 	if msg == "" {
 		msg = fmt.Sprintf("HTTP %d", statusCode)
 	}
+	e := httpError(statusCode, msg)
+	e.Cause = fmt.Errorf("HTTP %d: %s", statusCode, msg)
+	return e
+}
 
-	var kind ErrorKind
-	switch statusCode {
-	case 400:
-		lower := strings.ToLower(msg)
-		switch {
-		case strings.Contains(lower, "context length") || strings.Contains(lower, "too many tokens"):
-			kind = ErrContextLength
-		default:
-			kind = ErrInvalidRequest
-		}
-	case 401, 403:
-		kind = ErrAuthentication
-	case 404:
-		kind = ErrNotFound
-	case 429:
-		kind = ErrRateLimit
-	default:
-		kind = ErrServer
+// chatErrorKinds classifies the string codes and types of error documents,
+// as sent by OpenAI and gateways that mimic it, when there is no HTTP
+// status to go by.
+var chatErrorKinds = map[string]ErrorKind{
+	"rate_limit_exceeded":     ErrRateLimit,
+	"rate_limit_error":        ErrRateLimit,
+	"insufficient_quota":      ErrRateLimit,
+	"context_length_exceeded": ErrContextLength,
+	"invalid_request_error":   ErrInvalidRequest,
+	"invalid_api_key":         ErrAuthentication,
+	"authentication_error":    ErrAuthentication,
+	"permission_error":        ErrAuthentication,
+	"model_not_found":         ErrNotFound,
+	"not_found_error":         ErrNotFound,
+	"overloaded_error":        ErrCapacity,
+	"server_error":            ErrServer,
+	"api_error":               ErrServer,
+}
+
+// classify classifies an error document returned in place of a response
+// with a 200 status, by the HTTP status in its code if it has one, or else
+// by its string code or type.
+func (e *chatError) classify() error {
+	msg := e.Message
+	if msg == "" {
+		msg = "error in response body"
 	}
+	cause := fmt.Errorf("error in HTTP 200 response: %s", msg)
 
-	return &Error{
-		Kind:    kind,
-		Message: msg,
-		Cause:   fmt.Errorf("HTTP %d: %s", statusCode, msg),
+	var code int
+	if json.Unmarshal(e.Code, &code) == nil && code >= 400 {
+		err := httpError(code, msg)
+		err.Cause = cause
+		return err
+	}
+	var name string
+	_ = json.Unmarshal(e.Code, &name)
+	kind, ok := chatErrorKinds[name]
+	if !ok {
+		kind, ok = chatErrorKinds[e.Type]
 	}
+	switch {
+	case !ok:
+		kind = ErrServer
+	case kind == ErrInvalidRequest:
+		kind = httpError(http.StatusBadRequest, msg).Kind // may still be a context length error
+	}
+	return &Error{Kind: kind, Message: msg, Cause: cause}
+}
+
+// httpError returns an *Error for msg, classified by the HTTP status it
+// came with.
+func httpError(statusCode int, msg string) *Error {
+	return &Error{Kind: ErrorKindForStatus(statusCode, msg), Message: msg}
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -29,7 +30,7 @@ func newTestOpenAIServer(t *testing.T, statusCode int, respBody any) (*httptest.
 func TestOpenAIProvider_SimpleText(t *testing.T) {
 	resp := chatCompletionResponse{
 		Choices: []chatChoice{{
-			Message:      chatMessage{Role: "assistant", Content: strPtr("Hello!")},
+			Message:      chatMessage{Role: "assistant", Content: textContent("Hello!")},
 			FinishReason: "stop",
 		}},
 		Usage: &chatUsage{PromptTokens: 8, CompletionTokens: 3},
@@ -66,7 +67,7 @@ func TestOpenAIProvider_ReasoningContent(t *testing.T) {
 		Choices: []chatChoice{{
 			Message: chatMessage{
 				Role:             "assistant",
-				Content:          strPtr("42"),
+				Content:          textContent("42"),
 				ReasoningContent: "Let me think step by step...",
 			},
 			FinishReason: "stop",
@@ -102,6 +103,44 @@ func TestOpenAIProvider_ReasoningContent(t *testing.T) {
 	}
 }
 
+func TestOpenAIProvider_StopSequence(t *testing.T) {
+	srv, _ := newTestOpenAIServer(t, 200, json.RawMessage(`{"choices":[{"message":{"role":"assistant","content":"Step 1"},"finish_reason":"stop","stop_reason":"STEP 2"}]}`))
+	conv := NewConversation("llama3", WithStopSequences("END", "STEP 2"))
+	conv.Messages = []Message{UserMessage("hi")}
+
+	result, err := NewOpenAIProvider(srv.URL).Send(context.Background(), &conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.StopSequence != "STEP 2" {
+		t.Errorf("StopSequence = %q", result.StopSequence)
+	}
+
+	// A stop token ID is not a sequence.
+	srv, _ = newTestOpenAIServer(t, 200, json.RawMessage(`{"choices":[{"message":{"role":"assistant","content":"Done"},"finish_reason":"stop","stop_reason":128009}]}`))
+	if result, err := NewOpenAIProvider(srv.URL).Send(context.Background(), &conv); err != nil || result.StopSequence != "" {
+		t.Errorf("StopSequence = %q, %v", result.StopSequence, err)
+	}
+}
+
+func TestOpenAIProvider_CachedTokensBilledOnce(t *testing.T) {
+	srv, _ := newTestOpenAIServer(t, 200, json.RawMessage(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1000,"completion_tokens":10,"prompt_tokens_details":{"cached_tokens":800}}}`))
+	conv := NewConversation("gpt-4o")
+	conv.Messages = []Message{UserMessage("hi")}
+
+	result, err := NewOpenAIProvider(srv.URL).Send(context.Background(), &conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Usage.InputTokens != 200 || result.Usage.CacheReadTokens != 800 {
+		t.Errorf("Usage = %+v", result.Usage)
+	}
+	// $1/M input, $0.10/M cache reads: 200 uncached plus 800 cached tokens.
+	if got, want := (Pricing{Input: 1, CacheRead: 0.1}).Cost(result.Usage), (200*1+800*0.1)/1e6; math.Abs(got-want) > 1e-12 {
+		t.Errorf("Cost = %g, want %g", got, want)
+	}
+}
+
 func TestOpenAIProvider_ToolCallResponse(t *testing.T) {
 	resp := chatCompletionResponse{
 		Choices: []chatChoice{{
@@ -130,8 +169,8 @@ func TestOpenAIProvider_ToolCallResponse(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result.FinishReason != FinishReasonToolUse {
-		t.Errorf("FinishReason = %q, want tool_use", result.FinishReason)
+	if result.FinishReason != FinishReasonToolUse || result.RawFinishReason != "tool_calls" {
+		t.Errorf("FinishReason = %q (%q), want tool_use", result.FinishReason, result.RawFinishReason)
 	}
 
 	calls := result.Message.ToolCalls()
@@ -157,7 +196,7 @@ func TestOpenAIProvider_ToolCallResponse(t *testing.T) {
 func TestOpenAIProvider_RequestFormat(t *testing.T) {
 	resp := chatCompletionResponse{
 		Choices: []chatChoice{{
-			Message:      chatMessage{Role: "assistant", Content: strPtr("ok")},
+			Message:      chatMessage{Role: "assistant", Content: textContent("ok")},
 			FinishReason: "stop",
 		}},
 	}
@@ -170,6 +209,9 @@ func TestOpenAIProvider_RequestFormat(t *testing.T) {
 		WithTools(weatherTool),
 		WithMaxTokens(100),
 		WithTemperature(0.7),
+		WithFrequencyPenalty(0.5),
+		WithResponseFormat(ResponseFormat{Type: ResponseFormatJSON}),
+		WithReasoningEffort(ReasoningEffortLow),
 	)
 	conv.Messages = []Message{UserMessage("hello")}
 
@@ -189,6 +231,15 @@ func TestOpenAIProvider_RequestFormat(t *testing.T) {
 	if req["max_tokens"] != float64(100) {
 		t.Errorf("max_tokens = %v", req["max_tokens"])
 	}
+	if _, ok := req["presence_penalty"]; req["frequency_penalty"] != 0.5 || ok {
+		t.Errorf("frequency_penalty = %v, presence_penalty = %v", req["frequency_penalty"], req["presence_penalty"])
+	}
+	if rf, _ := req["response_format"].(map[string]any); rf["type"] != "json_object" {
+		t.Errorf("response_format = %v", req["response_format"])
+	}
+	if req["reasoning_effort"] != "low" {
+		t.Errorf("reasoning_effort = %v", req["reasoning_effort"])
+	}
 
 	msgs, ok := req["messages"].([]any)
 	if !ok {
@@ -216,10 +267,72 @@ func TestOpenAIProvider_RequestFormat(t *testing.T) {
 	}
 }
 
+func TestToOpenAIRequest_ResponseSchema(t *testing.T) {
+	conv := NewConversation("gpt-4o", WithResponseFormat(ResponseFormat{Type: ResponseFormatJSONSchema, Schema: citySchema, Strict: true}))
+	data, err := json.Marshal(toOpenAIRequest(&conv).ResponseFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAssertJSONEqual(t, data, []byte(`{"type":"json_schema","json_schema":{"name":"respond","schema":`+string(citySchema)+`,"strict":true}}`))
+}
+
+func TestOpenAIProvider_ImageRequest(t *testing.T) {
+	resp := chatCompletionResponse{
+		Choices: []chatChoice{{
+			Message:      chatMessage{Role: "assistant", Content: textContent("a cat")},
+			FinishReason: "stop",
+		}},
+	}
+	srv, captured := newTestOpenAIServer(t, 200, resp)
+
+	conv := NewConversation("gpt-4o")
+	conv.Messages = []Message{
+		{Role: RoleUser, Content: []ContentPart{
+			{Kind: ContentText, Text: "What are these?"},
+			{Kind: ContentImage, Image: &ImageData{Data: []byte{0x89, 'P', 'N', 'G'}, MediaType: "image/png"}},
+			{Kind: ContentImage, Image: &ImageData{URL: "https://example.com/cat.jpg"}},
+		}},
+		AssistantMessage("Two cats."),
+		UserMessage("Thanks"),
+	}
+	if _, err := NewOpenAIProvider(srv.URL).Send(context.Background(), &conv); err != nil {
+		t.Fatal(err)
+	}
+
+	var req struct {
+		Messages []json.RawMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(*captured, &req); err != nil {
+		t.Fatal(err)
+	}
+	testAssertJSONEqual(t, req.Messages[0], []byte(`{"role": "user", "content": [
+		{"type": "text", "text": "What are these?"},
+		{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw=="}},
+		{"type": "image_url", "image_url": {"url": "https://example.com/cat.jpg"}}
+	]}`))
+	// Messages without images keep string content.
+	testAssertJSONEqual(t, req.Messages[2], []byte(`{"role": "user", "content": "Thanks"}`))
+}
+
+func TestOpenAIProvider_ArrayContentResponse(t *testing.T) {
+	var resp chatCompletionResponse
+	body := `{"choices": [{"message": {"role": "assistant", "content": [{"type": "text", "text": "Hello, "}, {"type": "text", "text": "world"}]}, "finish_reason": "stop"}]}`
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	result, err := fromOpenAIResponse(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Message.Text() != "Hello, world" {
+		t.Errorf("Text = %q", result.Message.Text())
+	}
+}
+
 func TestOpenAIProvider_ToolResultRequest(t *testing.T) {
 	resp := chatCompletionResponse{
 		Choices: []chatChoice{{
-			Message:      chatMessage{Role: "assistant", Content: strPtr("It's sunny.")},
+			Message:      chatMessage{Role: "assistant", Content: textContent("It's sunny.")},
 			FinishReason: "stop",
 		}},
 	}
@@ -276,7 +389,7 @@ func TestOpenAIProvider_APIKey(t *testing.T) {
 		w.Header().Set("Content-Type", "application/json")
 		resp := chatCompletionResponse{
 			Choices: []chatChoice{{
-				Message:      chatMessage{Role: "assistant", Content: strPtr("ok")},
+				Message:      chatMessage{Role: "assistant", Content: textContent("ok")},
 				FinishReason: "stop",
 			}},
 		}
@@ -340,6 +453,60 @@ func TestOpenAIProvider_ErrorClassification(t *testing.T) {
 			body:     `{"error":{"message":"internal error","type":"server_error"}}`,
 			wantKind: ErrServer,
 		},
+		{
+			name:     "503 unavailable",
+			status:   503,
+			body:     `{"error":{"message":"overloaded","type":"server_error"}}`,
+			wantKind: ErrCapacity,
+		},
+		{
+			name:     "504 gateway timeout",
+			status:   504,
+			body:     `upstream request timeout`,
+			wantKind: ErrTimeout,
+		},
+		{
+			name:     "200 with gateway error",
+			status:   200,
+			body:     `{"error":{"message":"Rate limit exceeded upstream","code":429}}`,
+			wantKind: ErrRateLimit,
+		},
+		{
+			name:     "200 with error and no status",
+			status:   200,
+			body:     `{"error":{"message":"upstream failed","type":"server_error","code":"upstream_error"}}`,
+			wantKind: ErrServer,
+		},
+		{
+			name:     "200 with rate limit code",
+			status:   200,
+			body:     `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`,
+			wantKind: ErrRateLimit,
+		},
+		{
+			name:     "200 with context length code",
+			status:   200,
+			body:     `{"error":{"message":"Too long","type":"invalid_request_error","code":"context_length_exceeded"}}`,
+			wantKind: ErrContextLength,
+		},
+		{
+			name:     "200 with invalid request type",
+			status:   200,
+			body:     `{"error":{"message":"Unknown parameter","type":"invalid_request_error","code":null}}`,
+			wantKind: ErrInvalidRequest,
+		},
+		{
+			name:     "200 with invalid request about context length",
+			status:   200,
+			body:     `{"error":{"message":"maximum context length exceeded","type":"invalid_request_error"}}`,
+			wantKind: ErrContextLength,
+		},
+		{
+			name:     "200 with unknown code and type",
+			status:   200,
+			body:     `{"error":{"message":"something odd","type":"mystery","code":"odd"}}`,
+			wantKind: ErrServer,
+		},
 	}
 
 	for _, tt := range tests {
@@ -406,7 +573,7 @@ func TestOpenAIProvider_FinishReasons(t *testing.T) {
 		t.Run(tt.openai, func(t *testing.T) {
 			resp := chatCompletionResponse{
 				Choices: []chatChoice{{
-					Message:      chatMessage{Role: "assistant", Content: strPtr("ok")},
+					Message:      chatMessage{Role: "assistant", Content: textContent("ok")},
 					FinishReason: tt.openai,
 				}},
 			}
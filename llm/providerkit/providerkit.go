@@ -0,0 +1,124 @@
+// Package providerkit exposes building blocks for writing custom llm.Provider
+// implementations: content-part translation, role merging, finish-reason
+// mapping, error classification, and usage math.
+package providerkit
+
+import (
+	"strings"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+// Content is a message's content parts grouped by kind, in original order
+// within each group.
+type Content struct {
+	Text        string // all text parts concatenated
+	Images      []llm.ImageData
+	ToolCalls   []llm.ToolCallData
+	ToolResults []llm.ToolResultData
+	Thinking    []llm.ThinkingData
+}
+
+// SplitContent groups the content parts of m by kind. Parts whose payload
+// pointer is nil are skipped.
+func SplitContent(m llm.Message) Content {
+	var c Content
+	var text strings.Builder
+	for _, p := range m.Content {
+		switch p.Kind {
+		case llm.ContentText:
+			text.WriteString(p.Text)
+		case llm.ContentImage:
+			if p.Image != nil {
+				c.Images = append(c.Images, *p.Image)
+			}
+		case llm.ContentToolCall:
+			if p.ToolCall != nil {
+				c.ToolCalls = append(c.ToolCalls, *p.ToolCall)
+			}
+		case llm.ContentToolResult:
+			if p.ToolResult != nil {
+				c.ToolResults = append(c.ToolResults, *p.ToolResult)
+			}
+		case llm.ContentThinking:
+			if p.Thinking != nil {
+				c.Thinking = append(c.Thinking, *p.Thinking)
+			}
+		}
+	}
+	c.Text = text.String()
+	return c
+}
+
+// JoinSystem joins the conversation's system prompts with sep, for APIs that
+// accept a single system string.
+func JoinSystem(conv *llm.Conversation, sep string) string {
+	return strings.Join(conv.System, sep)
+}
+
+// Turn is a run of adjacent messages that map to the same provider role.
+type Turn struct {
+	Role    string
+	Content []llm.ContentPart
+}
+
+// MergeRoles maps each message to a provider role name and merges adjacent
+// messages with the same provider role into one Turn. Many APIs require
+// strictly alternating roles, or expect all tool results for an assistant
+// turn in a single user message; mapping RoleTool to "user" handles both.
+// Messages for which roleOf returns "" are dropped.
+func MergeRoles(msgs []llm.Message, roleOf func(llm.Role) string) []Turn {
+	var turns []Turn
+	for _, m := range msgs {
+		role := roleOf(m.Role)
+		if role == "" {
+			continue
+		}
+		if n := len(turns); n > 0 && turns[n-1].Role == role {
+			turns[n-1].Content = append(turns[n-1].Content, m.Content...)
+			continue
+		}
+		turns = append(turns, Turn{Role: role, Content: append([]llm.ContentPart(nil), m.Content...)})
+	}
+	return turns
+}
+
+// FinishReasons maps provider-specific finish reason strings to
+// llm.FinishReason values.
+type FinishReasons map[string]llm.FinishReason
+
+// Map returns the mapped finish reason, or raw itself when it is unknown,
+// so unexpected values remain visible to callers.
+func (f FinishReasons) Map(raw string) llm.FinishReason {
+	if r, ok := f[raw]; ok {
+		return r
+	}
+	return llm.FinishReason(raw)
+}
+
+// ClassifyHTTPStatus maps an HTTP status code and error message to an
+// llm.ErrorKind, as llm.ErrorKindForStatus does for the built-in providers.
+func ClassifyHTTPStatus(status int, message string) llm.ErrorKind {
+	return llm.ErrorKindForStatus(status, message)
+}
+
+// UsageFromPrompt builds an llm.Usage from APIs that report prompt tokens
+// inclusive of cached tokens. Cached tokens are reported as cache reads and
+// subtracted from InputTokens, matching how Bedrock reports usage.
+func UsageFromPrompt(promptTokens, cachedTokens, completionTokens, reasoningTokens int) llm.Usage {
+	return llm.Usage{
+		InputTokens:     promptTokens - cachedTokens,
+		OutputTokens:    completionTokens,
+		CacheReadTokens: cachedTokens,
+		ReasoningTokens: reasoningTokens,
+	}
+}
+
+// SumUsage adds up any number of usage values.
+func SumUsage(us ...llm.Usage) llm.Usage {
+	var total llm.Usage
+	for _, u := range us {
+		total = total.Add(u)
+	}
+	return total
+}
@@ -0,0 +1,109 @@
+package providerkit
+
+import (
+	"testing"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+func TestSplitContent(t *testing.T) {
+	m := llm.Message{Role: llm.RoleAssistant, Content: []llm.ContentPart{
+		{Kind: llm.ContentThinking, Thinking: &llm.ThinkingData{Text: "hmm"}},
+		{Kind: llm.ContentText, Text: "Hello "},
+		{Kind: llm.ContentToolCall, ToolCall: &llm.ToolCallData{ID: "c1", Name: "f"}},
+		{Kind: llm.ContentText, Text: "world"},
+		{Kind: llm.ContentImage},
+	}}
+	c := SplitContent(m)
+	if c.Text != "Hello world" {
+		t.Errorf("Text = %q", c.Text)
+	}
+	if len(c.ToolCalls) != 1 || c.ToolCalls[0].ID != "c1" {
+		t.Errorf("ToolCalls = %+v", c.ToolCalls)
+	}
+	if len(c.Thinking) != 1 || c.Thinking[0].Text != "hmm" {
+		t.Errorf("Thinking = %+v", c.Thinking)
+	}
+	if len(c.Images) != 0 {
+		t.Errorf("Images = %+v, nil payloads should be skipped", c.Images)
+	}
+}
+
+func TestMergeRoles(t *testing.T) {
+	msgs := []llm.Message{
+		llm.SystemMessage("sys"),
+		llm.UserMessage("go"),
+		{Role: llm.RoleAssistant, Content: []llm.ContentPart{
+			{Kind: llm.ContentToolCall, ToolCall: &llm.ToolCallData{ID: "c1"}},
+			{Kind: llm.ContentToolCall, ToolCall: &llm.ToolCallData{ID: "c2"}},
+		}},
+		llm.ToolResultMessage("c1", "one", false),
+		llm.ToolResultMessage("c2", "two", false),
+		llm.UserMessage("and?"),
+	}
+	roleOf := func(r llm.Role) string {
+		switch r {
+		case llm.RoleUser, llm.RoleTool:
+			return "user"
+		case llm.RoleAssistant:
+			return "assistant"
+		}
+		return ""
+	}
+	turns := MergeRoles(msgs, roleOf)
+	if len(turns) != 3 {
+		t.Fatalf("turns len = %d, want 3: %+v", len(turns), turns)
+	}
+	if turns[2].Role != "user" || len(turns[2].Content) != 3 {
+		t.Errorf("turns[2] = %+v", turns[2])
+	}
+	if len(msgs[3].Content) != 1 {
+		t.Error("MergeRoles must not modify the input messages")
+	}
+}
+
+func TestFinishReasonsMap(t *testing.T) {
+	m := FinishReasons{"end_turn": llm.FinishReasonStop, "max_tokens": llm.FinishReasonLength}
+	if got := m.Map("end_turn"); got != llm.FinishReasonStop {
+		t.Errorf("Map(end_turn) = %q", got)
+	}
+	if got := m.Map("weird"); got != "weird" {
+		t.Errorf("Map(weird) = %q, want passthrough", got)
+	}
+}
+
+func TestClassifyHTTPStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		msg    string
+		want   llm.ErrorKind
+	}{
+		{400, "bad field", llm.ErrInvalidRequest},
+		{400, "maximum context length exceeded", llm.ErrContextLength},
+		{400, "This exceeds the maximum number of tokens allowed", llm.ErrContextLength},
+		{401, "", llm.ErrAuthentication},
+		{403, "", llm.ErrAuthentication},
+		{404, "", llm.ErrNotFound},
+		{429, "", llm.ErrRateLimit},
+		{408, "", llm.ErrTimeout},
+		{500, "", llm.ErrServer},
+		{503, "", llm.ErrCapacity},
+		{504, "", llm.ErrTimeout},
+	}
+	for _, tt := range tests {
+		if got := ClassifyHTTPStatus(tt.status, tt.msg); got != tt.want {
+			t.Errorf("ClassifyHTTPStatus(%d, %q) = %v, want %v", tt.status, tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestUsageMath(t *testing.T) {
+	u := UsageFromPrompt(100, 80, 20, 5)
+	if u.InputTokens != 20 || u.CacheReadTokens != 80 || u.OutputTokens != 20 || u.ReasoningTokens != 5 {
+		t.Errorf("UsageFromPrompt = %+v", u)
+	}
+	total := SumUsage(u, llm.Usage{InputTokens: 1, OutputTokens: 2})
+	if total.InputTokens != 21 || total.OutputTokens != 22 {
+		t.Errorf("SumUsage = %+v", total)
+	}
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// WithProviderOptions sets the provider options config for provider, a
+// request format name: "openai" or "gemini".
+func WithProviderOptions(provider string, opts json.RawMessage) ConversationOption {
+	return func(c *Conversation) {
+		if c.Config.ProviderOptions == nil {
+			c.Config.ProviderOptions = make(map[string]json.RawMessage)
+		}
+		c.Config.ProviderOptions[provider] = opts
+	}
+}
+
+// applyProviderOptions merges conv's options for provider into body, a
+// marshaled request, as a JSON merge patch (RFC 7396).
+func applyProviderOptions(conv *Conversation, provider string, body []byte) ([]byte, error) {
+	opts := conv.Config.ProviderOptions[provider]
+	if len(opts) == 0 {
+		return body, nil
+	}
+	var patch map[string]any
+	if err := json.Unmarshal(opts, &patch); err != nil || patch == nil {
+		return nil, &Error{Kind: ErrConfig, Message: fmt.Sprintf("%s provider options are not a JSON object", provider), Cause: err}
+	}
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, &Error{Kind: ErrConfig, Message: "failed to apply provider options", Cause: err}
+	}
+	return json.Marshal(mergePatch(req, patch))
+}
+
+// mergePatch applies patch to target: objects merge recursively, null
+// removes a member, and any other value replaces it.
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any, len(p))
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":1}`, `{"b":2}`, `{"a":1,"b":2}`},
+		{`{"a":1,"b":2}`, `{"a":null}`, `{"b":2}`},
+		{`{"a":{"x":1,"y":2}}`, `{"a":{"y":3,"z":4}}`, `{"a":{"x":1,"y":3,"z":4}}`},
+		{`{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{`{"a":1}`, `{"a":{"b":{"c":null,"d":1}}}`, `{"a":{"b":{"d":1}}}`},
+	}
+	for _, tt := range tests {
+		var target, patch any
+		if err := json.Unmarshal([]byte(tt.target), &target); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+			t.Fatal(err)
+		}
+		got, err := json.Marshal(mergePatch(target, patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		testAssertJSONEqual(t, got, []byte(tt.want))
+	}
+}
+
+func TestOpenAIProvider_ProviderOptions(t *testing.T) {
+	srv, captured := newTestOpenAIServer(t, 200, chatCompletionResponse{
+		Choices: []chatChoice{{Message: chatMessage{Role: "assistant", Content: textContent("ok")}, FinishReason: "stop"}},
+	})
+	conv := NewConversation("llama3",
+		WithTemperature(0.5),
+		WithProviderOptions("openai", json.RawMessage(`{"seed": 7, "temperature": null, "chat_template_kwargs": {"enable_thinking": false}}`)),
+		WithProviderOptions("gemini", json.RawMessage(`{"ignored": true}`)),
+	)
+	conv.Messages = []Message{UserMessage("hi")}
+
+	if _, err := NewOpenAIProvider(srv.URL).Send(context.Background(), &conv); err != nil {
+		t.Fatal(err)
+	}
+	var req map[string]any
+	if err := json.Unmarshal(*captured, &req); err != nil {
+		t.Fatal(err)
+	}
+	_, hasTemp := req["temperature"]
+	_, hasIgnored := req["ignored"]
+	kwargs, _ := req["chat_template_kwargs"].(map[string]any)
+	if req["seed"] != float64(7) || hasTemp || hasIgnored || kwargs["enable_thinking"] != false || req["model"] != "llama3" {
+		t.Errorf("request = %v", req)
+	}
+
+	conv.Config.ProviderOptions["openai"] = json.RawMessage(`"seed"`)
+	_, err := NewOpenAIProvider(srv.URL).Send(context.Background(), &conv)
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrConfig {
+		t.Errorf("err = %v, want ErrConfig", err)
+	}
+}
+
+func TestGeminiProvider_ProviderOptions(t *testing.T) {
+	srv, _, captured := newTestGeminiServer(t, 200, `{"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`)
+	conv := NewConversation("gemini-2.5-flash",
+		WithMaxTokens(100),
+		WithProviderOptions("gemini", json.RawMessage(`{"generationConfig": {"topK": 40}, "safetySettings": [{"category": "HARM_CATEGORY_HARASSMENT", "threshold": "BLOCK_NONE"}]}`)),
+	)
+	conv.Messages = []Message{UserMessage("hi")}
+
+	if _, err := NewGeminiProvider("", WithGeminiBaseURL(srv.URL)).Send(context.Background(), &conv); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+		"contents":[{"role":"user","parts":[{"text":"hi"}]}],
+		"generationConfig":{"maxOutputTokens":100,"topK":40},
+		"safetySettings":[{"category":"HARM_CATEGORY_HARASSMENT","threshold":"BLOCK_NONE"}]
+	}`
+	testAssertJSONEqual(t, *captured, []byte(want))
+}
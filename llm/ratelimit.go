@@ -0,0 +1,171 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimit caps request and token throughput. A zero field is unlimited.
+type RateLimit struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+// WithRateLimit adds a RateLimiter middleware to the client.
+func WithRateLimit(limits map[string]RateLimit) ClientOption {
+	return WithMiddleware(RateLimiter(limits))
+}
+
+// RateLimiter returns middleware that throttles sends with token buckets
+// keyed by model ID. limits maps model IDs to their limits; the "" entry,
+// if present, applies to every model without its own entry, each with its
+// own buckets. Because a Client wraps a single provider, limits are
+// effectively per provider and model.
+//
+// Token buckets are charged the estimated input tokens before the send and
+// reconciled against the reported usage afterward, so bursts are absorbed
+// up to one minute of capacity and then smoothed. Sends wait for capacity;
+// if the wait would outlast the context deadline the send fails with
+// ErrRateLimit without calling the provider, and if the context is canceled
+// during the wait it fails with ErrCanceled.
+func RateLimiter(limits map[string]RateLimit) Middleware {
+	var mu sync.Mutex
+	buckets := make(map[string]*modelBuckets)
+
+	bucketsFor := func(model string) *modelBuckets {
+		mu.Lock()
+		defer mu.Unlock()
+		if b, ok := buckets[model]; ok {
+			return b
+		}
+		limit, ok := limits[model]
+		if !ok {
+			limit = limits[""]
+		}
+		b := &modelBuckets{
+			requests: newTokenBucket(limit.RequestsPerMinute),
+			tokens:   newTokenBucket(limit.TokensPerMinute),
+		}
+		buckets[model] = b
+		return b
+	}
+
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		b := bucketsFor(conv.Model)
+		estimate := estimateInputTokens(conv)
+
+		if err := b.requests.wait(ctx, 1); err != nil {
+			return nil, err
+		}
+		if err := b.tokens.wait(ctx, estimate); err != nil {
+			b.requests.refund(1)
+			return nil, err
+		}
+
+		resp, err := next(ctx, conv)
+		if err == nil {
+			b.tokens.charge(resp.Usage.InputTokens + resp.Usage.OutputTokens - b.tokens.reservation(estimate))
+		}
+		return resp, err
+	}
+}
+
+type modelBuckets struct {
+	requests *tokenBucket
+	tokens   *tokenBucket
+}
+
+// tokenBucket refills continuously at capacity per minute. The balance may
+// go negative: callers reserve before waiting, which keeps waiters in order.
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	balance  float64
+	last     time.Time
+}
+
+// newTokenBucket returns a bucket holding perMinute tokens, or nil when
+// perMinute is not positive. A nil bucket never blocks.
+func newTokenBucket(perMinute int) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{capacity: float64(perMinute), balance: float64(perMinute), last: time.Now()}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.balance = min(b.capacity, b.balance+now.Sub(b.last).Minutes()*b.capacity)
+	b.last = now
+}
+
+// reservation returns how many of n tokens wait reserves: n, capped at the
+// bucket's capacity.
+func (b *tokenBucket) reservation(n int) int {
+	if b == nil || n <= 0 {
+		return 0
+	}
+	return min(n, int(b.capacity))
+}
+
+// wait reserves n tokens and blocks until the balance covers them. A single
+// request larger than the bucket only waits for a full bucket. If ctx is
+// done first, the reservation is returned and wait fails with ErrTimeout or
+// ErrCanceled.
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	need := float64(b.reservation(n))
+	if need == 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.refill(now)
+	b.balance -= need
+	var delay time.Duration
+	if b.balance < 0 {
+		delay = time.Duration(-b.balance / b.capacity * float64(time.Minute))
+	}
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+		b.refund(need)
+		return &Error{Kind: ErrRateLimit, Message: fmt.Sprintf("rate limit wait of %s exceeds context deadline", delay.Round(time.Millisecond))}
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		b.refund(need)
+		return contextError("waiting for rate limit capacity", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// charge adjusts the balance by n tokens after the fact; negative n refunds.
+func (b *tokenBucket) charge(n int) {
+	if b == nil || n == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	b.balance = min(b.capacity, b.balance-float64(n))
+}
+
+// refund returns n reserved tokens, never exceeding capacity.
+func (b *tokenBucket) refund(n float64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	b.balance = min(b.capacity, b.balance+n)
+}
@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingProvider counts calls and returns resp.
+type countingProvider struct {
+	resp  *Response
+	calls int
+}
+
+func (p *countingProvider) Send(_ context.Context, _ *Conversation) (*Response, error) {
+	p.calls++
+	r := *p.resp
+	return &r, nil
+}
+
+func shortContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestRateLimiter_RequestsPerModel(t *testing.T) {
+	provider := &countingProvider{resp: simpleResponse("ok")}
+	client := NewClientWithProvider(provider, WithRateLimit(map[string]RateLimit{
+		"limited": {RequestsPerMinute: 1},
+	}))
+	ctx := shortContext(t)
+
+	if _, _, err := client.Send(ctx, NewConversation("limited"), UserMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := client.Send(ctx, NewConversation("limited"), UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrRateLimit {
+		t.Fatalf("err = %v, want ErrRateLimit", err)
+	}
+
+	// Models without an entry and no "" default are unlimited.
+	for range 3 {
+		if _, _, err := client.Send(ctx, NewConversation("other"), UserMessage("hi")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.calls != 4 {
+		t.Errorf("calls = %d, want 4", provider.calls)
+	}
+}
+
+func TestRateLimiter_DefaultLimitIsPerModel(t *testing.T) {
+	provider := &countingProvider{resp: simpleResponse("ok")}
+	client := NewClientWithProvider(provider, WithRateLimit(map[string]RateLimit{
+		"": {RequestsPerMinute: 1},
+	}))
+	ctx := shortContext(t)
+
+	for _, model := range []string{"a", "b"} {
+		if _, _, err := client.Send(ctx, NewConversation(model), UserMessage("hi")); err != nil {
+			t.Fatalf("%s: %v", model, err)
+		}
+	}
+	if _, _, err := client.Send(ctx, NewConversation("a"), UserMessage("hi")); err == nil {
+		t.Error("expected second send to a to be throttled")
+	}
+}
+
+func TestRateLimiter_TokensReconciledWithUsage(t *testing.T) {
+	provider := &countingProvider{resp: &Response{
+		Message: AssistantMessage("ok"),
+		Usage:   Usage{InputTokens: 90, OutputTokens: 20},
+	}}
+	client := NewClientWithProvider(provider, WithRateLimit(map[string]RateLimit{
+		"": {TokensPerMinute: 100},
+	}))
+	ctx := shortContext(t)
+
+	if _, _, err := client.Send(ctx, NewConversation("m"), UserMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+	// The first send used 110 of 100 tokens, so the bucket is in debt.
+	if _, _, err := client.Send(ctx, NewConversation("m"), UserMessage("hi")); err == nil {
+		t.Error("expected token limit to throttle the second send")
+	}
+	if provider.calls != 1 {
+		t.Errorf("calls = %d, want 1", provider.calls)
+	}
+}
+
+func TestRateLimiter_OversizedRequestReconciledAgainstReservation(t *testing.T) {
+	provider := &countingProvider{resp: &Response{
+		Message: AssistantMessage("ok"),
+		Usage:   Usage{InputTokens: 10000},
+	}}
+	client := NewClientWithProvider(provider, WithRateLimit(map[string]RateLimit{
+		"": {TokensPerMinute: 6000},
+	}))
+	ctx := shortContext(t)
+
+	// The estimate exceeds the bucket, so only 6000 tokens are reserved and
+	// the other 4000 used must still be charged afterward.
+	if _, _, err := client.Send(ctx, NewConversation("m"), UserMessage(strings.Repeat("word ", 8000))); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := client.Send(ctx, NewConversation("m"), UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrRateLimit {
+		t.Errorf("err = %v, want ErrRateLimit", err)
+	}
+}
+
+func TestTokenBucket_Waits(t *testing.T) {
+	b := newTokenBucket(600) // refills 10 per second
+	b.balance = 0
+
+	start := time.Now()
+	if err := b.wait(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("waited %s, want about 100ms", elapsed)
+	}
+}
+
+func TestTokenBucket_CanceledWaitRefunds(t *testing.T) {
+	b := newTokenBucket(1)
+	b.balance = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := b.wait(ctx, 1)
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrCanceled || !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want ErrCanceled caused by context.Canceled", err)
+	}
+	if b.balance < 0 {
+		t.Errorf("balance = %v, reservation was not refunded", b.balance)
+	}
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ProviderFactory creates a Provider from a provider-specific configuration
+// string, such as a base URL.
+type ProviderFactory func(config string) (Provider, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]ProviderFactory)
+)
+
+func init() {
+	RegisterProvider("openai", func(config string) (Provider, error) {
+		if config == "" {
+			return nil, &Error{Kind: ErrConfig, Message: "openai provider requires a base URL"}
+		}
+		return NewOpenAIProvider(config), nil
+	})
+	RegisterProvider("ollama", func(config string) (Provider, error) {
+		if config == "" {
+			config = OllamaBaseURL
+		}
+		return NewOpenAIProvider(config), nil
+	})
+	RegisterProvider("llamacpp", func(config string) (Provider, error) {
+		if config == "" {
+			config = LlamaCppBaseURL
+		}
+		return NewOpenAIProvider(config), nil
+	})
+	RegisterProvider("deepseek", func(config string) (Provider, error) {
+		if config == "" {
+			config = DeepSeekBaseURL
+		}
+		return NewDeepSeekProvider(config), nil
+	})
+	RegisterProvider("gemini", func(config string) (Provider, error) {
+		return NewGeminiProvider(config), nil
+	})
+}
+
+// RegisterProvider makes a provider available to OpenClient under name.
+// Like database/sql drivers, third-party modules typically call it from an
+// init function. It panics if name is already registered or factory is nil.
+func RegisterProvider(name string, factory ProviderFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if factory == nil {
+		panic("llm: RegisterProvider factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("llm: RegisterProvider called twice for provider " + name)
+	}
+	factories[name] = factory
+}
+
+// Providers returns the sorted names of the registered providers.
+func Providers() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenClient creates a Client backed by the provider registered under name.
+func OpenClient(name, config string, opts ...ClientOption) (*Client, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, &Error{Kind: ErrConfig, Message: fmt.Sprintf("unknown provider %q (forgotten import?)", name)}
+	}
+	provider, err := factory(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithProvider(provider, opts...), nil
+}
@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRegisterProvider(t *testing.T) {
+	RegisterProvider("test-registry", func(config string) (Provider, error) {
+		return &mockProvider{resp: simpleResponse("from " + config)}, nil
+	})
+	if !slices.Contains(Providers(), "test-registry") {
+		t.Fatalf("Providers() = %v", Providers())
+	}
+
+	client, err := OpenClient("test-registry", "cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, resp, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Text() != "from cfg" {
+		t.Errorf("Text = %q", resp.Message.Text())
+	}
+}
+
+func TestRegisterProviderDuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	RegisterProvider("openai", func(string) (Provider, error) { return nil, nil })
+}
+
+func TestOpenClientUnknownProvider(t *testing.T) {
+	_, err := OpenClient("no-such-provider", "")
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrConfig {
+		t.Errorf("err = %v, want ErrConfig", err)
+	}
+}
+
+func TestOpenClientBuiltins(t *testing.T) {
+	for _, name := range []string{"openai", "deepseek", "gemini"} {
+		if _, err := OpenClient(name, "http://localhost:8080"); err != nil {
+			t.Errorf("OpenClient(%q) error: %v", name, err)
+		}
+	}
+	for _, name := range []string{"ollama", "llamacpp", "deepseek"} {
+		if _, err := OpenClient(name, ""); err != nil {
+			t.Errorf("OpenClient(%q) with default URL error: %v", name, err)
+		}
+	}
+	if _, err := OpenClient("openai", ""); err == nil {
+		t.Error("openai without a base URL should fail")
+	}
+}
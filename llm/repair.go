@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+)
+
+// Repair returns a copy of tc whose Arguments are rewritten into valid JSON
+// if they are malformed in one of the ways models commonly produce:
+// trailing commas, single-quoted strings, unescaped newlines in strings,
+// a surrounding Markdown code fence, or a document cut off before its
+// strings and brackets are closed. The original bytes are kept in
+// RawArguments as a record of the repair.
+//
+// The repair is best effort. It reports false, and returns tc unchanged,
+// if the arguments are already valid or cannot be repaired.
+func (tc ToolCallData) Repair() (ToolCallData, bool) {
+	if len(tc.Arguments) == 0 || json.Valid(tc.Arguments) {
+		return tc, false
+	}
+	fixed, ok := repairJSON(tc.Arguments)
+	if !ok {
+		return tc, false
+	}
+	tc.RawArguments = string(tc.Arguments)
+	tc.Arguments = fixed
+	return tc, true
+}
+
+// repairToolCalls repairs the tool calls in msg, replacing its Content with
+// a copy if any changed. It reports whether any call was repaired.
+func repairToolCalls(msg *Message) bool {
+	var repaired bool
+	for i, p := range msg.Content {
+		if p.ToolCall == nil {
+			continue
+		}
+		tc, ok := p.ToolCall.Repair()
+		if !ok {
+			continue
+		}
+		if !repaired {
+			msg.Content = slices.Clone(msg.Content)
+			repaired = true
+		}
+		msg.Content[i].ToolCall = &tc
+	}
+	return repaired
+}
+
+// repairJSON rewrites almost-JSON into JSON in a single pass, tracking
+// open strings and containers, then checks that the result is valid.
+func repairJSON(data []byte) ([]byte, bool) {
+	data = stripCodeFence(bytes.TrimSpace(data))
+
+	var (
+		out   []byte
+		stack []byte // closing brackets of the open containers
+		quote byte   // quote character of the open string, or 0
+	)
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if quote != 0 {
+			switch {
+			case c == '\\' && i+1 < len(data):
+				i++
+				if data[i] == '\'' {
+					out = append(out, '\'')
+				} else {
+					out = append(out, c, data[i])
+				}
+			case c == quote:
+				out = append(out, '"')
+				quote = 0
+			case c == '"':
+				out = append(out, '\\', '"')
+			case c == '\n':
+				out = append(out, '\\', 'n')
+			case c == '\r':
+				out = append(out, '\\', 'r')
+			case c == '\t':
+				out = append(out, '\\', 't')
+			default:
+				out = append(out, c)
+			}
+			continue
+		}
+
+		switch c {
+		case '"', '\'':
+			quote = c
+			out = append(out, '"')
+		case '{':
+			stack = append(stack, '}')
+			out = append(out, c)
+		case '[':
+			stack = append(stack, ']')
+			out = append(out, c)
+		case '}', ']':
+			out = trimTrailingComma(out)
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+
+	// Close whatever a truncated document left open.
+	if quote != 0 {
+		out = append(out, '"')
+	}
+	out = trimTrailingComma(out)
+	if trimmed := bytes.TrimRight(out, " \t\r\n"); len(trimmed) > 0 && trimmed[len(trimmed)-1] == ':' {
+		out = append(trimmed, "null"...)
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		out = append(out, stack[i])
+	}
+
+	if !json.Valid(out) {
+		return nil, false
+	}
+	return out, true
+}
+
+// trimTrailingComma removes a comma, and any whitespace after it, from the
+// end of out.
+func trimTrailingComma(out []byte) []byte {
+	trimmed := bytes.TrimRight(out, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[len(trimmed)-1] == ',' {
+		return trimmed[:len(trimmed)-1]
+	}
+	return out
+}
+
+// stripCodeFence removes a Markdown code fence, with or without a language
+// tag, from around data.
+func stripCodeFence(data []byte) []byte {
+	if !bytes.HasPrefix(data, []byte("```")) {
+		return data
+	}
+	if nl := bytes.IndexByte(data, '\n'); nl >= 0 {
+		data = data[nl+1:]
+	} else {
+		data = data[3:]
+	}
+	data = bytes.TrimSpace(data)
+	return bytes.TrimSpace(bytes.TrimSuffix(data, []byte("```")))
+}
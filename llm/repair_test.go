@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"trailing comma", `{"a": 1, "b": [1, 2,],}`, `{"a": 1, "b": [1, 2]}`},
+		{"single quotes", `{'a': 'it\'s "x"'}`, `{"a": "it's \"x\""}`},
+		{"raw newline", "{\"a\": \"line1\nline2\"}", `{"a": "line1\nline2"}`},
+		{"unterminated string", `{"a": "hello`, `{"a": "hello"}`},
+		{"truncated containers", `{"a": [1, {"b": 2`, `{"a": [1, {"b": 2}]}`},
+		{"dangling key", `{"a": 1, "b":`, `{"a": 1, "b":null}`},
+		{"code fence", "```json\n{\"a\": 1}\n```", `{"a": 1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := repairJSON([]byte(tt.in))
+			if !ok || string(got) != tt.want {
+				t.Errorf("repairJSON(%q) = %q, %v, want %q", tt.in, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepairJSON_Unrepairable(t *testing.T) {
+	for _, in := range []string{`{"a": undefined}`, `not json`, `{"a" 1}`} {
+		if got, ok := repairJSON([]byte(in)); ok {
+			t.Errorf("repairJSON(%q) = %q, want failure", in, got)
+		}
+	}
+}
+
+func TestToolCallDataRepair(t *testing.T) {
+	tc := ToolCallData{ID: "1", Name: "f", Arguments: json.RawMessage(`{"a": 1,}`)}
+	fixed, ok := tc.Repair()
+	if !ok {
+		t.Fatal("Repair reported no change")
+	}
+	if string(fixed.Arguments) != `{"a": 1}` || fixed.RawArguments != `{"a": 1,}` {
+		t.Errorf("repaired = %+v", fixed)
+	}
+	if string(tc.Arguments) != `{"a": 1,}` {
+		t.Error("Repair modified the receiver")
+	}
+
+	valid := ToolCallData{Arguments: json.RawMessage(`{"a":1}`)}
+	if same, ok := valid.Repair(); ok || same.RawArguments != "" {
+		t.Errorf("valid arguments repaired: %+v", same)
+	}
+}
@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy configures Retry. Zero fields take the documented defaults.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first; default 3
+	BaseDelay   time.Duration // delay before the first retry, doubled per retry; default 500ms
+	MaxDelay    time.Duration // cap on a single delay; default 30s
+	Jitter      float64       // fraction of each delay that is randomized, 0 to 1
+	Budget      time.Duration // cap on total time spent across attempts; 0 means none
+
+	// Retryable reports whether an error should be retried. The default
+	// retries *Error values of kind ErrRateLimit, ErrServer, ErrTimeout, or
+	// ErrCapacity.
+	Retryable func(error) bool
+}
+
+// WithRetry adds a Retry middleware to the client.
+func WithRetry(policy RetryPolicy) ClientOption {
+	return WithMiddleware(Retry(policy))
+}
+
+// Retry returns middleware that retries failed sends with exponential
+// backoff. It never sleeps past the context deadline or the retry budget;
+// when the next delay would exceed either, the last error is returned.
+func Retry(policy RetryPolicy) Middleware {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = 500 * time.Millisecond
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = 30 * time.Second
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = isRetryable
+	}
+
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		start := time.Now()
+		delay := policy.BaseDelay
+		for attempt := 1; ; attempt++ {
+			resp, err := next(ctx, conv)
+			if err == nil {
+				resp.Retries += attempt - 1
+				return resp, nil
+			}
+			if attempt >= policy.MaxAttempts || !policy.Retryable(err) {
+				return resp, err
+			}
+
+			wait := min(delay, policy.MaxDelay)
+			if policy.Jitter > 0 {
+				wait -= time.Duration(policy.Jitter * rand.Float64() * float64(wait))
+			}
+			wakeAt := time.Now().Add(wait)
+			if policy.Budget > 0 && wakeAt.After(start.Add(policy.Budget)) {
+				return resp, err
+			}
+			if deadline, ok := ctx.Deadline(); ok && wakeAt.After(deadline) {
+				return resp, err
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return resp, err
+			case <-timer.C:
+			}
+			delay *= 2
+		}
+	}
+}
+
+func isRetryable(err error) bool {
+	var llmErr *Error
+	if !errors.As(err, &llmErr) {
+		return false
+	}
+	switch llmErr.Kind {
+	case ErrRateLimit, ErrServer, ErrTimeout, ErrCapacity:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyProvider fails with errs in order, then succeeds.
+type flakyProvider struct {
+	errs  []error
+	calls int
+}
+
+func (p *flakyProvider) Send(_ context.Context, _ *Conversation) (*Response, error) {
+	p.calls++
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return nil, err
+	}
+	return simpleResponse("ok"), nil
+}
+
+func TestRetry_RecoversFromTransientErrors(t *testing.T) {
+	provider := &flakyProvider{errs: []error{
+		&Error{Kind: ErrRateLimit, Message: "slow down"},
+		&Error{Kind: ErrServer, Message: "oops"},
+		&Error{Kind: ErrCapacity, Message: "model not ready"},
+	}}
+	client := NewClientWithProvider(provider, WithRetry(RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, Jitter: 0.5}))
+
+	_, resp, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Text() != "ok" {
+		t.Errorf("Text = %q", resp.Message.Text())
+	}
+	if provider.calls != 4 {
+		t.Errorf("calls = %d, want 4", provider.calls)
+	}
+	if resp.Retries != 3 || resp.Latency < time.Millisecond {
+		t.Errorf("Retries = %d, Latency = %v", resp.Retries, resp.Latency)
+	}
+}
+
+func TestRetry_StopsAtMaxAttempts(t *testing.T) {
+	provider := &flakyProvider{errs: []error{
+		&Error{Kind: ErrServer}, &Error{Kind: ErrServer}, &Error{Kind: ErrServer},
+	}}
+	client := NewClientWithProvider(provider, WithRetry(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}))
+
+	_, _, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if provider.calls != 2 {
+		t.Errorf("calls = %d, want 2", provider.calls)
+	}
+}
+
+func TestRetry_DoesNotRetryPermanentErrors(t *testing.T) {
+	provider := &flakyProvider{errs: []error{&Error{Kind: ErrInvalidRequest}}}
+	client := NewClientWithProvider(provider, WithRetry(RetryPolicy{BaseDelay: time.Millisecond}))
+
+	_, _, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrInvalidRequest {
+		t.Fatalf("err = %v", err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("calls = %d, want 1", provider.calls)
+	}
+}
+
+func TestRetry_HonorsContextDeadline(t *testing.T) {
+	provider := &flakyProvider{errs: []error{&Error{Kind: ErrRateLimit}, &Error{Kind: ErrRateLimit}}}
+	client := NewClientWithProvider(provider, WithRetry(RetryPolicy{BaseDelay: time.Hour}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, _, err := client.Send(ctx, NewConversation("model"), UserMessage("hi"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("retry slept past the context deadline")
+	}
+	if provider.calls != 1 {
+		t.Errorf("calls = %d, want 1", provider.calls)
+	}
+}
+
+func TestRetry_HonorsBudget(t *testing.T) {
+	provider := &flakyProvider{errs: []error{&Error{Kind: ErrServer}, &Error{Kind: ErrServer}, &Error{Kind: ErrServer}}}
+	client := NewClientWithProvider(provider, WithRetry(RetryPolicy{
+		MaxAttempts: 10,
+		BaseDelay:   20 * time.Millisecond,
+		Budget:      30 * time.Millisecond,
+	}))
+
+	_, _, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	// 20ms fits the budget, the following 40ms does not.
+	if provider.calls != 2 {
+		t.Errorf("calls = %d, want 2", provider.calls)
+	}
+}
+
+func TestRetry_CustomRetryable(t *testing.T) {
+	provider := &flakyProvider{errs: []error{errors.New("plain error")}}
+	client := NewClientWithProvider(provider, WithRetry(RetryPolicy{
+		BaseDelay: time.Millisecond,
+		Retryable: func(error) bool { return true },
+	}))
+	if _, _, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("calls = %d, want 2", provider.calls)
+	}
+}
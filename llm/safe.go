@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// SafeConversation guards a Conversation shared by several goroutines,
+// such as a chat session on a server. Reads return copies, so callers may
+// change what they get back without affecting the shared state or racing
+// with writers. Messages themselves are shared between copies and must be
+// treated as immutable, as Client does.
+//
+// Use a Session instead to also serialize model turns and publish events.
+type SafeConversation struct {
+	mu   sync.RWMutex
+	conv Conversation
+}
+
+// NewSafeConversation creates a SafeConversation holding a copy of conv.
+func NewSafeConversation(conv Conversation) *SafeConversation {
+	return &SafeConversation{conv: copyConversation(conv)}
+}
+
+// Snapshot returns a copy of the current conversation.
+func (s *SafeConversation) Snapshot() Conversation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyConversation(s.conv)
+}
+
+// Messages returns a copy of the message history.
+func (s *SafeConversation) Messages() []Message {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.conv.Messages)
+}
+
+// Len returns the number of messages.
+func (s *SafeConversation) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.conv.Messages)
+}
+
+// Append adds messages to the end of the history.
+func (s *SafeConversation) Append(messages ...Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conv.Messages = append(s.conv.Messages, messages...)
+}
+
+// Replace sets the conversation to a copy of conv, such as the result of
+// Client.Send.
+func (s *SafeConversation) Replace(conv Conversation) {
+	conv = copyConversation(conv)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conv = conv
+}
+
+// Update calls fn with the conversation while holding the write lock, for
+// read-modify-write changes that must not interleave with other writers.
+// fn must not keep conv or call other methods of s.
+func (s *SafeConversation) Update(fn func(conv *Conversation)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.conv)
+}
+
+// copyConversation copies the slices and maps of conv that callers
+// commonly change in place.
+func copyConversation(conv Conversation) Conversation {
+	conv.System = slices.Clone(conv.System)
+	conv.Messages = slices.Clone(conv.Messages)
+	conv.Tools = slices.Clone(conv.Tools)
+	conv.Config.StopSequences = slices.Clone(conv.Config.StopSequences)
+	conv.Metadata = maps.Clone(conv.Metadata)
+	conv.UsageHistory = slices.Clone(conv.UsageHistory)
+	return conv
+}
@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSafeConversation_CopyOnRead(t *testing.T) {
+	conv := NewConversation("m", WithMetadata("user", "u1"))
+	safe := NewSafeConversation(conv)
+	conv.Metadata["user"] = "changed"
+
+	snap := safe.Snapshot()
+	snap.Metadata["user"] = "mallory"
+	snap.Messages = append(snap.Messages, UserMessage("not appended"))
+	_ = append(safe.Messages(), UserMessage("nor this"))
+
+	got := safe.Snapshot()
+	if got.Metadata["user"] != "u1" || len(got.Messages) != 0 || safe.Len() != 0 {
+		t.Errorf("shared state changed through a copy: %+v", got)
+	}
+}
+
+func TestSafeConversation_Concurrent(t *testing.T) {
+	safe := NewSafeConversation(NewConversation("m"))
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			safe.Append(UserMessage(fmt.Sprint(i)))
+		}()
+		go func() {
+			defer wg.Done()
+			snap := safe.Snapshot()
+			_ = snap.EstimateTokens("")
+		}()
+		go func() {
+			defer wg.Done()
+			safe.Update(func(conv *Conversation) { conv.Usage.InputTokens++ })
+		}()
+	}
+	wg.Wait()
+	if got := safe.Snapshot(); safe.Len() != 50 || got.Usage.InputTokens != 50 {
+		t.Errorf("Len = %d, InputTokens = %d", safe.Len(), got.Usage.InputTokens)
+	}
+}
+
+func TestSafeConversation_Replace(t *testing.T) {
+	client := NewClientWithProvider(&mockProvider{resp: simpleResponse("hi")})
+	safe := NewSafeConversation(NewConversation("m"))
+	next, _, err := client.Send(context.Background(), safe.Snapshot(), UserMessage("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	safe.Replace(next)
+	if msgs := safe.Messages(); len(msgs) != 2 || msgs[1].Text() != "hi" {
+		t.Errorf("Messages = %+v", msgs)
+	}
+}
@@ -0,0 +1,224 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// toolFromStruct builds a ToolDefinition whose parameters are the fields
+// of the struct type t, as described on NewTypedTool.
+func toolFromStruct(name, description string, t reflect.Type) (ToolDefinition, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return ToolDefinition{}, fmt.Errorf("tool %s: arguments must be a struct, got %s", name, t)
+	}
+	schema, err := structSchema(t, map[reflect.Type]bool{})
+	if err != nil {
+		return ToolDefinition{}, fmt.Errorf("tool %s: %w", name, err)
+	}
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return ToolDefinition{}, fmt.Errorf("tool %s: %w", name, err)
+	}
+
+	// Params let ParseArgs check required fields and types, including those
+	// of nested structs and slice elements. structSchema has already
+	// rejected recursive types.
+	return ToolDefinition{Name: name, Description: description, Parameters: raw, params: structParams(t)}, nil
+}
+
+// structParams returns the Params for the fields of struct type t.
+func structParams(t reflect.Type) []Param {
+	var params []Param
+	for _, f := range structFields(t) {
+		p := typeParam(f.typ)
+		p.Name = f.name
+		p.Description = f.description
+		p.Required = f.required
+		p.Enum = f.enum
+		params = append(params, p)
+	}
+	return params
+}
+
+// typeParam returns a Param describing values of type t, mirroring
+// typeSchema. Types ParseArgs cannot check, such as interfaces, have an
+// empty Type.
+func typeParam(t reflect.Type) Param {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return Param{Type: "string"}
+	case t == rawMessageType:
+		return Param{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return Param{Type: "string"}
+	case reflect.Bool:
+		return Param{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Param{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Param{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Param{Type: "string"}
+		}
+		items := typeParam(t.Elem())
+		return Param{Type: "array", Items: &items}
+	case reflect.Map:
+		return Param{Type: "object"}
+	case reflect.Struct:
+		return Param{Type: "object", Properties: structParams(t)}
+	}
+	return Param{}
+}
+
+type schemaField struct {
+	name        string
+	description string
+	enum        []string
+	required    bool
+	typ         reflect.Type
+}
+
+// structFields lists the JSON-visible fields of t, flattening embedded
+// structs without a JSON name like encoding/json does.
+func structFields(t reflect.Type) []schemaField {
+	var fields []schemaField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := sf.Type
+		if sf.Anonymous && name == "" {
+			et := ft
+			if et.Kind() == reflect.Pointer {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				fields = append(fields, structFields(et)...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		optional := ft.Kind() == reflect.Pointer || strings.Contains(","+opts+",", ",omitempty,") || strings.Contains(","+opts+",", ",omitzero,")
+		var enum []string
+		if e := sf.Tag.Get("enum"); e != "" {
+			enum = strings.Split(e, ",")
+		}
+		fields = append(fields, schemaField{
+			name:        name,
+			description: sf.Tag.Get("description"),
+			enum:        enum,
+			required:    !optional,
+			typ:         ft,
+		})
+	}
+	return fields
+}
+
+// structSchema returns the object schema for struct type t. seen holds the
+// structs being expanded, to reject recursive types.
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) (map[string]any, error) {
+	if seen[t] {
+		return nil, fmt.Errorf("recursive type %s", t)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	properties := make(map[string]any)
+	required := []string{}
+	for _, f := range structFields(t) {
+		prop, err := typeSchema(f.typ, seen)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+		if f.description != "" {
+			prop["description"] = f.description
+		}
+		if len(f.enum) > 0 {
+			prop["enum"] = f.enum
+		}
+		properties[f.name] = prop
+		if f.required {
+			required = append(required, f.name)
+		}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}, nil
+}
+
+// typeSchema returns the JSON Schema for values of type t.
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) (map[string]any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case t == rawMessageType:
+		return map[string]any{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := typeSchema(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key type %s is not string", t.Key())
+		}
+		values, err := typeSchema(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return structSchema(t, seen)
+	case reflect.Interface:
+		return map[string]any{}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
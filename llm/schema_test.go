@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type scheduleArgs struct {
+	Title     string    `json:"title" description:"Event title"`
+	Attendees []string  `json:"attendees"`
+	Start     time.Time `json:"start"`
+	Minutes   int       `json:"minutes,omitempty"`
+	Room      *struct {
+		Building string `json:"building"`
+		Floor    int    `json:"floor"`
+	} `json:"room"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	internal string
+	Ignored  string `json:"-"`
+}
+
+func TestToolFromStruct(t *testing.T) {
+	def, err := toolFromStruct("schedule", "Schedule an event", reflect.TypeFor[scheduleArgs]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAssertJSONEqual(t, def.Parameters, []byte(`{
+		"type": "object",
+		"properties": {
+			"title": {"type": "string", "description": "Event title"},
+			"attendees": {"type": "array", "items": {"type": "string"}},
+			"start": {"type": "string", "format": "date-time"},
+			"minutes": {"type": "integer"},
+			"room": {
+				"type": "object",
+				"properties": {"building": {"type": "string"}, "floor": {"type": "integer"}},
+				"required": ["building", "floor"]
+			},
+			"tags": {"type": "object", "additionalProperties": {"type": "string"}}
+		},
+		"required": ["title", "attendees", "start"]
+	}`))
+}
+
+type embeddedBase struct {
+	ID string `json:"id"`
+}
+
+type withEmbedded struct {
+	embeddedBase
+	Name string `json:"name"`
+}
+
+func TestToolFromStruct_Embedded(t *testing.T) {
+	def, err := toolFromStruct("t", "", reflect.TypeFor[withEmbedded]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	json.Unmarshal(def.Parameters, &schema)
+	if _, ok := schema.Properties["id"]; !ok {
+		t.Errorf("embedded fields not flattened: %s", def.Parameters)
+	}
+}
+
+type recursiveArgs struct {
+	Children []recursiveArgs `json:"children"`
+}
+
+func TestToolFromStruct_Errors(t *testing.T) {
+	tests := []struct {
+		typ  reflect.Type
+		want string
+	}{
+		{reflect.TypeFor[string](), "must be a struct"},
+		{reflect.TypeFor[struct{ C chan int }](), "unsupported type"},
+		{reflect.TypeFor[recursiveArgs](), "recursive type"},
+	}
+	for _, tt := range tests {
+		_, err := toolFromStruct("t", "", tt.typ)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.typ, err, tt.want)
+		}
+	}
+}
+
+func TestToolFromStruct_Enum(t *testing.T) {
+	type args struct {
+		Unit string `json:"unit" enum:"celsius,fahrenheit"`
+	}
+	def, err := toolFromStruct("t", "", reflect.TypeFor[args]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAssertJSONEqual(t, def.Parameters, []byte(`{"type":"object","properties":{"unit":{"type":"string","enum":["celsius","fahrenheit"]}},"required":["unit"]}`))
+	if _, err := def.ParseArgs(ToolCallData{Arguments: json.RawMessage(`{"unit":"kelvin"}`)}); err == nil {
+		t.Error("value outside the enum tag accepted")
+	}
+}
+
+func TestToolFromStruct_NestedValidation(t *testing.T) {
+	def, err := toolFromStruct("t", "", reflect.TypeFor[scheduleArgs]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = def.ParseArgs(ToolCallData{Arguments: json.RawMessage(`{"title":"x","attendees":["a",1],"start":"2024-01-01T00:00:00Z","room":{"building":"B","floor":1}}`)})
+	if want := `parameter "attendees[1]": expected string, got float64`; err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+	_, err = def.ParseArgs(ToolCallData{Arguments: json.RawMessage(`{"title":"x","attendees":[],"start":"2024-01-01T00:00:00Z","room":{"floor":1}}`)})
+	if want := `missing required parameter "room.building"`; err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,200 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// EventKind identifies the type of a session Event.
+type EventKind string
+
+const (
+	EventMessageAppended EventKind = "message_appended"
+	EventToolStarted     EventKind = "tool_started"
+	EventToolFinished    EventKind = "tool_finished"
+	EventTurnComplete    EventKind = "turn_complete"
+	EventError           EventKind = "error"
+)
+
+// Event is a single entry in a Session's ordered event feed. It is
+// JSON-serializable so it can be forwarded to a frontend over SSE or
+// WebSocket as is.
+type Event struct {
+	Seq        int             `json:"seq"`
+	Kind       EventKind       `json:"kind"`
+	Message    *Message        `json:"message,omitempty"`     // EventMessageAppended
+	ToolCall   *ToolCallData   `json:"tool_call,omitempty"`   // EventToolStarted, EventToolFinished
+	ToolResult *ToolResultData `json:"tool_result,omitempty"` // EventToolFinished
+	Response   *Response       `json:"response,omitempty"`    // EventTurnComplete
+	Error      string          `json:"error,omitempty"`       // EventError
+}
+
+// Session owns a Conversation and serializes turns against it, publishing
+// an event for every state change to its subscribers.
+type Session struct {
+	client *Client
+	turn   sync.Mutex // held for the duration of a turn
+
+	mu   sync.Mutex
+	conv Conversation
+	seq  int
+	subs map[*subscriber]struct{}
+}
+
+// NewSession creates a Session starting from conv.
+func NewSession(client *Client, conv Conversation) *Session {
+	return &Session{
+		client: client,
+		conv:   conv,
+		subs:   make(map[*subscriber]struct{}),
+	}
+}
+
+// Conversation returns the current conversation state.
+func (s *Session) Conversation() Conversation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conv
+}
+
+// Send runs one turn. Concurrent calls are serialized. Events for the new
+// messages are published only once the turn succeeds, so the feed never
+// shows messages that are not part of the Conversation.
+func (s *Session) Send(ctx context.Context, messages ...Message) (*Response, error) {
+	s.turn.Lock()
+	defer s.turn.Unlock()
+
+	conv, resp, err := s.client.Send(ctx, s.Conversation(), messages...)
+	if err != nil {
+		s.publish(Event{Kind: EventError, Error: err.Error()})
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.conv = conv
+	s.mu.Unlock()
+
+	for i := range messages {
+		s.publish(Event{Kind: EventMessageAppended, Message: &messages[i]})
+	}
+	s.publish(Event{Kind: EventMessageAppended, Message: &resp.Message})
+	s.publish(Event{Kind: EventTurnComplete, Response: resp})
+	return resp, nil
+}
+
+// Run runs one turn through runner's tool-use loop. Concurrent calls are
+// serialized with Send. Unlike Send, it publishes events as the run goes:
+// the messages of each model call as soon as the call succeeds, then the
+// started and finished events of the tool calls it made, so the feed stays
+// in order and a UI can show progress. The Conversation keeps up with the
+// feed, so after a failure it holds every completed model call, and calling
+// Run again without messages resumes the run.
+func (s *Session) Run(ctx context.Context, runner *Runner, messages ...Message) (*Response, error) {
+	s.turn.Lock()
+	defer s.turn.Unlock()
+
+	before := s.Conversation()
+	published := len(before.Messages)
+	r := *runner
+	r.onReply = func(conv Conversation) {
+		s.mu.Lock()
+		s.conv = conv
+		s.mu.Unlock()
+		for ; published < len(conv.Messages); published++ {
+			s.publish(Event{Kind: EventMessageAppended, Message: &conv.Messages[published]})
+		}
+	}
+	r.onToolStart = func(tc ToolCallData) {
+		s.publish(Event{Kind: EventToolStarted, ToolCall: &tc})
+	}
+	r.onToolFinish = func(tc ToolCallData, res ToolResultData) {
+		s.publish(Event{Kind: EventToolFinished, ToolCall: &tc, ToolResult: &res})
+	}
+
+	_, resp, err := r.Run(ctx, before, messages...)
+	if err != nil {
+		s.publish(Event{Kind: EventError, Error: err.Error()})
+		return nil, err
+	}
+	s.publish(Event{Kind: EventTurnComplete, Response: resp})
+	return resp, nil
+}
+
+// Subscribe returns a channel receiving every event published after the
+// call, in order. Slow subscribers never block the session; their events
+// are queued. Call cancel to unsubscribe, which closes the channel.
+func (s *Session) Subscribe() (events <-chan Event, cancel func()) {
+	sub := &subscriber{
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		out:    make(chan Event),
+	}
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	go sub.run()
+
+	var once sync.Once
+	return sub.out, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subs, sub)
+			s.mu.Unlock()
+			close(sub.done)
+		})
+	}
+}
+
+func (s *Session) publish(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	e.Seq = s.seq
+	for sub := range s.subs {
+		sub.push(e)
+	}
+}
+
+// subscriber is an unbounded queue drained into out by its own goroutine.
+type subscriber struct {
+	mu     sync.Mutex
+	queue  []Event
+	notify chan struct{}
+	done   chan struct{}
+	out    chan Event
+}
+
+func (sub *subscriber) push(e Event) {
+	sub.mu.Lock()
+	sub.queue = append(sub.queue, e)
+	sub.mu.Unlock()
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (sub *subscriber) run() {
+	defer close(sub.out)
+	for {
+		sub.mu.Lock()
+		if len(sub.queue) == 0 {
+			sub.mu.Unlock()
+			select {
+			case <-sub.notify:
+				continue
+			case <-sub.done:
+				return
+			}
+		}
+		e := sub.queue[0]
+		sub.queue = sub.queue[1:]
+		sub.mu.Unlock()
+
+		select {
+		case sub.out <- e:
+		case <-sub.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func receiveEvents(t *testing.T, ch <-chan Event, n int) []Event {
+	t.Helper()
+	var events []Event
+	for len(events) < n {
+		select {
+		case e := <-ch:
+			events = append(events, e)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out after %d of %d events", len(events), n)
+		}
+	}
+	return events
+}
+
+func TestSession_SendPublishesOrderedEvents(t *testing.T) {
+	session := NewSession(NewClientWithProvider(&mockProvider{resp: simpleResponse("Hello!")}), NewConversation("model"))
+	events, cancel := session.Subscribe()
+	defer cancel()
+
+	if _, err := session.Send(context.Background(), UserMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := receiveEvents(t, events, 3)
+	wantKinds := []EventKind{EventMessageAppended, EventMessageAppended, EventTurnComplete}
+	for i, e := range got {
+		if e.Kind != wantKinds[i] {
+			t.Errorf("event[%d].Kind = %q, want %q", i, e.Kind, wantKinds[i])
+		}
+		if e.Seq != i+1 {
+			t.Errorf("event[%d].Seq = %d, want %d", i, e.Seq, i+1)
+		}
+	}
+	if got[0].Message.Text() != "hi" || got[1].Message.Text() != "Hello!" {
+		t.Errorf("messages = %q, %q", got[0].Message.Text(), got[1].Message.Text())
+	}
+	if got[2].Response.Message.Text() != "Hello!" {
+		t.Errorf("turn response = %q", got[2].Response.Message.Text())
+	}
+	if n := len(session.Conversation().Messages); n != 2 {
+		t.Errorf("Messages len = %d, want 2", n)
+	}
+}
+
+func TestSession_RunPublishesToolEvents(t *testing.T) {
+	provider := &scriptedProvider{responses: []*Response{
+		toolUseResponse(ToolCallData{ID: "c1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}),
+		simpleResponse("15 degrees"),
+	}}
+	session := NewSession(NewClientWithProvider(provider), NewConversation("model"))
+	events, cancel := session.Subscribe()
+	defer cancel()
+
+	runner := NewRunner(session.client, weatherRegistry())
+	if _, err := session.Run(context.Background(), runner, UserMessage("weather?")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := receiveEvents(t, events, 7)
+	wantKinds := []EventKind{
+		EventMessageAppended, EventMessageAppended, // the prompt and the tool call
+		EventToolStarted, EventToolFinished,
+		EventMessageAppended, EventMessageAppended, // the tool result and the answer
+		EventTurnComplete,
+	}
+	for i, e := range got {
+		if e.Kind != wantKinds[i] {
+			t.Errorf("event[%d].Kind = %q, want %q", i, e.Kind, wantKinds[i])
+		}
+	}
+	if got[2].ToolCall.ID != "c1" || got[3].ToolResult.Content != `{"location":"Paris","temp":15}` {
+		t.Errorf("tool events = %+v, %+v", got[2], got[3])
+	}
+	if calls := got[1].Message.ToolCalls(); len(calls) != 1 || calls[0].ID != "c1" {
+		t.Errorf("message before the tool events = %+v, want the tool call", got[1].Message)
+	}
+	if got[5].Message.Text() != "15 degrees" {
+		t.Errorf("last message = %q", got[5].Message.Text())
+	}
+	if n := len(session.Conversation().Messages); n != 4 {
+		t.Errorf("Messages len = %d, want 4", n)
+	}
+	if runner.onToolStart != nil || runner.onReply != nil {
+		t.Error("Run modified the caller's runner")
+	}
+}
+
+func TestSession_RunFailureKeepsFeedAndConversationInStep(t *testing.T) {
+	provider := &scriptedProvider{responses: []*Response{
+		toolUseResponse(ToolCallData{ID: "c1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}),
+		simpleResponse("15 degrees"),
+	}}
+	sends := 0
+	failSecond := func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		if sends++; sends == 2 {
+			return nil, &Error{Kind: ErrServer, Message: "down"}
+		}
+		return next(ctx, conv)
+	}
+	session := NewSession(NewClientWithProvider(provider, WithMiddleware(failSecond)), NewConversation("model"))
+	events, cancel := session.Subscribe()
+	defer cancel()
+
+	runner := NewRunner(session.client, weatherRegistry())
+	if _, err := session.Run(context.Background(), runner, UserMessage("weather?")); err == nil {
+		t.Fatal("expected error")
+	}
+	got := receiveEvents(t, events, 5)
+	if got[1].Kind != EventMessageAppended || got[4].Kind != EventError {
+		t.Errorf("events = %+v", got)
+	}
+	if n := len(session.Conversation().Messages); n != 2 {
+		t.Fatalf("Messages len = %d, want the 2 published", n)
+	}
+
+	resp, err := session.Run(context.Background(), runner)
+	if err != nil || resp.Message.Text() != "15 degrees" {
+		t.Fatalf("resume = %v, %v", resp, err)
+	}
+	if n := len(session.Conversation().Messages); n != 4 {
+		t.Errorf("Messages len = %d, want 4", n)
+	}
+}
+
+func TestSession_ErrorEventLeavesConversation(t *testing.T) {
+	session := NewSession(NewClientWithProvider(&mockProvider{err: &Error{Kind: ErrServer, Message: "boom"}}), NewConversation("model"))
+	events, cancel := session.Subscribe()
+	defer cancel()
+
+	if _, err := session.Send(context.Background(), UserMessage("hi")); err == nil {
+		t.Fatal("expected error")
+	}
+	got := receiveEvents(t, events, 1)
+	if got[0].Kind != EventError || got[0].Error == "" {
+		t.Errorf("event = %+v", got[0])
+	}
+	if n := len(session.Conversation().Messages); n != 0 {
+		t.Errorf("Messages len = %d, want 0", n)
+	}
+}
+
+func TestSession_SlowSubscriberDoesNotBlock(t *testing.T) {
+	session := NewSession(NewClientWithProvider(&mockProvider{resp: simpleResponse("ok")}), NewConversation("model"))
+	events, cancel := session.Subscribe()
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		if _, err := session.Send(context.Background(), UserMessage("hi")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := receiveEvents(t, events, 15)
+	if got[14].Seq != 15 || got[14].Kind != EventTurnComplete {
+		t.Errorf("last event = %+v", got[14])
+	}
+}
+
+func TestSession_CancelClosesChannel(t *testing.T) {
+	session := NewSession(NewClientWithProvider(&mockProvider{resp: simpleResponse("ok")}), NewConversation("model"))
+	events, cancel := session.Subscribe()
+	cancel()
+	cancel() // idempotent
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected closed channel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed")
+	}
+	if _, err := session.Send(context.Background(), UserMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,32 @@
+-- Schema for sqlstore with MySQL 8. Open the database with parseTime=true.
+
+CREATE TABLE conversations (
+    id            VARCHAR(255) NOT NULL PRIMARY KEY,
+    revision      BIGINT NOT NULL,
+    model         VARCHAR(255) NOT NULL,
+    created_at    DATETIME(6) NULL,
+    updated_at    DATETIME(6) NULL,
+    message_count INT NOT NULL,
+    metadata      JSON NOT NULL,
+    data          JSON NOT NULL, -- the conversation without its messages
+    INDEX conversations_model_updated_at (model, updated_at),
+    INDEX conversations_updated_at (updated_at)
+);
+
+CREATE TABLE conversation_messages (
+    conversation_id VARCHAR(255) NOT NULL,
+    seq             INT NOT NULL,
+    role            VARCHAR(32) NOT NULL,
+    message         LONGTEXT NOT NULL,
+    PRIMARY KEY (conversation_id, seq),
+    FOREIGN KEY (conversation_id) REFERENCES conversations (id) ON DELETE CASCADE
+);
+
+CREATE TABLE conversation_metadata (
+    conversation_id VARCHAR(255) NOT NULL,
+    name            VARCHAR(255) NOT NULL,
+    value           VARCHAR(1024) NOT NULL,
+    PRIMARY KEY (conversation_id, name),
+    INDEX conversation_metadata_name_value (name, value(255)),
+    FOREIGN KEY (conversation_id) REFERENCES conversations (id) ON DELETE CASCADE
+);
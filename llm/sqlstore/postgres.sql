@@ -0,0 +1,32 @@
+-- Schema for sqlstore with PostgreSQL.
+
+CREATE TABLE conversations (
+    id            TEXT PRIMARY KEY,
+    revision      BIGINT NOT NULL,
+    model         TEXT NOT NULL,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    message_count INTEGER NOT NULL,
+    metadata      JSONB NOT NULL,
+    data          JSONB NOT NULL -- the conversation without its messages
+);
+
+CREATE INDEX conversations_model_updated_at ON conversations (model, updated_at);
+CREATE INDEX conversations_updated_at ON conversations (updated_at);
+
+CREATE TABLE conversation_messages (
+    conversation_id TEXT NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+    seq             INTEGER NOT NULL,
+    role            TEXT NOT NULL,
+    message         JSONB NOT NULL,
+    PRIMARY KEY (conversation_id, seq)
+);
+
+CREATE TABLE conversation_metadata (
+    conversation_id TEXT NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+    name            TEXT NOT NULL,
+    value           TEXT NOT NULL,
+    PRIMARY KEY (conversation_id, name)
+);
+
+CREATE INDEX conversation_metadata_name_value ON conversation_metadata (name, value);
@@ -0,0 +1,377 @@
+// Package sqlstore implements llm.Store on a SQL database through
+// database/sql, for PostgreSQL and MySQL.
+//
+// Each message is a row in conversation_messages and each metadata entry a
+// row in conversation_metadata, so conversations can be queried in SQL —
+// by user or any other metadata key, model, time range, or message content
+// — as well as through List. Create the tables with Dialect.Schema
+// (postgres.sql or mysql.sql in this directory) before use.
+package sqlstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quells-bot/unified-llm/llm"
+)
+
+// Dialect selects the SQL flavor of the database.
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	MySQL
+)
+
+var (
+	//go:embed postgres.sql
+	postgresSchema string
+	//go:embed mysql.sql
+	mysqlSchema string
+)
+
+// Schema returns the statements that create the store's tables and
+// indexes.
+func (d Dialect) Schema() string {
+	if d == MySQL {
+		return mysqlSchema
+	}
+	return postgresSchema
+}
+
+// rebind rewrites the ? placeholders in query for the dialect.
+func (d Dialect) rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Store is an llm.Store backed by a SQL database. It is safe for
+// concurrent use.
+type Store struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+var _ llm.Store = (*Store)(nil)
+
+// New creates a Store on db, whose tables must already exist.
+func New(db *sql.DB, dialect Dialect) *Store {
+	return &Store{db: db, dialect: dialect}
+}
+
+// Save implements llm.Store. The conversation row is inserted, or updated
+// where its revision matches, and its metadata rows are replaced, all in
+// one transaction. Message rows that are already stored unchanged are kept,
+// so saving after a turn only writes the turn's new messages.
+func (s *Store) Save(ctx context.Context, conv *llm.Conversation) error {
+	if conv.ID == "" {
+		return &llm.Error{Kind: llm.ErrInvalidRequest, Message: "conversation has no ID"}
+	}
+	saved := *conv
+	saved.Revision++
+	header := saved
+	header.Messages = nil
+	data, err := json.Marshal(header)
+	if err != nil {
+		return &llm.Error{Kind: llm.ErrInvalidRequest, Message: "encoding conversation " + conv.ID, Cause: err}
+	}
+	meta, err := json.Marshal(metadataOrEmpty(saved.Metadata))
+	if err != nil {
+		return &llm.Error{Kind: llm.ErrInvalidRequest, Message: "encoding conversation " + conv.ID, Cause: err}
+	}
+	messages := make([][]byte, len(saved.Messages))
+	for i, m := range saved.Messages {
+		if messages[i], err = json.Marshal(m); err != nil {
+			return &llm.Error{Kind: llm.ErrInvalidRequest, Message: "encoding conversation " + conv.ID + ": message " + strconv.Itoa(i), Cause: err}
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError("saving conversation "+conv.ID, err)
+	}
+	defer tx.Rollback()
+
+	from := 0 // first message row to write
+	row := []any{saved.Revision, saved.Model, nullTime(saved.CreatedAt), nullTime(saved.UpdatedAt), len(saved.Messages), string(meta), string(data)}
+	if conv.Revision == 0 {
+		_, err = tx.ExecContext(ctx, s.dialect.rebind(
+			`INSERT INTO conversations (revision, model, created_at, updated_at, message_count, metadata, data, id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+			append(row, saved.ID)...)
+		if err != nil {
+			// A failed statement aborts a PostgreSQL transaction, so look
+			// for the existing row outside it.
+			tx.Rollback()
+			if s.exists(ctx, conv.ID) {
+				return conflictError(conv)
+			}
+			return dbError("saving conversation "+conv.ID, err)
+		}
+	} else {
+		res, err := tx.ExecContext(ctx, s.dialect.rebind(
+			`UPDATE conversations SET revision = ?, model = ?, created_at = ?, updated_at = ?, message_count = ?, metadata = ?, data = ? WHERE id = ? AND revision = ?`),
+			append(row, saved.ID, conv.Revision)...)
+		if err != nil {
+			return dbError("saving conversation "+conv.ID, err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return dbError("saving conversation "+conv.ID, err)
+		} else if n == 0 {
+			return conflictError(conv)
+		}
+		if from, err = s.storedPrefix(ctx, tx, saved.ID, messages); err != nil {
+			return dbError("saving conversation "+conv.ID, err)
+		}
+	}
+
+	stmts := []struct {
+		query string
+		args  [][]any
+	}{
+		{`DELETE FROM conversation_messages WHERE conversation_id = ? AND seq >= ?`, [][]any{{saved.ID, from}}},
+		{`DELETE FROM conversation_metadata WHERE conversation_id = ?`, [][]any{{saved.ID}}},
+		{`INSERT INTO conversation_messages (conversation_id, seq, role, message) VALUES (?, ?, ?, ?)`, nil},
+		{`INSERT INTO conversation_metadata (conversation_id, name, value) VALUES (?, ?, ?)`, nil},
+	}
+	for i := from; i < len(saved.Messages); i++ {
+		stmts[2].args = append(stmts[2].args, []any{saved.ID, i, string(saved.Messages[i].Role), string(messages[i])})
+	}
+	for _, k := range slices.Sorted(maps.Keys(saved.Metadata)) {
+		stmts[3].args = append(stmts[3].args, []any{saved.ID, k, saved.Metadata[k]})
+	}
+	for _, st := range stmts {
+		for _, args := range st.args {
+			if _, err := tx.ExecContext(ctx, s.dialect.rebind(st.query), args...); err != nil {
+				return dbError("saving conversation "+conv.ID, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return dbError("saving conversation "+conv.ID, err)
+	}
+	conv.Revision = saved.Revision
+	return nil
+}
+
+// storedPrefix returns how many of the stored message rows of id match the
+// encoded messages, in order. Rows are compared as JSON values, since
+// PostgreSQL's JSONB does not keep the bytes it was given.
+func (s *Store) storedPrefix(ctx context.Context, tx *sql.Tx, id string, messages [][]byte) (int, error) {
+	rows, err := tx.QueryContext(ctx, s.dialect.rebind(`SELECT message FROM conversation_messages WHERE conversation_id = ? ORDER BY seq`), id)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	n := 0
+	for n < len(messages) && rows.Next() {
+		var stored []byte
+		if err := rows.Scan(&stored); err != nil {
+			return 0, err
+		}
+		if !sameJSON(stored, messages[n]) {
+			break
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+func sameJSON(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var va, vb any
+	return json.Unmarshal(a, &va) == nil && json.Unmarshal(b, &vb) == nil && reflect.DeepEqual(va, vb)
+}
+
+// Load implements llm.Store. The conversation and its messages are read
+// in one read-only transaction.
+func (s *Store) Load(ctx context.Context, id string) (llm.Conversation, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return llm.Conversation{}, dbError("loading conversation "+id, err)
+	}
+	defer tx.Rollback()
+
+	var data []byte
+	err = tx.QueryRowContext(ctx, s.dialect.rebind(`SELECT data FROM conversations WHERE id = ?`), id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return llm.Conversation{}, &llm.Error{Kind: llm.ErrNotFound, Message: "conversation " + id + " not found"}
+	}
+	if err != nil {
+		return llm.Conversation{}, dbError("loading conversation "+id, err)
+	}
+
+	rows, err := tx.QueryContext(ctx, s.dialect.rebind(`SELECT message FROM conversation_messages WHERE conversation_id = ? ORDER BY seq`), id)
+	if err != nil {
+		return llm.Conversation{}, dbError("loading conversation "+id, err)
+	}
+	defer rows.Close()
+	var messages []json.RawMessage
+	for rows.Next() {
+		var m []byte
+		if err := rows.Scan(&m); err != nil {
+			return llm.Conversation{}, dbError("loading conversation "+id, err)
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return llm.Conversation{}, dbError("loading conversation "+id, err)
+	}
+
+	// Reassemble the full document so that decoding migrates messages
+	// written by older schema versions along with the rest.
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return llm.Conversation{}, decodeError(id, err)
+	}
+	if doc["messages"], err = json.Marshal(messages); err != nil {
+		return llm.Conversation{}, decodeError(id, err)
+	}
+	full, err := json.Marshal(doc)
+	if err != nil {
+		return llm.Conversation{}, decodeError(id, err)
+	}
+	conv, err := llm.DecodeConversation(full)
+	if err != nil {
+		return llm.Conversation{}, decodeError(id, err)
+	}
+	return conv, nil
+}
+
+// List implements llm.Store. Filters run in SQL; conversations are listed
+// in ID order and the cursor is the last ID of the previous page. The
+// default limit is 100.
+func (s *Store) List(ctx context.Context, opts llm.ListOptions) (llm.ListPage, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	var where []string
+	var args []any
+	if opts.Cursor != "" {
+		where, args = append(where, "id > ?"), append(args, opts.Cursor)
+	}
+	if opts.Model != "" {
+		where, args = append(where, "model = ?"), append(args, opts.Model)
+	}
+	if !opts.UpdatedAfter.IsZero() {
+		where, args = append(where, "updated_at >= ?"), append(args, opts.UpdatedAfter.UTC())
+	}
+	if !opts.UpdatedBefore.IsZero() {
+		where, args = append(where, "(updated_at < ? OR updated_at IS NULL)"), append(args, opts.UpdatedBefore.UTC())
+	}
+	for _, k := range slices.Sorted(maps.Keys(opts.Metadata)) {
+		where = append(where, "EXISTS (SELECT 1 FROM conversation_metadata m WHERE m.conversation_id = conversations.id AND m.name = ? AND m.value = ?)")
+		args = append(args, k, opts.Metadata[k])
+	}
+	query := `SELECT id, revision, model, created_at, updated_at, message_count, metadata FROM conversations`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id LIMIT " + strconv.Itoa(limit+1)
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return llm.ListPage{}, dbError("listing conversations", err)
+	}
+	defer rows.Close()
+	var page llm.ListPage
+	for rows.Next() {
+		var info llm.ConversationInfo
+		var created, updated sql.NullTime
+		var meta []byte
+		if err := rows.Scan(&info.ID, &info.Revision, &info.Model, &created, &updated, &info.Messages, &meta); err != nil {
+			return llm.ListPage{}, dbError("listing conversations", err)
+		}
+		info.CreatedAt, info.UpdatedAt = created.Time, updated.Time
+		if err := json.Unmarshal(meta, &info.Metadata); err != nil {
+			return llm.ListPage{}, decodeError(info.ID, err)
+		}
+		if len(info.Metadata) == 0 {
+			info.Metadata = nil
+		}
+		if len(page.Conversations) == limit {
+			page.Next = page.Conversations[limit-1].ID
+			break
+		}
+		page.Conversations = append(page.Conversations, info)
+	}
+	if err := rows.Err(); err != nil {
+		return llm.ListPage{}, dbError("listing conversations", err)
+	}
+	return page, nil
+}
+
+// Delete implements llm.Store.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError("deleting conversation "+id, err)
+	}
+	defer tx.Rollback()
+	for _, table := range []string{"conversation_messages", "conversation_metadata"} {
+		if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM `+table+` WHERE conversation_id = ?`), id); err != nil {
+			return dbError("deleting conversation "+id, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM conversations WHERE id = ?`), id); err != nil {
+		return dbError("deleting conversation "+id, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return dbError("deleting conversation "+id, err)
+	}
+	return nil
+}
+
+func (s *Store) exists(ctx context.Context, id string) bool {
+	var n int
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT 1 FROM conversations WHERE id = ?`), id).Scan(&n)
+	return err == nil
+}
+
+func metadataOrEmpty(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+// nullTime stores the zero time as NULL and others in UTC.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+func conflictError(conv *llm.Conversation) error {
+	return &llm.Error{Kind: llm.ErrConflict, Message: "conversation " + conv.ID + " is not at revision " + strconv.FormatInt(conv.Revision, 10)}
+}
+
+func decodeError(id string, err error) error {
+	return &llm.Error{Kind: llm.ErrServer, Message: "decoding conversation " + id, Cause: err}
+}
+
+func dbError(op string, err error) error {
+	return &llm.Error{Kind: llm.ErrServer, Message: op + ": " + err.Error(), Cause: err}
+}
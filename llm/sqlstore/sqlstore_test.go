@@ -0,0 +1,198 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "modernc.org/sqlite"
+
+	"github.com/quells-bot/unified-llm/llm"
+	"github.com/quells-bot/unified-llm/llm/llmtest"
+)
+
+// openSQLite creates a SQLite database with the PostgreSQL schema, with
+// types SQLite's driver does not recognize swapped for ones it does.
+// SQLite accepts both ? and $n placeholders, so it can stand in for either
+// dialect.
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "llm.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	schema := strings.NewReplacer("TIMESTAMPTZ", "TIMESTAMP", "JSONB", "TEXT").Replace(Postgres.Schema())
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestStoreConformance(t *testing.T) {
+	for _, d := range []struct {
+		name    string
+		dialect Dialect
+	}{{"postgres", Postgres}, {"mysql", MySQL}} {
+		t.Run(d.name, func(t *testing.T) {
+			llmtest.RunStoreConformance(t, func(t *testing.T) llm.Store {
+				return New(openSQLite(t), d.dialect)
+			})
+		})
+	}
+}
+
+func TestSave_WritesMessageAndMetadataRows(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	store := New(db, Postgres)
+	conv := llm.NewConversation("m", llm.WithID("c1"), llm.WithMetadata("user", "u1"), llm.WithMetadata("team", "t1"))
+	conv.Messages = []llm.Message{llm.UserMessage("hi"), llm.AssistantMessage("hello"), llm.UserMessage("bye")}
+	if err := store.Save(ctx, &conv); err != nil {
+		t.Fatal(err)
+	}
+	conv.Messages = conv.Messages[:2]
+	if err := store.Save(ctx, &conv); err != nil {
+		t.Fatal(err)
+	}
+
+	var roles []string
+	rows, err := db.Query(`SELECT role FROM conversation_messages WHERE conversation_id = 'c1' ORDER BY seq`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var r string
+		rows.Scan(&r)
+		roles = append(roles, r)
+	}
+	rows.Close()
+	if strings.Join(roles, ",") != "user,assistant" {
+		t.Errorf("message rows = %v", roles)
+	}
+
+	var user string
+	if err := db.QueryRow(`SELECT value FROM conversation_metadata WHERE conversation_id = 'c1' AND name = 'user'`).Scan(&user); err != nil || user != "u1" {
+		t.Errorf("metadata row = %q, %v", user, err)
+	}
+
+	if err := store.Delete(ctx, "c1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"conversations", "conversation_messages", "conversation_metadata"} {
+		var n int
+		db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n)
+		if n != 0 {
+			t.Errorf("%s has %d rows after Delete", table, n)
+		}
+	}
+}
+
+func TestSave_WritesOnlyChangedMessages(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	store := New(db, Postgres)
+	conv := llm.NewConversation("m", llm.WithID("c1"))
+	conv.Messages = []llm.Message{llm.UserMessage("hi"), llm.AssistantMessage("hello")}
+	if err := store.Save(ctx, &conv); err != nil {
+		t.Fatal(err)
+	}
+	// Mark the stored rows so a rewrite would show.
+	if _, err := db.Exec(`UPDATE conversation_messages SET role = 'kept'`); err != nil {
+		t.Fatal(err)
+	}
+	roles := func() string {
+		var got []string
+		rows, err := db.Query(`SELECT role FROM conversation_messages WHERE conversation_id = 'c1' ORDER BY seq`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var r string
+			rows.Scan(&r)
+			got = append(got, r)
+		}
+		return strings.Join(got, ",")
+	}
+
+	conv.Messages = append(conv.Messages, llm.UserMessage("more"), llm.AssistantMessage("sure"))
+	if err := store.Save(ctx, &conv); err != nil {
+		t.Fatal(err)
+	}
+	if got := roles(); got != "kept,kept,user,assistant" {
+		t.Errorf("after append, rows = %s", got)
+	}
+
+	// An edited message is rewritten along with everything after it.
+	conv.Messages[1] = llm.AssistantMessage("hello again")
+	if err := store.Save(ctx, &conv); err != nil {
+		t.Fatal(err)
+	}
+	if got := roles(); got != "kept,assistant,user,assistant" {
+		t.Errorf("after edit, rows = %s", got)
+	}
+	loaded, err := store.Load(ctx, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Messages) != 4 || loaded.Messages[1].Text() != "hello again" {
+		t.Errorf("loaded messages = %+v", loaded.Messages)
+	}
+}
+
+// TestMySQL runs the conformance suite against a real MySQL 8 server when
+// SQLSTORE_MYSQL_DSN names one, such as
+// "user:pass@tcp(localhost:3306)/llmtest?parseTime=true". The store's
+// tables in that database are dropped and recreated.
+func TestMySQL(t *testing.T) {
+	dsn := os.Getenv("SQLSTORE_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("SQLSTORE_MYSQL_DSN not set")
+	}
+	llmtest.RunStoreConformance(t, func(t *testing.T) llm.Store {
+		db, err := sql.Open("mysql", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		for _, table := range []string{"conversation_metadata", "conversation_messages", "conversations"} {
+			if _, err := db.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, stmt := range strings.Split(MySQL.Schema(), ";") {
+			if strings.Contains(stmt, "CREATE") {
+				if _, err := db.Exec(stmt); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		return New(db, MySQL)
+	})
+}
+
+func TestRebind(t *testing.T) {
+	q := `SELECT id FROM conversations WHERE model = ? AND id > ?`
+	if got := Postgres.rebind(q); got != `SELECT id FROM conversations WHERE model = $1 AND id > $2` {
+		t.Errorf("Postgres = %s", got)
+	}
+	if got := MySQL.rebind(q); got != q {
+		t.Errorf("MySQL = %s", got)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	for _, d := range []Dialect{Postgres, MySQL} {
+		s := d.Schema()
+		for _, table := range []string{"conversations", "conversation_messages", "conversation_metadata"} {
+			if !strings.Contains(s, "CREATE TABLE "+table+" (") {
+				t.Errorf("dialect %d schema lacks table %s", d, table)
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package llm
+
+import "time"
+
+// ConversationStats summarizes a conversation, for dashboards and
+// trimming heuristics.
+type ConversationStats struct {
+	Messages        map[Role]int   `json:"messages"`           // messages by role
+	ToolCalls       int            `json:"tool_calls"`         // tool calls made by the model
+	ToolCallsByName map[string]int `json:"tool_calls_by_name"` // tool calls by tool name
+	ToolErrors      int            `json:"tool_errors"`        // tool results marked as errors
+	Images          int            `json:"images"`             // images in messages and tool results
+	EstimatedTokens int            `json:"estimated_tokens"`   // EstimateTokens for the conversation's model
+	Usage           Usage          `json:"usage"`              // cumulative usage reported by providers
+	Elapsed         time.Duration  `json:"elapsed"`            // UpdatedAt - CreatedAt, or zero if either is unset
+}
+
+// Stats returns statistics about the conversation. It makes no network
+// calls.
+func (c Conversation) Stats() ConversationStats {
+	s := ConversationStats{
+		Messages:        make(map[Role]int),
+		ToolCallsByName: make(map[string]int),
+		EstimatedTokens: c.EstimateTokens(""),
+		Usage:           c.Usage,
+	}
+	for _, m := range c.Messages {
+		s.Messages[m.Role]++
+		for _, p := range m.Content {
+			switch {
+			case p.Kind == ContentToolCall && p.ToolCall != nil:
+				s.ToolCalls++
+				s.ToolCallsByName[p.ToolCall.Name]++
+			case p.Kind == ContentToolResult && p.ToolResult != nil:
+				if p.ToolResult.IsError {
+					s.ToolErrors++
+				}
+				s.Images += len(p.ToolResult.Images)
+			case p.Kind == ContentImage:
+				s.Images++
+			}
+		}
+	}
+	if !c.CreatedAt.IsZero() && !c.UpdatedAt.IsZero() {
+		s.Elapsed = c.UpdatedAt.Sub(c.CreatedAt)
+	}
+	return s
+}
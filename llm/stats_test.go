@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	conv := Conversation{
+		Model: "gpt-4o",
+		Messages: []Message{
+			UserMessage("weather in Paris and Rome?"),
+			toolUseResponse(ToolCallData{ID: "c1", Name: "get_weather"}, ToolCallData{ID: "c2", Name: "get_weather"}).Message,
+			ToolResultMessage("c1", "sunny", false),
+			ToolCallData{ID: "c2"}.ImageResult("radar unavailable", ImageData{Data: []byte{1}}),
+			AssistantMessage("Paris is sunny."),
+			{Role: RoleUser, Content: []ContentPart{{Kind: ContentImage, Image: &ImageData{URL: "https://example.com/a.png"}}}},
+			toolUseResponse(ToolCallData{ID: "c3", Name: "search"}).Message,
+			ToolResultMessage("c3", "no results", true),
+		},
+		Usage:     Usage{InputTokens: 300, OutputTokens: 40},
+		CreatedAt: start,
+		UpdatedAt: start.Add(90 * time.Second),
+	}
+
+	s := conv.Stats()
+	if s.Messages[RoleUser] != 2 || s.Messages[RoleAssistant] != 3 || s.Messages[RoleTool] != 3 {
+		t.Errorf("Messages = %v", s.Messages)
+	}
+	if s.ToolCalls != 3 || s.ToolCallsByName["get_weather"] != 2 || s.ToolCallsByName["search"] != 1 {
+		t.Errorf("ToolCalls = %d, by name %v", s.ToolCalls, s.ToolCallsByName)
+	}
+	if s.ToolErrors != 1 || s.Images != 2 {
+		t.Errorf("ToolErrors = %d, Images = %d", s.ToolErrors, s.Images)
+	}
+	if s.EstimatedTokens != conv.EstimateTokens("") || s.EstimatedTokens == 0 {
+		t.Errorf("EstimatedTokens = %d", s.EstimatedTokens)
+	}
+	if s.Usage != conv.Usage || s.Elapsed != 90*time.Second {
+		t.Errorf("Usage = %+v, Elapsed = %v", s.Usage, s.Elapsed)
+	}
+
+	if s := NewConversation("m").Stats(); s.Elapsed != 0 || len(s.Messages) != 0 {
+		t.Errorf("empty conversation stats = %+v", s)
+	}
+}
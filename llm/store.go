@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Store persists conversations by ID, outside of workflow payloads.
+//
+// Save uses optimistic locking: it succeeds only if the stored revision
+// equals conv.Revision (zero for a conversation that has never been
+// saved), then increments conv.Revision. A Save based on a stale copy fails
+// with ErrConflict; reload the conversation and reapply the change.
+//
+// Load fails with ErrNotFound for an unknown ID. Delete of an unknown ID is
+// not an error.
+type Store interface {
+	Save(ctx context.Context, conv *Conversation) error
+	Load(ctx context.Context, id string) (Conversation, error)
+	List(ctx context.Context, opts ListOptions) (ListPage, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// ListOptions selects the conversations returned by Store.List. Zero
+// fields do not filter.
+type ListOptions struct {
+	Model         string
+	Metadata      map[string]string // entries every conversation must have, such as a user ID
+	UpdatedAfter  time.Time         // inclusive
+	UpdatedBefore time.Time         // exclusive
+	Limit         int               // page size; the store's default if zero
+	Cursor        string            // ListPage.Next of the previous page
+}
+
+// Matches reports whether info passes the filters in o, for Store
+// implementations that filter in Go.
+func (o ListOptions) Matches(info ConversationInfo) bool {
+	switch {
+	case o.Model != "" && info.Model != o.Model:
+		return false
+	case !o.UpdatedAfter.IsZero() && info.UpdatedAt.Before(o.UpdatedAfter):
+		return false
+	case !o.UpdatedBefore.IsZero() && !info.UpdatedAt.Before(o.UpdatedBefore):
+		return false
+	}
+	for k, v := range o.Metadata {
+		if got, ok := info.Metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ListPage is a page of Store.List results.
+type ListPage struct {
+	Conversations []ConversationInfo
+	Next          string // cursor for the next page, or "" if this is the last
+}
+
+// ConversationInfo summarizes a stored conversation without its messages.
+type ConversationInfo struct {
+	ID        string            `json:"id"`
+	Model     string            `json:"model"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Messages  int               `json:"messages"` // number of messages
+	Revision  int64             `json:"revision"`
+}
+
+// Info returns the conversation's summary.
+func (c Conversation) Info() ConversationInfo {
+	return ConversationInfo{
+		ID:        c.ID,
+		Model:     c.Model,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Metadata:  c.Metadata,
+		Messages:  len(c.Messages),
+		Revision:  c.Revision,
+	}
+}
+
+func conflictError(id string, want, got int64) *Error {
+	return &Error{Kind: ErrConflict, Message: "conversation " + id + " is at revision " + strconv.FormatInt(got, 10) + ", not " + strconv.FormatInt(want, 10)}
+}
+
+func notFoundError(id string) *Error {
+	return &Error{Kind: ErrNotFound, Message: "conversation " + id + " not found"}
+}
+
+func missingIDError() *Error {
+	return &Error{Kind: ErrInvalidRequest, Message: "conversation has no ID"}
+}
+
+// MemoryStore is a Store that keeps conversations in memory, for tests and
+// single-process use. It is safe for concurrent use. Conversations are
+// stored as JSON, so later changes to a saved or loaded Conversation never
+// affect the stored copy.
+type MemoryStore struct {
+	mu    sync.Mutex
+	convs map[string][]byte
+	infos map[string]ConversationInfo
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{convs: make(map[string][]byte), infos: make(map[string]ConversationInfo)}
+}
+
+// Save implements Store.
+func (s *MemoryStore) Save(_ context.Context, conv *Conversation) error {
+	if conv.ID == "" {
+		return missingIDError()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if got := s.infos[conv.ID].Revision; got != conv.Revision {
+		return conflictError(conv.ID, conv.Revision, got)
+	}
+	saved := *conv
+	saved.Revision++
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return &Error{Kind: ErrInvalidRequest, Message: "encoding conversation " + conv.ID, Cause: err}
+	}
+	info := saved.Info()
+	info.Metadata = maps.Clone(info.Metadata)
+	s.convs[conv.ID], s.infos[conv.ID] = data, info
+	conv.Revision = saved.Revision
+	return nil
+}
+
+// Load implements Store.
+func (s *MemoryStore) Load(_ context.Context, id string) (Conversation, error) {
+	s.mu.Lock()
+	data, ok := s.convs[id]
+	s.mu.Unlock()
+	if !ok {
+		return Conversation{}, notFoundError(id)
+	}
+	return DecodeConversation(data)
+}
+
+// List implements Store. Conversations are listed in ID order; the cursor
+// is the last ID of the previous page. The default limit is 100.
+func (s *MemoryStore) List(_ context.Context, opts ListOptions) (ListPage, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var page ListPage
+	for _, id := range slices.Sorted(maps.Keys(s.infos)) {
+		if id <= opts.Cursor || !opts.Matches(s.infos[id]) {
+			continue
+		}
+		if len(page.Conversations) == limit {
+			page.Next = page.Conversations[limit-1].ID
+			break
+		}
+		info := s.infos[id]
+		info.Metadata = maps.Clone(info.Metadata)
+		page.Conversations = append(page.Conversations, info)
+	}
+	return page, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.convs, id)
+	delete(s.infos, id)
+	return nil
+}
@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestListOptions_Matches(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	info := ConversationInfo{ID: "c", Model: "m", UpdatedAt: t0, Metadata: map[string]string{"user": "u1", "team": "t"}}
+	tests := []struct {
+		name string
+		opts ListOptions
+		want bool
+	}{
+		{"no filters", ListOptions{}, true},
+		{"model", ListOptions{Model: "m"}, true},
+		{"other model", ListOptions{Model: "x"}, false},
+		{"metadata subset", ListOptions{Metadata: map[string]string{"user": "u1"}}, true},
+		{"metadata mismatch", ListOptions{Metadata: map[string]string{"user": "u2"}}, false},
+		{"metadata missing", ListOptions{Metadata: map[string]string{"org": ""}}, false},
+		{"after is inclusive", ListOptions{UpdatedAfter: t0}, true},
+		{"before is exclusive", ListOptions{UpdatedBefore: t0}, false},
+		{"in range", ListOptions{UpdatedAfter: t0.Add(-time.Hour), UpdatedBefore: t0.Add(time.Hour)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.Matches(info); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_CopiesConversations(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	conv := NewConversation("m", WithID("c1"), WithMetadata("user", "u1"))
+	conv.Messages = []Message{UserMessage("hi")}
+	if err := store.Save(ctx, &conv); err != nil {
+		t.Fatal(err)
+	}
+	conv.Messages[0].Content[0].Text = "changed"
+	conv.Metadata["user"] = "u2"
+
+	got, err := store.Load(ctx, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Messages[0].Text() != "hi" || got.Metadata["user"] != "u1" {
+		t.Errorf("stored copy changed: %+v", got)
+	}
+	page, _ := store.List(ctx, ListOptions{Metadata: map[string]string{"user": "u1"}})
+	if len(page.Conversations) != 1 {
+		t.Errorf("List = %+v", page)
+	}
+}
@@ -0,0 +1,164 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ResponseFormatError is the Cause of an ErrInvalidResponse error: a reply
+// that does not match the conversation's response schema.
+type ResponseFormatError struct {
+	Text  string // the reply text
+	Err   error  // how it fails the schema
+	Usage Usage  // tokens spent on the rejected reply
+}
+
+func (e *ResponseFormatError) Error() string { return e.Err.Error() }
+
+func (e *ResponseFormatError) Unwrap() error { return e.Err }
+
+// checkResponseFormat checks the text of resp against conv's response
+// schema, if it has one. Replies that end in tool use are not checked.
+func checkResponseFormat(conv *Conversation, resp *Response) error {
+	rf := conv.Config.ResponseFormat
+	if rf == nil || rf.Type != ResponseFormatJSONSchema || len(rf.Schema) == 0 || resp.FinishReason == FinishReasonToolUse {
+		return nil
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(rf.Schema, &schema); err != nil {
+		return &Error{Kind: ErrConfig, Message: "response schema " + rf.schemaName() + " is not a JSON object", Cause: err}
+	}
+	text := resp.Message.Text()
+	var v any
+	err := json.Unmarshal([]byte(strings.TrimSpace(text)), &v)
+	if err == nil {
+		err = checkSchema(schema, v, "")
+	}
+	if err != nil {
+		return &Error{
+			Kind:    ErrInvalidResponse,
+			Message: fmt.Sprintf("reply does not match response schema %s: %v", rf.schemaName(), err),
+			Cause:   &ResponseFormatError{Text: text, Err: err, Usage: resp.Usage},
+		}
+	}
+	return nil
+}
+
+// CompleteOption configures CompleteAs.
+type CompleteOption func(*completeOptions)
+
+type completeOptions struct {
+	name    string
+	retries int
+}
+
+// WithSchemaName names the response schema CompleteAs sends. By default it
+// is the name of the result type.
+func WithSchemaName(name string) CompleteOption {
+	return func(o *completeOptions) {
+		o.name = name
+	}
+}
+
+// WithSchemaRetries makes CompleteAs re-prompt the model up to n times when
+// a reply does not match the schema, sending the invalid reply back with a
+// description of what was wrong.
+func WithSchemaRetries(n int) CompleteOption {
+	return func(o *completeOptions) {
+		o.retries = n
+	}
+}
+
+// CompleteAs sends msg on conv with a response schema derived from T, as
+// NewTypedTool derives tool parameters, and decodes the reply into T:
+//
+//	city, _, err := llm.CompleteAs[City](ctx, client, conv, llm.UserMessage(text))
+//
+// T must be a struct or pointer to struct. It returns the updated
+// conversation as Send does, with the caller's own response format. A
+// reply that does not match the schema fails with ErrInvalidResponse unless
+// WithSchemaRetries allows another attempt. Rejected replies and the retry
+// prompts are not added to the returned conversation, but their usage is,
+// even when CompleteAs fails.
+func CompleteAs[T any](ctx context.Context, c *Client, conv Conversation, msg Message, opts ...CompleteOption) (T, Conversation, error) {
+	var zero T
+	orig := conv
+	var o completeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return zero, conv, &Error{Kind: ErrConfig, Message: fmt.Sprintf("CompleteAs: result must be a struct, got %s", t)}
+	}
+	schema, err := structSchema(t, map[reflect.Type]bool{})
+	if err == nil {
+		var raw json.RawMessage
+		if raw, err = json.Marshal(schema); err == nil {
+			if o.name == "" && !strings.ContainsAny(t.Name(), "[]., ") {
+				o.name = t.Name()
+			}
+			conv.Config.ResponseFormat = &ResponseFormat{Type: ResponseFormatJSONSchema, Name: o.name, Schema: raw}
+		}
+	}
+	if err != nil {
+		return zero, conv, &Error{Kind: ErrConfig, Message: fmt.Sprintf("CompleteAs: %s: %v", t, err), Cause: err}
+	}
+
+	base := len(conv.Messages)
+	messages := []Message{msg}
+	for attempt := 0; ; attempt++ {
+		next, resp, err := c.Send(ctx, conv, messages...)
+		// Carry the spend forward so rejected attempts are counted.
+		var formatErr *ResponseFormatError
+		if resp != nil {
+			conv.Usage, conv.UsageHistory = next.Usage, next.UsageHistory
+		} else if errors.As(err, &formatErr) {
+			conv.Usage = conv.Usage.Add(formatErr.Usage)
+			if c.usageHistory {
+				conv.UsageHistory = append(slices.Clip(conv.UsageHistory), TurnUsage{
+					Model: conv.Model,
+					Usage: formatErr.Usage,
+					Cost:  formatErr.Usage.Cost(conv.Model),
+					At:    time.Now().UTC(),
+				})
+			}
+		}
+		var result T
+		if err == nil {
+			text := resp.Message.Text()
+			if decodeErr := json.Unmarshal([]byte(strings.TrimSpace(text)), &result); decodeErr != nil {
+				err = &Error{
+					Kind:    ErrInvalidResponse,
+					Message: fmt.Sprintf("decoding reply as %s: %v", t, decodeErr),
+					Cause:   &ResponseFormatError{Text: text, Err: decodeErr},
+				}
+			}
+		}
+		if err == nil || attempt >= o.retries || !errors.As(err, &formatErr) {
+			if err != nil {
+				orig.Usage, orig.UsageHistory = conv.Usage, conv.UsageHistory
+				return zero, orig, err
+			}
+			// Keep only the prompt and the accepted reply, and leave later
+			// turns free of the schema.
+			next.Messages = append(append(next.Messages[:base:base], msg), resp.Message)
+			next.Config.ResponseFormat = orig.Config.ResponseFormat
+			return result, next, nil
+		}
+		messages = append(messages, AssistantMessage(formatErr.Text), UserMessage(schemaRetryPrompt(formatErr)))
+	}
+}
+
+func schemaRetryPrompt(err *ResponseFormatError) string {
+	return fmt.Sprintf("Your previous response did not match the required JSON schema: %v\nRespond again with only the corrected JSON.", err.Err)
+}
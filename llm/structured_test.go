@@ -0,0 +1,156 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var citySchema = json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`)
+
+func TestClient_ResponseSchema(t *testing.T) {
+	p := &scriptedProvider{responses: []*Response{
+		simpleResponse(`{"city": "Paris"}`),
+		simpleResponse(`{"town": "Paris"}`),
+		simpleResponse(`Paris`),
+		toolUseResponse(ToolCallData{ID: "c1", Name: "lookup", Arguments: json.RawMessage(`{}`)}),
+	}}
+	client := NewClientWithProvider(p)
+	conv := NewConversation("m", WithResponseSchema("city", citySchema))
+
+	if _, resp, err := client.Send(context.Background(), conv, UserMessage("where?")); err != nil || resp.Message.Text() != `{"city": "Paris"}` {
+		t.Fatalf("valid reply: %v", err)
+	}
+	for _, want := range []string{`missing required property "city"`, "invalid character"} {
+		_, _, err := client.Send(context.Background(), conv, UserMessage("where?"))
+		var llmErr *Error
+		var formatErr *ResponseFormatError
+		if !errors.As(err, &llmErr) || llmErr.Kind != ErrInvalidResponse || !errors.As(err, &formatErr) {
+			t.Fatalf("err = %v, want ErrInvalidResponse", err)
+		}
+		if formatErr.Text == "" || !strings.Contains(formatErr.Error(), want) {
+			t.Errorf("ResponseFormatError = %q, %v; want %q", formatErr.Text, formatErr, want)
+		}
+	}
+	if _, _, err := client.Send(context.Background(), conv, UserMessage("where?")); err != nil {
+		t.Errorf("tool use reply was checked: %v", err)
+	}
+}
+
+type extractedCity struct {
+	City       string `json:"city" description:"City name"`
+	Population int    `json:"population,omitempty"`
+}
+
+func TestCompleteAs(t *testing.T) {
+	p := &scriptedProvider{responses: []*Response{simpleResponse(` {"city": "Paris", "population": 2100000}`)}}
+	client := NewClientWithProvider(p)
+
+	city, conv, err := CompleteAs[extractedCity](context.Background(), client, NewConversation("m"), UserMessage("Paris has 2.1M people."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if city != (extractedCity{City: "Paris", Population: 2100000}) {
+		t.Errorf("city = %+v", city)
+	}
+	if len(conv.Messages) != 2 {
+		t.Errorf("messages = %d, want 2", len(conv.Messages))
+	}
+	rf := p.received[0].Config.ResponseFormat
+	if rf == nil || rf.Type != ResponseFormatJSONSchema || rf.Name != "extractedCity" {
+		t.Fatalf("response format = %+v", rf)
+	}
+	testAssertJSONEqual(t, rf.Schema, []byte(`{"type":"object","properties":{"city":{"type":"string","description":"City name"},"population":{"type":"integer"}},"required":["city"]}`))
+}
+
+func TestCompleteAs_ThenPlainTurn(t *testing.T) {
+	p := &scriptedProvider{responses: []*Response{
+		simpleResponse(`{"city": "Paris"}`),
+		simpleResponse("Paris is lovely in spring."),
+	}}
+	client := NewClientWithProvider(p)
+
+	_, conv, err := CompleteAs[extractedCity](context.Background(), client, NewConversation("m"), UserMessage("I live in Paris."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.Config.ResponseFormat != nil {
+		t.Errorf("response format kept: %+v", conv.Config.ResponseFormat)
+	}
+	_, resp, err := client.Send(context.Background(), conv, UserMessage("When should I visit?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Text() != "Paris is lovely in spring." || p.received[1].Config.ResponseFormat != nil {
+		t.Errorf("reply = %q, response format = %+v", resp.Message.Text(), p.received[1].Config.ResponseFormat)
+	}
+}
+
+func TestCompleteAs_Retries(t *testing.T) {
+	p := &scriptedProvider{responses: []*Response{
+		simpleResponse(`{"town": "Paris"}`),
+		simpleResponse(`{"city": "Paris"}`),
+	}}
+	client := NewClientWithProvider(p)
+	conv := NewConversation("m", WithSystem("Extract the city."))
+
+	city, got, err := CompleteAs[*extractedCity](context.Background(), client, conv, UserMessage("I live in Paris."), WithSchemaRetries(1), WithSchemaName("city"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if city.City != "Paris" {
+		t.Errorf("city = %+v", city)
+	}
+	retry := p.received[1].Messages
+	if len(retry) != 3 || retry[1].Text() != `{"town": "Paris"}` || !strings.Contains(retry[2].Text(), `missing required property "city"`) {
+		t.Errorf("retry messages = %+v", retry)
+	}
+	// Rejected replies are not kept.
+	if len(got.Messages) != 2 || got.Messages[1].Text() != `{"city": "Paris"}` {
+		t.Errorf("messages = %+v", got.Messages)
+	}
+	if p.received[1].Config.ResponseFormat.Name != "city" {
+		t.Errorf("schema name = %q", p.received[1].Config.ResponseFormat.Name)
+	}
+	// The rejected attempt's usage is kept.
+	if got.Usage.InputTokens != 20 || got.Usage.OutputTokens != 10 {
+		t.Errorf("usage = %+v, want both attempts", got.Usage)
+	}
+}
+
+func TestCompleteAs_RetriesExhaustedKeepUsage(t *testing.T) {
+	p := &scriptedProvider{responses: []*Response{
+		simpleResponse(`{"town": "Paris"}`),
+		simpleResponse(`{"town": "Paris"}`),
+	}}
+	client := NewClientWithProvider(p, WithUsageHistory())
+	conv := NewConversation("m")
+
+	_, got, err := CompleteAs[extractedCity](context.Background(), client, conv, UserMessage("I live in Paris."), WithSchemaRetries(1))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if len(got.Messages) != 0 {
+		t.Errorf("messages = %+v, want the original conversation", got.Messages)
+	}
+	if got.Usage.InputTokens != 20 || len(got.UsageHistory) != 2 {
+		t.Errorf("usage = %+v, history = %d, want both attempts", got.Usage, len(got.UsageHistory))
+	}
+}
+
+func TestCompleteAs_Errors(t *testing.T) {
+	p := &scriptedProvider{responses: []*Response{simpleResponse(`{"city": 7}`)}}
+	client := NewClientWithProvider(p)
+	_, _, err := CompleteAs[extractedCity](context.Background(), client, NewConversation("m"), UserMessage("?"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrInvalidResponse {
+		t.Errorf("err = %v, want ErrInvalidResponse", err)
+	}
+
+	_, _, err = CompleteAs[[]string](context.Background(), client, NewConversation("m"), UserMessage("?"))
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrConfig {
+		t.Errorf("err = %v, want ErrConfig", err)
+	}
+}
@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"slices"
+)
+
+// Thinking returns the reasoning text of the response message.
+func (r *Response) Thinking() string {
+	return r.Message.Thinking()
+}
+
+// WithoutThinking returns a copy of c with every thinking content part
+// removed, such as before persisting a conversation whose reasoning should
+// not be kept. Assistant messages left empty are dropped. c is not
+// modified.
+func (c Conversation) WithoutThinking() Conversation {
+	c.Messages = stripThinking(c.Messages)
+	return c
+}
+
+// stripThinking returns msgs without thinking parts, sharing the messages
+// that have none.
+func stripThinking(msgs []Message) []Message {
+	out := make([]Message, 0, len(msgs))
+	for _, m := range msgs {
+		if !slices.ContainsFunc(m.Content, isThinking) {
+			out = append(out, m)
+			continue
+		}
+		m.Content = slices.DeleteFunc(slices.Clone(m.Content), isThinking)
+		if len(m.Content) > 0 || m.Role != RoleAssistant {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func isThinking(p ContentPart) bool { return p.Kind == ContentThinking }
+
+// WithThinkingStripped adds a ThinkingStripper middleware to the client.
+func WithThinkingStripped() ClientOption {
+	return WithMiddleware(ThinkingStripper())
+}
+
+// ThinkingStripper returns middleware that removes thinking parts from the
+// request sent to the provider, for providers or models that reject
+// reasoning from earlier turns. The conversation returned by Client.Send
+// keeps them. Claude requires the thinking of an assistant turn that
+// called tools to be sent back with the tool results, so do not use it
+// with Claude and reasoning enabled.
+func ThinkingStripper() Middleware {
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		stripped := conv.WithoutThinking()
+		return next(ctx, &stripped)
+	}
+}
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+func thinkingMessage(thinking, text string) Message {
+	return Message{Role: RoleAssistant, Content: []ContentPart{
+		{Kind: ContentThinking, Thinking: &ThinkingData{Text: thinking, Signature: "sig"}},
+		{Kind: ContentText, Text: text},
+	}}
+}
+
+func TestThinking(t *testing.T) {
+	resp := &Response{Message: thinkingMessage("Let me see. ", "42")}
+	resp.Message.Content = append(resp.Message.Content, ContentPart{Kind: ContentThinking, Thinking: &ThinkingData{Text: "Yes."}})
+	if got := resp.Thinking(); got != "Let me see. Yes." {
+		t.Errorf("Thinking() = %q", got)
+	}
+	if got := UserMessage("hi").Thinking(); got != "" {
+		t.Errorf("Thinking() = %q, want empty", got)
+	}
+}
+
+func TestConversation_WithoutThinking(t *testing.T) {
+	conv := NewConversation("model")
+	conv.Messages = []Message{
+		UserMessage("hi"),
+		thinkingMessage("hmm", "hello"),
+		{Role: RoleAssistant, Content: []ContentPart{{Kind: ContentThinking, Thinking: &ThinkingData{Text: "only"}}}},
+	}
+
+	got := conv.WithoutThinking()
+	if len(got.Messages) != 2 || len(got.Messages[1].Content) != 1 || got.Messages[1].Text() != "hello" {
+		t.Errorf("messages = %+v", got.Messages)
+	}
+	if len(conv.Messages) != 3 || conv.Messages[1].Thinking() != "hmm" {
+		t.Errorf("original modified: %+v", conv.Messages)
+	}
+}
+
+func TestThinkingStripper(t *testing.T) {
+	provider := &scriptedProvider{responses: []*Response{{Message: thinkingMessage("again", "ok"), FinishReason: FinishReasonStop}}}
+	client := NewClientWithProvider(provider, WithThinkingStripped())
+	conv := NewConversation("model")
+	conv.Messages = []Message{UserMessage("hi"), thinkingMessage("hmm", "hello")}
+
+	conv, _, err := client.Send(context.Background(), conv, UserMessage("again"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := provider.received[0].Messages[1]; len(sent.Content) != 1 || sent.Thinking() != "" {
+		t.Errorf("sent = %+v", sent)
+	}
+	if conv.Messages[1].Thinking() != "hmm" || conv.Messages[3].Thinking() != "again" {
+		t.Errorf("returned conversation lost thinking: %+v", conv.Messages)
+	}
+}
@@ -0,0 +1,212 @@
+package llm
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif" // register decoders for image size estimates
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Rough token-count heuristics. Most BPE tokenizers average about four
+// characters of English text per token; messages carry a few tokens of
+// role/formatting overhead; images are billed at a roughly fixed size.
+const (
+	charsPerToken         = 4
+	messageOverheadTokens = 4
+	imageTokens           = 1600
+)
+
+// tokenizer approximates a model family's tokenizer. Text is counted as
+// charsPerToken characters per token, except that ideographic and kana
+// characters, which tokenizers encode one or more per token, count as a
+// token each.
+type tokenizer struct {
+	charsPerToken   float64
+	messageOverhead int
+
+	// imageTokens returns the cost of a w×h image, or nil for a flat
+	// imageTokens per image.
+	imageTokens func(w, h int) int
+}
+
+var defaultTokenizer = tokenizer{charsPerToken: charsPerToken, messageOverhead: messageOverheadTokens}
+
+// tokenizers maps substrings of model IDs to their family's tokenizer,
+// checked in order.
+var tokenizers = []struct {
+	key string
+	tok tokenizer
+}{
+	{"anthropic.", tokenizer{charsPerToken: 3.5, messageOverhead: 5, imageTokens: claudeImageTokens}},
+	{"claude", tokenizer{charsPerToken: 3.5, messageOverhead: 5, imageTokens: claudeImageTokens}},
+	{"gpt", tokenizer{charsPerToken: 4, messageOverhead: 4, imageTokens: openAIImageTokens}},
+	{"gemini", tokenizer{charsPerToken: 4, messageOverhead: 4, imageTokens: geminiImageTokens}},
+	{"llama", tokenizer{charsPerToken: 3.8, messageOverhead: 5}},
+	{"deepseek", tokenizer{charsPerToken: 3.5, messageOverhead: 4}},
+}
+
+func tokenizerFor(model string) tokenizer {
+	model = strings.ToLower(model)
+	for _, t := range tokenizers {
+		if strings.Contains(model, t.key) {
+			return t.tok
+		}
+	}
+	return defaultTokenizer
+}
+
+// EstimateTokens approximates the input size of the conversation for the
+// given model, or for the conversation's own model if model is "": system
+// prompts, tool definitions, and every message. It uses a local
+// approximation of the model family's tokenizer, including image sizes
+// where the image data can be decoded, and makes no network call. Expect
+// it to be within about 10–20% of the provider's count for typical text.
+func (c Conversation) EstimateTokens(model string) int {
+	if model == "" {
+		model = c.Model
+	}
+	return tokenizerFor(model).conversation(&c)
+}
+
+// estimateTextTokens approximates the token count of s.
+func estimateTextTokens(s string) int {
+	return defaultTokenizer.text(s)
+}
+
+// estimateInputTokens approximates the prompt size of a conversation with
+// its model's tokenizer, as EstimateTokens does: system prompts, tool
+// definitions, and every message.
+func estimateInputTokens(conv *Conversation) int {
+	return tokenizerFor(conv.Model).conversation(conv)
+}
+
+func (t tokenizer) text(s string) int {
+	var chars, wide int
+	for _, r := range s {
+		if isWide(r) {
+			wide++
+		} else {
+			chars++
+		}
+	}
+	return wide + int(math.Ceil(float64(chars)/t.charsPerToken))
+}
+
+// fit returns the length in bytes of the longest prefix of s estimated at
+// no more than max tokens, but at least one rune so callers make progress.
+func (t tokenizer) fit(s string, max int) int {
+	var chars, wide int
+	for i, r := range s {
+		if isWide(r) {
+			wide++
+		} else {
+			chars++
+		}
+		if i > 0 && wide+int(math.Ceil(float64(chars)/t.charsPerToken)) > max {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// isWide reports whether r is an ideographic or kana character, which
+// tokenizers encode one or more per token.
+func isWide(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func (t tokenizer) conversation(conv *Conversation) int {
+	total := 0
+	for _, s := range conv.System {
+		total += t.text(s) + t.messageOverhead
+	}
+	for _, td := range conv.Tools {
+		total += t.text(td.Name) + t.text(td.Description) + t.text(string(td.Parameters))
+	}
+	for _, m := range conv.Messages {
+		total += t.message(m)
+	}
+	return total
+}
+
+func (t tokenizer) message(m Message) int {
+	total := t.messageOverhead
+	for _, p := range m.Content {
+		switch p.Kind {
+		case ContentText:
+			total += t.text(p.Text)
+		case ContentImage:
+			total += t.image(p.Image)
+		case ContentToolCall:
+			if p.ToolCall != nil {
+				total += t.text(p.ToolCall.Name) + t.text(string(p.ToolCall.Arguments))
+			}
+		case ContentToolResult:
+			if p.ToolResult != nil {
+				total += t.text(p.ToolResult.Text())
+				for i := range p.ToolResult.Images {
+					total += t.image(&p.ToolResult.Images[i])
+				}
+			}
+		case ContentThinking:
+			if p.Thinking != nil {
+				total += t.text(p.Thinking.Text)
+			}
+		}
+	}
+	return total
+}
+
+func (t tokenizer) image(img *ImageData) int {
+	if t.imageTokens == nil || img == nil || len(img.Data) == 0 {
+		return imageTokens
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return imageTokens
+	}
+	return t.imageTokens(cfg.Width, cfg.Height)
+}
+
+// fit scales w×h down, keeping its aspect ratio, so neither side exceeds
+// maxSide.
+func fit(w, h, maxSide int) (int, int) {
+	if long := max(w, h); long > maxSide {
+		scale := float64(maxSide) / float64(long)
+		return int(float64(w) * scale), int(float64(h) * scale)
+	}
+	return w, h
+}
+
+// claudeImageTokens follows Anthropic's published estimate of w×h/750
+// after images are scaled to at most 1568 pixels on the long side.
+func claudeImageTokens(w, h int) int {
+	w, h = fit(w, h, 1568)
+	return max(1, w*h/750)
+}
+
+// openAIImageTokens follows OpenAI's high-detail tiling: the image is
+// scaled to fit 2048×2048, then to 768 pixels on the short side, and
+// costs 170 tokens per 512-pixel tile plus 85.
+func openAIImageTokens(w, h int) int {
+	w, h = fit(w, h, 2048)
+	if short := min(w, h); short > 768 {
+		scale := 768 / float64(short)
+		w, h = int(float64(w)*scale), int(float64(h)*scale)
+	}
+	tiles := ((w + 511) / 512) * ((h + 511) / 512)
+	return 85 + 170*tiles
+}
+
+// geminiImageTokens follows Gemini's tiling: 258 tokens for an image up to
+// 384 pixels on both sides, else 258 per 768-pixel tile.
+func geminiImageTokens(w, h int) int {
+	if w <= 384 && h <= 384 {
+		return 258
+	}
+	return 258 * ((w + 767) / 768) * ((h + 767) / 768)
+}
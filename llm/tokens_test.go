@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTextTokens(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{strings.Repeat("x", 400), 100},
+		{"héllo", 2}, // counts runes, not bytes
+	}
+	for _, tt := range tests {
+		if got := estimateTextTokens(tt.in); got != tt.want {
+			t.Errorf("estimateTextTokens(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEstimateInputTokens(t *testing.T) {
+	conv := NewConversation("model", WithSystem(strings.Repeat("s", 40)))
+	conv.Messages = []Message{
+		UserMessage(strings.Repeat("u", 80)),
+		{Role: RoleUser, Content: []ContentPart{{Kind: ContentImage, Image: &ImageData{MediaType: "image/png"}}}},
+	}
+	// system: 10 + 4, user: 20 + 4, image: 1600 + 4
+	if got, want := estimateInputTokens(&conv), 1642; got != want {
+		t.Errorf("estimateInputTokens = %d, want %d", got, want)
+	}
+}
+
+func TestEstimateTextTokens_Wide(t *testing.T) {
+	if got := estimateTextTokens("日本語 text"); got != 3+2 {
+		t.Errorf("estimateTextTokens = %d, want 5", got)
+	}
+}
+
+func TestConversation_EstimateTokens(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1000, 750))); err != nil {
+		t.Fatal(err)
+	}
+	conv := NewConversation("us.anthropic.claude-haiku-4-5-20251001-v1:0", WithSystem(strings.Repeat("s", 35)))
+	conv.Messages = []Message{
+		{Role: RoleUser, Content: []ContentPart{
+			{Kind: ContentText, Text: strings.Repeat("u", 70)},
+			{Kind: ContentImage, Image: &ImageData{MediaType: "image/png", Data: buf.Bytes()}},
+		}},
+	}
+
+	tests := []struct {
+		model string
+		want  int
+	}{
+		// system: 10 + 5, user: 5 + 20 + 1000*750/750
+		{"", 1040},
+		// system: 9 + 4, user: 4 + 18 + 85 + 170*2*2 (768x1024 → 4 tiles)
+		{"gpt-4o", 800},
+		// system: 9 + 4, user: 4 + 18 + 258*2*1
+		{"gemini-2.5-flash", 551},
+		// unknown models use the flat image estimate
+		{"mystery", 9 + 4 + 4 + 18 + 1600},
+	}
+	for _, tt := range tests {
+		if got := conv.EstimateTokens(tt.model); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,605 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+	"unicode/utf8"
+)
+
+// ToolHandler executes a tool call with validated arguments. The returned
+// string is sent to the model as the tool result; a returned error is sent
+// as an error result so the model can correct itself.
+type ToolHandler func(ctx context.Context, args ToolCallArgs) (string, error)
+
+// ToolResultHandler is a ToolHandler for tools whose output is more than
+// text, such as a screenshot or structured JSON. The registry fills in the
+// result's ToolCallID; a returned error is sent as an error result.
+type ToolResultHandler func(ctx context.Context, args ToolCallArgs) (ToolResultData, error)
+
+// Tool pairs a ToolDefinition with the handler that executes it. Set
+// either Handler or ResultHandler; ResultHandler wins if both are set.
+type Tool struct {
+	Definition    ToolDefinition
+	Handler       ToolHandler
+	ResultHandler ToolResultHandler
+	Timeout       time.Duration // per-call deadline; zero uses the registry default
+}
+
+// NewTypedTool creates a Tool whose parameter schema is derived from the
+// fields of the Args struct and whose handler receives the arguments
+// decoded into an Args value. Parameters are named after the fields'
+// encoding/json tags, fields tagged omitempty or of pointer type are
+// optional, a description tag documents a field, and an enum tag lists the
+// allowed values of a string field:
+//
+//	type WeatherArgs struct {
+//		Location string `json:"location" description:"City name"`
+//		Unit     string `json:"unit,omitempty" enum:"celsius,fahrenheit"`
+//	}
+//
+// It panics if Args is not a struct or has a field type with no JSON
+// Schema equivalent, such as a channel or func.
+func NewTypedTool[Args any](name, description string, fn func(ctx context.Context, args Args) (string, error)) Tool {
+	def, err := toolFromStruct(name, description, reflect.TypeFor[Args]())
+	if err != nil {
+		panic("llm: NewTypedTool: " + err.Error())
+	}
+	return Tool{
+		Definition: def,
+		Handler: func(ctx context.Context, args ToolCallArgs) (string, error) {
+			var typed Args
+			if err := decodeArgs(args, &typed); err != nil {
+				return "", err
+			}
+			return fn(ctx, typed)
+		},
+	}
+}
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+// NewToolFromFunc creates a Tool from a Go function, deriving the schema
+// from its argument struct as NewTypedTool does. fn must have one of the
+// forms
+//
+//	func(Args) (R, error)
+//	func(context.Context, Args) (R, error)
+//
+// where Args is a struct or pointer to struct. A string R is returned to
+// the model as is; any other R is encoded as JSON. Unlike NewTypedTool it
+// reports an unusable fn as an error, which suits tool catalogs assembled
+// at run time.
+func NewToolFromFunc(name, description string, fn any) (Tool, error) {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		return Tool{}, fmt.Errorf("tool %s: expected a func, got %s", name, t)
+	}
+	withCtx := t.NumIn() == 2 && t.In(0) == contextType
+	if !(t.NumIn() == 1 || withCtx) || t.NumOut() != 2 || t.Out(1) != errorType {
+		return Tool{}, fmt.Errorf("tool %s: func must be func([context.Context,] Args) (R, error), got %s", name, t)
+	}
+	argsType := t.In(t.NumIn() - 1)
+	def, err := toolFromStruct(name, description, argsType)
+	if err != nil {
+		return Tool{}, err
+	}
+
+	handler := func(ctx context.Context, args ToolCallArgs) (string, error) {
+		argsPtr := reflect.New(argsType)
+		if err := decodeArgs(args, argsPtr.Interface()); err != nil {
+			return "", err
+		}
+		in := []reflect.Value{argsPtr.Elem()}
+		if withCtx {
+			in = append([]reflect.Value{reflect.ValueOf(ctx)}, in...)
+		}
+		out := v.Call(in)
+		if err, _ := out[1].Interface().(error); err != nil {
+			return "", err
+		}
+		if s, ok := out[0].Interface().(string); ok {
+			return s, nil
+		}
+		data, err := json.Marshal(out[0].Interface())
+		if err != nil {
+			return "", fmt.Errorf("encoding result: %w", err)
+		}
+		return string(data), nil
+	}
+	return Tool{Definition: def, Handler: handler}, nil
+}
+
+// decodeArgs decodes validated tool arguments into the struct pointed to
+// by v.
+func decodeArgs(args ToolCallArgs, v any) error {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// ToolRegistry pairs tool definitions with the handlers that execute them.
+type ToolRegistry struct {
+	defs     []ToolDefinition
+	handlers map[string]ToolResultHandler
+	timeouts map[string]time.Duration
+}
+
+// NewToolRegistry creates an empty ToolRegistry.
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{handlers: make(map[string]ToolResultHandler), timeouts: make(map[string]time.Duration)}
+}
+
+// Register adds a tool. It panics if a tool with the same name is already
+// registered or handler is nil, since both are programming errors.
+func (r *ToolRegistry) Register(def ToolDefinition, handler ToolHandler) *ToolRegistry {
+	if handler == nil {
+		panic("llm: Register handler is nil for tool " + def.Name)
+	}
+	return r.RegisterResult(def, func(ctx context.Context, args ToolCallArgs) (ToolResultData, error) {
+		content, err := handler(ctx, args)
+		return ToolResultData{Content: content}, err
+	})
+}
+
+// RegisterResult adds a tool whose handler returns a full ToolResultData,
+// so it can send images or JSON to the model. It panics under the same
+// conditions as Register.
+func (r *ToolRegistry) RegisterResult(def ToolDefinition, handler ToolResultHandler) *ToolRegistry {
+	if handler == nil {
+		panic("llm: Register handler is nil for tool " + def.Name)
+	}
+	if _, dup := r.handlers[def.Name]; dup {
+		panic("llm: Register called twice for tool " + def.Name)
+	}
+	r.defs = append(r.defs, def)
+	r.handlers[def.Name] = handler
+	return r
+}
+
+// Add registers tools, such as those built by NewTypedTool.
+func (r *ToolRegistry) Add(tools ...Tool) *ToolRegistry {
+	for _, t := range tools {
+		if t.ResultHandler != nil {
+			r.RegisterResult(t.Definition, t.ResultHandler)
+		} else {
+			r.Register(t.Definition, t.Handler)
+		}
+		if t.Timeout > 0 {
+			r.SetTimeout(t.Definition.Name, t.Timeout)
+		}
+	}
+	return r
+}
+
+// SetTimeout limits each execution of the named tool to d. The "" name
+// sets the default for tools without their own timeout. A handler still
+// running at the deadline is abandoned and the model receives an error
+// result; handlers should honor ctx so they don't keep running.
+func (r *ToolRegistry) SetTimeout(name string, d time.Duration) *ToolRegistry {
+	r.timeouts[name] = d
+	return r
+}
+
+// Definitions returns the registered tool definitions in registration order,
+// ready for WithTools.
+func (r *ToolRegistry) Definitions() []ToolDefinition {
+	return append([]ToolDefinition(nil), r.defs...)
+}
+
+// Lookup returns the definition and handler registered under name. For a
+// tool registered with RegisterResult, the handler returns the result's
+// Text.
+func (r *ToolRegistry) Lookup(name string) (ToolDefinition, ToolHandler, bool) {
+	def, h, ok := r.lookup(name)
+	if !ok {
+		return ToolDefinition{}, nil, false
+	}
+	return def, func(ctx context.Context, args ToolCallArgs) (string, error) {
+		res, err := h(ctx, args)
+		return res.Text(), err
+	}, true
+}
+
+func (r *ToolRegistry) lookup(name string) (ToolDefinition, ToolResultHandler, bool) {
+	h, ok := r.handlers[name]
+	if !ok {
+		return ToolDefinition{}, nil, false
+	}
+	for _, d := range r.defs {
+		if d.Name == name {
+			return d, h, true
+		}
+	}
+	return ToolDefinition{}, nil, false
+}
+
+// Execute runs the handler for tc and returns the tool result message.
+// Unknown tools, tools forbidden by the context's ToolPolicy, invalid
+// arguments, handler errors, timeouts, and panics all produce error results
+// describing the problem to the model.
+func (r *ToolRegistry) Execute(ctx context.Context, tc ToolCallData) Message {
+	msg, _ := r.execute(ctx, tc)
+	return msg
+}
+
+// execute is Execute that also returns the reason tc was rejected without
+// running a handler: an unknown tool or invalid arguments.
+func (r *ToolRegistry) execute(ctx context.Context, tc ToolCallData) (Message, error) {
+	def, handler, ok := r.lookup(tc.Name)
+	if !ok {
+		err := fmt.Errorf("unknown tool %q", tc.Name)
+		return tc.ErrorResult(err.Error()), err
+	}
+	if !toolAllowed(ctx, tc.Name) {
+		err := fmt.Errorf("tool %q is not allowed", tc.Name)
+		return tc.ErrorResult(err.Error()), err
+	}
+	args, err := def.ParseArgs(tc)
+	if err != nil {
+		err = fmt.Errorf("invalid arguments: %w", err)
+		return tc.ErrorResult(err.Error()), err
+	}
+	timeout, ok := r.timeouts[tc.Name]
+	if !ok {
+		timeout = r.timeouts[""]
+	}
+	result, err := callHandler(ctx, handler, args, timeout)
+	if err != nil {
+		return tc.ErrorResult(err.Error()), nil
+	}
+	msg := ToolResultMessage(tc.ID, "", false)
+	result.ToolCallID = tc.ID
+	*msg.Content[0].ToolResult = result
+	return msg, nil
+}
+
+// callHandler runs handler, turning a panic into an error. With a positive
+// timeout it runs handler on its own goroutine and stops waiting for it
+// at the deadline.
+func callHandler(ctx context.Context, handler ToolResultHandler, args ToolCallArgs, timeout time.Duration) (ToolResultData, error) {
+	type outcome struct {
+		result ToolResultData
+		err    error
+	}
+	call := func(ctx context.Context) (o outcome) {
+		defer func() {
+			if p := recover(); p != nil {
+				o = outcome{err: fmt.Errorf("tool panicked: %v", p)}
+			}
+		}()
+		result, err := handler(ctx, args)
+		return outcome{result, err}
+	}
+	if timeout <= 0 {
+		o := call(ctx)
+		return o.result, o.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan outcome, 1)
+	go func() { done <- call(ctx) }()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ToolResultData{}, fmt.Errorf("tool timed out after %s", timeout)
+		}
+		return ToolResultData{}, ctx.Err()
+	}
+}
+
+// Runner drives the tool-use loop: it sends the conversation, executes the
+// tool calls in each reply through a ToolRegistry, and sends the results
+// back until the model stops calling tools.
+type Runner struct {
+	client   *Client
+	tools    *ToolRegistry
+	maxTurns int
+	repair   bool
+	retries  int
+	limit    ResultLimit
+
+	maxCalls   int // total tool invocations per Run; 0 is unlimited
+	maxRepeats int // identical invocations per Run; 0 is unlimited
+	audit      AuditSink
+
+	// onToolStart and onToolFinish, if set, are called around each tool
+	// execution, and onReply with the conversation after each successful
+	// model call; Session uses them to publish events as the run goes.
+	onToolStart  func(ToolCallData)
+	onToolFinish func(ToolCallData, ToolResultData)
+	onReply      func(Conversation)
+}
+
+// RunnerOption configures a Runner.
+type RunnerOption func(*Runner)
+
+// WithMaxTurns caps the number of model calls in one Run, and so the
+// number of consecutive tool rounds. The default is 20.
+func WithMaxTurns(n int) RunnerOption {
+	return func(r *Runner) {
+		r.maxTurns = n
+	}
+}
+
+// WithMaxToolCalls caps the total number of tool invocations in one Run.
+// A turn that would exceed it stops the loop, before any of its calls run,
+// with an ErrToolLoop error. The default is no limit.
+func WithMaxToolCalls(n int) RunnerOption {
+	return func(r *Runner) {
+		r.maxCalls = n
+	}
+}
+
+// WithMaxRepeatedCalls caps how many times one Run may execute the same
+// tool with the same arguments, which usually means the model is stuck. A
+// turn that would exceed it stops the loop with an ErrToolLoop error. The
+// default is no limit.
+func WithMaxRepeatedCalls(n int) RunnerOption {
+	return func(r *Runner) {
+		r.maxRepeats = n
+	}
+}
+
+// WithInvalidCallRetries sets how many turns in a row the model may send
+// tool calls that name an unknown tool or fail argument validation. Each
+// such call is answered with an error result describing the problem so the
+// model can correct itself; once the limit is exceeded, Run stops with an
+// ErrToolLoop error whose Cause is the last validation failure. The
+// default is 3.
+func WithInvalidCallRetries(n int) RunnerOption {
+	return func(r *Runner) {
+		r.retries = n
+	}
+}
+
+// ResultLimit caps the size of tool results before they are added to the
+// conversation, so one oversized payload can't crowd out the context
+// window. The limit covers Content and JSON together; an oversized JSON
+// result is sent as truncated text. Zero limits are unlimited.
+type ResultLimit struct {
+	MaxChars  int
+	MaxTokens int // estimated at about four characters per token
+
+	// Marker ends truncated content, within the limit. The default is
+	// "\n...[truncated]". A marker longer than the limit is itself cut to
+	// fit.
+	Marker string
+
+	// Summarize, if set, is called for oversized content instead of
+	// truncating it, for example to have a smaller model condense it. Its
+	// output is still truncated if it exceeds the limit, and the content is
+	// truncated if it returns an error.
+	Summarize func(ctx context.Context, content string) (string, error)
+}
+
+// WithResultLimit makes the Runner shrink tool results that exceed limit.
+func WithResultLimit(limit ResultLimit) RunnerOption {
+	return func(r *Runner) {
+		r.limit = limit
+	}
+}
+
+// maxChars returns the effective character limit, or 0 for none.
+func (l ResultLimit) maxChars() int {
+	n := l.MaxChars
+	if t := l.MaxTokens * charsPerToken; t > 0 && (n == 0 || t < n) {
+		n = t
+	}
+	return n
+}
+
+// Apply returns content shrunk to fit the limit.
+func (l ResultLimit) Apply(ctx context.Context, content string) string {
+	limit := l.maxChars()
+	if limit == 0 || utf8.RuneCountInString(content) <= limit {
+		return content
+	}
+	if l.Summarize != nil {
+		if summary, err := l.Summarize(ctx, content); err == nil {
+			content = summary
+			if utf8.RuneCountInString(content) <= limit {
+				return content
+			}
+		}
+	}
+
+	marker := l.Marker
+	if marker == "" {
+		marker = "\n...[truncated]"
+	}
+	marker = truncateRunes(marker, limit)
+	return truncateRunes(content, limit-utf8.RuneCountInString(marker)) + marker
+}
+
+// applyResult caps a tool result. JSON that would take the result over the
+// limit is folded into Content first, since cutting it would leave JSON
+// that no longer parses.
+func (l ResultLimit) applyResult(ctx context.Context, res *ToolResultData) {
+	if limit := l.maxChars(); len(res.JSON) > 0 && limit > 0 && utf8.RuneCountInString(res.Text()) > limit {
+		res.Content, res.JSON = res.Text(), nil
+	}
+	res.Content = l.Apply(ctx, res.Content)
+}
+
+// truncateRunes returns the first n runes of s.
+func truncateRunes(s string, n int) string {
+	runes := 0
+	for i := range s {
+		if runes == n {
+			return s[:i]
+		}
+		runes++
+	}
+	return s
+}
+
+// WithToolCallRepair makes the Runner repair malformed tool-call arguments,
+// as ToolCallData.Repair does, before executing the calls. Repaired calls
+// replace the originals in the conversation, with the model's bytes kept in
+// RawArguments.
+func WithToolCallRepair() RunnerOption {
+	return func(r *Runner) {
+		r.repair = true
+	}
+}
+
+// NewRunner creates a Runner that sends through client and executes tools
+// from tools.
+func NewRunner(client *Client, tools *ToolRegistry, opts ...RunnerOption) *Runner {
+	r := &Runner{client: client, tools: tools, maxTurns: 20, retries: 3}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// Run appends messages to conv and sends it, then keeps executing tool
+// calls and sending their results until the model finishes for any reason
+// other than tool use. If conv has no tools, the registry's definitions are
+// attached. It returns the final conversation and the last response.
+//
+// If the model is still calling tools after the maximum number of turns,
+// Run returns the conversation so far, including the unanswered tool calls,
+// with an ErrToolLoop error. It does the same when the model keeps sending
+// invalid tool calls; see WithInvalidCallRetries.
+//
+// Given no messages and a conversation that ends in unanswered tool calls,
+// such as one returned with ErrToolLoop, Run executes those calls first
+// instead of sending, so the loop picks up where it stopped.
+func (r *Runner) Run(ctx context.Context, conv Conversation, messages ...Message) (Conversation, *Response, error) {
+	if len(conv.Tools) == 0 {
+		conv.Tools = r.tools.Definitions()
+	}
+
+	var (
+		resp *Response
+		err  error
+	)
+	send := func(messages ...Message) {
+		conv, resp, err = r.client.Send(ctx, conv, messages...)
+		if err != nil {
+			return
+		}
+		if r.repair && resp.FinishReason == FinishReasonToolUse && repairToolCalls(&resp.Message) {
+			conv.Messages[len(conv.Messages)-1] = resp.Message
+		}
+		if r.onReply != nil {
+			r.onReply(conv)
+		}
+	}
+	if last, ok := pendingToolCalls(conv); ok && len(messages) == 0 {
+		resp = &Response{Model: conv.Model, Message: last, FinishReason: FinishReasonToolUse}
+	} else {
+		send(messages...)
+	}
+	var (
+		invalidTurns int
+		calls        int
+		seen         = make(map[string]int)
+	)
+	for turn := 1; err == nil && resp.FinishReason == FinishReasonToolUse; turn++ {
+		if turn >= r.maxTurns {
+			return conv, resp, &Error{Kind: ErrToolLoop, Message: fmt.Sprintf("model still calling tools after %d turns", turn)}
+		}
+		for _, tc := range resp.Message.ToolCalls() {
+			if calls++; r.maxCalls > 0 && calls > r.maxCalls {
+				return conv, resp, &Error{Kind: ErrToolLoop, Message: fmt.Sprintf("tool call limit of %d reached", r.maxCalls)}
+			}
+			key := toolCallKey(tc)
+			if seen[key]++; r.maxRepeats > 0 && seen[key] > r.maxRepeats {
+				return conv, resp, &Error{Kind: ErrToolLoop, Message: fmt.Sprintf("tool %s called with the same arguments %d times", tc.Name, seen[key])}
+			}
+		}
+		var (
+			results []Message
+			invalid error
+		)
+		for _, tc := range resp.Message.ToolCalls() {
+			if r.onToolStart != nil {
+				r.onToolStart(tc)
+			}
+			start := time.Now()
+			msg, callErr := r.tools.execute(ctx, tc)
+			res := msg.Content[0].ToolResult
+			if r.audit != nil {
+				r.audit.Audit(newToolAudit(turn, tc, res, start))
+			}
+			r.limit.applyResult(ctx, res)
+			if r.onToolFinish != nil {
+				r.onToolFinish(tc, *res)
+			}
+			results = append(results, msg)
+			if callErr != nil {
+				invalid = fmt.Errorf("tool %s: %w", tc.Name, callErr)
+			}
+		}
+		if invalid == nil {
+			invalidTurns = 0
+		} else if invalidTurns++; invalidTurns > r.retries {
+			return conv, resp, &Error{
+				Kind:    ErrToolLoop,
+				Message: fmt.Sprintf("model sent invalid tool calls %d turns in a row", invalidTurns),
+				Cause:   invalid,
+			}
+		}
+		send(results...)
+	}
+	return conv, resp, err
+}
+
+// Run runs the tool-use loop on c in place with the default Runner
+// settings, and returns the final response; its Message is the last
+// assistant message. Every intermediate assistant and tool message is
+// kept in c. On error c holds the state reached so far, so a failed run
+// can be resumed by calling Run again without messages; tool calls left
+// unanswered are executed before anything is sent.
+func (c *Conversation) Run(ctx context.Context, client *Client, tools *ToolRegistry, messages ...Message) (*Response, error) {
+	next, resp, err := NewRunner(client, tools).Run(ctx, *c, messages...)
+	*c = next
+	return resp, err
+}
+
+// pendingToolCalls returns conv's last message if it is an assistant
+// message with tool calls, which no tool results have answered yet.
+func pendingToolCalls(conv Conversation) (Message, bool) {
+	if len(conv.Messages) == 0 {
+		return Message{}, false
+	}
+	last := conv.Messages[len(conv.Messages)-1]
+	return last, last.Role == RoleAssistant && len(last.ToolCalls()) > 0
+}
+
+// toolCallKey identifies a call by tool name and arguments, ignoring key
+// order and whitespace in the arguments.
+func toolCallKey(tc ToolCallData) string {
+	args := string(tc.Arguments)
+	var v any
+	if json.Unmarshal(tc.Arguments, &v) == nil {
+		if data, err := json.Marshal(v); err == nil {
+			args = string(data)
+		}
+	}
+	return tc.Name + "\x00" + args
+}
+
+// RunConversation runs the tool-use loop for conv with the tools in
+// registry. It is shorthand for NewRunner(c, tools).Run(ctx, conv, messages...).
+func (c *Client) RunConversation(ctx context.Context, conv Conversation, tools *ToolRegistry, messages ...Message) (Conversation, *Response, error) {
+	return NewRunner(c, tools).Run(ctx, conv, messages...)
+}
@@ -0,0 +1,549 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// toolUseResponse builds a tool_use response calling the given tools.
+func toolUseResponse(calls ...ToolCallData) *Response {
+	msg := Message{Role: RoleAssistant}
+	for i := range calls {
+		msg.Content = append(msg.Content, ContentPart{Kind: ContentToolCall, ToolCall: &calls[i]})
+	}
+	return &Response{Message: msg, FinishReason: FinishReasonToolUse, Usage: Usage{InputTokens: 10, OutputTokens: 5}}
+}
+
+func weatherRegistry() *ToolRegistry {
+	return NewToolRegistry().
+		Register(NewTool("get_weather", "Get the weather", StringParam("location")), func(_ context.Context, args ToolCallArgs) (string, error) {
+			loc, _ := args.String("location")
+			if loc == "Atlantis" {
+				return "", errors.New("unknown location")
+			}
+			return fmt.Sprintf(`{"location":%q,"temp":15}`, loc), nil
+		})
+}
+
+func toolResult(t *testing.T, m Message) ToolResultData {
+	t.Helper()
+	if len(m.Content) != 1 || m.Content[0].ToolResult == nil {
+		t.Fatalf("not a tool result: %+v", m)
+	}
+	return *m.Content[0].ToolResult
+}
+
+func TestToolRegistry_Execute(t *testing.T) {
+	reg := weatherRegistry()
+	tests := []struct {
+		name      string
+		call      ToolCallData
+		wantError bool
+		want      string
+	}{
+		{"ok", ToolCallData{ID: "1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}, false, `{"location":"Paris","temp":15}`},
+		{"handler error", ToolCallData{ID: "2", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Atlantis"}`)}, true, "unknown location"},
+		{"invalid args", ToolCallData{ID: "3", Name: "get_weather", Arguments: json.RawMessage(`{}`)}, true, `invalid arguments: missing required parameter "location"`},
+		{"unknown tool", ToolCallData{ID: "4", Name: "launch_rockets"}, true, `unknown tool "launch_rockets"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := toolResult(t, reg.Execute(context.Background(), tt.call))
+			if res.ToolCallID != tt.call.ID || res.IsError != tt.wantError || res.Content != tt.want {
+				t.Errorf("result = %+v", res)
+			}
+		})
+	}
+}
+
+func TestToolRegistry_ResultHandler(t *testing.T) {
+	png := ImageData{Data: []byte{0x89, 'P', 'N', 'G'}, MediaType: "image/png"}
+	reg := NewToolRegistry().Add(Tool{
+		Definition: NewTool("screenshot", ""),
+		ResultHandler: func(context.Context, ToolCallArgs) (ToolResultData, error) {
+			return ToolResultData{Content: "captured", JSON: json.RawMessage(`{"width":800}`), Images: []ImageData{png}}, nil
+		},
+	})
+
+	res := toolResult(t, reg.Execute(context.Background(), ToolCallData{ID: "1", Name: "screenshot"}))
+	if res.ToolCallID != "1" || res.IsError || res.Content != "captured" || string(res.JSON) != `{"width":800}` || len(res.Images) != 1 {
+		t.Errorf("result = %+v", res)
+	}
+	_, handler, ok := reg.Lookup("screenshot")
+	if !ok {
+		t.Fatal("Lookup found no handler")
+	}
+	if text, err := handler(context.Background(), nil); err != nil || text != res.Text() {
+		t.Errorf("Lookup handler = %q, %v, want %q", text, err, res.Text())
+	}
+}
+
+func TestToolRegistry_RegisterDuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	weatherRegistry().Register(NewTool("get_weather", "again"), func(context.Context, ToolCallArgs) (string, error) { return "", nil })
+}
+
+func TestToolRegistry_ExecuteRecoversPanic(t *testing.T) {
+	reg := NewToolRegistry().Register(NewTool("explode", ""), func(context.Context, ToolCallArgs) (string, error) {
+		panic("boom")
+	})
+	res := toolResult(t, reg.Execute(context.Background(), ToolCallData{ID: "1", Name: "explode"}))
+	if !res.IsError || res.Content != "tool panicked: boom" {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestToolRegistry_ExecuteTimeout(t *testing.T) {
+	hang := func(ctx context.Context, _ ToolCallArgs) (string, error) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond) // ignores cancellation for a while
+		return "late", nil
+	}
+	fast := func(context.Context, ToolCallArgs) (string, error) { return "ok", nil }
+	reg := NewToolRegistry().
+		Register(NewTool("hang", ""), hang).
+		Register(NewTool("fast", ""), fast).
+		Add(Tool{Definition: NewTool("slow", ""), Handler: hang, Timeout: 20 * time.Millisecond}).
+		SetTimeout("", 10*time.Millisecond)
+
+	res := toolResult(t, reg.Execute(context.Background(), ToolCallData{ID: "1", Name: "hang"}))
+	if !res.IsError || res.Content != "tool timed out after 10ms" {
+		t.Errorf("default timeout result = %+v", res)
+	}
+	res = toolResult(t, reg.Execute(context.Background(), ToolCallData{ID: "2", Name: "slow"}))
+	if !res.IsError || res.Content != "tool timed out after 20ms" {
+		t.Errorf("per-tool timeout result = %+v", res)
+	}
+	res = toolResult(t, reg.Execute(context.Background(), ToolCallData{ID: "3", Name: "fast"}))
+	if res.IsError || res.Content != "ok" {
+		t.Errorf("fast result = %+v", res)
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	provider := &scriptedProvider{responses: []*Response{
+		toolUseResponse(
+			ToolCallData{ID: "c1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)},
+			ToolCallData{ID: "c2", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Atlantis"}`)},
+		),
+		simpleResponse("Paris is 15 degrees."),
+	}}
+	client := NewClientWithProvider(provider)
+
+	conv, resp, err := client.RunConversation(context.Background(), NewConversation("model"), weatherRegistry(), UserMessage("Weather?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Text() != "Paris is 15 degrees." {
+		t.Errorf("Text = %q", resp.Message.Text())
+	}
+	// user, assistant tool calls, two tool results, assistant answer
+	if len(conv.Messages) != 5 {
+		t.Fatalf("messages = %d, want 5", len(conv.Messages))
+	}
+	if res := toolResult(t, conv.Messages[3]); res.ToolCallID != "c2" || !res.IsError {
+		t.Errorf("second result = %+v", res)
+	}
+	if len(provider.received[0].Tools) != 1 {
+		t.Error("registry definitions were not attached to the conversation")
+	}
+	if conv.Usage.InputTokens != 20 {
+		t.Errorf("usage = %+v, want both turns", conv.Usage)
+	}
+}
+
+func TestConversationRun(t *testing.T) {
+	call := ToolCallData{ID: "c1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}
+	provider := &scriptedProvider{responses: []*Response{toolUseResponse(call), simpleResponse("15 degrees.")}}
+	client := NewClientWithProvider(provider)
+
+	conv := NewConversation("model")
+	resp, err := conv.Run(context.Background(), client, weatherRegistry(), UserMessage("Weather?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.Text() != "15 degrees." {
+		t.Errorf("Text = %q", resp.Message.Text())
+	}
+	// user, tool call, tool result, answer
+	if len(conv.Messages) != 4 || conv.Usage.InputTokens != 20 || len(conv.Tools) != 1 {
+		t.Errorf("conv = %d messages, usage %+v, %d tools", len(conv.Messages), conv.Usage, len(conv.Tools))
+	}
+	data, err := json.Marshal(conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Conversation
+	if err := json.Unmarshal(data, &restored); err != nil || len(restored.Messages) != 4 {
+		t.Errorf("round trip: %v, %d messages", err, len(restored.Messages))
+	}
+}
+
+func TestConversationRun_ResumesAfterError(t *testing.T) {
+	call := ToolCallData{ID: "c1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}
+	provider := &scriptedProvider{responses: []*Response{toolUseResponse(call), simpleResponse("15 degrees.")}}
+	sends := 0
+	failSecond := func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		if sends++; sends == 2 {
+			return nil, &Error{Kind: ErrServer, Message: "down"}
+		}
+		return next(ctx, conv)
+	}
+	client := NewClientWithProvider(provider, WithMiddleware(failSecond))
+
+	conv := NewConversation("model")
+	if _, err := conv.Run(context.Background(), client, weatherRegistry(), UserMessage("Weather?")); err == nil {
+		t.Fatal("expected error")
+	}
+	// The tool result was kept, so the next run continues from it.
+	if last := conv.Messages[len(conv.Messages)-1]; last.Role != RoleTool {
+		t.Fatalf("last message role = %q, want tool", last.Role)
+	}
+	resp, err := conv.Run(context.Background(), client, weatherRegistry())
+	if err != nil || resp.Message.Text() != "15 degrees." {
+		t.Fatalf("resume = %v, %v", resp, err)
+	}
+}
+
+func TestRunner_MaxTurns(t *testing.T) {
+	call := ToolCallData{ID: "c", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}
+	provider := &scriptedProvider{responses: []*Response{
+		toolUseResponse(call), toolUseResponse(call), toolUseResponse(call),
+	}}
+	runner := NewRunner(NewClientWithProvider(provider), weatherRegistry(), WithMaxTurns(2))
+
+	conv, _, err := runner.Run(context.Background(), NewConversation("model"), UserMessage("Weather?"))
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || llmErr.Kind != ErrToolLoop {
+		t.Fatalf("err = %v, want ErrToolLoop", err)
+	}
+	if len(provider.received) != 2 {
+		t.Errorf("model calls = %d, want 2", len(provider.received))
+	}
+	if last := conv.Messages[len(conv.Messages)-1]; len(last.ToolCalls()) != 1 {
+		t.Error("conversation should end with the unanswered tool call")
+	}
+}
+
+func TestRunner_ResumesPendingToolCalls(t *testing.T) {
+	call := ToolCallData{ID: "c", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}
+	provider := &scriptedProvider{responses: []*Response{
+		toolUseResponse(call), toolUseResponse(call), simpleResponse("15 degrees."),
+	}}
+	runner := NewRunner(NewClientWithProvider(provider), weatherRegistry(), WithMaxTurns(2))
+
+	conv, _, err := runner.Run(context.Background(), NewConversation("model"), UserMessage("Weather?"))
+	if err == nil {
+		t.Fatal("expected ErrToolLoop")
+	}
+	conv, resp, err := runner.Run(context.Background(), conv)
+	if err != nil || resp.Message.Text() != "15 degrees." {
+		t.Fatalf("resume = %v, %v", resp, err)
+	}
+	sent := provider.received[2].Messages
+	if last := sent[len(sent)-1]; last.Role != RoleTool {
+		t.Errorf("resumed request ends with %q, want the pending call's result", last.Role)
+	}
+	if err := conv.Validate(); err != nil {
+		t.Errorf("resumed conversation invalid: %v", err)
+	}
+}
+
+func TestRunner_LoopGuards(t *testing.T) {
+	call := func(id, args string) ToolCallData {
+		return ToolCallData{ID: id, Name: "get_weather", Arguments: json.RawMessage(args)}
+	}
+	tests := []struct {
+		name      string
+		opt       RunnerOption
+		responses []*Response
+		wantCalls int // model calls before the guard stopped the loop
+		wantErr   string
+	}{
+		{
+			name: "repeated call",
+			opt:  WithMaxRepeatedCalls(2),
+			responses: []*Response{
+				toolUseResponse(call("1", `{"location":"Paris"}`)),
+				toolUseResponse(call("2", `{"location": "Paris"}`)),
+				toolUseResponse(call("3", `{"location":"Rome"}`), call("4", `{ "location":"Paris" }`)),
+			},
+			wantCalls: 3,
+			wantErr:   "tool get_weather called with the same arguments 3 times",
+		},
+		{
+			name: "total calls",
+			opt:  WithMaxToolCalls(2),
+			responses: []*Response{
+				toolUseResponse(call("1", `{"location":"Paris"}`)),
+				toolUseResponse(call("2", `{"location":"Rome"}`), call("3", `{"location":"Oslo"}`)),
+			},
+			wantCalls: 2,
+			wantErr:   "tool call limit of 2 reached",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &scriptedProvider{responses: tt.responses}
+			runner := NewRunner(NewClientWithProvider(provider), weatherRegistry(), tt.opt)
+			conv, _, err := runner.Run(context.Background(), NewConversation("model"), UserMessage("Weather?"))
+			var llmErr *Error
+			if !errors.As(err, &llmErr) || llmErr.Kind != ErrToolLoop || llmErr.Message != tt.wantErr {
+				t.Fatalf("err = %v, want ErrToolLoop %q", err, tt.wantErr)
+			}
+			if len(provider.received) != tt.wantCalls {
+				t.Errorf("model calls = %d, want %d", len(provider.received), tt.wantCalls)
+			}
+			if last := conv.Messages[len(conv.Messages)-1]; last.Role != RoleAssistant {
+				t.Error("calls from the stopped turn were executed")
+			}
+		})
+	}
+}
+
+func TestRunner_InvalidCallRetries(t *testing.T) {
+	bad := ToolCallData{ID: "bad", Name: "get_weather", Arguments: json.RawMessage(`{}`)}
+	good := ToolCallData{ID: "good", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}
+
+	t.Run("recovers", func(t *testing.T) {
+		provider := &scriptedProvider{responses: []*Response{
+			toolUseResponse(bad), toolUseResponse(bad), toolUseResponse(good), simpleResponse("15 degrees."),
+		}}
+		runner := NewRunner(NewClientWithProvider(provider), weatherRegistry(), WithInvalidCallRetries(2))
+		_, resp, err := runner.Run(context.Background(), NewConversation("model"), UserMessage("Weather?"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Message.Text() != "15 degrees." {
+			t.Errorf("Text = %q", resp.Message.Text())
+		}
+		feedback := toolResult(t, provider.received[1].Messages[2])
+		if !feedback.IsError || feedback.Content != `invalid arguments: missing required parameter "location"` {
+			t.Errorf("feedback = %+v", feedback)
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		provider := &scriptedProvider{responses: []*Response{
+			toolUseResponse(bad), toolUseResponse(bad), toolUseResponse(bad),
+		}}
+		runner := NewRunner(NewClientWithProvider(provider), weatherRegistry(), WithInvalidCallRetries(1))
+		_, _, err := runner.Run(context.Background(), NewConversation("model"), UserMessage("Weather?"))
+		var llmErr *Error
+		if !errors.As(err, &llmErr) || llmErr.Kind != ErrToolLoop || llmErr.Cause == nil {
+			t.Fatalf("err = %v, want ErrToolLoop with a cause", err)
+		}
+		if len(provider.received) != 2 {
+			t.Errorf("model calls = %d, want 2", len(provider.received))
+		}
+	})
+}
+
+func TestRunner_ToolCallRepair(t *testing.T) {
+	broken := ToolCallData{ID: "c", Name: "get_weather", Arguments: json.RawMessage(`{'location': 'Paris',}`)}
+	provider := &scriptedProvider{responses: []*Response{
+		toolUseResponse(broken), simpleResponse("15 degrees."),
+	}}
+	runner := NewRunner(NewClientWithProvider(provider), weatherRegistry(), WithToolCallRepair())
+
+	conv, _, err := runner.Run(context.Background(), NewConversation("model"), UserMessage("Weather?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := toolResult(t, conv.Messages[2]); res.IsError {
+		t.Errorf("repaired call failed: %+v", res)
+	}
+	sent := provider.received[1].Messages[1].ToolCalls()[0]
+	if string(sent.Arguments) != `{"location": "Paris"}` || sent.RawArguments != `{'location': 'Paris',}` {
+		t.Errorf("call sent back = %+v", sent)
+	}
+}
+
+func TestRunner_NoRepairByDefault(t *testing.T) {
+	broken := ToolCallData{ID: "c", Name: "get_weather", Arguments: json.RawMessage(`{'location': 'Paris'}`)}
+	provider := &scriptedProvider{responses: []*Response{
+		toolUseResponse(broken), simpleResponse("Sorry."),
+	}}
+	conv, _, err := NewClientWithProvider(provider).RunConversation(context.Background(), NewConversation("model"), weatherRegistry(), UserMessage("Weather?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := toolResult(t, conv.Messages[2]); !res.IsError {
+		t.Errorf("malformed call succeeded without repair: %+v", res)
+	}
+}
+
+type weatherArgs struct {
+	Location string `json:"location" description:"City name"`
+	Days     int    `json:"days,omitempty"`
+}
+
+func TestNewTypedTool(t *testing.T) {
+	tool := NewTypedTool("forecast", "Get a forecast", func(_ context.Context, args weatherArgs) (string, error) {
+		return fmt.Sprintf("%s for %d days", args.Location, args.Days), nil
+	})
+	reg := NewToolRegistry().Add(tool)
+
+	res := toolResult(t, reg.Execute(context.Background(), ToolCallData{ID: "1", Name: "forecast", Arguments: json.RawMessage(`{"location":"Oslo","days":3}`)}))
+	if res.IsError || res.Content != "Oslo for 3 days" {
+		t.Errorf("result = %+v", res)
+	}
+
+	res = toolResult(t, reg.Execute(context.Background(), ToolCallData{ID: "2", Name: "forecast", Arguments: json.RawMessage(`{"days":3}`)}))
+	if !res.IsError || res.Content != `invalid arguments: missing required parameter "location"` {
+		t.Errorf("result = %+v", res)
+	}
+
+	res = toolResult(t, reg.Execute(context.Background(), ToolCallData{ID: "3", Name: "forecast", Arguments: json.RawMessage(`{"location":"Oslo","days":1.5}`)}))
+	if !res.IsError {
+		t.Errorf("fractional integer accepted: %+v", res)
+	}
+}
+
+func TestNewTypedToolPanicsOnNonStruct(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	NewTypedTool("bad", "", func(context.Context, string) (string, error) { return "", nil })
+}
+
+type orderArgs struct {
+	OrderID int `json:"order_id" description:"Order number"`
+}
+
+type order struct {
+	ID     int     `json:"id"`
+	Amount float64 `json:"amount"`
+}
+
+func TestNewToolFromFunc(t *testing.T) {
+	getOrder := func(ctx context.Context, args orderArgs) (order, error) {
+		if args.OrderID != 1000 {
+			return order{}, errors.New("unknown order")
+		}
+		return order{ID: 1000, Amount: 12.34}, nil
+	}
+	greet := func(args *struct {
+		Name string `json:"name"`
+	}) (string, error) {
+		return "hello " + args.Name, nil
+	}
+
+	reg := NewToolRegistry()
+	for _, fn := range []struct {
+		name string
+		fn   any
+	}{{"get_order", getOrder}, {"greet", greet}} {
+		tool, err := NewToolFromFunc(fn.name, "", fn.fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reg.Add(tool)
+	}
+
+	tests := []struct {
+		call      ToolCallData
+		want      string
+		wantError bool
+	}{
+		{ToolCallData{Name: "get_order", Arguments: json.RawMessage(`{"order_id":1000}`)}, `{"id":1000,"amount":12.34}`, false},
+		{ToolCallData{Name: "get_order", Arguments: json.RawMessage(`{"order_id":1}`)}, "unknown order", true},
+		{ToolCallData{Name: "greet", Arguments: json.RawMessage(`{"name":"Ada"}`)}, "hello Ada", false},
+	}
+	for _, tt := range tests {
+		res := toolResult(t, reg.Execute(context.Background(), tt.call))
+		if res.Content != tt.want || res.IsError != tt.wantError {
+			t.Errorf("%s: result = %+v", tt.call.Arguments, res)
+		}
+	}
+}
+
+func TestNewToolFromFunc_InvalidSignatures(t *testing.T) {
+	for _, fn := range []any{
+		"not a func",
+		func(orderArgs) string { return "" },
+		func(orderArgs, orderArgs) (string, error) { return "", nil },
+		func(int) (string, error) { return "", nil },
+	} {
+		if _, err := NewToolFromFunc("bad", "", fn); err == nil {
+			t.Errorf("%T: expected error", fn)
+		}
+	}
+}
+
+func TestResultLimit_Apply(t *testing.T) {
+	ctx := context.Background()
+	long := strings.Repeat("é", 100)
+
+	if got := (ResultLimit{}).Apply(ctx, long); got != long {
+		t.Error("zero limit changed content")
+	}
+	if got := (ResultLimit{MaxChars: 100}).Apply(ctx, long); got != long {
+		t.Error("content at the limit was truncated")
+	}
+	got := ResultLimit{MaxChars: 20, Marker: "[cut]"}.Apply(ctx, long)
+	if got != strings.Repeat("é", 15)+"[cut]" {
+		t.Errorf("truncated = %q", got)
+	}
+	got = ResultLimit{MaxChars: 50, MaxTokens: 5}.Apply(ctx, long)
+	if utf8.RuneCountInString(got) != 20 || !strings.HasSuffix(got, "\n...[truncated]") {
+		t.Errorf("token-limited = %q", got)
+	}
+	if got := (ResultLimit{MaxChars: 5}).Apply(ctx, long); got != "\n...[" {
+		t.Errorf("limit shorter than the marker = %q", got)
+	}
+
+	summarize := func(_ context.Context, s string) (string, error) { return "summary", nil }
+	if got := (ResultLimit{MaxChars: 20, Summarize: summarize}).Apply(ctx, long); got != "summary" {
+		t.Errorf("summarized = %q", got)
+	}
+	failing := func(_ context.Context, s string) (string, error) { return "", errors.New("down") }
+	if got := (ResultLimit{MaxChars: 20, Summarize: failing}).Apply(ctx, long); utf8.RuneCountInString(got) != 20 {
+		t.Errorf("fallback = %q", got)
+	}
+}
+
+func TestRunner_ResultLimit(t *testing.T) {
+	call := ToolCallData{ID: "c", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}
+	provider := &scriptedProvider{responses: []*Response{toolUseResponse(call), simpleResponse("done")}}
+	runner := NewRunner(NewClientWithProvider(provider), weatherRegistry(), WithResultLimit(ResultLimit{MaxChars: 10, Marker: "..."}))
+
+	conv, _, err := runner.Run(context.Background(), NewConversation("model"), UserMessage("Weather?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := toolResult(t, conv.Messages[2]); res.Content != `{"locat...` {
+		t.Errorf("result = %q", res.Content)
+	}
+}
+
+func TestRunner_ResultLimitCoversJSON(t *testing.T) {
+	big := json.RawMessage(`{"rows":[` + strings.Repeat(`"row",`, 1000) + `"row"]}`)
+	reg := NewToolRegistry().RegisterResult(NewTool("query", ""), func(context.Context, ToolCallArgs) (ToolResultData, error) {
+		return ToolResultData{JSON: big}, nil
+	})
+	call := ToolCallData{ID: "c", Name: "query", Arguments: json.RawMessage(`{}`)}
+	provider := &scriptedProvider{responses: []*Response{toolUseResponse(call), simpleResponse("done")}}
+	runner := NewRunner(NewClientWithProvider(provider), reg, WithResultLimit(ResultLimit{MaxChars: 20, Marker: "..."}))
+
+	conv, _, err := runner.Run(context.Background(), NewConversation("model"), UserMessage("Query"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := toolResult(t, conv.Messages[2])
+	if len(res.JSON) != 0 || res.Content != `{"rows":["row","r...` {
+		t.Errorf("result = %+v", res)
+	}
+}
@@ -0,0 +1,82 @@
+package llm
+
+import "slices"
+
+// Toolset groups related tools, such as every operation of one API, so a
+// capability can be given to or taken from an agent as a unit.
+type Toolset struct {
+	Name string
+
+	// Prefix, if set, is prepended to every tool name, such as "github_",
+	// to keep names unique when toolsets are combined.
+	Prefix string
+
+	Tools []Tool
+}
+
+// NewToolset creates a Toolset from tools.
+func NewToolset(name string, tools ...Tool) Toolset {
+	return Toolset{Name: name, Tools: tools}
+}
+
+// WithPrefix returns a copy of ts whose tool names start with prefix.
+func (ts Toolset) WithPrefix(prefix string) Toolset {
+	ts.Prefix = prefix
+	return ts
+}
+
+// Definitions returns the toolset's definitions under their prefixed names.
+func (ts Toolset) Definitions() []ToolDefinition {
+	defs := make([]ToolDefinition, len(ts.Tools))
+	for i, t := range ts.Tools {
+		defs[i] = t.Definition
+		defs[i].Name = ts.Prefix + t.Definition.Name
+	}
+	return defs
+}
+
+// Names returns the prefixed tool names.
+func (ts Toolset) Names() []string {
+	names := make([]string, len(ts.Tools))
+	for i, t := range ts.Tools {
+		names[i] = ts.Prefix + t.Definition.Name
+	}
+	return names
+}
+
+// WithToolset attaches toolsets to the conversation, as AttachToolset does.
+func WithToolset(sets ...Toolset) ConversationOption {
+	return func(c *Conversation) {
+		for _, ts := range sets {
+			*c = c.AttachToolset(ts)
+		}
+	}
+}
+
+// AttachToolset returns a copy of c with the toolset's definitions added.
+// A tool already on the conversation under the same name is replaced.
+func (c Conversation) AttachToolset(ts Toolset) Conversation {
+	c = c.DetachToolset(ts)
+	c.Tools = append(c.Tools, ts.Definitions()...)
+	return c
+}
+
+// DetachToolset returns a copy of c without the toolset's tools. Other
+// tools are kept in order.
+func (c Conversation) DetachToolset(ts Toolset) Conversation {
+	names := ts.Names()
+	c.Tools = slices.DeleteFunc(slices.Clone(c.Tools), func(td ToolDefinition) bool {
+		return slices.Contains(names, td.Name)
+	})
+	return c
+}
+
+// AddToolset registers the toolset's tools under their prefixed names.
+func (r *ToolRegistry) AddToolset(ts Toolset) *ToolRegistry {
+	for i, def := range ts.Definitions() {
+		t := ts.Tools[i]
+		t.Definition = def
+		r.Add(t)
+	}
+	return r
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func githubToolset() Toolset {
+	handler := func(_ context.Context, args ToolCallArgs) (string, error) {
+		repo, _ := args.String("repo")
+		return "issues for " + repo, nil
+	}
+	return NewToolset("github",
+		Tool{Definition: NewTool("list_issues", "List issues", StringParam("repo")), Handler: handler},
+		Tool{Definition: NewTool("get_issue", "Get an issue", StringParam("repo"), IntegerParam("number")), Handler: handler},
+	).WithPrefix("github_")
+}
+
+func toolNames(defs []ToolDefinition) []string {
+	var names []string
+	for _, d := range defs {
+		names = append(names, d.Name)
+	}
+	return names
+}
+
+func TestToolset_AttachDetach(t *testing.T) {
+	search := NewTool("search", "Search the web", StringParam("query"))
+	conv := NewConversation("model", WithTools(search), WithToolset(githubToolset()))
+
+	if got, want := toolNames(conv.Tools), []string{"search", "github_list_issues", "github_get_issue"}; !slices.Equal(got, want) {
+		t.Errorf("tools = %v, want %v", got, want)
+	}
+
+	// Attaching again replaces rather than duplicates.
+	again := conv.AttachToolset(githubToolset())
+	if len(again.Tools) != 3 {
+		t.Errorf("tools after reattach = %v", toolNames(again.Tools))
+	}
+
+	detached := conv.DetachToolset(githubToolset())
+	if got := toolNames(detached.Tools); !slices.Equal(got, []string{"search"}) {
+		t.Errorf("tools after detach = %v", got)
+	}
+	if len(conv.Tools) != 3 {
+		t.Error("DetachToolset modified the original conversation")
+	}
+}
+
+func TestToolRegistry_AddToolset(t *testing.T) {
+	reg := NewToolRegistry().AddToolset(githubToolset())
+
+	res := toolResult(t, reg.Execute(context.Background(), ToolCallData{ID: "1", Name: "github_list_issues", Arguments: json.RawMessage(`{"repo":"go"}`)}))
+	if res.IsError || res.Content != "issues for go" {
+		t.Errorf("result = %+v", res)
+	}
+	// The prefixed definition keeps the original's validation.
+	res = toolResult(t, reg.Execute(context.Background(), ToolCallData{ID: "2", Name: "github_get_issue", Arguments: json.RawMessage(`{"repo":"go"}`)}))
+	if !res.IsError || res.Content != `invalid arguments: missing required parameter "number"` {
+		t.Errorf("result = %+v", res)
+	}
+	if _, _, ok := reg.Lookup("list_issues"); ok {
+		t.Error("unprefixed name registered")
+	}
+}
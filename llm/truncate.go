@@ -0,0 +1,79 @@
+package llm
+
+import "context"
+
+// TruncationPolicy bounds how much history is sent to the model. When a
+// conversation exceeds MaxMessages or MaxTokens, the oldest turns are
+// dropped until it fits. A turn starts at a user message and runs up to
+// the next one, so an assistant's tool calls always travel with their
+// results. System prompts and tools are always kept, and so is the latest
+// turn, even if it alone exceeds the limits. A zero limit is unlimited.
+type TruncationPolicy struct {
+	MaxMessages int
+	MaxTokens   int // input tokens, as estimated by Conversation.EstimateTokens
+
+	// KeepTurns, if set, is how many recent turns to keep once a limit is
+	// exceeded, so truncation drops history in larger steps and runs less
+	// often. Fewer are kept if KeepTurns turns are still over a limit.
+	KeepTurns int
+}
+
+// Apply returns conv with its oldest turns dropped according to p. The
+// message slice is not modified; conv is returned unchanged if it already
+// fits.
+func (p TruncationPolicy) Apply(conv Conversation) Conversation {
+	if p.fits(&conv) {
+		return conv
+	}
+	turns := turnStarts(conv.Messages)
+	if len(turns) == 0 {
+		return conv
+	}
+
+	first := 0
+	if p.KeepTurns > 0 && len(turns) > p.KeepTurns {
+		first = len(turns) - p.KeepTurns
+	}
+	all := conv.Messages
+	for ; first < len(turns)-1; first++ {
+		conv.Messages = all[turns[first]:]
+		if p.fits(&conv) {
+			return conv
+		}
+	}
+	conv.Messages = all[turns[len(turns)-1]:]
+	return conv
+}
+
+// turnStarts returns the index of each user message, where a turn starts.
+func turnStarts(msgs []Message) []int {
+	var starts []int
+	for i, m := range msgs {
+		if m.Role == RoleUser {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+func (p TruncationPolicy) fits(conv *Conversation) bool {
+	if p.MaxMessages > 0 && len(conv.Messages) > p.MaxMessages {
+		return false
+	}
+	return p.MaxTokens <= 0 || conv.EstimateTokens("") <= p.MaxTokens
+}
+
+// WithTruncation adds a Truncation middleware to the client.
+func WithTruncation(p TruncationPolicy) ClientOption {
+	return WithMiddleware(Truncation(p))
+}
+
+// Truncation returns middleware that applies p to each request before it is
+// sent. Only the request is truncated: the conversation returned by Send
+// still holds the full history.
+func Truncation(p TruncationPolicy) Middleware {
+	return func(ctx context.Context, conv *Conversation, next SendFunc) (*Response, error) {
+		truncated := p.Apply(*conv)
+		return next(ctx, &truncated)
+	}
+}
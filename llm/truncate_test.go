@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// toolTurnHistory returns four turns; the second runs a tool call.
+func toolTurnHistory() []Message {
+	call := ToolCallData{ID: "c1", Name: "get_weather", Arguments: json.RawMessage(`{"location":"Paris"}`)}
+	return []Message{
+		UserMessage("one"), AssistantMessage("1"),
+		UserMessage("two"), toolUseResponse(call).Message, call.Result("sunny"), AssistantMessage("2"),
+		UserMessage("three"), AssistantMessage("3"),
+		UserMessage("four"),
+	}
+}
+
+func messageTexts(msgs []Message) string {
+	var texts []string
+	for _, m := range msgs {
+		if t := m.Text(); t != "" {
+			texts = append(texts, t)
+		} else {
+			texts = append(texts, string(m.Role))
+		}
+	}
+	return strings.Join(texts, ",")
+}
+
+func TestTruncationPolicy_Apply(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy TruncationPolicy
+		want   string
+	}{
+		{"under limit", TruncationPolicy{MaxMessages: 9}, "one,1,two,assistant,tool,2,three,3,four"},
+		{"drops oldest turn", TruncationPolicy{MaxMessages: 8}, "two,assistant,tool,2,three,3,four"},
+		{"keeps tool pairs", TruncationPolicy{MaxMessages: 6}, "three,3,four"},
+		{"keeps latest turn", TruncationPolicy{MaxMessages: 1}, "four"},
+		{"keep turns", TruncationPolicy{MaxMessages: 8, KeepTurns: 2}, "three,3,four"},
+		{"keep turns still over", TruncationPolicy{MaxMessages: 2, KeepTurns: 3}, "four"},
+		{"tokens", TruncationPolicy{MaxTokens: 22}, "three,3,four"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := NewConversation("m", WithSystem("be brief"))
+			conv.Messages = toolTurnHistory()
+			got := tt.policy.Apply(conv)
+			if texts := messageTexts(got.Messages); texts != tt.want {
+				t.Errorf("messages = %s, want %s", texts, tt.want)
+			}
+			if len(got.System) != 1 {
+				t.Errorf("system = %v", got.System)
+			}
+			if err := got.Validate(); err != nil {
+				t.Errorf("Validate() = %v", err)
+			}
+		})
+	}
+}
+
+func TestTruncation_Middleware(t *testing.T) {
+	provider := &scriptedProvider{responses: []*Response{simpleResponse("4")}}
+	client := NewClientWithProvider(provider, WithTruncation(TruncationPolicy{MaxMessages: 3}))
+
+	conv := NewConversation("m")
+	conv.Messages = toolTurnHistory()[:8]
+	conv, _, err := client.Send(context.Background(), conv, UserMessage("four"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := messageTexts(provider.received[0].Messages); got != "three,3,four" {
+		t.Errorf("sent = %s", got)
+	}
+	if len(conv.Messages) != 10 {
+		t.Errorf("history has %d messages, want 10", len(conv.Messages))
+	}
+}
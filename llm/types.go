@@ -3,7 +3,9 @@ package llm
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 )
 
 // Role represents a message participant.
@@ -25,6 +27,12 @@ const (
 	ContentToolCall   ContentKind = "tool_call"
 	ContentToolResult ContentKind = "tool_result"
 	ContentThinking   ContentKind = "thinking"
+	ContentAudio      ContentKind = "audio"
+	ContentDocument   ContentKind = "document"
+
+	// ContentCachePoint marks the end of a prompt prefix to cache, for
+	// providers with prompt caching. It has no payload.
+	ContentCachePoint ContentKind = "cache_point"
 )
 
 // ContentPart is a tagged union — only the field matching Kind is populated.
@@ -35,18 +43,50 @@ type ContentPart struct {
 	ToolCall   *ToolCallData   `json:"tool_call,omitempty"`
 	ToolResult *ToolResultData `json:"tool_result,omitempty"`
 	Thinking   *ThinkingData   `json:"thinking,omitempty"`
+	Audio      *AudioData      `json:"audio,omitempty"`
+	Document   *DocumentData   `json:"document,omitempty"`
 }
 
 type ImageData struct {
 	URL       string `json:"url,omitempty"`
 	Data      []byte `json:"data,omitempty"`
 	MediaType string `json:"media_type,omitempty"`
+	Ref       string `json:"ref,omitempty"` // BlobStore reference standing in for Data
+}
+
+// AudioData is a clip of recorded speech or sound, given as data or, for
+// Bedrock, as an s3:// URL. MediaType is an audio type such as
+// "audio/mpeg" or "audio/wav".
+type AudioData struct {
+	URL       string `json:"url,omitempty"`
+	Data      []byte `json:"data,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	Ref       string `json:"ref,omitempty"` // BlobStore reference standing in for Data
+}
+
+// DocumentData is a file for the model to read, such as a PDF or
+// spreadsheet, given as data or, for Bedrock, as an s3:// URL. Bedrock
+// requires a Name, unique within the request.
+type DocumentData struct {
+	Name      string `json:"name,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Data      []byte `json:"data,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	Ref       string `json:"ref,omitempty"` // BlobStore reference standing in for Data
 }
 
 type ToolCallData struct {
 	ID        string          `json:"id"`
 	Name      string          `json:"name"`
 	Arguments json.RawMessage `json:"arguments,omitempty"`
+
+	// RawArguments holds the malformed arguments the model sent when
+	// Repair rewrote Arguments, and is empty otherwise.
+	RawArguments string `json:"raw_arguments,omitempty"`
+
+	// Signature is an opaque token the provider attached to the call, such
+	// as Gemini's thought signature, which must be sent back with it.
+	Signature string `json:"signature,omitempty"`
 }
 
 // ParseArgs unmarshals the tool call's JSON arguments into a ToolCallArgs map.
@@ -70,6 +110,15 @@ func (tc ToolCallData) ErrorResult(content string) Message {
 	return ToolResultMessage(tc.ID, content, true)
 }
 
+// ImageResult creates a successful tool result message for this call that
+// carries images alongside its text content. Providers whose tool results
+// cannot hold images drop them.
+func (tc ToolCallData) ImageResult(content string, images ...ImageData) Message {
+	msg := ToolResultMessage(tc.ID, content, false)
+	msg.Content[0].ToolResult.Images = images
+	return msg
+}
+
 // ToolCallArgs provides typed access to parsed tool call arguments.
 type ToolCallArgs map[string]any
 
@@ -113,9 +162,25 @@ func (a ToolCallArgs) Bool(name string) (bool, bool) {
 }
 
 type ToolResultData struct {
-	ToolCallID string `json:"tool_call_id"`
-	Content    string `json:"content"`
-	IsError    bool   `json:"is_error,omitempty"`
+	ToolCallID string          `json:"tool_call_id"`
+	Content    string          `json:"content"`
+	JSON       json.RawMessage `json:"json,omitempty"` // structured output, sent as a JSON block where supported
+	IsError    bool            `json:"is_error,omitempty"`
+	Images     []ImageData     `json:"images,omitempty"`      // visual output, such as a screenshot or chart
+	ContentRef string          `json:"content_ref,omitempty"` // BlobStore reference standing in for Content
+	JSONRef    string          `json:"json_ref,omitempty"`    // BlobStore reference standing in for JSON
+}
+
+// Text returns the result as plain text for providers without structured
+// tool output: Content, followed by JSON on its own line if both are set.
+func (tr ToolResultData) Text() string {
+	switch {
+	case len(tr.JSON) == 0:
+		return tr.Content
+	case tr.Content == "":
+		return string(tr.JSON)
+	}
+	return tr.Content + "\n" + string(tr.JSON)
 }
 
 type ThinkingData struct {
@@ -141,6 +206,18 @@ func (m Message) Text() string {
 	return b.String()
 }
 
+// Thinking concatenates the text of all thinking content parts in the
+// message.
+func (m Message) Thinking() string {
+	var b strings.Builder
+	for _, p := range m.Content {
+		if p.Kind == ContentThinking && p.Thinking != nil {
+			b.WriteString(p.Thinking.Text)
+		}
+	}
+	return b.String()
+}
+
 // ToolCalls returns all tool call content parts in the message.
 func (m Message) ToolCalls() []ToolCallData {
 	var calls []ToolCallData
@@ -152,6 +229,25 @@ func (m Message) ToolCalls() []ToolCallData {
 	return calls
 }
 
+// ToolCallsNamed returns the message's calls to the tool name, in order.
+// Models sometimes call the same tool more than once in a turn.
+func (m Message) ToolCallsNamed(name string) []ToolCallData {
+	var calls []ToolCallData
+	for _, tc := range m.ToolCalls() {
+		if tc.Name == name {
+			calls = append(calls, tc)
+		}
+	}
+	return calls
+}
+
+// HasToolCall reports whether the message calls the tool name.
+func (m Message) HasToolCall(name string) bool {
+	return slices.ContainsFunc(m.Content, func(p ContentPart) bool {
+		return p.Kind == ContentToolCall && p.ToolCall != nil && p.ToolCall.Name == name
+	})
+}
+
 // SystemMessage creates a system message with a single text part.
 func SystemMessage(text string) Message {
 	return Message{
@@ -176,6 +272,45 @@ func AssistantMessage(text string) Message {
 	}
 }
 
+// UserMessageWithImages creates a user message with a text part followed by
+// an image part for each image.
+func UserMessageWithImages(text string, images ...ImageData) Message {
+	msg := UserMessage(text)
+	for _, img := range images {
+		msg.Content = append(msg.Content, ContentPart{Kind: ContentImage, Image: &img})
+	}
+	return msg
+}
+
+// ImagePart creates an image content part from encoded image data.
+func ImagePart(data []byte, mediaType string) ContentPart {
+	return ContentPart{Kind: ContentImage, Image: &ImageData{Data: data, MediaType: mediaType}}
+}
+
+// ImageURLPart creates an image content part for an image given by URL.
+func ImageURLPart(url string) ContentPart {
+	return ContentPart{Kind: ContentImage, Image: &ImageData{URL: url}}
+}
+
+// AudioPart creates an audio content part from encoded audio data.
+func AudioPart(data []byte, mediaType string) ContentPart {
+	return ContentPart{Kind: ContentAudio, Audio: &AudioData{Data: data, MediaType: mediaType}}
+}
+
+// DocumentPart creates a document content part from a file's contents.
+func DocumentPart(name string, data []byte, mediaType string) ContentPart {
+	return ContentPart{Kind: ContentDocument, Document: &DocumentData{Name: name, Data: data, MediaType: mediaType}}
+}
+
+// CachePointPart creates a prompt cache breakpoint to place after the
+// content to cache. Bedrock sends it to Claude and Nova models, which allow
+// four breakpoints per request, including the two Converse places after
+// the system prompt and tools for Claude; the earliest message
+// breakpoints beyond that are dropped. Other models and providers drop it.
+func CachePointPart() ContentPart {
+	return ContentPart{Kind: ContentCachePoint}
+}
+
 // ToolResultMessage creates a tool result message.
 func ToolResultMessage(callID, content string, isError bool) Message {
 	return Message{
@@ -192,6 +327,29 @@ func ToolResultMessage(callID, content string, isError bool) Message {
 	}
 }
 
+// ToolResultJSON creates a tool result message whose JSON is v encoded as
+// JSON. If v cannot be encoded, the message is an error result saying so,
+// which the model can act on like any other tool failure.
+func ToolResultJSON(callID string, v any) Message {
+	return toolResultJSON(callID, v, false)
+}
+
+// ErrorResultJSON creates an error tool result message whose JSON is v
+// encoded as JSON, for structured error details.
+func ErrorResultJSON(callID string, v any) Message {
+	return toolResultJSON(callID, v, true)
+}
+
+func toolResultJSON(callID string, v any, isError bool) Message {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ToolResultMessage(callID, "encoding result: "+err.Error(), true)
+	}
+	msg := ToolResultMessage(callID, "", isError)
+	msg.Content[0].ToolResult.JSON = data
+	return msg
+}
+
 // ToolChoiceMode controls how the model selects tools.
 type ToolChoiceMode string
 
@@ -225,38 +383,81 @@ func (td ToolDefinition) ParseArgs(tc ToolCallData) (ToolCallArgs, error) {
 			return nil, err
 		}
 	}
-	for _, p := range td.params {
-		v, ok := args[p.Name]
+	if err := validateParams(td.params, args, ""); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// validateParams checks the members of an object against params. prefix is
+// the path of the object, empty at the top level.
+func validateParams(params []Param, obj map[string]any, prefix string) error {
+	for _, p := range params {
+		v, ok := obj[p.Name]
 		if !ok {
 			if p.Required {
-				return nil, fmt.Errorf("missing required parameter %q", p.Name)
+				return fmt.Errorf("missing required parameter %q", prefix+p.Name)
 			}
 			continue
 		}
-		switch p.Type {
-		case "string":
-			if _, ok := v.(string); !ok {
-				return nil, fmt.Errorf("parameter %q: expected string, got %T", p.Name, v)
-			}
-		case "number", "integer":
-			if _, ok := v.(float64); !ok {
-				return nil, fmt.Errorf("parameter %q: expected number, got %T", p.Name, v)
-			}
-		case "boolean":
-			if _, ok := v.(bool); !ok {
-				return nil, fmt.Errorf("parameter %q: expected boolean, got %T", p.Name, v)
+		if err := validateParam(p, v, prefix+p.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateParam checks a single value against p; path names it in errors,
+// such as "items[2].sku".
+func validateParam(p Param, v any, path string) error {
+	switch p.Type {
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("parameter %q: expected string, got %T", path, v)
+		}
+		if len(p.Enum) > 0 && !slices.Contains(p.Enum, s) {
+			return fmt.Errorf("parameter %q: %q is not one of %s", path, s, strings.Join(p.Enum, ", "))
+		}
+	case "number", "integer":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("parameter %q: expected number, got %T", path, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("parameter %q: expected boolean, got %T", path, v)
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("parameter %q: expected array, got %T", path, v)
+		}
+		if p.Items != nil {
+			for i, item := range items {
+				if err := validateParam(*p.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
 			}
 		}
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("parameter %q: expected object, got %T", path, v)
+		}
+		return validateParams(p.Properties, obj, path+".")
 	}
-	return args, nil
+	return nil
 }
 
 // Param describes a single tool input parameter.
 type Param struct {
 	Name        string
-	Type        string // "string", "number", "integer", "boolean"
+	Type        string // "string", "number", "integer", "boolean", "array", "object"
 	Description string
 	Required    bool
+	Enum        []string // allowed values of a string parameter
+	Items       *Param   // element type of an array parameter; its Name is unused
+	Properties  []Param  // members of an object parameter
 }
 
 func newParam(name, typ string, required bool, desc []string) Param {
@@ -299,32 +500,101 @@ func OptionalBoolParam(name string, desc ...string) Param {
 	return newParam(name, "boolean", false, desc)
 }
 
+// EnumParam creates a required string parameter limited to values.
+func EnumParam(name string, values []string, desc ...string) Param {
+	p := newParam(name, "string", true, desc)
+	p.Enum = values
+	return p
+}
+
+// OptionalEnumParam creates an optional string parameter limited to values.
+func OptionalEnumParam(name string, values []string, desc ...string) Param {
+	p := newParam(name, "string", false, desc)
+	p.Enum = values
+	return p
+}
+
+// ArrayParam creates a required array parameter whose elements match items,
+// for example ArrayParam("tags", StringParam("tag")).
+func ArrayParam(name string, items Param, desc ...string) Param {
+	p := newParam(name, "array", true, desc)
+	p.Items = &items
+	return p
+}
+
+// OptionalArrayParam creates an optional array parameter.
+func OptionalArrayParam(name string, items Param, desc ...string) Param {
+	p := newParam(name, "array", false, desc)
+	p.Items = &items
+	return p
+}
+
+// ObjectParam creates a required object parameter with the given members.
+func ObjectParam(name string, properties []Param, desc ...string) Param {
+	p := newParam(name, "object", true, desc)
+	p.Properties = properties
+	return p
+}
+
+// OptionalObjectParam creates an optional object parameter.
+func OptionalObjectParam(name string, properties []Param, desc ...string) Param {
+	p := newParam(name, "object", false, desc)
+	p.Properties = properties
+	return p
+}
+
 // NewTool creates a ToolDefinition with JSON Schema built from params.
 func NewTool(name, description string, params ...Param) ToolDefinition {
-	properties := make(map[string]map[string]string, len(params))
+	raw, _ := json.Marshal(objectSchema(params))
+	return ToolDefinition{
+		Name:        name,
+		Description: description,
+		Parameters:  raw,
+		params:      params,
+	}
+}
+
+func objectSchema(params []Param) map[string]any {
+	properties := make(map[string]map[string]any, len(params))
 	required := make([]string, 0, len(params))
 	for _, p := range params {
-		prop := map[string]string{"type": p.Type}
-		if p.Description != "" {
-			prop["description"] = p.Description
-		}
-		properties[p.Name] = prop
+		properties[p.Name] = paramSchema(p)
 		if p.Required {
 			required = append(required, p.Name)
 		}
 	}
-	schema := map[string]any{
+	return map[string]any{
 		"type":       "object",
 		"properties": properties,
 		"required":   required,
 	}
-	raw, _ := json.Marshal(schema)
-	return ToolDefinition{
-		Name:        name,
-		Description: description,
-		Parameters:  raw,
-		params:      params,
+}
+
+func paramSchema(p Param) map[string]any {
+	var prop map[string]any
+	switch p.Type {
+	case "object":
+		prop = objectSchema(p.Properties)
+	case "":
+		prop = map[string]any{} // any JSON value
+	default:
+		prop = map[string]any{"type": p.Type}
+	}
+	if p.Description != "" {
+		prop["description"] = p.Description
 	}
+	if len(p.Enum) > 0 {
+		prop["enum"] = p.Enum
+	}
+	if p.Items != nil {
+		items := paramSchema(*p.Items)
+		delete(items, "description")
+		if p.Items.Description != "" {
+			items["description"] = p.Items.Description
+		}
+		prop["items"] = items
+	}
+	return prop
 }
 
 // Config holds inference parameters for a conversation.
@@ -334,16 +604,144 @@ type Config struct {
 	TopP          *float64    `json:"top_p,omitempty"`
 	StopSequences []string    `json:"stop_sequences,omitempty"`
 	ToolChoice    *ToolChoice `json:"tool_choice,omitempty"`
+
+	// FrequencyPenalty and PresencePenalty discourage repeated tokens, in
+	// proportion to how often they have appeared and for having appeared
+	// at all. OpenAI-compatible servers and Gemini take values from -2 to
+	// 2; Bedrock's Converse API has no such settings and omits them.
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	ReasoningEffort ReasoningEffort `json:"reasoning_effort,omitempty"`
+
+	Guardrail *Guardrail `json:"guardrail,omitempty"`
+
+	// AdditionalModelRequestFields is a JSON object sent to Bedrock as
+	// Converse additionalModelRequestFields, for model parameters without
+	// a Config field. Its top-level keys replace those set for
+	// ReasoningEffort. Other providers ignore it.
+	AdditionalModelRequestFields json.RawMessage `json:"additional_model_request_fields,omitempty"`
+
+	// ProviderOptions holds a JSON object per request format, "openai" or
+	// "gemini", merged into the request body as a JSON merge patch: objects
+	// merge recursively, null removes a field, and other values replace
+	// it. The "openai" options apply to every OpenAI-compatible server,
+	// including DeepSeek. Bedrock uses AdditionalModelRequestFields.
+	ProviderOptions map[string]json.RawMessage `json:"provider_options,omitempty"`
+}
+
+// ReasoningEffort sets how much a reasoning model thinks before it replies.
+// OpenAI-compatible servers take it as reasoning_effort. Gemini and Claude
+// take a thinking token budget instead: 1024, 4096, or 16384 tokens from
+// low to high. Claude's budget must be below MaxTokens, so it is capped by
+// a lower MaxTokens, and an unset MaxTokens becomes the budget plus 4096;
+// Bedrock rejects a MaxTokens of 1024 or less as ErrConfig, since no
+// budget fits below it.
+// Claude also rejects Temperature, forced tool choice, and response
+// schemas while thinking. On Bedrock it is also sent to Nova 2 and gpt-oss
+// models; others ignore it.
+type ReasoningEffort string
+
+const (
+	ReasoningEffortLow    ReasoningEffort = "low"
+	ReasoningEffortMedium ReasoningEffort = "medium"
+	ReasoningEffortHigh   ReasoningEffort = "high"
+)
+
+// thinkingBudget returns the thinking token budget for e, or 0 if e is
+// empty or unknown.
+func (e ReasoningEffort) thinkingBudget() int {
+	switch e {
+	case ReasoningEffortLow:
+		return 1024
+	case ReasoningEffortMedium:
+		return 4096
+	case ReasoningEffortHigh:
+		return 16384
+	}
+	return 0
+}
+
+// ResponseFormatType selects the form of the model's reply.
+type ResponseFormatType string
+
+const (
+	ResponseFormatText       ResponseFormatType = "text"
+	ResponseFormatJSON       ResponseFormatType = "json_object"
+	ResponseFormatJSONSchema ResponseFormatType = "json_schema"
+)
+
+// ResponseFormat constrains the model's reply. ResponseFormatJSON is sent
+// as OpenAI's response_format and Gemini's JSON response MIME type. Bedrock
+// has no JSON mode, so for Claude and Nova models the reply is prefilled
+// with "{", which is restored to the returned text; other Bedrock models
+// are sent the request unchanged. OpenAI also requires the word "JSON" to
+// appear in the prompt.
+//
+// ResponseFormatJSONSchema asks for JSON matching Schema, through OpenAI's
+// json_schema format and Gemini's response schema. On Bedrock the model is
+// made to call a synthetic tool, named Name, whose input is the schema, so
+// the schema must describe an object; the call's arguments become the
+// reply text. Client.Send checks the reply against the schema and fails
+// with ErrInvalidResponse if it does not match.
+type ResponseFormat struct {
+	Type ResponseFormatType `json:"type"`
+
+	Name   string          `json:"name,omitempty"`   // schema name; "respond" if empty
+	Schema json.RawMessage `json:"schema,omitempty"` // JSON Schema of the reply
+	Strict bool            `json:"strict,omitempty"` // OpenAI strict mode, which restricts the schema
+}
+
+// schemaName returns the name of f's schema.
+func (f ResponseFormat) schemaName() string {
+	if f.Name == "" {
+		return "respond"
+	}
+	return f.Name
 }
 
 // Conversation represents a full conversation with a model.
 type Conversation struct {
+	SchemaVersion ConversationSchema `json:"schema_version"` // always encoded as the package's SchemaVersion
+
 	Model    string           `json:"model"`
 	System   []string         `json:"system,omitempty"`
 	Messages []Message        `json:"messages"`
 	Tools    []ToolDefinition `json:"tools,omitempty"`
 	Config   Config           `json:"config,omitempty"`
 	Usage    Usage            `json:"usage"`
+
+	// ID, timestamps, and Metadata identify and tag the conversation for
+	// persistence layers and middleware; they are never sent to the model.
+	// Client.Send sets CreatedAt on the first successful turn and UpdatedAt
+	// on every one.
+	ID        string            `json:"id,omitempty"`
+	CreatedAt time.Time         `json:"created_at,omitzero"`
+	UpdatedAt time.Time         `json:"updated_at,omitzero"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+
+	// Parent is set on conversations created by Fork.
+	Parent *ForkPoint `json:"parent,omitempty"`
+
+	// Revision counts the saves of the conversation to a Store, which
+	// uses it to reject writes based on a stale copy.
+	Revision int64 `json:"revision,omitempty"`
+
+	// UsageHistory holds one entry per assistant turn, oldest first, when
+	// the client was created with WithUsageHistory.
+	UsageHistory []TurnUsage `json:"usage_history,omitempty"`
+}
+
+// TurnUsage records the usage of a single assistant turn, for attributing
+// spend to individual turns.
+type TurnUsage struct {
+	Model   string        `json:"model"`
+	Usage   Usage         `json:"usage"`
+	Cost    float64       `json:"cost,omitempty"` // estimated USD, as in Response.Cost
+	Latency time.Duration `json:"latency"`        // time Send spent in middleware and the provider
+	At      time.Time     `json:"at"`             // when the turn completed
 }
 
 // ConversationOption is a functional option for NewConversation.
@@ -363,6 +761,22 @@ func WithTools(tools ...ToolDefinition) ConversationOption {
 	}
 }
 
+// WithMessages appends messages to the conversation, to start it with an
+// existing history.
+func WithMessages(msgs ...Message) ConversationOption {
+	return func(c *Conversation) {
+		c.Messages = append(slices.Clip(c.Messages), msgs...)
+	}
+}
+
+// WithConfig replaces the conversation's config. Options after it can
+// still set individual fields.
+func WithConfig(cfg Config) ConversationOption {
+	return func(c *Conversation) {
+		c.Config = cfg
+	}
+}
+
 // WithMaxTokens sets the max tokens config.
 func WithMaxTokens(n int) ConversationOption {
 	return func(c *Conversation) {
@@ -384,6 +798,48 @@ func WithTopP(p float64) ConversationOption {
 	}
 }
 
+// WithFrequencyPenalty sets the frequency penalty config.
+func WithFrequencyPenalty(p float64) ConversationOption {
+	return func(c *Conversation) {
+		c.Config.FrequencyPenalty = &p
+	}
+}
+
+// WithPresencePenalty sets the presence penalty config.
+func WithPresencePenalty(p float64) ConversationOption {
+	return func(c *Conversation) {
+		c.Config.PresencePenalty = &p
+	}
+}
+
+// WithResponseFormat sets the response format config.
+func WithResponseFormat(f ResponseFormat) ConversationOption {
+	return func(c *Conversation) {
+		c.Config.ResponseFormat = &f
+	}
+}
+
+// WithResponseSchema sets the response format config to JSON matching
+// schema.
+func WithResponseSchema(name string, schema json.RawMessage) ConversationOption {
+	return WithResponseFormat(ResponseFormat{Type: ResponseFormatJSONSchema, Name: name, Schema: schema})
+}
+
+// WithReasoningEffort sets the reasoning effort config.
+func WithReasoningEffort(e ReasoningEffort) ConversationOption {
+	return func(c *Conversation) {
+		c.Config.ReasoningEffort = e
+	}
+}
+
+// WithAdditionalModelRequestFields sets the additional model request
+// fields config.
+func WithAdditionalModelRequestFields(fields json.RawMessage) ConversationOption {
+	return func(c *Conversation) {
+		c.Config.AdditionalModelRequestFields = fields
+	}
+}
+
 // WithStopSequences sets the stop sequences config.
 func WithStopSequences(seqs ...string) ConversationOption {
 	return func(c *Conversation) {
@@ -398,6 +854,23 @@ func WithToolChoice(tc ToolChoice) ConversationOption {
 	}
 }
 
+// WithID sets the conversation's ID.
+func WithID(id string) ConversationOption {
+	return func(c *Conversation) {
+		c.ID = id
+	}
+}
+
+// WithMetadata sets a metadata entry on the conversation.
+func WithMetadata(key, value string) ConversationOption {
+	return func(c *Conversation) {
+		if c.Metadata == nil {
+			c.Metadata = make(map[string]string)
+		}
+		c.Metadata[key] = value
+	}
+}
+
 // NewConversation creates a Conversation with the given model and options.
 func NewConversation(model string, opts ...ConversationOption) Conversation {
 	c := Conversation{Model: model}
@@ -407,7 +880,10 @@ func NewConversation(model string, opts ...ConversationOption) Conversation {
 	return c
 }
 
-// FinishReason describes why generation stopped.
+// FinishReason describes why generation stopped. Every provider maps its
+// own values onto these constants, such as Bedrock's "end_turn" and
+// OpenAI's "tool_calls"; values with no equivalent are passed through
+// unchanged. Response.RawFinishReason keeps the provider's value.
 type FinishReason string
 
 const (
@@ -440,7 +916,41 @@ func (u Usage) Add(other Usage) Usage {
 
 // Response is the unified response from any LLM provider.
 type Response struct {
-	Message      Message      `json:"message"`
-	FinishReason FinishReason `json:"finish_reason"`
-	Usage        Usage        `json:"usage"`
+	Model           string            `json:"model"` // model that produced the response
+	Message         Message           `json:"message"`
+	FinishReason    FinishReason      `json:"finish_reason"`
+	RawFinishReason string            `json:"raw_finish_reason,omitempty"` // as the provider reported it
+	StopSequence    string            `json:"stop_sequence,omitempty"`     // the stop sequence that ended the reply, if known
+	Usage           Usage             `json:"usage"`
+	Cost            float64           `json:"cost,omitempty"` // estimated USD, 0 if the model's pricing is unknown
+	Fingerprint     Fingerprint       `json:"fingerprint"`
+	Violations      []Violation       `json:"violations,omitempty"`
+	Experiments     map[string]string `json:"experiments,omitempty"` // experiment name to assigned arm
+
+	Guardrail *GuardrailAssessment `json:"guardrail,omitempty"` // Bedrock guardrail trace, if enabled
+
+	Latency         time.Duration `json:"latency,omitempty"`          // time Client.Send spent in middleware and the provider
+	ProviderLatency time.Duration `json:"provider_latency,omitempty"` // latency the provider reported, from Bedrock only
+	Retries         int           `json:"retries,omitempty"`          // attempts retried by Retry and the AWS SDK
+
+	RequestID string `json:"request_id,omitempty"` // provider's ID for the request, from Bedrock only
+
+	// Raw is the provider's response as received, for debugging and
+	// re-parsing fields this package does not translate: the
+	// *bedrockruntime.ConverseOutput from Bedrock, or the JSON body as a
+	// json.RawMessage from HTTP providers. RawRequest is the request the
+	// provider built, in the same forms, and is kept only for contexts
+	// from WithRawRequest. Neither is serialized.
+	Raw        any `json:"-"`
+	RawRequest any `json:"-"`
+}
+
+// ToolCall returns the response's first call to the tool name. Use
+// Message.ToolCallsNamed for every call when the model repeats a tool.
+func (r *Response) ToolCall(name string) (ToolCallData, bool) {
+	calls := r.Message.ToolCallsNamed(name)
+	if len(calls) == 0 {
+		return ToolCallData{}, false
+	}
+	return calls[0], true
 }
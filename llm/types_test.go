@@ -2,7 +2,10 @@ package llm
 
 import (
 	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSystemMessage(t *testing.T) {
@@ -35,6 +38,39 @@ func TestAssistantMessage(t *testing.T) {
 	}
 }
 
+func TestUserMessageWithImages(t *testing.T) {
+	m := UserMessageWithImages("compare", ImageData{URL: "https://example.com/a.png"}, ImageData{Data: []byte("b"), MediaType: "image/png"})
+	if m.Role != RoleUser || len(m.Content) != 3 || m.Text() != "compare" {
+		t.Fatalf("got %+v", m)
+	}
+	if m.Content[1].Image.URL != "https://example.com/a.png" || string(m.Content[2].Image.Data) != "b" {
+		t.Errorf("images = %+v, %+v", m.Content[1].Image, m.Content[2].Image)
+	}
+	if err := (Conversation{Messages: []Message{m}}).Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestContentPartConstructors(t *testing.T) {
+	tests := []struct {
+		part ContentPart
+		want ContentPart
+	}{
+		{ImagePart([]byte("png"), "image/png"), ContentPart{Kind: ContentImage, Image: &ImageData{Data: []byte("png"), MediaType: "image/png"}}},
+		{ImageURLPart("s3://b/a.jpg"), ContentPart{Kind: ContentImage, Image: &ImageData{URL: "s3://b/a.jpg"}}},
+		{AudioPart([]byte("ID3"), "audio/mpeg"), ContentPart{Kind: ContentAudio, Audio: &AudioData{Data: []byte("ID3"), MediaType: "audio/mpeg"}}},
+		{DocumentPart("report.pdf", []byte("%PDF"), "application/pdf"), ContentPart{Kind: ContentDocument, Document: &DocumentData{Name: "report.pdf", Data: []byte("%PDF"), MediaType: "application/pdf"}}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.part, tt.want) {
+			t.Errorf("got %+v, want %+v", tt.part, tt.want)
+		}
+		if err := checkPart(tt.part); err != nil {
+			t.Errorf("checkPart(%s) = %v", tt.part.Kind, err)
+		}
+	}
+}
+
 func TestToolResultMessage(t *testing.T) {
 	m := ToolResultMessage("call-123", "result data", false)
 	if m.Role != RoleTool {
@@ -59,6 +95,43 @@ func TestToolResultMessageError(t *testing.T) {
 	}
 }
 
+func TestToolResultJSON(t *testing.T) {
+	m := ToolResultJSON("call-1", map[string]any{"temp": 15, "unit": "C"})
+	tr := m.Content[0].ToolResult
+	if tr.ToolCallID != "call-1" || tr.IsError || tr.Content != "" {
+		t.Errorf("unexpected tool result: %+v", tr)
+	}
+	testAssertJSONEqual(t, tr.JSON, []byte(`{"temp":15,"unit":"C"}`))
+
+	m = ErrorResultJSON("call-2", struct {
+		Code string `json:"code"`
+	}{"not_found"})
+	if tr := m.Content[0].ToolResult; !tr.IsError || tr.Text() != `{"code":"not_found"}` {
+		t.Errorf("unexpected error result: %+v", tr)
+	}
+
+	m = ToolResultJSON("call-3", make(chan int))
+	if tr := m.Content[0].ToolResult; !tr.IsError || !strings.HasPrefix(tr.Content, "encoding result: ") {
+		t.Errorf("unencodable value: %+v", tr)
+	}
+}
+
+func TestToolResultDataText(t *testing.T) {
+	tests := []struct {
+		tr   ToolResultData
+		want string
+	}{
+		{ToolResultData{Content: "done"}, "done"},
+		{ToolResultData{JSON: json.RawMessage(`{"a":1}`)}, `{"a":1}`},
+		{ToolResultData{Content: "done", JSON: json.RawMessage(`{"a":1}`)}, "done\n{\"a\":1}"},
+	}
+	for _, tt := range tests {
+		if got := tt.tr.Text(); got != tt.want {
+			t.Errorf("Text() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
 func TestMessageTextConcatenatesAllTextParts(t *testing.T) {
 	m := Message{
 		Role: RoleAssistant,
@@ -192,6 +265,15 @@ func TestNewToolNoDescription(t *testing.T) {
 	testAssertJSONEqual(t, tool.Parameters, []byte(want))
 }
 
+func TestNewToolEnumParam(t *testing.T) {
+	tool := NewTool("list_tickets", "List tickets",
+		EnumParam("status", []string{"open", "closed"}, "Ticket status"),
+		OptionalEnumParam("sort", []string{"newest", "oldest"}),
+	)
+	want := `{"type":"object","properties":{"status":{"type":"string","description":"Ticket status","enum":["open","closed"]},"sort":{"type":"string","enum":["newest","oldest"]}},"required":["status"]}`
+	testAssertJSONEqual(t, tool.Parameters, []byte(want))
+}
+
 func TestToolCallDataParseArgs(t *testing.T) {
 	tc := ToolCallData{
 		ID:        "call-1",
@@ -393,7 +475,10 @@ func TestConversationJSONRoundTrip(t *testing.T) {
 			MaxTokens:   &maxTok,
 			Temperature: &temp,
 		},
-		Usage: Usage{InputTokens: 10, OutputTokens: 5},
+		Usage:     Usage{InputTokens: 10, OutputTokens: 5},
+		ID:        "conv-1",
+		CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Metadata:  map[string]string{"tenant": "acme"},
 	}
 	data, err := json.Marshal(conv)
 	if err != nil {
@@ -424,6 +509,12 @@ func TestConversationJSONRoundTrip(t *testing.T) {
 	if restored.Usage.InputTokens != 10 {
 		t.Errorf("InputTokens = %d", restored.Usage.InputTokens)
 	}
+	if restored.ID != "conv-1" || !restored.CreatedAt.Equal(conv.CreatedAt) || restored.Metadata["tenant"] != "acme" {
+		t.Errorf("ID = %q, CreatedAt = %v, Metadata = %v", restored.ID, restored.CreatedAt, restored.Metadata)
+	}
+	if strings.Contains(string(data), "updated_at") {
+		t.Errorf("zero UpdatedAt serialized: %s", data)
+	}
 }
 
 func TestNewConversation(t *testing.T) {
@@ -451,6 +542,24 @@ func TestNewConversation(t *testing.T) {
 	}
 }
 
+func TestNewConversation_MessagesAndConfig(t *testing.T) {
+	temp := 0.2
+	history := []Message{UserMessage("hi"), AssistantMessage("hello")}
+	conv := NewConversation("my-model",
+		WithMaxTokens(10),
+		WithConfig(Config{Temperature: &temp, StopSequences: []string{"END"}}),
+		WithMaxTokens(1024),
+		WithMessages(history...),
+		WithMessages(UserMessage("again")),
+	)
+	if conv.Config.MaxTokens == nil || *conv.Config.MaxTokens != 1024 || *conv.Config.Temperature != 0.2 || len(conv.Config.StopSequences) != 1 {
+		t.Errorf("Config = %+v", conv.Config)
+	}
+	if len(conv.Messages) != 3 || conv.Messages[2].Text() != "again" {
+		t.Errorf("Messages = %+v", conv.Messages)
+	}
+}
+
 func TestMessageToolCalls(t *testing.T) {
 	m := Message{
 		Role: RoleAssistant,
@@ -468,3 +577,91 @@ func TestMessageToolCalls(t *testing.T) {
 		t.Errorf("calls = %+v", calls)
 	}
 }
+
+func TestToolCallLookup(t *testing.T) {
+	resp := &Response{Message: Message{
+		Role: RoleAssistant,
+		Content: []ContentPart{
+			{Kind: ContentToolCall, ToolCall: &ToolCallData{ID: "c1", Name: "get_user"}},
+			{Kind: ContentToolCall, ToolCall: &ToolCallData{ID: "c2", Name: "get_order"}},
+			{Kind: ContentToolCall, ToolCall: &ToolCallData{ID: "c3", Name: "get_user"}},
+		},
+	}}
+	if tc, ok := resp.ToolCall("get_user"); !ok || tc.ID != "c1" {
+		t.Errorf("ToolCall = %+v, %v", tc, ok)
+	}
+	if _, ok := resp.ToolCall("delete_user"); ok {
+		t.Error("found a call to a tool that was not called")
+	}
+	if calls := resp.Message.ToolCallsNamed("get_user"); len(calls) != 2 || calls[1].ID != "c3" {
+		t.Errorf("ToolCallsNamed = %+v", calls)
+	}
+	if !resp.Message.HasToolCall("get_order") || resp.Message.HasToolCall("delete_user") {
+		t.Error("HasToolCall mismatch")
+	}
+}
+
+func TestToolDefinitionParseArgsEnum(t *testing.T) {
+	tool := NewTool("list_tickets", "List tickets", EnumParam("status", []string{"open", "closed"}))
+
+	if _, err := tool.ParseArgs(ToolCallData{Arguments: json.RawMessage(`{"status":"open"}`)}); err != nil {
+		t.Errorf("valid value rejected: %v", err)
+	}
+	_, err := tool.ParseArgs(ToolCallData{Arguments: json.RawMessage(`{"status":"pending"}`)})
+	want := `parameter "status": "pending" is not one of open, closed`
+	if err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+}
+
+func TestNewToolArrayAndObjectParams(t *testing.T) {
+	tool := NewTool("create_order", "Create an order",
+		ArrayParam("items", ObjectParam("", []Param{
+			StringParam("sku"),
+			OptionalIntegerParam("quantity"),
+		}), "Line items"),
+		OptionalArrayParam("tags", StringParam("", "A tag")),
+		OptionalObjectParam("address", []Param{StringParam("city")}),
+	)
+	want := `{"type":"object","properties":{
+		"items":{"type":"array","description":"Line items","items":{"type":"object","properties":{"sku":{"type":"string"},"quantity":{"type":"integer"}},"required":["sku"]}},
+		"tags":{"type":"array","items":{"type":"string","description":"A tag"}},
+		"address":{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}
+	},"required":["items"]}`
+	testAssertJSONEqual(t, tool.Parameters, []byte(want))
+}
+
+func TestToolDefinitionParseArgsNested(t *testing.T) {
+	tool := NewTool("create_order", "Create an order",
+		ArrayParam("items", ObjectParam("", []Param{
+			StringParam("sku"),
+			OptionalIntegerParam("quantity"),
+		})),
+		OptionalObjectParam("address", []Param{
+			StringParam("city"),
+			OptionalEnumParam("country", []string{"US", "CA"}),
+		}),
+	)
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{`{"items":[{"sku":"a","quantity":2}],"address":{"city":"Oslo"}}`, ""},
+		{`{"items":"a"}`, `parameter "items": expected array, got string`},
+		{`{"items":[{"sku":"a"},{"quantity":1}]}`, `missing required parameter "items[1].sku"`},
+		{`{"items":[{"sku":"a","quantity":"2"}]}`, `parameter "items[0].quantity": expected number, got string`},
+		{`{"items":[],"address":"Oslo"}`, `parameter "address": expected object, got string`},
+		{`{"items":[],"address":{"city":"Oslo","country":"NO"}}`, `parameter "address.country": "NO" is not one of US, CA`},
+	}
+	for _, tt := range tests {
+		_, err := tool.ParseArgs(ToolCallData{Arguments: json.RawMessage(tt.args)})
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("ParseArgs(%s) error = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Violation describes one way a Response fails a Validator.
+type Violation struct {
+	Validator string `json:"validator"`
+	Message   string `json:"message"`
+}
+
+// Validator checks a Response for output problems.
+type Validator interface {
+	Validate(resp *Response) []Violation
+}
+
+// ValidatorFunc adapts a function to the Validator interface.
+type ValidatorFunc func(resp *Response) []Violation
+
+// Validate calls f(resp).
+func (f ValidatorFunc) Validate(resp *Response) []Violation { return f(resp) }
+
+// WithValidators runs the validators on every Response the provider returns
+// and attaches their violations to Response.Violations.
+func WithValidators(v ...Validator) ClientOption {
+	return func(c *Client) {
+		c.validators = append(c.validators, v...)
+	}
+}
+
+// WithRepair re-prompts the model up to attempts times when a Response has
+// violations, sending the invalid reply back with a description of what was
+// wrong. The repair turns are not added to the returned conversation, but
+// their usage is included in the final Response. Responses that end in
+// tool use are never repaired.
+func WithRepair(attempts int) ClientOption {
+	return func(c *Client) {
+		c.repairAttempts = attempts
+	}
+}
+
+// sendValidated calls the provider and runs validation and repair.
+func (c *Client) sendValidated(ctx context.Context, conv *Conversation) (*Response, error) {
+	resp, err := c.invoke(ctx, conv)
+	if err != nil || len(c.validators) == 0 {
+		return resp, err
+	}
+
+	attempt := *conv
+	var spent Usage
+	for i := 0; ; i++ {
+		resp.Violations = nil
+		for _, v := range c.validators {
+			resp.Violations = append(resp.Violations, v.Validate(resp)...)
+		}
+		if len(resp.Violations) == 0 || i >= c.repairAttempts || resp.FinishReason == FinishReasonToolUse {
+			resp.Usage = spent.Add(resp.Usage)
+			return resp, nil
+		}
+
+		spent = spent.Add(resp.Usage)
+		attempt.Messages = append(append([]Message(nil), attempt.Messages...), resp.Message, UserMessage(repairPrompt(resp.Violations)))
+		resp, err = c.invoke(ctx, &attempt)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func repairPrompt(violations []Violation) string {
+	var b strings.Builder
+	b.WriteString("Your previous response was invalid:\n")
+	for _, v := range violations {
+		fmt.Fprintf(&b, "- %s\n", v.Message)
+	}
+	b.WriteString("Respond again, fixing these problems.")
+	return b.String()
+}
+
+// MaxLength returns a Validator that rejects reply text longer than n characters.
+func MaxLength(n int) Validator {
+	return ValidatorFunc(func(resp *Response) []Violation {
+		if l := utf8.RuneCountInString(resp.Message.Text()); l > n {
+			return []Violation{{Validator: "max_length", Message: fmt.Sprintf("response is %d characters, limit is %d", l, n)}}
+		}
+		return nil
+	})
+}
+
+// RequiredJSONKeys returns a Validator that requires the reply text to be a
+// JSON object containing every key.
+func RequiredJSONKeys(keys ...string) Validator {
+	return ValidatorFunc(func(resp *Response) []Violation {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(strings.TrimSpace(resp.Message.Text())), &obj); err != nil {
+			return []Violation{{Validator: "required_json_keys", Message: "response is not a JSON object"}}
+		}
+		var violations []Violation
+		for _, k := range keys {
+			if _, ok := obj[k]; !ok {
+				violations = append(violations, Violation{Validator: "required_json_keys", Message: fmt.Sprintf("missing required key %q", k)})
+			}
+		}
+		return violations
+	})
+}
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// scriptedProvider returns its responses in order and records the
+// conversations it receives.
+type scriptedProvider struct {
+	responses []*Response
+	received  []Conversation
+}
+
+func (p *scriptedProvider) Send(_ context.Context, conv *Conversation) (*Response, error) {
+	p.received = append(p.received, *conv)
+	resp := *p.responses[0]
+	p.responses = p.responses[1:]
+	return &resp, nil
+}
+
+func TestMaxLength(t *testing.T) {
+	if v := MaxLength(5).Validate(simpleResponse("hello")); len(v) != 0 {
+		t.Errorf("violations = %+v", v)
+	}
+	v := MaxLength(4).Validate(simpleResponse("hello"))
+	if len(v) != 1 || v[0].Validator != "max_length" {
+		t.Errorf("violations = %+v", v)
+	}
+}
+
+func TestRequiredJSONKeys(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{`{"name":"a","age":1}`, 0},
+		{`{"name":"a"}`, 1},
+		{`{}`, 2},
+		{`not json`, 1},
+	}
+	for _, tt := range tests {
+		if v := RequiredJSONKeys("name", "age").Validate(simpleResponse(tt.text)); len(v) != tt.want {
+			t.Errorf("%q: violations = %+v, want %d", tt.text, v, tt.want)
+		}
+	}
+}
+
+func TestClientSend_AttachesViolations(t *testing.T) {
+	client := NewClientWithProvider(&mockProvider{resp: simpleResponse("too long")}, WithValidators(MaxLength(3)))
+	_, resp, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Violations) != 1 {
+		t.Errorf("Violations = %+v", resp.Violations)
+	}
+}
+
+func TestClientSend_Repair(t *testing.T) {
+	provider := &scriptedProvider{responses: []*Response{
+		simpleResponse(`{"name":"a"}`),
+		simpleResponse(`{"name":"a","age":1}`),
+	}}
+	client := NewClientWithProvider(provider, WithValidators(RequiredJSONKeys("name", "age")), WithRepair(2))
+
+	conv, resp, err := client.Send(context.Background(), NewConversation("model"), UserMessage("json please"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Violations) != 0 {
+		t.Errorf("Violations = %+v", resp.Violations)
+	}
+	if resp.Message.Text() != `{"name":"a","age":1}` {
+		t.Errorf("Text = %q", resp.Message.Text())
+	}
+	if resp.Usage.InputTokens != 20 {
+		t.Errorf("InputTokens = %d, want 20 (both attempts)", resp.Usage.InputTokens)
+	}
+	// Repair turns are not persisted.
+	if len(conv.Messages) != 2 {
+		t.Errorf("Messages len = %d, want 2", len(conv.Messages))
+	}
+	// The repair request carries the invalid reply and the feedback.
+	repair := provider.received[1].Messages
+	if len(repair) != 3 || !strings.Contains(repair[2].Text(), `missing required key "age"`) {
+		t.Errorf("repair messages = %+v", repair)
+	}
+}
+
+func TestClientSend_RepairGivesUp(t *testing.T) {
+	provider := &scriptedProvider{responses: []*Response{
+		simpleResponse("bad"), simpleResponse("still bad"),
+	}}
+	client := NewClientWithProvider(provider, WithValidators(RequiredJSONKeys("x")), WithRepair(1))
+
+	_, resp, err := client.Send(context.Background(), NewConversation("model"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.received) != 2 {
+		t.Errorf("provider calls = %d, want 2", len(provider.received))
+	}
+	if len(resp.Violations) == 0 {
+		t.Error("expected remaining violations")
+	}
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersion is the version of the Conversation JSON schema this package
+// writes. Serialized conversations carry it in a "schema_version" field, and
+// older payloads are upgraded by Migrate when they are decoded with
+// DecodeConversation, so stored conversations and long-lived workflow
+// histories keep loading after the schema changes.
+const SchemaVersion = 1
+
+// migrations[v] upgrades a version v payload to version v+1 in place.
+// Payloads written before the version field was added are version 0.
+var migrations = []func(fields map[string]json.RawMessage) error{
+	0: func(map[string]json.RawMessage) error { return nil }, // only the version field was added
+}
+
+// Migrate upgrades a serialized Conversation to SchemaVersion. Payloads
+// already at SchemaVersion are returned unchanged; payloads from a newer
+// version of this package are rejected rather than silently losing fields.
+// DecodeConversation calls Migrate, so it is only needed to rewrite stored
+// payloads.
+func Migrate(data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	version := 0
+	if raw, ok := fields["schema_version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, fmt.Errorf("schema_version: %w", err)
+		}
+	}
+	switch {
+	case version == SchemaVersion:
+		return data, nil
+	case version > SchemaVersion:
+		return nil, fmt.Errorf("conversation schema version %d is newer than supported version %d", version, SchemaVersion)
+	case version < 0:
+		return nil, fmt.Errorf("invalid conversation schema version %d", version)
+	}
+	for ; version < SchemaVersion; version++ {
+		if err := migrations[version](fields); err != nil {
+			return nil, fmt.Errorf("migrating conversation from schema version %d: %w", version, err)
+		}
+	}
+	fields["schema_version"], _ = json.Marshal(SchemaVersion)
+	return json.Marshal(fields)
+}
+
+// DecodeConversation decodes a JSON-encoded conversation, upgrading older
+// schema versions with Migrate first. Plain json.Unmarshal also decodes
+// conversations, and rejects newer versions, but does not migrate.
+func DecodeConversation(data []byte) (Conversation, error) {
+	var conv Conversation
+	data, err := Migrate(data)
+	if err != nil {
+		return conv, err
+	}
+	err = json.Unmarshal(data, &conv)
+	return conv, err
+}
+
+// ConversationSchema is the type of Conversation.SchemaVersion. It has no
+// state: it always encodes as SchemaVersion, since a Conversation in memory
+// has the current schema, and decoding rejects payloads from a newer
+// version of this package rather than silently losing fields. Keeping the
+// methods on this field rather than on Conversation lets types that embed
+// a Conversation, such as workflow state, encode their own fields too.
+type ConversationSchema struct{}
+
+// MarshalJSON implements json.Marshaler.
+func (ConversationSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(SchemaVersion)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (*ConversationSchema) UnmarshalJSON(data []byte) error {
+	var version int
+	if err := json.Unmarshal(data, &version); err != nil {
+		return fmt.Errorf("schema_version: %w", err)
+	}
+	if version < 0 || version > SchemaVersion {
+		return fmt.Errorf("conversation schema version %d is not supported; this package reads up to version %d", version, SchemaVersion)
+	}
+	return nil
+}
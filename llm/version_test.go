@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConversation_SchemaVersion(t *testing.T) {
+	conv := NewConversation("model", WithSystem("be brief"))
+	conv.Messages = []Message{UserMessage("hi")}
+	data, err := json.Marshal(conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), `{"schema_version":1,"model":"model"`) {
+		t.Errorf("json = %s", data)
+	}
+
+	var restored Conversation
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Model != "model" || restored.Messages[0].Text() != "hi" {
+		t.Errorf("restored = %+v", restored)
+	}
+
+	// Pointer and value encodings match.
+	if ptr, _ := json.Marshal(&conv); string(ptr) != string(data) {
+		t.Errorf("pointer json = %s", ptr)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	unversioned := `{"model":"m","system":["s"],"messages":[{"role":"user","content":[{"kind":"text","text":"hi"}]}],"usage":{"input_tokens":3,"output_tokens":0}}`
+	data, err := Migrate([]byte(unversioned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"schema_version":1`) {
+		t.Errorf("migrated = %s", data)
+	}
+
+	conv, err := DecodeConversation([]byte(unversioned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.Model != "m" || conv.Usage.InputTokens != 3 || conv.Messages[0].Text() != "hi" {
+		t.Errorf("conv = %+v", conv)
+	}
+
+	current := `{"schema_version":1,"model":"m","messages":null,"usage":{"input_tokens":0,"output_tokens":0}}`
+	if data, err := Migrate([]byte(current)); err != nil || string(data) != current {
+		t.Errorf("Migrate(current) = %s, %v", data, err)
+	}
+
+	for _, bad := range []string{`{"schema_version":99,"model":"m"}`, `{"schema_version":"one"}`, `[]`} {
+		if err := json.Unmarshal([]byte(bad), &conv); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded", bad)
+		}
+		if _, err := DecodeConversation([]byte(bad)); err == nil {
+			t.Errorf("DecodeConversation(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestConversation_Embedded(t *testing.T) {
+	type workflowState struct {
+		Conversation
+		Step int `json:"step"`
+	}
+	state := workflowState{Conversation: NewConversation("model"), Step: 3}
+	state.Messages = []Message{UserMessage("hi")}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"step":3`) || !strings.Contains(string(data), `"schema_version":1`) {
+		t.Errorf("json = %s", data)
+	}
+
+	var restored workflowState
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Step != 3 || restored.Model != "model" || restored.Messages[0].Text() != "hi" {
+		t.Errorf("restored = %+v", restored)
+	}
+}